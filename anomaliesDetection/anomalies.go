@@ -16,6 +16,7 @@ package anomalies
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"time"
@@ -25,14 +26,18 @@ import (
 
 	"github.com/trackit/trackit/aws"
 	"github.com/trackit/trackit/aws/s3"
+	"github.com/trackit/trackit/config"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
 	"github.com/trackit/trackit/es"
 )
 
 type (
-	// AnalyzedCostProductMeta can be the additional metadata in AnalyzedCostEssentialMeta.
-	// It's used to detect product anomalies and store them in ElasticSearch with more info.
-	AnalyzedCostProductMeta struct {
-		Product string
+	// AnalyzedCostDimensionMeta can be the additional metadata in AnalyzedCostEssentialMeta.
+	// It's used to detect anomalies on a value of a dimension (e.g. a product or
+	// a region) and store them in ElasticSearch with more info.
+	AnalyzedCostDimensionMeta struct {
+		Dimension string
+		Value     string
 	}
 
 	// AnalyzedCostEssentialMeta is the mandatory metadata ignored by the algorithm
@@ -55,50 +60,64 @@ type (
 	AnalyzedCosts []AnalyzedCost
 
 	// AnomalyEsQueryParams will store the parsed query params
+	// and the detector used to analyse the costs.
 	AnomalyEsQueryParams struct {
 		DateBegin time.Time
 		DateEnd   time.Time
 		Account   string
 		Index     string
+		Detector  Detector
+		Cleaning  anomalyType.Cleaning
 	}
 
-	// ElasticSearchFunction is a function passed to makeElasticSearchRequest,
-	// used to get results from ElasticSearch.
-	ElasticSearchFunction func(
-		account string,
-		durationBegin time.Time,
-		durationEnd time.Time,
-		aggregationPeriod string,
-		client *elastic.Client,
-		index string,
-	) *elastic.SearchService
-
 	// elasticSearchDateElem is used to get usageStartDate from awsdetailedlineitems.
 	elasticSearchDateElem struct {
 		UsageStartDate string `json:"usageStartDate"`
 	}
 )
 
-// RunAnomaliesDetection run every anomaly detection algorithms and store results in ElasticSearch.
-func RunAnomaliesDetection(account aws.AwsAccount, lastUpdate time.Time, ctx context.Context) (time.Time, error) {
+// RunAnomaliesDetection run every anomaly detection algorithms with the
+// settings of the AWS account and store results in ElasticSearch.
+func RunAnomaliesDetection(tx *sql.Tx, account aws.AwsAccount, lastUpdate time.Time, ctx context.Context) (time.Time, error) {
 	esIndex := es.IndexNameForUserId(account.UserId, s3.IndexPrefixLineItem)
 	begin, end, err := getDateRange(account, lastUpdate, ctx)
 	if err != nil {
 		return begin, err
 	}
+	settings, err := GetSettings(tx, account)
+	if err != nil {
+		return begin, err
+	}
+	detector, err := settings.Detector(account.AnomalyDetector, nil)
+	if err != nil {
+		return begin, err
+	}
 	logger := jsonlog.LoggerFromContextOrDefault(ctx)
 	logger.Info("Starting anomalies detection", map[string]interface{}{
 		"awsAccount": account.Id,
 		"begin":      begin,
 		"end":        end,
+		"detector":   detector.Description(),
 	})
 	parsedParams := AnomalyEsQueryParams{
 		DateBegin: begin,
 		DateEnd:   end,
 		Account:   account.AwsIdentity,
 		Index:     esIndex,
+		Detector:  detector,
+		Cleaning:  settings.Cleaning,
+	}
+	return end, runAnomaliesDetectionForDimensions(parsedParams, account, ctx)
+}
+
+// DefaultCleaning returns the cleaning thresholds set in config.
+func DefaultCleaning() anomalyType.Cleaning {
+	return anomalyType.Cleaning{
+		MinPercentOfDailyBill:  config.AnomalyDetectionDisturbanceCleaningMinPercentOfDailyBill,
+		MinAbsoluteCost:        config.AnomalyDetectionDisturbanceCleaningMinAbsoluteCost,
+		HighestSpendingMinRank: config.AnomalyDetectionDisturbanceCleaningHighestSpendingMinRank,
+		RecurrenceThreshold:    config.AnomalyDetectionRecurrenceCleaningThreshold,
 	}
-	return end, runAnomaliesDetectionForProducts(parsedParams, account, ctx)
 }
 
 // makeElasticSearchDateRangeRequest makes the ElasticSearch request to get begin or end date
@@ -172,13 +191,16 @@ func deleteOffset(aCosts AnalyzedCosts, dateBegin time.Time) AnalyzedCosts {
 	return aCosts
 }
 
-// makeElasticSearchRequest prepares and run the request to retrieve the cost anomalies.
+// makeElasticSearchRequest prepares and run the request to retrieve the daily
+// costs of every value of a dimension.
 // It will return the data and an error.
-func makeElasticSearchRequest(ctx context.Context, esFct ElasticSearchFunction, parsedParams AnomalyEsQueryParams) (*elastic.SearchResult, error) {
-	searchService := esFct(
-		parsedParams.Account,
+func makeElasticSearchRequest(ctx context.Context, accountFilter elastic.Query, dim dimension, parsedParams AnomalyEsQueryParams) (*elastic.SearchResult, error) {
+	searchService := getDimensionElasticSearchParams(
+		accountFilter,
+		dim,
 		parsedParams.DateBegin,
 		parsedParams.DateEnd,
+		parsedParams.Detector.History(),
 		"day",
 		es.Client,
 		parsedParams.Index,
@@ -0,0 +1,98 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"context"
+	"time"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/aws/s3"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+	"github.com/trackit/trackit/es"
+)
+
+// Backtest runs the anomaly detection and the cleaning steps on the costs of
+// an AWS account between begin and end, like RunAnomaliesDetection, with the
+// given detector and cleaning thresholds. Nothing is saved in ElasticSearch:
+// the anomalies which would have been produced are returned by dimension and
+// by value of the dimension.
+func Backtest(ctx context.Context, account aws.AwsAccount, begin, end time.Time, detector Detector, cleaning anomalyType.Cleaning) (map[string]anomalyType.ProductAnomalies, error) {
+	dimensions, err := getDimensions()
+	if err != nil {
+		return nil, err
+	}
+	params := AnomalyEsQueryParams{
+		DateBegin: begin,
+		DateEnd:   end,
+		Account:   account.AwsIdentity,
+		Index:     es.IndexNameForUserId(account.UserId, s3.IndexPrefixLineItem),
+		Detector:  detector,
+		Cleaning:  cleaning,
+	}
+	description := detector.Description()
+	docs := make(esProductAnomaliesWithId, 0)
+	for _, dim := range dimensions {
+		aCosts, err := getAnomaliesData(ctx, params, account, dim)
+		if err != nil {
+			return nil, err
+		}
+		for _, aCost := range aCosts {
+			doc := newEsProductAnomaly(aCost, account, description)
+			id, err := generateElasticSearchDocumentId(doc)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, esProductAnomalyWithId{Source: doc, Id: id})
+		}
+	}
+	return getBacktestAnomalies(docs, cleaning.RecurrenceThreshold), nil
+}
+
+// getBacktestAnomalies flags the recurrent anomalies with the threshold t and
+// returns the abnormal costs by dimension and by value of the dimension.
+func getBacktestAnomalies(docs esProductAnomaliesWithId, t float64) map[string]anomalyType.ProductAnomalies {
+	recurrent := make(map[string]bool)
+	for _, an := range transformAnomaliesToMap(docs) {
+		for _, recurrentAnomaly := range detectRecurrence(an, t) {
+			recurrent[recurrentAnomaly.Id] = true
+		}
+	}
+	res := make(map[string]anomalyType.ProductAnomalies)
+	for _, doc := range docs {
+		if !doc.Source.Abnormal {
+			continue
+		}
+		date, err := time.Parse("2006-01-02T15:04:05.000Z", doc.Source.Date)
+		if err != nil {
+			continue
+		}
+		dimension, value := doc.Source.getDimensionAndValue()
+		if res[dimension] == nil {
+			res[dimension] = make(anomalyType.ProductAnomalies)
+		}
+		res[dimension][value] = append(res[dimension][value], anomalyType.ProductAnomaly{
+			Id:        doc.Id,
+			Date:      date,
+			Cost:      doc.Source.Cost.Value,
+			UpperBand: doc.Source.Cost.MaxExpected,
+			Abnormal:  true,
+			Recurrent: recurrent[doc.Id],
+			Detector:  doc.Source.Detector,
+			State:     anomalyType.AnomalyStateOpen,
+		})
+	}
+	return res
+}
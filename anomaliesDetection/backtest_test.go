@@ -0,0 +1,59 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"testing"
+)
+
+func newTestBacktestDoc(id, date, dimension, value string, cost float64, abnormal bool) esProductAnomalyWithId {
+	return esProductAnomalyWithId{
+		Id: id,
+		Source: esProductAnomaly{
+			Date:           date,
+			Dimension:      dimension,
+			DimensionValue: value,
+			Abnormal:       abnormal,
+			Cost: esProductAnomalyCost{
+				Value:       cost,
+				MaxExpected: 10,
+			},
+		},
+	}
+}
+
+func TestGetBacktestAnomalies(t *testing.T) {
+	docs := esProductAnomaliesWithId{
+		newTestBacktestDoc("a", "2019-01-05T00:00:00.000Z", DimensionProduct, "AmazonEC2", 100, true),
+		newTestBacktestDoc("b", "2019-02-05T00:00:00.000Z", DimensionProduct, "AmazonEC2", 105, true),
+		newTestBacktestDoc("c", "2019-02-06T00:00:00.000Z", DimensionProduct, "AmazonEC2", 8, false),
+		newTestBacktestDoc("d", "2019-02-05T00:00:00.000Z", DimensionRegion, "us-east-1", 200, true),
+	}
+	res := getBacktestAnomalies(docs, 0.1)
+	if len(res) != 2 || len(res[DimensionProduct]["AmazonEC2"]) != 2 || len(res[DimensionRegion]["us-east-1"]) != 1 {
+		t.Fatalf("Unexpected anomalies: %v", res)
+	}
+	for _, an := range res[DimensionProduct]["AmazonEC2"] {
+		if an.Recurrent != (an.Id == "b") {
+			t.Errorf("Anomaly %s recurrent is %t", an.Id, an.Recurrent)
+		}
+	}
+	if res[DimensionRegion]["us-east-1"][0].Recurrent {
+		t.Errorf("Anomaly d should not be recurrent")
+	}
+	if res := getBacktestAnomalies(docs, 0.01); res[DimensionProduct]["AmazonEC2"][1].Recurrent {
+		t.Errorf("Anomaly b should not be recurrent with a 1%% threshold")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/trackit/trackit/config"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
 )
 
 // min returns the minimum between a and b.
@@ -60,19 +61,53 @@ func deviation(sigma float64, period int) float64 {
 	return deviation
 }
 
-// analyseAnomalies calculates anomalies with Bollinger Bands algorithm and
-// const values above. It consists in generating an upper band, which, if
-// exceeded, make an alert.
-func analyseAnomalies(aCosts AnalyzedCosts) AnalyzedCosts {
+// bollingerDetector detects anomalies with the Bollinger Bands algorithm. It
+// consists in generating an upper band from the average and the standard
+// deviation of the costs of the previous days, which, if exceeded, make an alert.
+type bollingerDetector struct {
+	period                       int
+	standardDeviationCoefficient float64
+	upperBandCoefficient         float64
+}
+
+// newBollingerDetector creates a bollingerDetector with the parameters in config
+// overridden by parameters.
+func newBollingerDetector(parameters detectorParameters) Detector {
+	return bollingerDetector{
+		period:                       parameters.getInt("period", config.AnomalyDetectionBollingerBandPeriod),
+		standardDeviationCoefficient: parameters.get("standardDeviationCoefficient", config.AnomalyDetectionBollingerBandStandardDeviationCoefficient),
+		upperBandCoefficient:         parameters.get("upperBandCoefficient", config.AnomalyDetectionBollingerBandUpperBandCoefficient),
+	}
+}
+
+// Description returns the name and the parameters of the detector.
+func (d bollingerDetector) Description() anomalyType.Detector {
+	return anomalyType.Detector{
+		Name: DetectorBollinger,
+		Parameters: map[string]float64{
+			"period":                       float64(d.period),
+			"standardDeviationCoefficient": d.standardDeviationCoefficient,
+			"upperBandCoefficient":         d.upperBandCoefficient,
+		},
+	}
+}
+
+// History returns the period of the Bollinger Bands.
+func (d bollingerDetector) History() int {
+	return d.period
+}
+
+// Analyse calculates anomalies with Bollinger Bands algorithm.
+func (d bollingerDetector) Analyse(aCosts AnalyzedCosts) AnalyzedCosts {
 	for index := range aCosts {
 		if index > 0 {
 			a := &aCosts[index]
-			tempSliceSize := min(index, config.AnomalyDetectionBollingerBandPeriod)
+			tempSliceSize := min(index, d.period)
 			tempSlice := aCosts[index-tempSliceSize : index]
 			avg := average(tempSlice)
 			sigma := sigma(tempSlice, avg)
 			deviation := deviation(sigma, tempSliceSize)
-			a.UpperBand = avg*config.AnomalyDetectionBollingerBandUpperBandCoefficient + (deviation * config.AnomalyDetectionBollingerBandStandardDeviationCoefficient)
+			a.UpperBand = avg*d.upperBandCoefficient + (deviation * d.standardDeviationCoefficient)
 			if a.Cost > a.UpperBand {
 				a.Anomaly = true
 			}
@@ -99,9 +134,9 @@ func addPadding(aCosts AnalyzedCosts, dateBegin time.Time) AnalyzedCosts {
 }
 
 // computeAnomalies calls every functions to well format
-// AnalyzedCosts and do BollingerBand.
-func computeAnomalies(ctx context.Context, aCosts AnalyzedCosts, dateBegin time.Time) AnalyzedCosts {
+// AnalyzedCosts and runs the anomaly detector on them.
+func computeAnomalies(ctx context.Context, aCosts AnalyzedCosts, dateBegin time.Time, detector Detector) AnalyzedCosts {
 	aCosts = addPadding(aCosts, dateBegin)
-	aCosts = analyseAnomalies(aCosts)
+	aCosts = detector.Analyse(aCosts)
 	return aCosts
 }
@@ -0,0 +1,148 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/trackit/trackit/config"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+)
+
+const (
+	DetectorBollinger       = "bollinger"
+	DetectorWeekdaySeasonal = "weekday-seasonal"
+	DetectorEwma            = "ewma"
+	DetectorMad             = "mad"
+)
+
+type (
+	// Detector is an anomaly detection algorithm.
+	Detector interface {
+		// Description returns the name and the parameters of the detector.
+		// It is stored with the analysed costs to keep the anomalies explainable.
+		Description() anomalyType.Detector
+		// History returns the number of days preceding the analysed period
+		// the detector needs to compute the expected costs.
+		History() int
+		// Analyse sets the upper band of every cost, sorted by date, from the
+		// costs preceding it and flags the costs exceeding their upper band.
+		Analyse(aCosts AnalyzedCosts) AnalyzedCosts
+	}
+
+	// detectorParameters are the parameters of a detector, by name, which
+	// override the ones in config.
+	detectorParameters map[string]float64
+
+	// parameterRange is the range of the valid values of a parameter, min
+	// excluded and max included.
+	parameterRange struct {
+		min float64
+		max float64
+	}
+)
+
+// parameterRanges are the valid values of the parameters of the detectors.
+// The alpha of an exponentially weighted moving average must be at most 1 for
+// the variance to stay positive, and the histories are bounded so that a
+// detection does not aggregate years of costs.
+var parameterRanges = map[string]parameterRange{
+	"alpha":                        {0, 1},
+	"period":                       {1, 365},
+	"weeks":                        {0, 52},
+	"standardDeviationCoefficient": {0, 100},
+	"upperBandCoefficient":         {0, 100},
+	"threshold":                    {0, 100},
+}
+
+// valid returns true if value is in the range.
+func (r parameterRange) valid(value float64) bool {
+	return value > r.min && value <= r.max
+}
+
+// detectors maps the name of a detector to its constructor.
+var detectors = map[string]func(detectorParameters) Detector{
+	DetectorBollinger:       newBollingerDetector,
+	DetectorWeekdaySeasonal: newWeekdaySeasonalDetector,
+	DetectorEwma:            newEwmaDetector,
+	DetectorMad:             newMadDetector,
+}
+
+// get returns the parameter name, or defaultValue if it is not set.
+func (p detectorParameters) get(name string, defaultValue float64) float64 {
+	if value, ok := p[name]; ok {
+		return value
+	}
+	return defaultValue
+}
+
+// getInt returns the integer parameter name, or defaultValue if it is not set.
+func (p detectorParameters) getInt(name string, defaultValue int) int {
+	if value, ok := p[name]; ok {
+		return int(value)
+	}
+	return defaultValue
+}
+
+// GetDetector returns the detector registered under name. An empty name
+// returns the default detector set in config.
+func GetDetector(name string) (Detector, error) {
+	return GetDetectorWithParameters(name, nil)
+}
+
+// GetDetectorWithParameters returns the detector registered under name with
+// the parameters in config overridden by parameters. An empty name returns
+// the default detector set in config. Every parameter has to be a parameter
+// of the detector within its range in parameterRanges, and an integer for
+// integer ones.
+func GetDetectorWithParameters(name string, parameters map[string]float64) (Detector, error) {
+	if name == "" {
+		name = config.AnomalyDetectionDefaultDetector
+	}
+	newDetector, ok := detectors[name]
+	if !ok {
+		return nil, errors.New("Unknown anomaly detector: " + name)
+	}
+	detector := newDetector(parameters)
+	description := detector.Description()
+	for parameter, value := range parameters {
+		if actual, ok := description.Parameters[parameter]; !ok {
+			return nil, fmt.Errorf("Unknown parameter %s for anomaly detector %s.", parameter, name)
+		} else if r := parameterRanges[parameter]; !r.valid(value) {
+			return nil, fmt.Errorf("Invalid value %v for parameter %s of anomaly detector %s, it must be greater than %v and at most %v.", value, parameter, name, r.min, r.max)
+		} else if actual != value {
+			return nil, fmt.Errorf("Invalid value %v for parameter %s of anomaly detector %s, it must be an integer.", value, parameter, name)
+		}
+	}
+	return detector, nil
+}
+
+// IsDetector returns true if name is the name of a detector.
+func IsDetector(name string) bool {
+	_, ok := detectors[name]
+	return ok
+}
+
+// DetectorNames returns the names of the available detectors.
+func DetectorNames() []string {
+	names := make([]string, 0, len(detectors))
+	for name := range detectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,147 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"math"
+	"testing"
+)
+
+func newTestAnalyzedCosts(costs ...float64) AnalyzedCosts {
+	aCosts := make(AnalyzedCosts, len(costs))
+	for i, cost := range costs {
+		aCosts[i].Cost = cost
+	}
+	return aCosts
+}
+
+func checkAnomalies(t *testing.T, detector Detector, aCosts AnalyzedCosts, expected ...int) {
+	aCosts = detector.Analyse(aCosts)
+	abnormal := make(map[int]bool)
+	for _, index := range expected {
+		abnormal[index] = true
+	}
+	for index, aCost := range aCosts {
+		if aCost.Anomaly != abnormal[index] {
+			t.Errorf("%s: cost %d (%f, upper band %f) abnormal is %t instead of %t",
+				detector.Description().Name, index, aCost.Cost, aCost.UpperBand, aCost.Anomaly, abnormal[index])
+		}
+	}
+}
+
+func TestBollingerDetector(t *testing.T) {
+	detector := bollingerDetector{period: 3, standardDeviationCoefficient: 3.0, upperBandCoefficient: 1.05}
+	checkAnomalies(t, detector, newTestAnalyzedCosts(10, 10, 10.2, 10, 30, 10), 4)
+}
+
+func TestWeekdaySeasonalDetector(t *testing.T) {
+	detector := weekdaySeasonalDetector{weeks: 2, standardDeviationCoefficient: 3.0, upperBandCoefficient: 1.05}
+	// Costs are low on weekends: a saturday cost lower than the weekdays costs
+	// but much higher than the previous saturdays costs is abnormal
+	week := []float64{100, 100, 100, 100, 100, 10, 10}
+	costs := append(append(append([]float64{}, week...), week...), week...)
+	costs[17] = 200
+	costs[19] = 60
+	checkAnomalies(t, detector, newTestAnalyzedCosts(costs...), 17, 19)
+	bollinger := bollingerDetector{period: 3, standardDeviationCoefficient: 3.0, upperBandCoefficient: 1.05}
+	checkAnomalies(t, bollinger, newTestAnalyzedCosts(costs...), 17)
+}
+
+func TestEwmaDetector(t *testing.T) {
+	detector := ewmaDetector{alpha: 0.3, standardDeviationCoefficient: 3.0, upperBandCoefficient: 1.05}
+	checkAnomalies(t, detector, newTestAnalyzedCosts(10, 10, 10.5, 10, 9.5, 10, 25, 10), 6)
+	if history := detector.History(); history != 7 {
+		t.Errorf("History is %d instead of 7", history)
+	}
+}
+
+func TestMadDetector(t *testing.T) {
+	detector := madDetector{period: 5, threshold: 3.5, upperBandCoefficient: 1.2}
+	costs := []float64{10, 10, 11, 10, 100, 11, 60, 10}
+	// The high cost of the day 4 does not hide the anomaly of the day 6
+	checkAnomalies(t, detector, newTestAnalyzedCosts(costs...), 4, 6)
+	bollinger := bollingerDetector{period: 3, standardDeviationCoefficient: 3.0, upperBandCoefficient: 1.05}
+	checkAnomalies(t, bollinger, newTestAnalyzedCosts(costs...), 2, 4)
+}
+
+func TestMedian(t *testing.T) {
+	if m := median([]float64{3, 1, 2}); m != 2 {
+		t.Errorf("Median is %f instead of 2", m)
+	}
+	if m := median([]float64{4, 1, 2, 3}); m != 2.5 {
+		t.Errorf("Median is %f instead of 2.5", m)
+	}
+}
+
+func TestDetectorDescription(t *testing.T) {
+	description := madDetector{period: 5, threshold: 3.5, upperBandCoefficient: 1.0}.Description()
+	if description.Name != DetectorMad || description.Parameters["period"] != 5 || description.Parameters["threshold"] != 3.5 {
+		t.Errorf("Unexpected description: %v", description)
+	}
+	for _, name := range DetectorNames() {
+		if !IsDetector(name) {
+			t.Errorf("%s is not a detector", name)
+		}
+	}
+	if IsDetector("unknown") {
+		t.Errorf("unknown should not be a detector")
+	}
+}
+
+func TestGetDetectorWithParameters(t *testing.T) {
+	detector, err := GetDetectorWithParameters(DetectorMad, map[string]float64{"period": 7, "threshold": 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if d, ok := detector.(madDetector); !ok || d.period != 7 || d.threshold != 4 {
+		t.Errorf("Unexpected detector: %v", detector)
+	}
+	for _, parameters := range []map[string]float64{
+		{"alpha": 0.5},
+		{"period": 2.5},
+		{"period": -1},
+		{"period": 1},
+		{"period": 366},
+		{"threshold": math.Inf(1)},
+		{"threshold": math.NaN()},
+	} {
+		if _, err := GetDetectorWithParameters(DetectorMad, parameters); err == nil {
+			t.Errorf("Parameters %v should be invalid", parameters)
+		}
+	}
+	for _, parameters := range []map[string]float64{
+		{"alpha": 5},
+		{"alpha": 0},
+		{"standardDeviationCoefficient": 1000},
+	} {
+		if _, err := GetDetectorWithParameters(DetectorEwma, parameters); err == nil {
+			t.Errorf("Parameters %v should be invalid", parameters)
+		}
+	}
+	for _, parameters := range []map[string]float64{
+		{"weeks": 0},
+		{"weeks": 53},
+	} {
+		if _, err := GetDetectorWithParameters(DetectorWeekdaySeasonal, parameters); err == nil {
+			t.Errorf("Parameters %v should be invalid", parameters)
+		}
+	}
+	if _, err := GetDetectorWithParameters(DetectorEwma, map[string]float64{"alpha": 1}); err != nil {
+		t.Errorf("Alpha 1 should be valid, got %s", err.Error())
+	}
+	if _, err := GetDetectorWithParameters("unknown", nil); err == nil {
+		t.Errorf("unknown should not be a detector")
+	}
+}
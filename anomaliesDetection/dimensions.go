@@ -0,0 +1,193 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/olivere/elastic"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/config"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/models"
+)
+
+const (
+	DimensionProduct       = "product"
+	DimensionUsageType     = "usageType"
+	DimensionRegion        = "region"
+	DimensionLinkedAccount = "linkedAccount"
+	// DimensionTagPrefix prefixes the key of a tag to get the name of its dimension, e.g. "tag:team".
+	DimensionTagPrefix = "tag:"
+)
+
+type (
+	// dimension is a way to split the costs of an AWS account. Anomalies are
+	// detected on the daily costs of every value of a dimension.
+	dimension struct {
+		// Name is stored in the anomalies documents with the value of the dimension.
+		Name string
+		// aggregation returns the aggregation of the costs by value of the
+		// dimension, dates being the aggregation of the daily costs.
+		aggregation func(dates elastic.Aggregation) elastic.Aggregation
+		// parseAggregation parses the result of the aggregation.
+		parseAggregation func(raw *json.RawMessage) (esDimensionValues, error)
+		// valueQuery returns the query selecting the line items of a value of the dimension.
+		valueQuery func(value string) elastic.Query
+		// byBillRepositories is true if the line items are filtered by the bill
+		// repositories of the AWS account instead of its usage.
+		byBillRepositories bool
+	}
+
+	// esTagValuesResult is used to store the raw ElasticSearch response of the tag aggregation.
+	esTagValuesResult struct {
+		Key struct {
+			Values struct {
+				Buckets []struct {
+					Key       string `json:"key"`
+					LineItems struct {
+						Dates struct {
+							Buckets []esDimensionDatesBucket `json:"buckets"`
+						} `json:"dates"`
+					} `json:"lineItems"`
+				} `json:"buckets"`
+			} `json:"values"`
+		} `json:"key"`
+	}
+)
+
+// fieldDimension returns a dimension whose values are the values of a field of the line items.
+func fieldDimension(name, field string, byBillRepositories bool) dimension {
+	return dimension{
+		Name: name,
+		aggregation: func(dates elastic.Aggregation) elastic.Aggregation {
+			return elastic.NewTermsAggregation().Field(field).Size(aggregationMaxSize).SubAggregation("dates", dates)
+		},
+		parseAggregation: func(raw *json.RawMessage) (values esDimensionValues, err error) {
+			err = json.Unmarshal(*raw, &values)
+			return
+		},
+		valueQuery: func(value string) elastic.Query {
+			return elastic.NewTermQuery(field, value)
+		},
+		byBillRepositories: byBillRepositories,
+	}
+}
+
+// tagDimension returns a dimension whose values are the values of a tag.
+// Line items without this tag are ignored.
+func tagDimension(key string) dimension {
+	return dimension{
+		Name: DimensionTagPrefix + key,
+		aggregation: func(dates elastic.Aggregation) elastic.Aggregation {
+			return elastic.NewNestedAggregation().Path("tags").
+				SubAggregation("key", elastic.NewFilterAggregation().Filter(elastic.NewTermQuery("tags.key", key)).
+					SubAggregation("values", elastic.NewTermsAggregation().Field("tags.tag").Size(aggregationMaxSize).
+						SubAggregation("lineItems", elastic.NewReverseNestedAggregation().
+							SubAggregation("dates", dates))))
+		},
+		parseAggregation: func(raw *json.RawMessage) (values esDimensionValues, err error) {
+			var tagValues esTagValuesResult
+			if err = json.Unmarshal(*raw, &tagValues); err != nil {
+				return
+			}
+			for _, bucket := range tagValues.Key.Values.Buckets {
+				value := esDimensionValue{Key: bucket.Key}
+				value.Dates.Buckets = bucket.LineItems.Dates.Buckets
+				values.Buckets = append(values.Buckets, value)
+			}
+			return
+		},
+		valueQuery: func(value string) elastic.Query {
+			return elastic.NewNestedQuery("tags", elastic.NewBoolQuery().
+				Filter(elastic.NewTermQuery("tags.key", key)).
+				Filter(elastic.NewTermQuery("tags.tag", value)))
+		},
+	}
+}
+
+// dimensionsByName maps the names of the dimensions, except the tags, to their dimension.
+var dimensionsByName = map[string]dimension{
+	DimensionProduct:       fieldDimension(DimensionProduct, "productCode", false),
+	DimensionUsageType:     fieldDimension(DimensionUsageType, "usageType", false),
+	DimensionRegion:        fieldDimension(DimensionRegion, "region", false),
+	DimensionLinkedAccount: fieldDimension(DimensionLinkedAccount, "usageAccountId", true),
+}
+
+// getDimensions returns the dimensions on which anomalies are detected, as
+// set in config: the dimensions named in AnomalyDetectionDimensions and a
+// dimension for every tag key of AnomalyDetectionTagKeys.
+func getDimensions() ([]dimension, error) {
+	dimensions := make([]dimension, 0)
+	for _, name := range strings.Split(config.AnomalyDetectionDimensions, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+		} else if dim, ok := dimensionsByName[name]; ok {
+			dimensions = append(dimensions, dim)
+		} else {
+			return nil, errors.New("Unknown anomaly detection dimension: " + name)
+		}
+	}
+	for _, key := range strings.Split(config.AnomalyDetectionTagKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			dimensions = append(dimensions, tagDimension(key))
+		}
+	}
+	return dimensions, nil
+}
+
+// IsDimension returns true if name is the name of a dimension on which anomalies can be detected.
+func IsDimension(name string) bool {
+	_, ok := dimensionsByName[name]
+	return ok || (strings.HasPrefix(name, DimensionTagPrefix) && len(name) > len(DimensionTagPrefix))
+}
+
+// GetLineItemsFilter returns the query selecting the line items of the AWS
+// account account whose value for the dimension dimensionName is value, i.e.
+// the line items whose cost was analysed by an anomaly.
+func GetLineItemsFilter(account, dimensionName, value string) (elastic.Query, error) {
+	dim, ok := dimensionsByName[dimensionName]
+	if !ok && IsDimension(dimensionName) {
+		dim = tagDimension(strings.TrimPrefix(dimensionName, DimensionTagPrefix))
+	} else if !ok {
+		return nil, errors.New("Unknown anomaly detection dimension: " + dimensionName)
+	}
+	query := elastic.NewBoolQuery().Filter(dim.valueQuery(value))
+	if !dim.byBillRepositories {
+		query = query.Filter(createQueryAccountFilter(account))
+	}
+	return query, nil
+}
+
+// getAccountFilter returns the query selecting the line items of an AWS
+// account for a dimension. It returns nil if the dimension does not apply to
+// the AWS account.
+func getAccountFilter(ctx context.Context, account aws.AwsAccount, dim dimension) (elastic.Query, error) {
+	if !dim.byBillRepositories {
+		return createQueryAccountFilter(account.AwsIdentity), nil
+	}
+	billRepositories, err := models.AwsBillRepositoriesByAwsAccountID(db.Db, account.Id)
+	if err != nil || len(billRepositories) == 0 {
+		return nil, err
+	}
+	ids := make([]interface{}, len(billRepositories))
+	for i, billRepository := range billRepositories {
+		ids[i] = billRepository.ID
+	}
+	return elastic.NewTermsQuery("billRepositoryId", ids...), nil
+}
@@ -0,0 +1,328 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/aws/usageReports"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+	"github.com/trackit/trackit/es"
+)
+
+type (
+	// esProductAnomalyCost contains the cost data
+	esProductAnomalyCost struct {
+		Value       float64 `json:"value"`
+		MaxExpected float64 `json:"maxExpected"`
+	}
+
+	// esProductAnomaly is used to ingest in ElasticSearch.
+	// Product is only set for the product dimension, documents ingested before
+	// the other dimensions were added have no dimension.
+	esProductAnomaly struct {
+		Account        string               `json:"account"`
+		Date           string               `json:"date"`
+		Product        string               `json:"product,omitempty"`
+		Dimension      string               `json:"dimension"`
+		DimensionValue string               `json:"dimensionValue"`
+		Abnormal       bool                 `json:"abnormal"`
+		Recurrent      bool                 `json:"recurrent"`
+		Cost           esProductAnomalyCost `json:"cost"`
+		Detector       anomalyType.Detector `json:"detector"`
+	}
+
+	// esProductAnomalies is used to get anomalies from ElasticSearch.
+	esProductAnomalies []esProductAnomaly
+
+	// esDimensionDatesBucket is used to store the raw ElasticSearch response.
+	esDimensionDatesBucket struct {
+		Key  string `json:"key_as_string"`
+		Cost struct {
+			Value float64 `json:"value"`
+		} `json:"cost"`
+	}
+
+	// esDimensionValue is used to store the daily costs of a value of a dimension.
+	esDimensionValue struct {
+		Key   string `json:"key"`
+		Dates struct {
+			Buckets []esDimensionDatesBucket `json:"buckets"`
+		} `json:"dates"`
+	}
+
+	// esDimensionValues is used to store the raw ElasticSearch response.
+	esDimensionValues struct {
+		Buckets []esDimensionValue `json:"buckets"`
+	}
+
+	// costWithValue is used when a cost has to be wrapped by the value of a dimension.
+	costWithValue struct {
+		value string
+		cost  float64
+	}
+
+	// totalCostByDay is a named type for total cost for each day.
+	totalCostByDay map[string]float64
+
+	// highestSpendersByDay contains the more costly values podium for each day.
+	highestSpendersByDay map[string][]string
+)
+
+// getDimensionAndValue returns the dimension and the value of an anomaly
+// document, documents without dimension being product anomalies.
+func (doc esProductAnomaly) getDimensionAndValue() (string, string) {
+	if doc.Dimension == "" {
+		return DimensionProduct, doc.Product
+	}
+	return doc.Dimension, doc.DimensionValue
+}
+
+// runAnomaliesDetectionForDimensions will get data from ElasticSearch,
+// compute anomalies for every dimension and ingest the result in ElasticSearch.
+func runAnomaliesDetectionForDimensions(parsedParams AnomalyEsQueryParams, account aws.AwsAccount, ctx context.Context) (err error) {
+	var dimensions []dimension
+	if dimensions, err = getDimensions(); err != nil {
+		return
+	}
+	for _, dim := range dimensions {
+		var res AnalyzedCosts
+		if res, err = getAnomaliesData(ctx, parsedParams, account, dim); err != nil {
+			return
+		} else if err = saveAnomaliesData(ctx, res, account, parsedParams.Detector); err != nil {
+			return
+		}
+	}
+	return removeRecurrence(ctx, parsedParams, account)
+}
+
+// saveAnomaliesData will save anomalies in ElasticSearch.
+// If the index doesn't exist, it will be created.
+// Anomalies are unique and will replace the existing ones if
+// they changed (cost, upper band or detector).
+func saveAnomaliesData(ctx context.Context, aCosts AnalyzedCosts, account aws.AwsAccount, detector Detector) error {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	if len(aCosts) == 0 {
+		return nil
+	}
+	logger.Info("Updating anomalies for AWS account.", map[string]interface{}{
+		"awsAccount": account,
+		"dimension":  aCosts[0].Meta.AdditionalMeta.(AnalyzedCostDimensionMeta).Dimension,
+	})
+	index := es.IndexNameForUserId(account.UserId, IndexPrefixAnomaliesDetection)
+	bp, err := utils.GetBulkProcessor(ctx)
+	if err != nil {
+		logger.Error("Failed to get bulk processor.", err.Error())
+		return err
+	}
+	description := detector.Description()
+	for _, aCost := range aCosts {
+		doc := newEsProductAnomaly(aCost, account, description)
+		id, err := generateElasticSearchDocumentId(doc)
+		if err != nil {
+			logger.Error("Error when marshaling anomalies var", err.Error())
+			return err
+		}
+		bp = addDocToBulkProcessor(bp, doc, TypeProductAnomaliesDetection, index, id)
+	}
+	bp.Flush()
+	err = bp.Close()
+	if err != nil {
+		logger.Error("Failed when putting anomalies in ES", err.Error())
+		return err
+	}
+	logger.Info("Anomalies put in ES", nil)
+	return nil
+}
+
+// newEsProductAnomaly creates the document of an analyzed cost.
+func newEsProductAnomaly(aCost AnalyzedCost, account aws.AwsAccount, description anomalyType.Detector) esProductAnomaly {
+	meta := aCost.Meta.AdditionalMeta.(AnalyzedCostDimensionMeta)
+	doc := esProductAnomaly{
+		Account:        account.AwsIdentity,
+		Date:           aCost.Meta.Date,
+		Dimension:      meta.Dimension,
+		DimensionValue: meta.Value,
+		Abnormal:       aCost.Anomaly,
+		Recurrent:      false,
+		Cost: esProductAnomalyCost{
+			Value:       aCost.Cost,
+			MaxExpected: aCost.UpperBand,
+		},
+		Detector: description,
+	}
+	if meta.Dimension == DimensionProduct {
+		doc.Product = meta.Value
+	}
+	return doc
+}
+
+// generateElasticSearchDocumentId is used to generate the document id ingested in ElasticSearch.
+// The document id is not dependent on cost or upper band: if one of them change,
+// it will update the document in ElasticSearch instead of recreating one.
+// The id of product anomalies does not depend on the dimension to keep the ids
+// of the documents ingested before the other dimensions were added.
+func generateElasticSearchDocumentId(doc esProductAnomaly) (id string, err error) {
+	var ji []byte
+	if doc.Dimension == DimensionProduct {
+		ji, err = json.Marshal(struct {
+			Account string `json:"account"`
+			Date    string `json:"date"`
+			Product string `json:"product"`
+		}{
+			doc.Account,
+			doc.Date,
+			doc.Product,
+		})
+	} else {
+		ji, err = json.Marshal(struct {
+			Account        string `json:"account"`
+			Date           string `json:"date"`
+			Dimension      string `json:"dimension"`
+			DimensionValue string `json:"dimensionValue"`
+		}{
+			doc.Account,
+			doc.Date,
+			doc.Dimension,
+			doc.DimensionValue,
+		})
+	}
+	if err != nil {
+		return
+	}
+	hash := md5.Sum(ji)
+	id = base64.URLEncoding.EncodeToString(hash[:])
+	return
+}
+
+// clearDisturbances clears fake alerts with the cleaning thresholds.
+func clearDisturbances(aCosts AnalyzedCosts, totalCostByDay totalCostByDay, highestSpendersByDay highestSpendersByDay, cleaning anomalyType.Cleaning) AnalyzedCosts {
+	for index, aCost := range aCosts {
+		if aCost.Anomaly {
+			date := aCost.Meta.Date
+			increaseAmount := aCost.Cost - aCost.UpperBand
+			if increaseAmount < totalCostByDay[date]*cleaning.MinPercentOfDailyBill/100 ||
+				aCost.Cost < cleaning.MinAbsoluteCost {
+				aCosts[index].Anomaly = false
+			} else {
+				spenderInPodium := false
+				for _, spender := range highestSpendersByDay[date] {
+					if spender == aCost.Meta.AdditionalMeta.(AnalyzedCostDimensionMeta).Value {
+						spenderInPodium = true
+						break
+					}
+				}
+				aCosts[index].Anomaly = spenderInPodium
+			}
+		}
+	}
+	return aCosts
+}
+
+// addCostToCosts is a tool used by getHighestSpendersByDay.
+func addCostToCosts(value string, cost float64, costs []costWithValue) []costWithValue {
+	for idx := range costs {
+		if costs[idx].value == value {
+			costs[idx].cost += cost
+			return costs
+		}
+	}
+	return append(costs, costWithValue{value, cost})
+}
+
+// getHighestSpendersByDay gets a podium of the minRank highest spenders.
+func getHighestSpendersByDay(values esDimensionValues, minRank int) highestSpendersByDay {
+	costByDayByValue := map[string][]costWithValue{}
+	for _, value := range values.Buckets {
+		for _, date := range value.Dates.Buckets {
+			costByDayByValue[date.Key] = addCostToCosts(value.Key, date.Cost.Value, costByDayByValue[date.Key])
+		}
+	}
+	highestSpendersByDay := make(highestSpendersByDay)
+	for day, costs := range costByDayByValue {
+		sort.Slice(costs, func(i, j int) bool {
+			return costs[i].cost > costs[j].cost
+		})
+		for i := 0; i < minRank && i < len(costs); i++ {
+			highestSpendersByDay[day] = append(highestSpendersByDay[day], costs[i].value)
+		}
+	}
+	return highestSpendersByDay
+}
+
+// getTotalCostByDay gets the total cost for each day.
+// For the tag dimensions, it is the total cost of the tagged line items.
+func getTotalCostByDay(values esDimensionValues) totalCostByDay {
+	totalCostByDay := totalCostByDay{}
+	for _, value := range values.Buckets {
+		for _, date := range value.Dates.Buckets {
+			totalCostByDay[date.Key] += date.Cost.Value
+		}
+	}
+	return totalCostByDay
+}
+
+// getAnomaliesData returns the anomalies of a dimension based on query params.
+// It returns no anomalies if the dimension does not apply to the AWS account.
+func getAnomaliesData(ctx context.Context, params AnomalyEsQueryParams, account aws.AwsAccount, dim dimension) (AnalyzedCosts, error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	accountFilter, err := getAccountFilter(ctx, account, dim)
+	if err != nil || accountFilter == nil {
+		return nil, err
+	}
+	sr, err := makeElasticSearchRequest(ctx, accountFilter, dim, params)
+	if err != nil {
+		return nil, err
+	}
+	values, err := dim.parseAggregation(sr.Aggregations["values"])
+	if err != nil {
+		logger.Error("Failed to parse elasticsearch document.", err.Error())
+		return nil, err
+	}
+	totalAnalyzedCosts := make(AnalyzedCosts, 0)
+	totalCostsByDay := getTotalCostByDay(values)
+	highestSpendersByDay := getHighestSpendersByDay(values, params.Cleaning.HighestSpendingMinRank)
+	for _, value := range values.Buckets {
+		aCosts := make(AnalyzedCosts, 0, len(value.Dates.Buckets))
+		for _, date := range value.Dates.Buckets {
+			aCosts = append(aCosts, AnalyzedCost{
+				Meta: AnalyzedCostEssentialMeta{
+					AdditionalMeta: AnalyzedCostDimensionMeta{
+						Dimension: dim.Name,
+						Value:     value.Key,
+					},
+					Date: date.Key,
+				},
+				Cost:    date.Cost.Value,
+				Anomaly: false,
+			})
+		}
+		if len(aCosts) == 0 {
+			continue
+		}
+		aCosts = computeAnomalies(ctx, aCosts, params.DateBegin, params.Detector)
+		aCosts = deleteOffset(aCosts, params.DateBegin)
+		totalAnalyzedCosts = append(totalAnalyzedCosts, aCosts...)
+	}
+	totalAnalyzedCosts = clearDisturbances(totalAnalyzedCosts, totalCostsByDay, highestSpendersByDay, params.Cleaning)
+	return totalAnalyzedCosts, nil
+}
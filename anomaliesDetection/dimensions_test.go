@@ -0,0 +1,111 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testTagAggregation = `{
+	"doc_count": 6,
+	"key": {
+		"doc_count": 3,
+		"values": {
+			"buckets": [
+				{
+					"key": "backend",
+					"doc_count": 2,
+					"lineItems": {
+						"doc_count": 2,
+						"dates": {
+							"buckets": [
+								{"key_as_string": "2019-01-01T00:00:00.000Z", "cost": {"value": 12.5}},
+								{"key_as_string": "2019-01-02T00:00:00.000Z", "cost": {"value": 30}}
+							]
+						}
+					}
+				},
+				{
+					"key": "frontend",
+					"doc_count": 1,
+					"lineItems": {
+						"doc_count": 1,
+						"dates": {
+							"buckets": [
+								{"key_as_string": "2019-01-01T00:00:00.000Z", "cost": {"value": 2.5}}
+							]
+						}
+					}
+				}
+			]
+		}
+	}
+}`
+
+func TestTagDimensionParseAggregation(t *testing.T) {
+	raw := json.RawMessage(testTagAggregation)
+	dim := tagDimension("team")
+	if dim.Name != "tag:team" {
+		t.Errorf("Expected dimension name tag:team but got %s", dim.Name)
+	}
+	values, err := dim.parseAggregation(&raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values.Buckets) != 2 || values.Buckets[0].Key != "backend" || values.Buckets[1].Key != "frontend" {
+		t.Fatalf("Unexpected values %v", values.Buckets)
+	}
+	if len(values.Buckets[0].Dates.Buckets) != 2 || values.Buckets[0].Dates.Buckets[1].Cost.Value != 30 {
+		t.Errorf("Unexpected dates %v", values.Buckets[0].Dates.Buckets)
+	}
+	total := getTotalCostByDay(values)
+	if total["2019-01-01T00:00:00.000Z"] != 15 || total["2019-01-02T00:00:00.000Z"] != 30 {
+		t.Errorf("Unexpected total cost by day %v", total)
+	}
+}
+
+func TestGenerateElasticSearchDocumentId(t *testing.T) {
+	product := esProductAnomaly{
+		Account:        "123456789012",
+		Date:           "2019-01-01T00:00:00.000Z",
+		Product:        "AmazonEC2",
+		Dimension:      DimensionProduct,
+		DimensionValue: "AmazonEC2",
+	}
+	// Product anomalies keep the id they had before the dimensions were added.
+	if id, err := generateElasticSearchDocumentId(product); err != nil {
+		t.Fatal(err)
+	} else if id != "UgoAY-HuYDDl-2I90t5rEQ==" {
+		t.Errorf("Expected product anomaly id UgoAY-HuYDDl-2I90t5rEQ== but got %s", id)
+	}
+	region := product
+	region.Product = ""
+	region.Dimension = DimensionRegion
+	region.DimensionValue = "us-east-1"
+	usageType := region
+	usageType.Dimension = DimensionUsageType
+	regionId, err := generateElasticSearchDocumentId(region)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usageTypeId, err := generateElasticSearchDocumentId(usageType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regionId == usageTypeId {
+		t.Errorf("Anomalies of different dimensions have the same id %s", regionId)
+	}
+}
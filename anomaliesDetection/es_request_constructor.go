@@ -18,8 +18,6 @@ import (
 	"time"
 
 	"github.com/olivere/elastic"
-
-	"github.com/trackit/trackit/config"
 )
 
 const (
@@ -37,21 +35,22 @@ func createQueryAccountFilter(account string) *elastic.TermQuery {
 
 // createQueryTimeRange creates and return a new *elastic.RangeQuery based on the duration
 // defined by durationBegin and durationEnd.
-// durationBegin is reduced by period. This offset is deleted later.
-func createQueryTimeRange(durationBegin time.Time, durationEnd time.Time) *elastic.RangeQuery {
-	periodDuration := time.Duration(config.AnomalyDetectionBollingerBandPeriod) * 24 * time.Hour
+// durationBegin is reduced by the history needed by the detector. This offset is deleted later.
+func createQueryTimeRange(durationBegin time.Time, durationEnd time.Time, historyDays int) *elastic.RangeQuery {
+	periodDuration := time.Duration(historyDays) * 24 * time.Hour
 	durationBegin = durationBegin.Add(-periodDuration - 1)
 	return elastic.NewRangeQuery("usageStartDate").
 		From(durationBegin).To(durationEnd)
 }
 
-// getProductElasticSearchParams is used to construct an ElasticSearch *elastic.SearchService
-// used to retrieve the average cost by usageType for each day.
+// getDimensionElasticSearchParams is used to construct an ElasticSearch *elastic.SearchService
+// used to retrieve the cost of every value of a dimension for each day.
 // It takes as parameters :
-// 	- account string : A string representing aws account number, in the format of the field
-//	'awsdetailedlineitem.linked_account_id'
+// 	- accountFilter elastic.Query : The query selecting the line items of the AWS account
+//	- dim dimension : The dimension whose values are aggregated
 //	- durationBeing time.Time : A time.Time struct representing the begining of the time range in the query
 //	- durationEnd time.Time : A time.Time struct representing the end of the time range in the query
+//	- historyDays int : The number of days preceding durationBegin needed by the anomaly detector
 //  - aggregationPeriod string : An aggregation period, can be "day"
 //	- client *elastic.Client : an instance of *elastic.Client that represent an Elastic Search client.
 //	- index string : The Elastic Search index on wich to execute the query. In this context the default value
@@ -60,15 +59,15 @@ func createQueryTimeRange(durationBegin time.Time, durationEnd time.Time) *elast
 // it crash :
 //	- If the client is nil or malconfigured, it will crash
 //	- If the index is not an index present in the ES, it will crash
-func getProductElasticSearchParams(account string, durationBegin time.Time,
-	durationEnd time.Time, aggregationPeriod string, client *elastic.Client, index string) *elastic.SearchService {
+func getDimensionElasticSearchParams(accountFilter elastic.Query, dim dimension, durationBegin time.Time,
+	durationEnd time.Time, historyDays int, aggregationPeriod string, client *elastic.Client, index string) *elastic.SearchService {
 	query := elastic.NewBoolQuery()
-	query = query.Filter(createQueryAccountFilter(account))
-	query = query.Filter(createQueryTimeRange(durationBegin, durationEnd))
+	query = query.Filter(accountFilter)
+	query = query.Filter(createQueryTimeRange(durationBegin, durationEnd, historyDays))
 	search := client.Search().Index(index).Size(0).Query(query)
 
-	search.Aggregation("products", elastic.NewTermsAggregation().Field("productCode").Size(aggregationMaxSize).
-		SubAggregation("dates", elastic.NewDateHistogramAggregation().Field("usageStartDate").ExtendedBounds(durationBegin, durationEnd).Interval(aggregationPeriod).
+	search.Aggregation("values", dim.aggregation(
+		elastic.NewDateHistogramAggregation().Field("usageStartDate").ExtendedBounds(durationBegin, durationEnd).Interval(aggregationPeriod).
 			SubAggregation("cost", elastic.NewSumAggregation().Field("unblendedCost"))))
 	return search
 }
@@ -0,0 +1,85 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"math"
+
+	"github.com/trackit/trackit/config"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+)
+
+// ewmaDetector detects anomalies with an exponentially weighted moving
+// average and variance of the previous costs. Recent costs weigh more than
+// older ones, alpha being the weight of the latest cost.
+// The upper band is computed like the Bollinger Bands upper band.
+type ewmaDetector struct {
+	alpha                        float64
+	standardDeviationCoefficient float64
+	upperBandCoefficient         float64
+}
+
+// newEwmaDetector creates an ewmaDetector with the parameters in config
+// overridden by parameters.
+func newEwmaDetector(parameters detectorParameters) Detector {
+	return ewmaDetector{
+		alpha:                        parameters.get("alpha", config.AnomalyDetectionEwmaAlpha),
+		standardDeviationCoefficient: parameters.get("standardDeviationCoefficient", config.AnomalyDetectionEwmaStandardDeviationCoefficient),
+		upperBandCoefficient:         parameters.get("upperBandCoefficient", config.AnomalyDetectionEwmaUpperBandCoefficient),
+	}
+}
+
+// Description returns the name and the parameters of the detector.
+func (d ewmaDetector) Description() anomalyType.Detector {
+	return anomalyType.Detector{
+		Name: DetectorEwma,
+		Parameters: map[string]float64{
+			"alpha":                        d.alpha,
+			"standardDeviationCoefficient": d.standardDeviationCoefficient,
+			"upperBandCoefficient":         d.upperBandCoefficient,
+		},
+	}
+}
+
+// History returns the number of days after which the first cost weighs less
+// than the others in the average, so that the average is meaningful.
+func (d ewmaDetector) History() int {
+	if d.alpha <= 0 {
+		return 0
+	}
+	return int(math.Ceil(2 / d.alpha))
+}
+
+// Analyse calculates anomalies with the exponentially weighted moving average
+// and standard deviation of the costs preceding each cost.
+func (d ewmaDetector) Analyse(aCosts AnalyzedCosts) AnalyzedCosts {
+	var avg, variance float64
+	for index := range aCosts {
+		a := &aCosts[index]
+		if index == 0 {
+			avg = a.Cost
+			continue
+		}
+		a.UpperBand = avg*d.upperBandCoefficient + (math.Sqrt(variance) * d.standardDeviationCoefficient)
+		if a.Cost > a.UpperBand {
+			a.Anomaly = true
+		}
+		diff := a.Cost - avg
+		increment := d.alpha * diff
+		avg += increment
+		variance = (1 - d.alpha) * (variance + diff*increment)
+	}
+	return aCosts
+}
@@ -0,0 +1,100 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"math"
+	"sort"
+
+	"github.com/trackit/trackit/config"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+)
+
+// madConsistencyConstant makes the median absolute deviation consistent with
+// the standard deviation for normally distributed costs.
+const madConsistencyConstant = 0.6745
+
+// madDetector detects anomalies with a robust z-score: the distance of a cost
+// to the median of the previous costs, divided by their median absolute
+// deviation. Unlike the average and the standard deviation, the median and the
+// median absolute deviation are not skewed by a few very high costs.
+// A cost is abnormal when its robust z-score exceeds the threshold.
+type madDetector struct {
+	period               int
+	threshold            float64
+	upperBandCoefficient float64
+}
+
+// newMadDetector creates a madDetector with the parameters in config
+// overridden by parameters.
+func newMadDetector(parameters detectorParameters) Detector {
+	return madDetector{
+		period:               parameters.getInt("period", config.AnomalyDetectionMadPeriod),
+		threshold:            parameters.get("threshold", config.AnomalyDetectionMadThreshold),
+		upperBandCoefficient: parameters.get("upperBandCoefficient", config.AnomalyDetectionMadUpperBandCoefficient),
+	}
+}
+
+// Description returns the name and the parameters of the detector.
+func (d madDetector) Description() anomalyType.Detector {
+	return anomalyType.Detector{
+		Name: DetectorMad,
+		Parameters: map[string]float64{
+			"period":               float64(d.period),
+			"threshold":            d.threshold,
+			"upperBandCoefficient": d.upperBandCoefficient,
+		},
+	}
+}
+
+// History returns the number of days used to compute the median.
+func (d madDetector) History() int {
+	return d.period
+}
+
+// median returns the median of values. values is sorted in place.
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	middle := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[middle-1] + values[middle]) / 2
+	}
+	return values[middle]
+}
+
+// Analyse calculates anomalies with the robust z-score of each cost.
+// The upper band is the cost whose robust z-score equals the threshold.
+func (d madDetector) Analyse(aCosts AnalyzedCosts) AnalyzedCosts {
+	for index := range aCosts {
+		if index > 0 {
+			a := &aCosts[index]
+			tempSliceSize := min(index, d.period)
+			values := make([]float64, 0, tempSliceSize)
+			for _, previous := range aCosts[index-tempSliceSize : index] {
+				values = append(values, previous.Cost)
+			}
+			med := median(values)
+			for i := range values {
+				values[i] = math.Abs(values[i] - med)
+			}
+			mad := median(values)
+			a.UpperBand = med*d.upperBandCoefficient + (mad * d.threshold / madConsistencyConstant)
+			if a.Cost > a.UpperBand {
+				a.Anomaly = true
+			}
+		}
+	}
+	return aCosts
+}
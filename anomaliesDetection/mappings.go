@@ -42,7 +42,7 @@ func init() {
 const TemplateAnomaliesDetection = `
 {
 	"template": "*-` + IndexPrefixAnomaliesDetection + `",
-	"version": 2,
+	"version": 4,
 	"mappings": {
 		"` + TypeProductAnomaliesDetection + `": {
 			"properties": {
@@ -55,6 +55,12 @@ const TemplateAnomaliesDetection = `
 				"product" : {
 					"type": "keyword"
 				},
+				"dimension" : {
+					"type": "keyword"
+				},
+				"dimensionValue" : {
+					"type": "keyword"
+				},
 				"abnormal" : {
 					"type": "boolean"
 				},
@@ -71,6 +77,18 @@ const TemplateAnomaliesDetection = `
 							"type": "double"
 						}
 					}
+				},
+				"detector": {
+					"type": "object",
+					"properties": {
+						"name": {
+							"type": "keyword"
+						},
+						"parameters": {
+							"type": "object",
+							"enabled": false
+						}
+					}
 				}
 			},
 			"_all": {
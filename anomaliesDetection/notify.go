@@ -0,0 +1,236 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+	"github.com/trackit/trackit/es"
+	"github.com/trackit/trackit/mail"
+	"github.com/trackit/trackit/models"
+	"github.com/trackit/trackit/notifications"
+	"github.com/trackit/trackit/users"
+)
+
+// notificationMaxAgeDays is the number of days after which an anomaly which
+// was not notified yet is not notified anymore.
+const notificationMaxAgeDays = 7
+
+type (
+	// notifiedAnomaly is an anomaly as sent in the data of the notifications.
+	notifiedAnomaly struct {
+		Id          string               `json:"id"`
+		Date        time.Time            `json:"date"`
+		Dimension   string               `json:"dimension"`
+		Value       string               `json:"value"`
+		Cost        float64              `json:"cost"`
+		MaxExpected float64              `json:"maxExpected"`
+		Detector    anomalyType.Detector `json:"detector"`
+	}
+
+	// anomaliesNotificationData is the data of the anomalies notifications.
+	anomaliesNotificationData struct {
+		AwsAccount string            `json:"awsAccount"`
+		Anomalies  []notifiedAnomaly `json:"anomalies"`
+	}
+)
+
+// getNotifiedAnomalyKey returns the key identifying the value of a dimension
+// in the emailed_anomaly table. It is the product for the product dimension,
+// as it was before the other dimensions were added.
+func getNotifiedAnomalyKey(dimension, value string) string {
+	if dimension == DimensionProduct {
+		return value
+	}
+	return dimension + ":" + value
+}
+
+// getAnomalies returns the anomalies of an AWS account detected between
+// begin and end which are neither recurrent, snoozed by its owner, triaged
+// by one of its users nor below the emailing min level of its settings.
+func getAnomalies(ctx context.Context, tx *sql.Tx, account aws.AwsAccount, begin, end time.Time) ([]notifiedAnomaly, error) {
+	settings, err := GetSettings(tx, account)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := getAnomaliesFromEs(ctx, AnomalyEsQueryParams{
+		Account:   account.AwsIdentity,
+		DateBegin: begin,
+		DateEnd:   end,
+		Index:     es.IndexNameForUserId(account.UserId, IndexPrefixAnomaliesDetection),
+	})
+	if err != nil {
+		return nil, err
+	}
+	snoozedAnomalies, err := models.AnomalySnoozingsByUserID(tx, account.UserId)
+	if err != nil {
+		return nil, err
+	}
+	snoozed := make(map[string]bool, len(snoozedAnomalies))
+	for _, snoozedAnomaly := range snoozedAnomalies {
+		snoozed[snoozedAnomaly.AnomalyID] = true
+	}
+	anomalyStates, err := models.AnomalyStatesByAwsAccountID(tx, account.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, anomalyState := range anomalyStates {
+		if anomalyState.State != anomalyType.AnomalyStateOpen {
+			snoozed[anomalyState.AnomalyID] = true
+		}
+	}
+	res := make([]notifiedAnomaly, 0)
+	for _, anomaly := range raw {
+		if !anomaly.Source.Abnormal || anomaly.Source.Recurrent || snoozed[anomaly.Id] {
+			continue
+		} else if level, _ := settings.Level(anomaly.Source.Cost.Value, anomaly.Source.Cost.MaxExpected); level < settings.EmailingMinLevel {
+			continue
+		}
+		date, err := time.Parse("2006-01-02T15:04:05Z", anomaly.Source.Date)
+		if err != nil {
+			continue
+		}
+		dimension, value := anomaly.Source.getDimensionAndValue()
+		res = append(res, notifiedAnomaly{
+			Id:          anomaly.Id,
+			Date:        date,
+			Dimension:   dimension,
+			Value:       value,
+			Cost:        anomaly.Source.Cost.Value,
+			MaxExpected: anomaly.Source.Cost.MaxExpected,
+			Detector:    anomaly.Source.Detector,
+		})
+	}
+	return res, nil
+}
+
+// getAnomaliesToNotify returns the anomalies of an AWS account detected
+// during the last notificationMaxAgeDays days which are neither recurrent,
+// snoozed by its owner, triaged by one of its users, below the emailing min
+// level nor already notified.
+func getAnomaliesToNotify(ctx context.Context, tx *sql.Tx, account aws.AwsAccount) ([]notifiedAnomaly, error) {
+	end := time.Now().UTC()
+	anomalies, err := getAnomalies(ctx, tx, account, end.AddDate(0, 0, -notificationMaxAgeDays), end)
+	if err != nil {
+		return nil, err
+	}
+	toNotify := make([]notifiedAnomaly, 0, len(anomalies))
+	for _, anomaly := range anomalies {
+		if emailed, err := models.IsAnomalyAlreadyEmailed(tx, account.Id, getNotifiedAnomalyKey(anomaly.Dimension, anomaly.Value), anomaly.Date); err != nil {
+			return nil, err
+		} else if !emailed {
+			toNotify = append(toNotify, anomaly)
+		}
+	}
+	return toNotify, nil
+}
+
+// getAnomaliesMailData returns the anomalies as listed in the mails.
+func getAnomaliesMailData(anomalies []notifiedAnomaly) []mail.AnomalyData {
+	res := make([]mail.AnomalyData, len(anomalies))
+	for i, anomaly := range anomalies {
+		res[i] = mail.AnomalyData{
+			Date:        anomaly.Date,
+			Dimension:   anomaly.Dimension,
+			Value:       anomaly.Value,
+			Cost:        anomaly.Cost,
+			MaxExpected: anomaly.MaxExpected,
+		}
+	}
+	return res
+}
+
+// GetAnomaliesMailData returns the anomalies of an AWS account detected
+// between begin and end which are neither recurrent, snoozed by its owner,
+// triaged by one of its users nor below the emailing min level, as listed in
+// the mails.
+func GetAnomaliesMailData(ctx context.Context, tx *sql.Tx, account aws.AwsAccount, begin, end time.Time) ([]mail.AnomalyData, error) {
+	anomalies, err := getAnomalies(ctx, tx, account, begin, end)
+	if err != nil {
+		return nil, err
+	}
+	return getAnomaliesMailData(anomalies), nil
+}
+
+// getAnomaliesNotification returns the notification of the anomalies of an
+// AWS account, rendered with mail.AnomaliesTemplate.
+func getAnomaliesNotification(account aws.AwsAccount, toNotify []notifiedAnomaly) (notifications.Notification, error) {
+	data := mail.AnomaliesData{
+		AwsAccount:  account.Pretty,
+		AwsIdentity: account.AwsIdentity,
+		Anomalies:   getAnomaliesMailData(toNotify),
+	}
+	message, err := mail.AnomaliesTemplate.Render(data)
+	if err != nil {
+		return notifications.Notification{}, err
+	}
+	return notifications.Notification{
+		Event:   notifications.EventAnomalies,
+		Subject: message.Subject,
+		Body:    message.Text,
+		Html:    message.Html,
+		Data: anomaliesNotificationData{
+			AwsAccount: account.AwsIdentity,
+			Anomalies:  toNotify,
+		},
+	}, nil
+}
+
+// NotifyAnomalies notifies the owner of an AWS account, through their
+// notification channels, about the anomalies which were not notified yet.
+// The notified anomalies are saved in the emailed_anomaly table.
+func NotifyAnomalies(ctx context.Context, tx *sql.Tx, account aws.AwsAccount) error {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	toNotify, err := getAnomaliesToNotify(ctx, tx, account)
+	if err != nil || len(toNotify) == 0 {
+		return err
+	}
+	user, err := users.GetUserWithId(tx, account.UserId)
+	if err != nil {
+		return err
+	}
+	logger.Info("Notifying anomalies.", map[string]interface{}{
+		"awsAccount": account,
+		"anomalies":  len(toNotify),
+	})
+	notification, err := getAnomaliesNotification(account, toNotify)
+	if err != nil {
+		return err
+	}
+	recipients, err := notifications.Notify(ctx, tx, user, &account.Id, notification)
+	if err != nil {
+		return err
+	}
+	for _, anomaly := range toNotify {
+		for _, recipient := range recipients {
+			dbEmailedAnomaly := models.EmailedAnomaly{
+				AwsAccountID: account.Id,
+				Product:      getNotifiedAnomalyKey(anomaly.Dimension, anomaly.Value),
+				Recipient:    recipient,
+				Date:         anomaly.Date,
+			}
+			if err := dbEmailedAnomaly.Insert(tx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -9,7 +9,6 @@ import (
 
 	"github.com/trackit/trackit/aws"
 	"github.com/trackit/trackit/aws/usageReports"
-	"github.com/trackit/trackit/config"
 	"github.com/trackit/trackit/es"
 )
 
@@ -31,8 +30,15 @@ type (
 	// anomaliesByDate is used to get an anomaly with its date more easily.
 	anomaliesByDate map[time.Time]esProductAnomalyWithId
 
-	// anomaliesByProduct is used to get an anomaly with its product more easily.
-	anomaliesByProduct map[string]anomaliesByDate
+	// anomaliesByDimensionValue is used to get an anomaly with its dimension
+	// and value more easily.
+	anomaliesByDimensionValue map[dimensionValue]anomaliesByDate
+
+	// dimensionValue identifies a value of a dimension.
+	dimensionValue struct {
+		dimension string
+		value     string
+	}
 )
 
 // removeRecurrence gets all anomalies from ElasticSearch and removes recurrent anomalies.
@@ -45,8 +51,8 @@ func removeRecurrence(ctx context.Context, params AnomalyEsQueryParams, account
 	} else {
 		res := transformAnomaliesToMap(raw)
 		var recurrentAnomalies esProductAnomaliesWithId
-		for product := range res {
-			recurrentAnomalies = append(recurrentAnomalies, detectRecurrence(res[product])...)
+		for value := range res {
+			recurrentAnomalies = append(recurrentAnomalies, detectRecurrence(res[value], params.Cleaning.RecurrenceThreshold)...)
 		}
 		err := applyRecurrentAnomaliesToEs(ctx, account, recurrentAnomalies)
 		return err
@@ -85,18 +91,17 @@ func applyRecurrentAnomaliesToEs(ctx context.Context, account aws.AwsAccount, re
 }
 
 // approximateCostComparison compares two float64 with
-// an approximation of config.AnomalyDetectionRecurrenceCleaningThreshold.
+// an approximation of the threshold t.
 // For example +/- 10% if it is set to 0.1.
-func approximateCostComparison(a, b float64) bool {
-	t := config.AnomalyDetectionRecurrenceCleaningThreshold
+func approximateCostComparison(a, b, t float64) bool {
 	return a+a*t > b && a-a*t < b
 }
 
-// detectRecurrence detects recurrent anomalies.
-func detectRecurrence(an anomaliesByDate) (res esProductAnomaliesWithId) {
+// detectRecurrence detects recurrent anomalies with the threshold t.
+func detectRecurrence(an anomaliesByDate, t float64) (res esProductAnomaliesWithId) {
 	for date := range an {
 		prev := date.AddDate(0, -1, 0)
-		if an[prev].Source.Abnormal && approximateCostComparison(an[date].Source.Cost.Value, an[prev].Source.Cost.Value) {
+		if an[prev].Source.Abnormal && approximateCostComparison(an[date].Source.Cost.Value, an[prev].Source.Cost.Value, t) {
 			res = append(res, an[date])
 		}
 	}
@@ -104,20 +109,22 @@ func detectRecurrence(an anomaliesByDate) (res esProductAnomaliesWithId) {
 }
 
 // transformAnomaliesToMap transform a raw slice of anomalies in a parsed map.
-func transformAnomaliesToMap(raw esProductAnomaliesWithId) anomaliesByProduct {
-	res := make(anomaliesByProduct)
+func transformAnomaliesToMap(raw esProductAnomaliesWithId) anomaliesByDimensionValue {
+	res := make(anomaliesByDimensionValue)
 	for _, r := range raw {
-		if res[r.Source.Product] == nil {
-			res[r.Source.Product] = make(anomaliesByDate)
+		var key dimensionValue
+		key.dimension, key.value = r.Source.getDimensionAndValue()
+		if res[key] == nil {
+			res[key] = make(anomaliesByDate)
 		}
 		if date, err := time.Parse("2006-01-02T15:04:05Z", r.Source.Date); err == nil {
-			res[r.Source.Product][date] = r
+			res[key][date] = r
 		}
 	}
 	return res
 }
 
-// getAnomaliesFromEs returns product anomalies in ElasticSearch
+// getAnomaliesFromEs returns the anomalies of every dimension in ElasticSearch
 func getAnomaliesFromEs(ctx context.Context, params AnomalyEsQueryParams) (esProductAnomaliesWithId, error) {
 	logger := jsonlog.LoggerFromContextOrDefault(ctx)
 	sr, err := getAnomalyElasticSearchParams(params.Account, params.DateBegin, params.DateEnd, es.Client, params.Index, TypeProductAnomaliesDetection).Do(ctx)
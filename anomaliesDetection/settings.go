@@ -0,0 +1,202 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/config"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+	"github.com/trackit/trackit/models"
+)
+
+// Settings are the anomaly detection settings of an AWS account: the settings
+// in config overridden by the ones of its owner, then by its own.
+type Settings struct {
+	DetectorParameters map[string]map[string]float64 `json:"detectorParameters"`
+	Cleaning           anomalyType.Cleaning          `json:"cleaning"`
+	Levels             []float64                     `json:"levels"`
+	PrettyLevels       []string                      `json:"prettyLevels"`
+	EmailingMinLevel   int                           `json:"emailingMinLevel"`
+}
+
+var (
+	ErrInvalidLevels           = errors.New("levels must be increasing and have as many pretty names")
+	ErrInvalidCleaning         = errors.New("cleaning thresholds must be positive")
+	ErrInvalidEmailingMinLevel = errors.New("emailing min level must be positive")
+)
+
+// DefaultSettings returns the settings set in config.
+func DefaultSettings() Settings {
+	settings := Settings{
+		DetectorParameters: make(map[string]map[string]float64),
+		Cleaning:           DefaultCleaning(),
+		PrettyLevels:       strings.Split(config.AnomalyDetectionPrettyLevels, ","),
+		EmailingMinLevel:   config.AnomalyEmailingMinLevel,
+	}
+	for _, level := range strings.Split(config.AnomalyDetectionLevels, ",") {
+		l, _ := strconv.ParseFloat(level, 64)
+		settings.Levels = append(settings.Levels, l)
+	}
+	return settings
+}
+
+// Override returns the settings overridden by the set settings of override.
+// Detector parameters are overridden one by one.
+func (s Settings) Override(override anomalyType.Settings) Settings {
+	parameters := make(map[string]map[string]float64, len(s.DetectorParameters))
+	for name, detectorParameters := range s.DetectorParameters {
+		parameters[name] = detectorParameters
+	}
+	for name, detectorParameters := range override.DetectorParameters {
+		merged := make(map[string]float64, len(parameters[name])+len(detectorParameters))
+		for parameter, value := range parameters[name] {
+			merged[parameter] = value
+		}
+		for parameter, value := range detectorParameters {
+			merged[parameter] = value
+		}
+		parameters[name] = merged
+	}
+	s.DetectorParameters = parameters
+	if override.MinPercentOfDailyBill != nil {
+		s.Cleaning.MinPercentOfDailyBill = *override.MinPercentOfDailyBill
+	}
+	if override.MinAbsoluteCost != nil {
+		s.Cleaning.MinAbsoluteCost = *override.MinAbsoluteCost
+	}
+	if override.HighestSpendingMinRank != nil {
+		s.Cleaning.HighestSpendingMinRank = *override.HighestSpendingMinRank
+	}
+	if override.RecurrenceThreshold != nil {
+		s.Cleaning.RecurrenceThreshold = *override.RecurrenceThreshold
+	}
+	if len(override.Levels) > 0 {
+		s.Levels = override.Levels
+		s.PrettyLevels = override.PrettyLevels
+	}
+	if override.EmailingMinLevel != nil {
+		s.EmailingMinLevel = *override.EmailingMinLevel
+	}
+	return s
+}
+
+// Detector returns the detector registered under name with the parameters of
+// the settings overridden by parameters. An empty name returns the default
+// detector set in config.
+func (s Settings) Detector(name string, parameters map[string]float64) (Detector, error) {
+	if name == "" {
+		name = config.AnomalyDetectionDefaultDetector
+	}
+	merged := make(map[string]float64, len(s.DetectorParameters[name])+len(parameters))
+	for parameter, value := range s.DetectorParameters[name] {
+		merged[parameter] = value
+	}
+	for parameter, value := range parameters {
+		merged[parameter] = value
+	}
+	return GetDetectorWithParameters(name, merged)
+}
+
+// Level returns the level and the pretty level of an anomaly from its cost
+// and its expected cost, in percentage of which the levels are expressed.
+func (s Settings) Level(cost, maxExpected float64) (int, string) {
+	if len(s.Levels) == 0 {
+		return 0, ""
+	}
+	level := len(s.Levels) - 1
+	percent := (cost * 100) / maxExpected
+	for i, l := range s.Levels[1:] {
+		if percent < l {
+			level = i
+			break
+		}
+	}
+	if level < len(s.PrettyLevels) {
+		return level, s.PrettyLevels[level]
+	}
+	return level, ""
+}
+
+// ValidSettings verifies the validity of the settings overriding the ones of
+// a user or an AWS account.
+func ValidSettings(settings anomalyType.Settings) error {
+	for name, parameters := range settings.DetectorParameters {
+		if !IsDetector(name) {
+			return fmt.Errorf("unknown anomaly detector: %s", name)
+		} else if _, err := GetDetectorWithParameters(name, parameters); err != nil {
+			return err
+		}
+	}
+	for _, threshold := range []*float64{settings.MinPercentOfDailyBill, settings.MinAbsoluteCost, settings.RecurrenceThreshold} {
+		if threshold != nil && *threshold < 0 {
+			return ErrInvalidCleaning
+		}
+	}
+	if settings.HighestSpendingMinRank != nil && *settings.HighestSpendingMinRank <= 0 {
+		return ErrInvalidCleaning
+	}
+	if len(settings.Levels) != len(settings.PrettyLevels) {
+		return ErrInvalidLevels
+	}
+	for i := 1; i < len(settings.Levels); i++ {
+		if settings.Levels[i] <= settings.Levels[i-1] {
+			return ErrInvalidLevels
+		}
+	}
+	if settings.EmailingMinLevel != nil && *settings.EmailingMinLevel < 0 {
+		return ErrInvalidEmailingMinLevel
+	}
+	return nil
+}
+
+// ParseSettings parses the settings stored in the anomalies_settings column
+// of a user or an AWS account. No settings are stored as NULL.
+func ParseSettings(raw []byte) (anomalyType.Settings, error) {
+	var settings anomalyType.Settings
+	if raw == nil {
+		return settings, nil
+	}
+	err := json.Unmarshal(raw, &settings)
+	return settings, err
+}
+
+// GetSettings returns the anomaly detection settings of an AWS account.
+func GetSettings(tx *sql.Tx, account aws.AwsAccount) (Settings, error) {
+	settings := DefaultSettings()
+	dbUser, err := models.UserByID(tx, account.UserId)
+	if err != nil {
+		return settings, err
+	}
+	userSettings, err := ParseSettings(dbUser.AnomaliesSettings)
+	if err != nil {
+		return settings, err
+	}
+	dbAwsAccount, err := models.AwsAccountByID(tx, account.Id)
+	if err != nil {
+		return settings, err
+	}
+	awsAccountSettings, err := ParseSettings(dbAwsAccount.AnomaliesSettings)
+	if err != nil {
+		return settings, err
+	}
+	return settings.Override(userSettings).Override(awsAccountSettings), nil
+}
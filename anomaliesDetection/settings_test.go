@@ -0,0 +1,111 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"testing"
+
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+)
+
+func TestSettingsOverride(t *testing.T) {
+	minAbsoluteCost := 500.0
+	emailingMinLevel := 0
+	user := anomalyType.Settings{
+		DetectorParameters: map[string]map[string]float64{
+			DetectorBollinger: {"period": 7, "upperBandCoefficient": 1.2},
+		},
+		MinAbsoluteCost: &minAbsoluteCost,
+		Levels:          []float64{0, 200},
+		PrettyLevels:    []string{"low", "high"},
+	}
+	awsAccount := anomalyType.Settings{
+		DetectorParameters: map[string]map[string]float64{
+			DetectorBollinger: {"period": 14},
+		},
+		EmailingMinLevel: &emailingMinLevel,
+	}
+	settings := Settings{
+		DetectorParameters: map[string]map[string]float64{},
+		Cleaning:           anomalyType.Cleaning{MinAbsoluteCost: 20, HighestSpendingMinRank: 5},
+		Levels:             []float64{0, 120, 150},
+		PrettyLevels:       []string{"low", "medium", "high"},
+		EmailingMinLevel:   2,
+	}.Override(user).Override(awsAccount)
+	if parameters := settings.DetectorParameters[DetectorBollinger]; parameters["period"] != 14 || parameters["upperBandCoefficient"] != 1.2 {
+		t.Errorf("Unexpected detector parameters: %v", parameters)
+	}
+	if settings.Cleaning.MinAbsoluteCost != 500 || settings.Cleaning.HighestSpendingMinRank != 5 {
+		t.Errorf("Unexpected cleaning: %v", settings.Cleaning)
+	}
+	if len(settings.Levels) != 2 || settings.PrettyLevels[1] != "high" || settings.EmailingMinLevel != 0 {
+		t.Errorf("Unexpected settings: %v", settings)
+	}
+	detector, err := settings.Detector(DetectorBollinger, map[string]float64{"standardDeviationCoefficient": 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if d := detector.(bollingerDetector); d.period != 14 || d.upperBandCoefficient != 1.2 || d.standardDeviationCoefficient != 2 {
+		t.Errorf("Unexpected detector: %v", d)
+	}
+}
+
+func TestSettingsLevel(t *testing.T) {
+	settings := Settings{
+		Levels:       []float64{0, 120, 150, 200},
+		PrettyLevels: []string{"low", "medium", "high", "critical"},
+	}
+	for _, c := range []struct {
+		cost        float64
+		level       int
+		prettyLevel string
+	}{
+		{110, 0, "low"},
+		{130, 1, "medium"},
+		{150, 2, "high"},
+		{500, 3, "critical"},
+	} {
+		if level, prettyLevel := settings.Level(c.cost, 100); level != c.level || prettyLevel != c.prettyLevel {
+			t.Errorf("Cost %f: level is %d (%s) instead of %d (%s)", c.cost, level, prettyLevel, c.level, c.prettyLevel)
+		}
+	}
+}
+
+func TestValidSettings(t *testing.T) {
+	negative := -1.0
+	zero := 0
+	valid := anomalyType.Settings{
+		DetectorParameters: map[string]map[string]float64{
+			DetectorMad: {"period": 7},
+		},
+		Levels:       []float64{0, 150},
+		PrettyLevels: []string{"low", "high"},
+	}
+	if err := ValidSettings(valid); err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	for _, settings := range []anomalyType.Settings{
+		{DetectorParameters: map[string]map[string]float64{"unknown": {}}},
+		{DetectorParameters: map[string]map[string]float64{DetectorMad: {"alpha": 0.5}}},
+		{MinAbsoluteCost: &negative},
+		{HighestSpendingMinRank: &zero},
+		{Levels: []float64{0, 150}},
+		{Levels: []float64{0, 150, 120}, PrettyLevels: []string{"low", "high", "critical"}},
+	} {
+		if err := ValidSettings(settings); err == nil {
+			t.Errorf("Settings %v should be invalid", settings)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"github.com/trackit/trackit/config"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+)
+
+// daysPerWeek is the seasonality of the weekdaySeasonalDetector.
+const daysPerWeek = 7
+
+// weekdaySeasonalDetector detects anomalies by comparing a cost to the costs
+// of the same weekday during the previous weeks, so that costs that are
+// always lower on weekends do not make the first day of the week abnormal.
+// The upper band is computed like the Bollinger Bands upper band.
+type weekdaySeasonalDetector struct {
+	weeks                        int
+	standardDeviationCoefficient float64
+	upperBandCoefficient         float64
+}
+
+// newWeekdaySeasonalDetector creates a weekdaySeasonalDetector with the
+// parameters in config overridden by parameters.
+func newWeekdaySeasonalDetector(parameters detectorParameters) Detector {
+	return weekdaySeasonalDetector{
+		weeks:                        parameters.getInt("weeks", config.AnomalyDetectionWeekdaySeasonalWeeks),
+		standardDeviationCoefficient: parameters.get("standardDeviationCoefficient", config.AnomalyDetectionWeekdaySeasonalStandardDeviationCoefficient),
+		upperBandCoefficient:         parameters.get("upperBandCoefficient", config.AnomalyDetectionWeekdaySeasonalUpperBandCoefficient),
+	}
+}
+
+// Description returns the name and the parameters of the detector.
+func (d weekdaySeasonalDetector) Description() anomalyType.Detector {
+	return anomalyType.Detector{
+		Name: DetectorWeekdaySeasonal,
+		Parameters: map[string]float64{
+			"weeks":                        float64(d.weeks),
+			"standardDeviationCoefficient": d.standardDeviationCoefficient,
+			"upperBandCoefficient":         d.upperBandCoefficient,
+		},
+	}
+}
+
+// History returns the number of days of the weeks used as a baseline.
+func (d weekdaySeasonalDetector) History() int {
+	return d.weeks * daysPerWeek
+}
+
+// Analyse calculates anomalies from the costs of the same weekday.
+// The costs of the first week have no baseline and are never abnormal.
+func (d weekdaySeasonalDetector) Analyse(aCosts AnalyzedCosts) AnalyzedCosts {
+	for index := range aCosts {
+		sameWeekdays := make(AnalyzedCosts, 0, d.weeks)
+		for week := 1; week <= d.weeks && index-week*daysPerWeek >= 0; week++ {
+			sameWeekdays = append(sameWeekdays, aCosts[index-week*daysPerWeek])
+		}
+		if len(sameWeekdays) > 0 {
+			a := &aCosts[index]
+			avg := average(sameWeekdays)
+			deviation := deviation(sigma(sameWeekdays, avg), len(sameWeekdays))
+			a.UpperBand = avg*d.upperBandCoefficient + (deviation * d.standardDeviationCoefficient)
+			if a.Cost > a.UpperBand {
+				a.Anomaly = true
+			}
+		}
+	}
+	return aCosts
+}
@@ -32,16 +32,17 @@ import (
 
 // AwsAccount represents a client's AWS account.
 type AwsAccount struct {
-	Id             int           `json:"id"`
-	UserId         int           `json:"-"`
-	Pretty         string        `json:"pretty"`
-	RoleArn        string        `json:"roleArn"`
-	External       string        `json:"-"`
-	Payer          bool          `json:"payer"`
-	AccountOwner   bool          `json:"accountOwner"`
-	UserPermission int           `json:"permissionLevel"`
-	AwsIdentity    string        `json:"awsIdentity"`
-	ParentId       sql.NullInt64 `json:"-"`
+	Id              int           `json:"id"`
+	UserId          int           `json:"-"`
+	Pretty          string        `json:"pretty"`
+	RoleArn         string        `json:"roleArn"`
+	External        string        `json:"-"`
+	Payer           bool          `json:"payer"`
+	AccountOwner    bool          `json:"accountOwner"`
+	UserPermission  int           `json:"permissionLevel"`
+	AwsIdentity     string        `json:"awsIdentity"`
+	ParentId        sql.NullInt64 `json:"-"`
+	AnomalyDetector string        `json:"anomalyDetector"`
 }
 
 var (
@@ -94,7 +95,8 @@ func GetAwsAccountsFromUser(u users.User, tx *sql.Tx) ([]AwsAccount, error) {
 			true,
 			0,
 			key.AwsIdentity,
-			key.ParentID})
+			key.ParentID,
+			key.AnomalyDetector})
 	}
 	for _, key := range dbShareAccounts {
 		dbAwsAccountById, err := models.AwsAccountByID(tx, key.AccountID)
@@ -111,7 +113,8 @@ func GetAwsAccountsFromUser(u users.User, tx *sql.Tx) ([]AwsAccount, error) {
 			false,
 			key.UserPermission,
 			dbAwsAccountById.AwsIdentity,
-			dbAwsAccountById.ParentID})
+			dbAwsAccountById.ParentID,
+			dbAwsAccountById.AnomalyDetector})
 	}
 	return res, nil
 }
@@ -170,7 +173,7 @@ func (a *AwsAccount) CreateAwsAccount(ctx context.Context, db models.XODB) error
 
 // UpdatePrettyAwsAccount updates an AWS account for a user. It does no error
 // checking: the caller should check themselves that the AWS account exists.
-// Only the Pretty will be updated.
+// Only the Pretty, the Payer, the RoleArn and the AnomalyDetector will be updated.
 func (a *AwsAccount) UpdatePrettyAwsAccount(ctx context.Context, tx *sql.Tx) error {
 	logger := jsonlog.LoggerFromContextOrDefault(ctx)
 	dbAwsAccount, err := models.AwsAccountByID(tx, a.Id)
@@ -180,6 +183,7 @@ func (a *AwsAccount) UpdatePrettyAwsAccount(ctx context.Context, tx *sql.Tx) err
 		dbAwsAccount.Pretty = a.Pretty
 		dbAwsAccount.Payer = a.Payer
 		dbAwsAccount.RoleArn = a.RoleArn
+		dbAwsAccount.AnomalyDetector = a.AnomalyDetector
 		err := dbAwsAccount.Update(tx)
 		if err != nil {
 			logger.Error("Failed to update AWS account in database.", err.Error())
@@ -247,13 +251,14 @@ func (a *AwsAccount) GetAwsAccountIdentity() (identity string, err error) {
 // the logic of the server.
 func AwsAccountFromDbAwsAccount(dbAwsAccount models.AwsAccount) AwsAccount {
 	return AwsAccount{
-		Id:          dbAwsAccount.ID,
-		UserId:      dbAwsAccount.UserID,
-		Pretty:      dbAwsAccount.Pretty,
-		RoleArn:     dbAwsAccount.RoleArn,
-		External:    dbAwsAccount.External,
-		Payer:       dbAwsAccount.Payer,
-		AwsIdentity: dbAwsAccount.AwsIdentity,
-		ParentId:    dbAwsAccount.ParentID,
+		Id:              dbAwsAccount.ID,
+		UserId:          dbAwsAccount.UserID,
+		Pretty:          dbAwsAccount.Pretty,
+		RoleArn:         dbAwsAccount.RoleArn,
+		External:        dbAwsAccount.External,
+		Payer:           dbAwsAccount.Payer,
+		AwsIdentity:     dbAwsAccount.AwsIdentity,
+		ParentId:        dbAwsAccount.ParentID,
+		AnomalyDetector: dbAwsAccount.AnomalyDetector,
 	}
 }
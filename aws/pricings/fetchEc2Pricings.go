@@ -17,6 +17,7 @@ package pricings
 import (
 	"context"
 	"errors"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -29,57 +30,122 @@ var (
 	EC2ServiceCode = "AmazonEC2"
 	// Pricing endpoints are only available in "us-east-1" and "ap-south-1"
 	PricingApiEndpointRegion = "us-east-1"
+	// EC2ProductFamilies are the product families of the EC2 pricings:
+	// instances, and the dedicated hosts they can be run on
+	EC2ProductFamilies = []string{"Compute Instance", "Dedicated Host"}
 )
 
+// EC2PricingVersion is the version of the format of the stored EC2 pricings.
+// It is increased whenever EC2Pricing changes shape, since pricings stored in
+// an older format would otherwise be read as empty.
+const EC2PricingVersion = 3
+
+// ErrOutdatedEC2Pricing is returned when the stored EC2 pricings are in an
+// older format and must be fetched again with the fetch-pricings task.
+var ErrOutdatedEC2Pricing = errors.New("EC2 pricings are outdated, they must be fetched again")
+
+const (
+	EC2TenancyShared    = "Shared"
+	EC2TenancyDedicated = "Dedicated"
+	EC2TenancyHost      = "Host"
+)
+
+const (
+	// EC2LicenseBYOL is the license model of instances whose license is
+	// brought by their owner
+	EC2LicenseBYOL = "Bring your own license"
+	// EC2PlatformAny is the platform of dedicated hosts, which are billed
+	// whatever the operating system of their instances
+	EC2PlatformAny = "Any"
+)
+
+// ReservationOffer stores the costs of a reservation purchase option.
+// The upfront cost is paid once for the whole term, the hourly cost is paid
+// every hour of the term.
+type ReservationOffer struct {
+	LeaseContractLength string  `json:"leaseContractLength"`
+	OfferingClass       string  `json:"offeringClass"`
+	PurchaseOption      string  `json:"purchaseOption"`
+	UpfrontCost         float64 `json:"upfrontCost"`
+	HourlyCost          float64 `json:"hourlyCost"`
+}
+
 // EC2Specs stores the cost specifications for an instance type
 type EC2Specs struct {
-	CurrentGeneration                     bool    `json:"currentGeneration"`
-	OnDemandHourlyCost                    float64 `json:"onDemandHourlyCost"`
-	OneYearStandardNoUpfrontHourlyCost    float64 `json:"oneYearStandardNoUpfrontHourlyCost"`
-	ThreeYearsStandardNoUpfrontHourlyCost float64 `json:"threeYearsStandardNoUpfrontHourlyCost"`
+	CurrentGeneration                     bool               `json:"currentGeneration"`
+	LicenseModel                          string             `json:"licenseModel"`
+	OnDemandHourlyCost                    float64            `json:"onDemandHourlyCost"`
+	OneYearStandardNoUpfrontHourlyCost    float64            `json:"oneYearStandardNoUpfrontHourlyCost"`
+	ThreeYearsStandardNoUpfrontHourlyCost float64            `json:"threeYearsStandardNoUpfrontHourlyCost"`
+	Reservations                          []ReservationOffer `json:"reservations"`
+}
+
+// EC2License maps a license model to a EC2Specs struct
+type EC2License struct {
+	License map[string]*EC2Specs `json:"license"`
 }
 
-// EC2Type maps an instance type to a EC2Specs struct
+// EC2Type maps an instance type to a EC2License struct
 type EC2Type struct {
-	Type map[string]*EC2Specs `json:"type"`
+	Type map[string]EC2License `json:"type"`
+}
+
+// EC2Tenancy maps a tenancy to a EC2Type struct
+type EC2Tenancy struct {
+	Tenancy map[string]EC2Type `json:"tenancy"`
 }
 
-// EC2Platform maps a platform to a EC2Type struct
+// EC2Platform maps a platform to a EC2Tenancy struct
 type EC2Platform struct {
-	Platform map[string]EC2Type `json:"platform"`
+	Platform map[string]EC2Tenancy `json:"platform"`
 }
 
 // EC2Pricing maps regions to a EC2Platform struct
 type EC2Pricing struct {
-	Region map[string]EC2Platform `json:"region"`
+	Version int                    `json:"version"`
+	Region  map[string]EC2Platform `json:"region"`
 }
 
-// getPricingProductInput takes a locationName (human readable region) and returns
-// a pricing.GetProductsInput with the correct filters to retrieve EC2 products
-func getPricingProductInput(locationName string) *pricing.GetProductsInput {
-	return &pricing.GetProductsInput{
-		Filters: []*pricing.Filter{
-			{
-				Field: aws.String("ServiceCode"),
-				Type:  aws.String("TERM_MATCH"),
-				Value: aws.String(EC2ServiceCode),
-			},
-			{
-				Field: aws.String("PreInstalledSw"),
-				Type:  aws.String("TERM_MATCH"),
-				Value: aws.String("NA"),
-			},
-			{
-				Field: aws.String("Location"),
-				Type:  aws.String("TERM_MATCH"),
-				Value: aws.String(locationName),
-			},
-			{
-				Field: aws.String("ProductFamily"),
-				Type:  aws.String("TERM_MATCH"),
-				Value: aws.String("Compute Instance"),
-			},
+// CheckVersion returns ErrOutdatedEC2Pricing if the pricings were not stored
+// with the current EC2PricingVersion.
+func (ec2Pricing EC2Pricing) CheckVersion() error {
+	if ec2Pricing.Version != EC2PricingVersion {
+		return ErrOutdatedEC2Pricing
+	}
+	return nil
+}
+
+// getPricingProductInput takes a locationName (human readable region) and a
+// product family and returns a pricing.GetProductsInput with the correct
+// filters to retrieve EC2 products
+func getPricingProductInput(locationName, productFamily string) *pricing.GetProductsInput {
+	filters := []*pricing.Filter{
+		{
+			Field: aws.String("ServiceCode"),
+			Type:  aws.String("TERM_MATCH"),
+			Value: aws.String(EC2ServiceCode),
+		},
+		{
+			Field: aws.String("Location"),
+			Type:  aws.String("TERM_MATCH"),
+			Value: aws.String(locationName),
 		},
+		{
+			Field: aws.String("ProductFamily"),
+			Type:  aws.String("TERM_MATCH"),
+			Value: aws.String(productFamily),
+		},
+	}
+	// Dedicated hosts come without pre-installed software
+	if productFamily == "Compute Instance" {
+		filters = append(filters, &pricing.Filter{
+			Field: aws.String("PreInstalledSw"),
+			Type:  aws.String("TERM_MATCH"),
+			Value: aws.String("NA"),
+		})
+	}
+	return &pricing.GetProductsInput{
+		Filters:       filters,
 		FormatVersion: aws.String("aws_v1"),
 		ServiceCode:   aws.String("AmazonEC2"),
 		MaxResults:    aws.Int64(100),
@@ -133,28 +199,43 @@ func isCurrentGeneration(item aws.JSONValue) bool {
 	return isCurrentGen
 }
 
-// isBoxUsage takes an item from the aws json pricing and returs true if
-// the item is a "BoxUsage" (an hourly instance cost)
-func isBoxUsage(item aws.JSONValue) bool {
+// getTenancy takes an item from the aws json pricing and returns its tenancy
+func getTenancy(item aws.JSONValue) string {
 	if attributes := getItemAttributes(item); attributes == nil {
-	} else if usageType, ok := attributes["usagetype"]; ok == false {
+	} else if tenancy, ok := attributes["tenancy"]; ok == false {
 	} else {
-		// The usage type is not formated the same way in all regions
-		return strings.HasPrefix(usageType.(string), "BoxUsage") ||
-			strings.Contains(usageType.(string), "-BoxUsage:")
+		return tenancy.(string)
 	}
-	return false
+	return ""
 }
 
-// isBYOL return true if the item is a "Bring your own licence" type
-// BYOL don't have reservations so we don't want to parse them
-func isBYOL(item aws.JSONValue) bool {
+// getLicenseModel takes an item from the aws json pricing and returns its license model
+func getLicenseModel(item aws.JSONValue) string {
 	if attributes := getItemAttributes(item); attributes == nil {
 	} else if licenseModel, ok := attributes["licenseModel"]; ok == false {
 	} else {
-		return licenseModel.(string) == "Bring your own license"
+		return licenseModel.(string)
 	}
-	return true
+	return ""
+}
+
+// isBoxUsage takes an item from the aws json pricing and returs true if
+// the item is a "BoxUsage", a "DedicatedUsage" or a "HostUsage" (an hourly
+// instance cost on shared or dedicated tenancy, or an hourly dedicated host
+// cost)
+func isBoxUsage(item aws.JSONValue) bool {
+	if attributes := getItemAttributes(item); attributes == nil {
+	} else if usageType, ok := attributes["usagetype"]; ok == false {
+	} else {
+		// The usage type is not formated the same way in all regions
+		for _, prefix := range []string{"BoxUsage", "DedicatedUsage", "HostUsage"} {
+			if strings.HasPrefix(usageType.(string), prefix) ||
+				strings.Contains(usageType.(string), "-"+prefix+":") {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // getTerms takes an item fron the aws json pricing and returns its terms
@@ -256,57 +337,161 @@ func getRIStandardNoUpfrontCost(item aws.JSONValue, duration string) float64 {
 	return -1.0
 }
 
-// FetchEc2Pricings fetches the EC2 pricings for all regions
+// getRIOffers takes an item from the aws JSON pricing and returns all its
+// reservation purchase options. Partial and all upfront options have an
+// upfront price dimension, in "Quantity", besides their hourly one.
+func getRIOffers(item aws.JSONValue) []ReservationOffer {
+	offers := make([]ReservationOffer, 0)
+	if terms := getTerms(item); terms == nil {
+	} else if reserved := getReserved(terms); reserved == nil {
+	} else {
+		for _, reservationType := range reserved {
+			termAttributes := getTermAttributes(reservationType.(map[string]interface{}))
+			priceDimensions := getPriceDimensions(reservationType.(map[string]interface{}))
+			if termAttributes == nil || priceDimensions == nil {
+				continue
+			}
+			offer := ReservationOffer{}
+			offer.LeaseContractLength, _ = termAttributes["LeaseContractLength"].(string)
+			offer.OfferingClass, _ = termAttributes["OfferingClass"].(string)
+			offer.PurchaseOption, _ = termAttributes["PurchaseOption"].(string)
+			for _, priceDimension := range priceDimensions {
+				price := getUSDPricePerUnit(priceDimension.(map[string]interface{}))
+				if price == -1.0 {
+					continue
+				}
+				if unit, _ := priceDimension.(map[string]interface{})["unit"].(string); unit == "Quantity" {
+					offer.UpfrontCost = price
+				} else {
+					offer.HourlyCost = price
+				}
+			}
+			offers = append(offers, offer)
+		}
+	}
+	sort.Slice(offers, func(i, j int) bool {
+		if offers[i].LeaseContractLength != offers[j].LeaseContractLength {
+			return offers[i].LeaseContractLength < offers[j].LeaseContractLength
+		} else if offers[i].OfferingClass != offers[j].OfferingClass {
+			return offers[i].OfferingClass > offers[j].OfferingClass
+		}
+		return offers[i].PurchaseOption < offers[j].PurchaseOption
+	})
+	return offers
+}
+
+// getEc2Specs takes an item from the aws JSON pricing and returns its EC2Specs
+func getEc2Specs(item aws.JSONValue) *EC2Specs {
+	// We do not verify that RI costs where extracted successfuly because
+	// some instance types don't have reservations
+	return &EC2Specs{
+		CurrentGeneration:                     isCurrentGeneration(item),
+		LicenseModel:                          getLicenseModel(item),
+		OnDemandHourlyCost:                    getOnDemandCost(item),
+		OneYearStandardNoUpfrontHourlyCost:    getRIStandardNoUpfrontCost(item, "1yr"),
+		ThreeYearsStandardNoUpfrontHourlyCost: getRIStandardNoUpfrontCost(item, "3yr"),
+		Reservations:                          getRIOffers(item),
+	}
+}
+
+// addEc2PricingItem adds an item from the aws JSON pricing to the pricings of a region
+// It returns false if the item could not be parsed
+func addEc2PricingItem(regionPricing EC2Platform, item aws.JSONValue) bool {
+	if !isBoxUsage(item) {
+		return true
+	}
+	platform := getNormalizedPlatform(item)
+	tenancy := getTenancy(item)
+	instanceType := getInstanceType(item)
+	specs := getEc2Specs(item)
+	if platform == "" && tenancy == EC2TenancyHost {
+		platform = EC2PlatformAny
+	}
+	if platform == "" || tenancy == "" || instanceType == "" || specs.OnDemandHourlyCost == -1.0 {
+		return false
+	}
+	if _, ok := regionPricing.Platform[platform]; !ok {
+		regionPricing.Platform[platform] = EC2Tenancy{Tenancy: make(map[string]EC2Type, 0)}
+	}
+	if _, ok := regionPricing.Platform[platform].Tenancy[tenancy]; !ok {
+		regionPricing.Platform[platform].Tenancy[tenancy] = EC2Type{Type: make(map[string]EC2License, 0)}
+	}
+	if _, ok := regionPricing.Platform[platform].Tenancy[tenancy].Type[instanceType]; !ok {
+		regionPricing.Platform[platform].Tenancy[tenancy].Type[instanceType] = EC2License{License: make(map[string]*EC2Specs, 0)}
+	}
+	regionPricing.Platform[platform].Tenancy[tenancy].Type[instanceType].License[specs.LicenseModel] = specs
+	return true
+}
+
+// GetSpecs returns the pricing of an instance type for a given region, platform,
+// tenancy and license model. An empty license model selects the pricing of
+// instances whose license, if any, is provided by AWS
+func (ec2Pricing EC2Pricing) GetSpecs(region, platform, tenancy, instanceType, licenseModel string) (EC2Specs, error) {
+	if platforms, ok := ec2Pricing.Region[region]; ok == false {
+		return EC2Specs{}, errors.New("Region not found in EC2 pricings")
+	} else if tenancies, ok := platforms.Platform[platform]; ok == false {
+		return EC2Specs{}, errors.New("EC2Platform not found in EC2 pricings")
+	} else if types, ok := tenancies.Tenancy[tenancy]; ok == false {
+		return EC2Specs{}, errors.New("EC2Tenancy not found in EC2 pricings")
+	} else if licenses, ok := types.Type[instanceType]; ok == false {
+		return EC2Specs{}, errors.New("EC2Type not found in EC2 pricings")
+	} else if costSpecs := licenses.getSpecs(licenseModel); costSpecs == nil {
+		return EC2Specs{}, errors.New("EC2License not found in EC2 pricings")
+	} else {
+		return *costSpecs, nil
+	}
+}
+
+// getSpecs returns the pricing of a license model, or of the license model
+// provided by AWS if licenseModel is empty
+func (licenses EC2License) getSpecs(licenseModel string) *EC2Specs {
+	if licenseModel != "" {
+		return licenses.License[licenseModel]
+	}
+	var found *EC2Specs
+	for model, specs := range licenses.License {
+		if model == EC2LicenseBYOL {
+			continue
+		} else if found == nil || model < found.LicenseModel {
+			// The order must not depend on the map iteration
+			found = specs
+		}
+	}
+	return found
+}
+
+// FetchEc2Pricings fetches the EC2 pricings of instances and dedicated hosts for all regions
 // The informations that are retrieved are the instance size, the platform,
-// the hourly costs for on demand, one year no upfront and 3 years no upfront
+// the tenancy, the license model, the hourly costs for on demand and the
+// upfront and hourly costs of all the reservation purchase options
 // If one of the buying options is not available, its cost is set to -1.0
 // FetchEc2Pricings returns an EC2Pricing struct and an error
 func FetchEc2Pricings(ctx context.Context) (EC2Pricing, error) {
 	logger := jsonlog.LoggerFromContextOrDefault(ctx)
 	parsingError := false
-	ec2Pricings := EC2Pricing{Region: make(map[string]EC2Platform, 0)}
+	ec2Pricings := EC2Pricing{Version: EC2PricingVersion, Region: make(map[string]EC2Platform, 0)}
 	svc := getPricingClient()
 	for regionCode, locationName := range EC2RegionCodeToPricingLocationName {
 		logger.Info("Fetching pricings for region", map[string]interface{}{"region": regionCode})
-		ec2Pricings.Region[regionCode] = EC2Platform{Platform: make(map[string]EC2Type, 0)}
-		input := getPricingProductInput(locationName)
-		err := svc.GetProductsPages(input,
-			func(page *pricing.GetProductsOutput, lastPage bool) bool {
-				for _, item := range page.PriceList {
-					if !isBoxUsage(item) || isBYOL(item) {
-						continue
-					}
-					platform := getNormalizedPlatform(item)
-					instanceType := getInstanceType(item)
-					currentGen := isCurrentGeneration(item)
-					onDemandCost := getOnDemandCost(item)
-					if platform == "" || instanceType == "" || onDemandCost == -1.0 {
-						// This case should not happen unless the pricing format has changed
-						// In case of format change, the error will be logged at the end of the function
-						// to avoid sending multiple alerts
-						parsingError = true
-						continue
-					}
-					if _, ok := ec2Pricings.Region[regionCode].Platform[platform]; !ok {
-						ec2Pricings.Region[regionCode].Platform[platform] = EC2Type{Type: make(map[string]*EC2Specs, 0)}
+		ec2Pricings.Region[regionCode] = EC2Platform{Platform: make(map[string]EC2Tenancy, 0)}
+		for _, productFamily := range EC2ProductFamilies {
+			input := getPricingProductInput(locationName, productFamily)
+			err := svc.GetProductsPages(input,
+				func(page *pricing.GetProductsOutput, lastPage bool) bool {
+					for _, item := range page.PriceList {
+						if !addEc2PricingItem(ec2Pricings.Region[regionCode], item) {
+							// This case should not happen unless the pricing format has changed
+							// In case of format change, the error will be logged at the end of the function
+							// to avoid sending multiple alerts
+							parsingError = true
+						}
 					}
-					if _, ok := ec2Pricings.Region[regionCode].Platform[platform].Type[instanceType]; !ok {
-						ec2Pricings.Region[regionCode].Platform[platform].Type[instanceType] = &EC2Specs{}
-					}
-					ec2Pricings.Region[regionCode].Platform[platform].Type[instanceType].CurrentGeneration = currentGen
-					ec2Pricings.Region[regionCode].Platform[platform].Type[instanceType].OnDemandHourlyCost = onDemandCost
-					// We do not verify that RI costs where extracted successfuly because
-					// some instance types don't have reservations
-					oneYearNoUpfrontCost := getRIStandardNoUpfrontCost(item, "1yr")
-					threeYearsNoUpfrontCost := getRIStandardNoUpfrontCost(item, "3yr")
-					ec2Pricings.Region[regionCode].Platform[platform].Type[instanceType].OneYearStandardNoUpfrontHourlyCost = oneYearNoUpfrontCost
-					ec2Pricings.Region[regionCode].Platform[platform].Type[instanceType].ThreeYearsStandardNoUpfrontHourlyCost = threeYearsNoUpfrontCost
-				}
-				return !lastPage
-			})
-		if err != nil {
-			logger.Error("Failed to get products pages", err.Error())
-			return ec2Pricings, err
+					return !lastPage
+				})
+			if err != nil {
+				logger.Error("Failed to get products pages", err.Error())
+				return ec2Pricings, err
+			}
 		}
 	}
 	if parsingError == true {
@@ -315,3 +500,35 @@ func FetchEc2Pricings(ctx context.Context) (EC2Pricing, error) {
 	}
 	return ec2Pricings, nil
 }
+
+// ec2OfferFilter returns true if a product of the EC2 bulk offer file matches
+// the filters used to retrieve EC2 products from the Pricing API
+func ec2OfferFilter(productFamily string, attributes map[string]interface{}) bool {
+	return productFamily == "Dedicated Host" ||
+		productFamily == "Compute Instance" && getStringAttribute(attributes, "preInstalledSw") == "NA"
+}
+
+// ImportEc2Pricings imports the EC2 pricings for all regions from the EC2 bulk
+// offer file found in location, instead of using the Pricing API
+// location is either a local directory or an S3 location (s3://bucket/prefix)
+// The informations that are retrieved are the same as FetchEc2Pricings
+func ImportEc2Pricings(ctx context.Context, location string) (EC2Pricing, error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	parsingError := false
+	ec2Pricings := EC2Pricing{Version: EC2PricingVersion, Region: make(map[string]EC2Platform, 0)}
+	err := importOfferFile(ctx, location, EC2ServiceCode, ec2OfferFilter, func(region string, item aws.JSONValue) {
+		if _, ok := ec2Pricings.Region[region]; !ok {
+			ec2Pricings.Region[region] = EC2Platform{Platform: make(map[string]EC2Tenancy, 0)}
+		}
+		if !addEc2PricingItem(ec2Pricings.Region[region], item) {
+			parsingError = true
+		}
+	})
+	if err != nil {
+		return ec2Pricings, err
+	} else if parsingError == true {
+		logger.Error("Parsing error while importing EC2 pricings", nil)
+		return ec2Pricings, errors.New("Parsing error while importing EC2 pricings")
+	}
+	return ec2Pricings, nil
+}
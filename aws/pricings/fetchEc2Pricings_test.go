@@ -0,0 +1,199 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package pricings
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const testDedicatedItem = `{
+	"product": {
+		"productFamily": "Compute Instance",
+		"attributes": {
+			"instanceType": "m5.large",
+			"currentGeneration": "Yes",
+			"operatingSystem": "Linux",
+			"tenancy": "Dedicated",
+			"licenseModel": "No License required",
+			"usagetype": "EU-DedicatedUsage:m5.large",
+			"preInstalledSw": "NA"
+		}
+	},
+	"terms": {
+		"OnDemand": {
+			"A.JRTCKXETXF": {
+				"priceDimensions": {
+					"A.JRTCKXETXF.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.1180000000"}}
+				}
+			}
+		},
+		"Reserved": {
+			"A.4NA7Y494T4": {
+				"priceDimensions": {
+					"A.4NA7Y494T4.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0760000000"}}
+				},
+				"termAttributes": {"LeaseContractLength": "1yr", "OfferingClass": "standard", "PurchaseOption": "No Upfront"}
+			},
+			"A.6QCMYABX3D": {
+				"priceDimensions": {
+					"A.6QCMYABX3D.2TG2D8R56U": {"unit": "Quantity", "pricePerUnit": {"USD": "636"}},
+					"A.6QCMYABX3D.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0000000000"}}
+				},
+				"termAttributes": {"LeaseContractLength": "1yr", "OfferingClass": "standard", "PurchaseOption": "All Upfront"}
+			},
+			"A.R5XV2EPZQZ": {
+				"priceDimensions": {
+					"A.R5XV2EPZQZ.2TG2D8R56U": {"unit": "Quantity", "pricePerUnit": {"USD": "854"}},
+					"A.R5XV2EPZQZ.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0330000000"}}
+				},
+				"termAttributes": {"LeaseContractLength": "3yr", "OfferingClass": "convertible", "PurchaseOption": "Partial Upfront"}
+			}
+		}
+	}
+}`
+
+// testHostItem is a dedicated host, which has no operating system and is
+// priced per host of an instance family
+const testHostItem = `{
+	"product": {
+		"productFamily": "Dedicated Host",
+		"attributes": {
+			"instanceType": "m5",
+			"tenancy": "Host",
+			"usagetype": "EU-HostUsage:m5"
+		}
+	},
+	"terms": {
+		"OnDemand": {
+			"B.JRTCKXETXF": {
+				"priceDimensions": {
+					"B.JRTCKXETXF.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "5.0690000000"}}
+				}
+			}
+		}
+	}
+}`
+
+func getTestItem(t *testing.T) aws.JSONValue {
+	return parseTestItem(t, testDedicatedItem)
+}
+
+func parseTestItem(t *testing.T, raw string) aws.JSONValue {
+	var item aws.JSONValue
+	if err := json.Unmarshal([]byte(raw), &item); err != nil {
+		t.Fatal(err)
+	}
+	return item
+}
+
+func TestGetRIOffers(t *testing.T) {
+	offers := getRIOffers(getTestItem(t))
+	expected := []ReservationOffer{
+		{"1yr", "standard", "All Upfront", 636, 0},
+		{"1yr", "standard", "No Upfront", 0, 0.076},
+		{"3yr", "convertible", "Partial Upfront", 854, 0.033},
+	}
+	if len(offers) != len(expected) {
+		t.Fatalf("Expected %d offers, got %d.", len(expected), len(offers))
+	}
+	for i := range expected {
+		if offers[i] != expected[i] {
+			t.Errorf("Expected offer %v, got %v.", expected[i], offers[i])
+		}
+	}
+}
+
+func TestAddEc2PricingItem(t *testing.T) {
+	pricing := EC2Pricing{Region: map[string]EC2Platform{"eu-west-1": {Platform: make(map[string]EC2Tenancy)}}}
+	if addEc2PricingItem(pricing.Region["eu-west-1"], getTestItem(t)) == false {
+		t.Fatal("Expected the item to be parsed.")
+	}
+	specs, err := pricing.GetSpecs("eu-west-1", "Linux/UNIX", EC2TenancyDedicated, "m5.large", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if specs.OnDemandHourlyCost != 0.118 || specs.OneYearStandardNoUpfrontHourlyCost != 0.076 ||
+		specs.ThreeYearsStandardNoUpfrontHourlyCost != -1.0 || specs.LicenseModel != "No License required" ||
+		len(specs.Reservations) != 3 {
+		t.Errorf("Unexpected specs: %v", specs)
+	}
+	if _, err := pricing.GetSpecs("eu-west-1", "Linux/UNIX", EC2TenancyShared, "m5.large", ""); err == nil {
+		t.Error("Expected no pricing for the shared tenancy.")
+	}
+}
+
+func TestAddEc2PricingItemLicenseModels(t *testing.T) {
+	pricing := EC2Pricing{Region: map[string]EC2Platform{"eu-west-1": {Platform: make(map[string]EC2Tenancy)}}}
+	for _, license := range []struct {
+		model  string
+		usage  string
+		hourly string
+	}{
+		{"License Included", "EU-DedicatedUsage:m5.large", "0.2100000000"},
+		{EC2LicenseBYOL, "EU-DedicatedUsage:m5.large", "0.1180000000"},
+	} {
+		raw := strings.Replace(testDedicatedItem, `"operatingSystem": "Linux"`, `"operatingSystem": "Windows"`, 1)
+		raw = strings.Replace(raw, `"No License required"`, `"`+license.model+`"`, 1)
+		raw = strings.Replace(raw, `"0.1180000000"`, `"`+license.hourly+`"`, 1)
+		if !addEc2PricingItem(pricing.Region["eu-west-1"], parseTestItem(t, raw)) {
+			t.Fatalf("Expected the %s item to be parsed.", license.model)
+		}
+	}
+	if specs, err := pricing.GetSpecs("eu-west-1", "Windows", EC2TenancyDedicated, "m5.large", EC2LicenseBYOL); err != nil {
+		t.Error(err)
+	} else if specs.OnDemandHourlyCost != 0.118 {
+		t.Errorf("Expected the BYOL on demand cost to be 0.118, got %f.", specs.OnDemandHourlyCost)
+	}
+	if specs, err := pricing.GetSpecs("eu-west-1", "Windows", EC2TenancyDedicated, "m5.large", ""); err != nil {
+		t.Error(err)
+	} else if specs.OnDemandHourlyCost != 0.21 || specs.LicenseModel != "License Included" {
+		t.Errorf("Expected the license included pricing by default, got %v.", specs)
+	}
+}
+
+func TestAddEc2PricingItemHost(t *testing.T) {
+	pricing := EC2Pricing{Region: map[string]EC2Platform{"eu-west-1": {Platform: make(map[string]EC2Tenancy)}}}
+	if !addEc2PricingItem(pricing.Region["eu-west-1"], parseTestItem(t, testHostItem)) {
+		t.Fatal("Expected the dedicated host item to be parsed.")
+	}
+	if specs, err := pricing.GetSpecs("eu-west-1", EC2PlatformAny, EC2TenancyHost, "m5", ""); err != nil {
+		t.Error(err)
+	} else if specs.OnDemandHourlyCost != 5.069 {
+		t.Errorf("Expected the host on demand cost to be 5.069, got %f.", specs.OnDemandHourlyCost)
+	}
+	if !ec2OfferFilter("Dedicated Host", map[string]interface{}{}) {
+		t.Error("Expected dedicated hosts to be imported from offer files.")
+	}
+}
+
+func TestCheckVersionOutdated(t *testing.T) {
+	// Pricings stored before the tenancy level was added have no version
+	const stored = `{"region":{"eu-west-1":{"platform":{"Linux":{"type":{"m5.large":{"onDemandHourlyCost":0.107}}}}}}}`
+	var pricing EC2Pricing
+	if err := json.Unmarshal([]byte(stored), &pricing); err != nil {
+		t.Fatalf("Failed to unmarshal the stored pricings: %s", err.Error())
+	}
+	if err := pricing.CheckVersion(); err != ErrOutdatedEC2Pricing {
+		t.Errorf("Expected the stored pricings to be outdated, got %v.", err)
+	}
+	pricing.Version = EC2PricingVersion
+	if err := pricing.CheckVersion(); err != nil {
+		t.Errorf("Expected the pricings to be up to date, got %s.", err.Error())
+	}
+}
@@ -0,0 +1,331 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package pricings
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/trackit/jsonlog"
+)
+
+var (
+	RDSServiceCode         = "AmazonRDS"
+	ElastiCacheServiceCode = "AmazonElastiCache"
+	ESServiceCode          = "AmazonES"
+
+	// ESEngine is the only engine of the Elasticsearch Service pricings
+	ESEngine = "Elasticsearch"
+
+	// rdsEngines maps the RDS engines and reservations product descriptions
+	// to the database engine and edition of the RDS pricings
+	rdsEngines = map[string]string{
+		"mysql":             "MySQL",
+		"mariadb":           "MariaDB",
+		"postgres":          "PostgreSQL",
+		"postgresql":        "PostgreSQL",
+		"aurora":            "Aurora MySQL",
+		"aurora-mysql":      "Aurora MySQL",
+		"aurora-postgresql": "Aurora PostgreSQL",
+		"oracle-se":         "Oracle Standard",
+		"oracle-se1":        "Oracle Standard One",
+		"oracle-se2":        "Oracle Standard Two",
+		"oracle-ee":         "Oracle Enterprise",
+		"sqlserver-ex":      "SQL Server Express",
+		"sqlserver-web":     "SQL Server Web",
+		"sqlserver-se":      "SQL Server Standard",
+		"sqlserver-ee":      "SQL Server Enterprise",
+	}
+
+	// rdsCatalog retrieves the database instances of RDS
+	rdsCatalog = nodesCatalog{
+		ServiceCode:   RDSServiceCode,
+		ProductFamily: "Database Instance",
+		GetEngine:     getRdsEngine,
+	}
+
+	// elastiCacheCatalog retrieves the cache nodes of ElastiCache
+	elastiCacheCatalog = nodesCatalog{
+		ServiceCode:   ElastiCacheServiceCode,
+		ProductFamily: "Cache Instance",
+		GetEngine:     getElastiCacheEngine,
+	}
+
+	// esCatalog retrieves the instances of Elasticsearch Service
+	esCatalog = nodesCatalog{
+		ServiceCode:   ESServiceCode,
+		ProductFamily: "Elastic Search Instance",
+		GetEngine:     getEsEngine,
+	}
+)
+
+type (
+	// NodeSpecs stores the cost specifications for a node type of a
+	// reservable service (RDS, ElastiCache or Elasticsearch Service)
+	NodeSpecs struct {
+		OnDemandHourlyCost float64            `json:"onDemandHourlyCost"`
+		Reservations       []ReservationOffer `json:"reservations"`
+	}
+
+	// NodeType maps a node type to a NodeSpecs struct
+	NodeType struct {
+		Type map[string]*NodeSpecs `json:"type"`
+	}
+
+	// NodeEngine maps an engine to a NodeType struct
+	NodeEngine struct {
+		Engine map[string]NodeType `json:"engine"`
+	}
+
+	// NodePricing maps a region to a NodeEngine struct
+	NodePricing struct {
+		Region map[string]NodeEngine `json:"region"`
+	}
+
+	// nodesCatalog describes how to retrieve the pricings of a reservable service
+	// GetEngine returns the engine of an item from its attributes, or an empty
+	// string if the item must be ignored
+	nodesCatalog struct {
+		ServiceCode   string
+		ProductFamily string
+		GetEngine     func(attributes map[string]interface{}) string
+	}
+)
+
+// getStringAttribute returns an attribute of an item from the aws json pricing,
+// or an empty string if it does not exist
+func getStringAttribute(attributes map[string]interface{}, name string) string {
+	if value, ok := attributes[name].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// GetRdsPricingEngine takes an RDS engine, or the product description of an RDS
+// reservation, and returns the engine used in the RDS pricings
+func GetRdsPricingEngine(engine string, multiAZ bool) string {
+	// Product descriptions of reservations end with the license model: "oracle-se2(li)"
+	if i := strings.Index(engine, "("); i != -1 {
+		engine = engine[:i]
+	}
+	pricingEngine, ok := rdsEngines[strings.ToLower(engine)]
+	if ok == false {
+		return ""
+	}
+	if multiAZ {
+		return pricingEngine + "/Multi-AZ"
+	}
+	return pricingEngine + "/Single-AZ"
+}
+
+// getRdsEngine returns the engine of an RDS item with its edition and its
+// deployment option, in the same format as GetRdsPricingEngine
+// Bring your own license items are ignored
+func getRdsEngine(attributes map[string]interface{}) string {
+	engine := getStringAttribute(attributes, "databaseEngine")
+	deployment := getStringAttribute(attributes, "deploymentOption")
+	if engine == "" || getStringAttribute(attributes, "licenseModel") == "Bring your own license" ||
+		(deployment != "Single-AZ" && deployment != "Multi-AZ") {
+		return ""
+	}
+	if edition := getStringAttribute(attributes, "databaseEdition"); edition != "" {
+		engine += " " + edition
+	}
+	return engine + "/" + deployment
+}
+
+// GetElastiCachePricingEngine takes an ElastiCache engine, or the product description
+// of an ElastiCache reservation, and returns the engine used in the ElastiCache pricings
+func GetElastiCachePricingEngine(engine string) string {
+	switch strings.ToLower(engine) {
+	case "redis":
+		return "Redis"
+	case "memcached":
+		return "Memcached"
+	}
+	return ""
+}
+
+// getElastiCacheEngine returns the engine of an ElastiCache item
+func getElastiCacheEngine(attributes map[string]interface{}) string {
+	return GetElastiCachePricingEngine(getStringAttribute(attributes, "cacheEngine"))
+}
+
+// getEsEngine returns the engine of an Elasticsearch Service item
+func getEsEngine(attributes map[string]interface{}) string {
+	return ESEngine
+}
+
+// getNodesPricingProductInput takes a catalog and a locationName (human readable region)
+// and returns a pricing.GetProductsInput with the correct filters to retrieve its products
+func getNodesPricingProductInput(catalog nodesCatalog, locationName string) *pricing.GetProductsInput {
+	return &pricing.GetProductsInput{
+		Filters: []*pricing.Filter{
+			{
+				Field: aws.String("ServiceCode"),
+				Type:  aws.String("TERM_MATCH"),
+				Value: aws.String(catalog.ServiceCode),
+			},
+			{
+				Field: aws.String("Location"),
+				Type:  aws.String("TERM_MATCH"),
+				Value: aws.String(locationName),
+			},
+			{
+				Field: aws.String("ProductFamily"),
+				Type:  aws.String("TERM_MATCH"),
+				Value: aws.String(catalog.ProductFamily),
+			},
+		},
+		FormatVersion: aws.String("aws_v1"),
+		ServiceCode:   aws.String(catalog.ServiceCode),
+		MaxResults:    aws.Int64(100),
+	}
+}
+
+// addNodePricingItem adds an item from the aws JSON pricing to the pricings of a region
+// It returns false if the item could not be parsed
+func addNodePricingItem(catalog nodesCatalog, regionPricing NodeEngine, item aws.JSONValue) bool {
+	attributes := getItemAttributes(item)
+	if attributes == nil {
+		return false
+	}
+	engine := catalog.GetEngine(attributes)
+	if engine == "" {
+		return true
+	}
+	nodeType := getInstanceType(item)
+	specs := &NodeSpecs{
+		OnDemandHourlyCost: getOnDemandCost(item),
+		Reservations:       getRIOffers(item),
+	}
+	if nodeType == "" || specs.OnDemandHourlyCost == -1.0 {
+		return false
+	}
+	if _, ok := regionPricing.Engine[engine]; !ok {
+		regionPricing.Engine[engine] = NodeType{Type: make(map[string]*NodeSpecs, 0)}
+	}
+	regionPricing.Engine[engine].Type[nodeType] = specs
+	return true
+}
+
+// GetSpecs returns the pricing of a node type for a given region and engine
+func (nodePricing NodePricing) GetSpecs(region, engine, nodeType string) (NodeSpecs, error) {
+	if engines, ok := nodePricing.Region[region]; ok == false {
+		return NodeSpecs{}, errors.New("Region not found in pricings")
+	} else if types, ok := engines.Engine[engine]; ok == false {
+		return NodeSpecs{}, errors.New("Engine not found in pricings")
+	} else if costSpecs, ok := types.Type[nodeType]; ok == false {
+		return NodeSpecs{}, errors.New("Node type not found in pricings")
+	} else {
+		return *costSpecs, nil
+	}
+}
+
+// fetchNodesPricings fetches the pricings of a catalog for all regions
+// The informations that are retrieved are the node type, the engine, the hourly
+// on demand cost and the upfront and hourly costs of all the reservation purchase options
+func fetchNodesPricings(ctx context.Context, catalog nodesCatalog) (NodePricing, error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	parsingError := false
+	nodePricing := NodePricing{Region: make(map[string]NodeEngine, 0)}
+	svc := getPricingClient()
+	for regionCode, locationName := range EC2RegionCodeToPricingLocationName {
+		logger.Info("Fetching pricings for region", map[string]interface{}{
+			"region":  regionCode,
+			"service": catalog.ServiceCode,
+		})
+		nodePricing.Region[regionCode] = NodeEngine{Engine: make(map[string]NodeType, 0)}
+		input := getNodesPricingProductInput(catalog, locationName)
+		err := svc.GetProductsPages(input,
+			func(page *pricing.GetProductsOutput, lastPage bool) bool {
+				for _, item := range page.PriceList {
+					if !addNodePricingItem(catalog, nodePricing.Region[regionCode], item) {
+						// The error is logged at the end of the function to avoid sending multiple alerts
+						parsingError = true
+					}
+				}
+				return !lastPage
+			})
+		if err != nil {
+			logger.Error("Failed to get products pages", err.Error())
+			return nodePricing, err
+		}
+	}
+	if parsingError == true {
+		logger.Error("Parsing error while retrieving pricings", map[string]interface{}{"service": catalog.ServiceCode})
+		return nodePricing, errors.New("Parsing error while retrieving " + catalog.ServiceCode + " pricings")
+	}
+	return nodePricing, nil
+}
+
+// FetchRdsPricings fetches the RDS pricings for all regions
+func FetchRdsPricings(ctx context.Context) (NodePricing, error) {
+	return fetchNodesPricings(ctx, rdsCatalog)
+}
+
+// FetchElastiCachePricings fetches the ElastiCache pricings for all regions
+func FetchElastiCachePricings(ctx context.Context) (NodePricing, error) {
+	return fetchNodesPricings(ctx, elastiCacheCatalog)
+}
+
+// FetchEsPricings fetches the Elasticsearch Service pricings for all regions
+func FetchEsPricings(ctx context.Context) (NodePricing, error) {
+	return fetchNodesPricings(ctx, esCatalog)
+}
+
+// importNodesPricings imports the pricings of a catalog for all regions from
+// the bulk offer file of its service found in location
+func importNodesPricings(ctx context.Context, catalog nodesCatalog, location string) (NodePricing, error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	parsingError := false
+	nodePricing := NodePricing{Region: make(map[string]NodeEngine, 0)}
+	filter := func(productFamily string, attributes map[string]interface{}) bool {
+		return productFamily == catalog.ProductFamily
+	}
+	err := importOfferFile(ctx, location, catalog.ServiceCode, filter, func(region string, item aws.JSONValue) {
+		if _, ok := nodePricing.Region[region]; !ok {
+			nodePricing.Region[region] = NodeEngine{Engine: make(map[string]NodeType, 0)}
+		}
+		if !addNodePricingItem(catalog, nodePricing.Region[region], item) {
+			parsingError = true
+		}
+	})
+	if err != nil {
+		return nodePricing, err
+	} else if parsingError == true {
+		logger.Error("Parsing error while importing pricings", map[string]interface{}{"service": catalog.ServiceCode})
+		return nodePricing, errors.New("Parsing error while importing " + catalog.ServiceCode + " pricings")
+	}
+	return nodePricing, nil
+}
+
+// ImportRdsPricings imports the RDS pricings for all regions from the RDS bulk offer file
+func ImportRdsPricings(ctx context.Context, location string) (NodePricing, error) {
+	return importNodesPricings(ctx, rdsCatalog, location)
+}
+
+// ImportElastiCachePricings imports the ElastiCache pricings for all regions from the ElastiCache bulk offer file
+func ImportElastiCachePricings(ctx context.Context, location string) (NodePricing, error) {
+	return importNodesPricings(ctx, elastiCacheCatalog, location)
+}
+
+// ImportEsPricings imports the Elasticsearch Service pricings for all regions from the Elasticsearch Service bulk offer file
+func ImportEsPricings(ctx context.Context, location string) (NodePricing, error) {
+	return importNodesPricings(ctx, esCatalog, location)
+}
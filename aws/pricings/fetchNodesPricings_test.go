@@ -0,0 +1,120 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package pricings
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const testRdsItem = `{
+	"product": {
+		"productFamily": "Database Instance",
+		"attributes": {
+			"instanceType": "db.m5.large",
+			"databaseEngine": "Oracle",
+			"databaseEdition": "Standard Two",
+			"deploymentOption": "Multi-AZ",
+			"licenseModel": "License included"
+		}
+	},
+	"terms": {
+		"OnDemand": {
+			"A.JRTCKXETXF": {
+				"priceDimensions": {
+					"A.JRTCKXETXF.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "1.0040000000"}}
+				}
+			}
+		},
+		"Reserved": {
+			"A.4NA7Y494T4": {
+				"priceDimensions": {
+					"A.4NA7Y494T4.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.7200000000"}}
+				},
+				"termAttributes": {"LeaseContractLength": "1yr", "OfferingClass": "standard", "PurchaseOption": "No Upfront"}
+			}
+		}
+	}
+}`
+
+func getTestNodeItem(t *testing.T, attributes map[string]interface{}) aws.JSONValue {
+	var item aws.JSONValue
+	if err := json.Unmarshal([]byte(testRdsItem), &item); err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range attributes {
+		getItemAttributes(item)[key] = value
+	}
+	return item
+}
+
+func TestGetRdsPricingEngine(t *testing.T) {
+	for _, c := range []struct {
+		engine   string
+		multiAZ  bool
+		expected string
+	}{
+		{"postgres", false, "PostgreSQL/Single-AZ"},
+		{"postgresql", true, "PostgreSQL/Multi-AZ"},
+		{"oracle-se2", true, "Oracle Standard Two/Multi-AZ"},
+		{"oracle-se2(li)", false, "Oracle Standard Two/Single-AZ"},
+		{"unknown", false, ""},
+	} {
+		if res := GetRdsPricingEngine(c.engine, c.multiAZ); res != c.expected {
+			t.Errorf("Expected engine '%s' for %v, got '%s'.", c.expected, c, res)
+		}
+	}
+}
+
+func TestAddNodePricingItem(t *testing.T) {
+	pricing := NodePricing{Region: map[string]NodeEngine{"eu-west-1": {Engine: make(map[string]NodeType)}}}
+	if addNodePricingItem(rdsCatalog, pricing.Region["eu-west-1"], getTestNodeItem(t, nil)) == false {
+		t.Fatal("Expected the item to be parsed.")
+	}
+	specs, err := pricing.GetSpecs("eu-west-1", GetRdsPricingEngine("oracle-se2", true), "db.m5.large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if specs.OnDemandHourlyCost != 1.004 || len(specs.Reservations) != 1 || specs.Reservations[0].HourlyCost != 0.72 {
+		t.Errorf("Unexpected specs: %v", specs)
+	}
+}
+
+func TestAddNodePricingItemIgnored(t *testing.T) {
+	pricing := NodePricing{Region: map[string]NodeEngine{"eu-west-1": {Engine: make(map[string]NodeType)}}}
+	byol := getTestNodeItem(t, map[string]interface{}{"licenseModel": "Bring your own license"})
+	mirror := getTestNodeItem(t, map[string]interface{}{"deploymentOption": "Multi-AZ (SQL Server Mirror)"})
+	for _, item := range []aws.JSONValue{byol, mirror} {
+		if addNodePricingItem(rdsCatalog, pricing.Region["eu-west-1"], item) == false {
+			t.Error("Expected the item to be ignored without error.")
+		}
+	}
+	if len(pricing.Region["eu-west-1"].Engine) != 0 {
+		t.Errorf("Expected no engine, got %v.", pricing.Region["eu-west-1"].Engine)
+	}
+}
+
+func TestAddNodePricingItemElastiCache(t *testing.T) {
+	pricing := NodePricing{Region: map[string]NodeEngine{"eu-west-1": {Engine: make(map[string]NodeType)}}}
+	item := getTestNodeItem(t, map[string]interface{}{"cacheEngine": "Redis", "instanceType": "cache.m5.large"})
+	if addNodePricingItem(elastiCacheCatalog, pricing.Region["eu-west-1"], item) == false {
+		t.Fatal("Expected the item to be parsed.")
+	}
+	if _, err := pricing.GetSpecs("eu-west-1", GetElastiCachePricingEngine("redis"), "cache.m5.large"); err != nil {
+		t.Error(err)
+	}
+}
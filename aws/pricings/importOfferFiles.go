@@ -0,0 +1,430 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package pricings
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/trackit/jsonlog"
+)
+
+const (
+	OfferFileFormatJson = "json"
+	OfferFileFormatCsv  = "csv"
+
+	s3LocationPrefix = "s3://"
+)
+
+var (
+	// offerFileFormats lists the formats of the bulk offer files, by order of preference
+	offerFileFormats = []string{OfferFileFormatJson, OfferFileFormatCsv}
+
+	// csvOfferFileProductColumns lists the columns of the CSV bulk offer files
+	// which describe a price dimension instead of a product attribute
+	csvOfferFileProductColumns = map[string]bool{
+		"SKU":                 true,
+		"OfferTermCode":       true,
+		"RateCode":            true,
+		"TermType":            true,
+		"PriceDescription":    true,
+		"EffectiveDate":       true,
+		"StartingRange":       true,
+		"EndingRange":         true,
+		"Unit":                true,
+		"PricePerUnit":        true,
+		"Currency":            true,
+		"LeaseContractLength": true,
+		"PurchaseOption":      true,
+		"OfferingClass":       true,
+		"Product Family":      true,
+	}
+
+	// csvOfferFileAttributes maps the columns of the CSV bulk offer files whose
+	// name can not be converted to the JSON attribute name by camel casing it
+	csvOfferFileAttributes = map[string]string{
+		"usageType":         "usagetype",
+		"serviceCode":       "servicecode",
+		"CapacityStatus":    "capacitystatus",
+		"Pre Installed S/W": "preInstalledSw",
+	}
+)
+
+type (
+	// offerFilter returns true if a product of a bulk offer file must be imported
+	offerFilter func(productFamily string, attributes map[string]interface{}) bool
+
+	// offerItemHandler is called for each product imported from a bulk offer file
+	// The product is formatted as an item of the aws json pricing, as returned
+	// by the Pricing API, so that the same parsing functions can be used
+	offerItemHandler func(region string, item aws.JSONValue)
+
+	// offerProduct is a product of a bulk offer file
+	offerProduct struct {
+		Sku           string                 `json:"sku"`
+		ProductFamily string                 `json:"productFamily"`
+		Attributes    map[string]interface{} `json:"attributes"`
+	}
+
+	// offerTerms maps a SKU to its terms, by term type ("OnDemand" or "Reserved")
+	offerTerms map[string]map[string]interface{}
+)
+
+// getOfferProductRegion returns the region code of a product from a bulk offer
+// file, or an empty string if the region is unknown
+func getOfferProductRegion(attributes map[string]interface{}) string {
+	if regionCode := getStringAttribute(attributes, "regionCode"); regionCode != "" {
+		if _, ok := EC2RegionCodeToPricingLocationName[regionCode]; ok {
+			return regionCode
+		}
+	}
+	location := getStringAttribute(attributes, "location")
+	for regionCode, locationName := range EC2RegionCodeToPricingLocationName {
+		if locationName == location {
+			return regionCode
+		}
+	}
+	return ""
+}
+
+// getOfferItem formats a product from a bulk offer file and its terms as an
+// item of the aws json pricing
+func getOfferItem(product offerProduct, terms map[string]interface{}) aws.JSONValue {
+	if terms == nil {
+		terms = make(map[string]interface{})
+	}
+	return aws.JSONValue{
+		"product": map[string]interface{}{
+			"sku":           product.Sku,
+			"productFamily": product.ProductFamily,
+			"attributes":    product.Attributes,
+		},
+		"terms": terms,
+	}
+}
+
+// addOfferTerm adds a term of a product to the terms read from an offer file
+func (terms offerTerms) addOfferTerm(sku, termType string, term interface{}) {
+	if _, ok := terms[sku]; !ok {
+		terms[sku] = make(map[string]interface{})
+	}
+	terms[sku][termType] = term
+}
+
+// readJsonObject reads a JSON object from a decoder and calls readValue for
+// each of its keys. readValue must consume the value of the key.
+func readJsonObject(decoder *json.Decoder, readValue func(key string) error) error {
+	if token, err := decoder.Token(); err != nil {
+		return err
+	} else if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return errors.New("Expected a JSON object in offer file")
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return errors.New("Expected a JSON key in offer file")
+		}
+		if err := readValue(key); err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+// skipJsonValue consumes the next value of a decoder
+func skipJsonValue(decoder *json.Decoder) error {
+	var value json.RawMessage
+	return decoder.Decode(&value)
+}
+
+// readJsonOfferFile reads a JSON bulk offer file and calls handler for each of
+// its products matching filter. The file is streamed since the offer files of
+// some services weigh several gigabytes: only the matching products and their
+// terms are kept in memory. The products are listed before the terms in the
+// offer files, terms of unknown products are ignored.
+func readJsonOfferFile(reader io.Reader, filter offerFilter, handler offerItemHandler) error {
+	decoder := json.NewDecoder(reader)
+	products := make(map[string]offerProduct)
+	regions := make(map[string]string)
+	terms := make(offerTerms)
+	err := readJsonObject(decoder, func(key string) error {
+		switch key {
+		case "products":
+			return readJsonObject(decoder, func(sku string) error {
+				var product offerProduct
+				if err := decoder.Decode(&product); err != nil {
+					return err
+				}
+				product.Sku = sku
+				if region := getOfferProductRegion(product.Attributes); region != "" && filter(product.ProductFamily, product.Attributes) {
+					products[sku] = product
+					regions[sku] = region
+				}
+				return nil
+			})
+		case "terms":
+			return readJsonObject(decoder, func(termType string) error {
+				return readJsonObject(decoder, func(sku string) error {
+					if _, ok := products[sku]; !ok {
+						return skipJsonValue(decoder)
+					}
+					var term map[string]interface{}
+					if err := decoder.Decode(&term); err != nil {
+						return err
+					}
+					terms.addOfferTerm(sku, termType, term)
+					return nil
+				})
+			})
+		default:
+			return skipJsonValue(decoder)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for sku, product := range products {
+		handler(regions[sku], getOfferItem(product, terms[sku]))
+	}
+	return nil
+}
+
+// getCsvOfferAttributeName converts the name of a column of a CSV bulk offer
+// file to the name of the attribute in the JSON offer files
+// e.g. "Instance Type" becomes "instanceType"
+func getCsvOfferAttributeName(column string) string {
+	if name, ok := csvOfferFileAttributes[column]; ok {
+		return name
+	}
+	words := strings.FieldsFunc(column, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(word[:1]) + word[1:]
+		} else {
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// getCsvOfferProduct returns the product described by a row of a CSV bulk offer file
+func getCsvOfferProduct(row map[string]string) offerProduct {
+	product := offerProduct{
+		Sku:           row["SKU"],
+		ProductFamily: row["Product Family"],
+		Attributes:    make(map[string]interface{}),
+	}
+	for column, value := range row {
+		if !csvOfferFileProductColumns[column] && value != "" {
+			product.Attributes[getCsvOfferAttributeName(column)] = value
+		}
+	}
+	return product
+}
+
+// addCsvOfferPriceDimension adds the price dimension described by a row of a
+// CSV bulk offer file to the terms of its product, using the layout of the
+// JSON offer files
+func addCsvOfferPriceDimension(terms offerTerms, row map[string]string) {
+	sku := row["SKU"]
+	termType := row["TermType"]
+	termCode := sku + "." + row["OfferTermCode"]
+	skuTerms, _ := terms[sku][termType].(map[string]interface{})
+	if skuTerms == nil {
+		skuTerms = make(map[string]interface{})
+		terms.addOfferTerm(sku, termType, skuTerms)
+	}
+	term, _ := skuTerms[termCode].(map[string]interface{})
+	if term == nil {
+		termAttributes := make(map[string]interface{})
+		for _, name := range []string{"LeaseContractLength", "OfferingClass", "PurchaseOption"} {
+			if row[name] != "" {
+				termAttributes[name] = row[name]
+			}
+		}
+		term = map[string]interface{}{
+			"sku":             sku,
+			"offerTermCode":   row["OfferTermCode"],
+			"effectiveDate":   row["EffectiveDate"],
+			"priceDimensions": make(map[string]interface{}),
+			"termAttributes":  termAttributes,
+		}
+		skuTerms[termCode] = term
+	}
+	term["priceDimensions"].(map[string]interface{})[row["RateCode"]] = map[string]interface{}{
+		"rateCode":     row["RateCode"],
+		"description":  row["PriceDescription"],
+		"unit":         row["Unit"],
+		"pricePerUnit": map[string]interface{}{row["Currency"]: row["PricePerUnit"]},
+	}
+}
+
+// readCsvOfferFile reads a CSV bulk offer file and calls handler for each of
+// its products matching filter. The CSV offer files start with a few lines of
+// metadata, followed by a header and one line per price dimension.
+func readCsvOfferFile(reader io.Reader, filter offerFilter, handler offerItemHandler) error {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.ReuseRecord = true
+	var header []string
+	products := make(map[string]*offerProduct)
+	regions := make(map[string]string)
+	terms := make(offerTerms)
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		} else if header == nil {
+			if len(record) > 0 && record[0] == "SKU" {
+				header = append([]string{}, record...)
+			}
+			continue
+		}
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[column] = record[i]
+			}
+		}
+		product, ok := products[row["SKU"]]
+		if !ok {
+			newProduct := getCsvOfferProduct(row)
+			if region := getOfferProductRegion(newProduct.Attributes); region != "" && filter(newProduct.ProductFamily, newProduct.Attributes) {
+				product = &newProduct
+				regions[newProduct.Sku] = region
+			}
+			// Ignored products are stored as nil to avoid parsing them again
+			products[newProduct.Sku] = product
+		}
+		if product != nil {
+			addCsvOfferPriceDimension(terms, row)
+		}
+	}
+	if header == nil {
+		return errors.New("Missing header in CSV offer file")
+	}
+	for sku, product := range products {
+		if product != nil {
+			handler(regions[sku], getOfferItem(*product, terms[sku]))
+		}
+	}
+	return nil
+}
+
+// openLocalOfferFile opens the offer file of a service from a local directory
+func openLocalOfferFile(directory, serviceCode string) (io.ReadCloser, string, error) {
+	for _, format := range offerFileFormats {
+		file, err := os.Open(filepath.Join(directory, serviceCode+"."+format))
+		if err == nil {
+			return file, format, nil
+		} else if !os.IsNotExist(err) {
+			return nil, "", err
+		}
+	}
+	return nil, "", errors.New("No offer file found for " + serviceCode + " in " + directory)
+}
+
+// openS3OfferFile opens the offer file of a service from an S3 location
+// formatted as s3://bucket/prefix, using the instance role
+func openS3OfferFile(ctx context.Context, location, serviceCode string) (io.ReadCloser, string, error) {
+	path := strings.SplitN(strings.TrimPrefix(location, s3LocationPrefix), "/", 2)
+	bucket, prefix := path[0], ""
+	if len(path) == 2 && path[1] != "" {
+		prefix = strings.TrimSuffix(path[1], "/") + "/"
+	}
+	sess := getPricingSession()
+	region, err := s3manager.GetBucketRegion(ctx, sess, bucket, PricingApiEndpointRegion)
+	if err != nil {
+		return nil, "", err
+	}
+	svc := s3.New(sess, &aws.Config{Region: aws.String(region)})
+	for _, format := range offerFileFormats {
+		output, err := svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(prefix + serviceCode + "." + format),
+		})
+		if err == nil {
+			return output.Body, format, nil
+		} else if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != s3.ErrCodeNoSuchKey {
+			return nil, "", err
+		}
+	}
+	return nil, "", errors.New("No offer file found for " + serviceCode + " in " + location)
+}
+
+// openOfferFile opens the bulk offer file of a service from a location, which
+// is either a local directory or an S3 location formatted as s3://bucket/prefix
+// The offer file must be named after the service code, with a ".json" or a
+// ".csv" extension (e.g. "AmazonEC2.json"). It returns the file and its format.
+func openOfferFile(ctx context.Context, location, serviceCode string) (io.ReadCloser, string, error) {
+	if strings.HasPrefix(location, s3LocationPrefix) {
+		return openS3OfferFile(ctx, location, serviceCode)
+	}
+	return openLocalOfferFile(location, serviceCode)
+}
+
+// importOfferFile reads the bulk offer file of a service from a location and
+// calls handler for each of its products matching filter
+func importOfferFile(ctx context.Context, location, serviceCode string, filter offerFilter, handler offerItemHandler) error {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	file, format, err := openOfferFile(ctx, location, serviceCode)
+	if err != nil {
+		logger.Error("Failed to open offer file", map[string]interface{}{
+			"service":  serviceCode,
+			"location": location,
+			"error":    err.Error(),
+		})
+		return err
+	}
+	defer file.Close()
+	logger.Info("Importing pricings from offer file", map[string]interface{}{
+		"service":  serviceCode,
+		"location": location,
+		"format":   format,
+	})
+	if format == OfferFileFormatCsv {
+		err = readCsvOfferFile(file, filter, handler)
+	} else {
+		err = readJsonOfferFile(file, filter, handler)
+	}
+	if err != nil {
+		logger.Error("Failed to read offer file", map[string]interface{}{
+			"service":  serviceCode,
+			"location": location,
+			"error":    err.Error(),
+		})
+	}
+	return err
+}
@@ -0,0 +1,264 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package pricings
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+const testEc2JsonOfferFile = `{
+	"formatVersion": "v1.0",
+	"disclaimer": "This pricing list is for informational purposes only.",
+	"offerCode": "AmazonEC2",
+	"version": "20190101000000",
+	"publicationDate": "2019-01-01T00:00:00Z",
+	"products": {
+		"SKU1": {
+			"sku": "SKU1",
+			"productFamily": "Compute Instance",
+			"attributes": {
+				"servicecode": "AmazonEC2",
+				"location": "US East (N. Virginia)",
+				"instanceType": "m5.large",
+				"currentGeneration": "Yes",
+				"tenancy": "Shared",
+				"operatingSystem": "Linux",
+				"licenseModel": "No License required",
+				"usagetype": "BoxUsage:m5.large",
+				"preInstalledSw": "NA"
+			}
+		},
+		"SKU2": {
+			"sku": "SKU2",
+			"productFamily": "Storage",
+			"attributes": {
+				"servicecode": "AmazonEC2",
+				"location": "US East (N. Virginia)",
+				"volumeType": "General Purpose",
+				"usagetype": "EBS:VolumeUsage.gp2"
+			}
+		},
+		"SKU3": {
+			"sku": "SKU3",
+			"productFamily": "Compute Instance",
+			"attributes": {
+				"servicecode": "AmazonEC2",
+				"location": "US East (N. Virginia)",
+				"instanceType": "m5.large",
+				"currentGeneration": "Yes",
+				"tenancy": "Shared",
+				"operatingSystem": "Windows",
+				"licenseModel": "No License required",
+				"usagetype": "BoxUsage:m5.large",
+				"preInstalledSw": "SQL Web"
+			}
+		}
+	},
+	"terms": {
+		"OnDemand": {
+			"SKU1": {
+				"SKU1.JRTCKXETXF": {
+					"offerTermCode": "JRTCKXETXF",
+					"sku": "SKU1",
+					"priceDimensions": {
+						"SKU1.JRTCKXETXF.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0960000000"}}
+					},
+					"termAttributes": {}
+				}
+			},
+			"SKU2": {
+				"SKU2.JRTCKXETXF": {
+					"offerTermCode": "JRTCKXETXF",
+					"sku": "SKU2",
+					"priceDimensions": {
+						"SKU2.JRTCKXETXF.6YS6EN2CT7": {"unit": "GB-Mo", "pricePerUnit": {"USD": "0.1000000000"}}
+					},
+					"termAttributes": {}
+				}
+			}
+		},
+		"Reserved": {
+			"SKU1": {
+				"SKU1.4NA7Y494T4": {
+					"offerTermCode": "4NA7Y494T4",
+					"sku": "SKU1",
+					"priceDimensions": {
+						"SKU1.4NA7Y494T4.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0600000000"}}
+					},
+					"termAttributes": {"LeaseContractLength": "1yr", "OfferingClass": "standard", "PurchaseOption": "No Upfront"}
+				},
+				"SKU1.6QCMYABX3D": {
+					"offerTermCode": "6QCMYABX3D",
+					"sku": "SKU1",
+					"priceDimensions": {
+						"SKU1.6QCMYABX3D.2TG2D8R56U": {"unit": "Quantity", "pricePerUnit": {"USD": "501"}},
+						"SKU1.6QCMYABX3D.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0000000000"}}
+					},
+					"termAttributes": {"LeaseContractLength": "1yr", "OfferingClass": "standard", "PurchaseOption": "All Upfront"}
+				}
+			}
+		}
+	}
+}`
+
+const testEc2CsvOfferFile = `"FormatVersion","v1.0"
+"Disclaimer","This pricing list is for informational purposes only."
+"Publication Date","2019-01-01T00:00:00Z"
+"Version","20190101000000"
+"OfferCode","AmazonEC2"
+"SKU","OfferTermCode","RateCode","TermType","PriceDescription","EffectiveDate","StartingRange","EndingRange","Unit","PricePerUnit","Currency","LeaseContractLength","PurchaseOption","OfferingClass","Product Family","serviceCode","Location","Location Type","Instance Type","Current Generation","Tenancy","Operating System","License Model","usageType","Pre Installed S/W","Volume Type"
+"SKU1","JRTCKXETXF","SKU1.JRTCKXETXF.6YS6EN2CT7","OnDemand","$0.096 per On Demand Linux m5.large Instance Hour","2019-01-01","0","Inf","Hrs","0.0960000000","USD","","","","Compute Instance","AmazonEC2","US East (N. Virginia)","AWS Region","m5.large","Yes","Shared","Linux","No License required","BoxUsage:m5.large","NA",""
+"SKU1","4NA7Y494T4","SKU1.4NA7Y494T4.6YS6EN2CT7","Reserved","Linux/UNIX (Amazon VPC), m5.large reserved instance applied","2019-01-01","0","Inf","Hrs","0.0600000000","USD","1yr","No Upfront","standard","Compute Instance","AmazonEC2","US East (N. Virginia)","AWS Region","m5.large","Yes","Shared","Linux","No License required","BoxUsage:m5.large","NA",""
+"SKU1","6QCMYABX3D","SKU1.6QCMYABX3D.2TG2D8R56U","Reserved","Upfront Fee","2019-01-01","","","Quantity","501","USD","1yr","All Upfront","standard","Compute Instance","AmazonEC2","US East (N. Virginia)","AWS Region","m5.large","Yes","Shared","Linux","No License required","BoxUsage:m5.large","NA",""
+"SKU1","6QCMYABX3D","SKU1.6QCMYABX3D.6YS6EN2CT7","Reserved","USD 0.0 per Linux/UNIX (Amazon VPC), m5.large reserved instance applied","2019-01-01","0","Inf","Hrs","0.0000000000","USD","1yr","All Upfront","standard","Compute Instance","AmazonEC2","US East (N. Virginia)","AWS Region","m5.large","Yes","Shared","Linux","No License required","BoxUsage:m5.large","NA",""
+"SKU2","JRTCKXETXF","SKU2.JRTCKXETXF.6YS6EN2CT7","OnDemand","$0.10 per GB-month of General Purpose SSD (gp2) provisioned storage","2019-01-01","0","Inf","GB-Mo","0.1000000000","USD","","","","Storage","AmazonEC2","US East (N. Virginia)","AWS Region","","","","","","EBS:VolumeUsage.gp2","","General Purpose"
+`
+
+const testRdsCsvOfferFile = `"FormatVersion","v1.0"
+"OfferCode","AmazonRDS"
+"SKU","OfferTermCode","RateCode","TermType","PriceDescription","EffectiveDate","StartingRange","EndingRange","Unit","PricePerUnit","Currency","LeaseContractLength","PurchaseOption","OfferingClass","Product Family","serviceCode","Location","Instance Type","Database Engine","Database Edition","License Model","Deployment Option","usageType"
+"SKU4","JRTCKXETXF","SKU4.JRTCKXETXF.6YS6EN2CT7","OnDemand","$0.171 per RDS db.m4.large Multi-AZ instance hour","2019-01-01","0","Inf","Hrs","0.1710000000","USD","","","","Database Instance","AmazonRDS","EU (Ireland)","db.m4.large","MySQL","","No license required","Multi-AZ","EU-Multi-AZUsage:db.m4.large"
+"SKU4","HU7G6KETJZ","SKU4.HU7G6KETJZ.2TG2D8R56U","Reserved","Upfront Fee","2019-01-01","","","Quantity","500","USD","1yr","Partial Upfront","standard","Database Instance","AmazonRDS","EU (Ireland)","db.m4.large","MySQL","","No license required","Multi-AZ","EU-Multi-AZUsage:db.m4.large"
+"SKU4","HU7G6KETJZ","SKU4.HU7G6KETJZ.6YS6EN2CT7","Reserved","USD 0.057 hourly fee per MySQL, db.m4.large Multi-AZ Instance","2019-01-01","0","Inf","Hrs","0.0570000000","USD","1yr","Partial Upfront","standard","Database Instance","AmazonRDS","EU (Ireland)","db.m4.large","MySQL","","No license required","Multi-AZ","EU-Multi-AZUsage:db.m4.large"
+"SKU5","JRTCKXETXF","SKU5.JRTCKXETXF.6YS6EN2CT7","OnDemand","$0.20 per RDS db.m4.large instance hour in an unknown region","2019-01-01","0","Inf","Hrs","0.2000000000","USD","","","","Database Instance","AmazonRDS","Unknown Region","db.m4.large","MySQL","","No license required","Single-AZ","XX-InstanceUsage:db.m4.large"
+`
+
+var expectedOfferFileEc2Specs = EC2Specs{
+	CurrentGeneration:                     true,
+	LicenseModel:                          "No License required",
+	OnDemandHourlyCost:                    0.096,
+	OneYearStandardNoUpfrontHourlyCost:    0.06,
+	ThreeYearsStandardNoUpfrontHourlyCost: -1.0,
+	Reservations: []ReservationOffer{
+		{"1yr", "standard", "All Upfront", 501, 0},
+		{"1yr", "standard", "No Upfront", 0, 0.06},
+	},
+}
+
+func TestGetCsvOfferAttributeName(t *testing.T) {
+	for column, expected := range map[string]string{
+		"Instance Type":      "instanceType",
+		"Location":           "location",
+		"Current Generation": "currentGeneration",
+		"usageType":          "usagetype",
+		"Pre Installed S/W":  "preInstalledSw",
+		"Region Code":        "regionCode",
+	} {
+		if name := getCsvOfferAttributeName(column); name != expected {
+			t.Errorf("Attribute name of %q is %q instead of %q", column, name, expected)
+		}
+	}
+}
+
+func TestGetOfferProductRegion(t *testing.T) {
+	if region := getOfferProductRegion(map[string]interface{}{"location": "EU (Paris)"}); region != "eu-west-3" {
+		t.Errorf("Region of \"EU (Paris)\" is %q instead of \"eu-west-3\"", region)
+	}
+	if region := getOfferProductRegion(map[string]interface{}{"regionCode": "eu-west-1", "location": "EU (Ireland)"}); region != "eu-west-1" {
+		t.Errorf("Region of \"eu-west-1\" is %q instead of \"eu-west-1\"", region)
+	}
+	if region := getOfferProductRegion(map[string]interface{}{"location": "Unknown Region"}); region != "" {
+		t.Errorf("Region of an unknown location is %q instead of an empty string", region)
+	}
+}
+
+func testReadEc2OfferFile(t *testing.T, read func(reader *strings.Reader, filter offerFilter, handler offerItemHandler) error, offerFile string) {
+	items := 0
+	pricing := EC2Platform{Platform: make(map[string]EC2Tenancy, 0)}
+	err := read(strings.NewReader(offerFile), ec2OfferFilter, func(region string, item aws.JSONValue) {
+		items++
+		if region != "us-east-1" {
+			t.Errorf("Region is %q instead of \"us-east-1\"", region)
+		}
+		if !addEc2PricingItem(pricing, item) {
+			t.Errorf("Failed to parse item %v", item)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to read offer file: %s", err.Error())
+	} else if items != 1 {
+		t.Fatalf("%d items were read instead of 1", items)
+	}
+	specs, ok := pricing.Platform["Linux/UNIX"].Tenancy[EC2TenancyShared].Type["m5.large"].License["No License required"]
+	if !ok {
+		t.Fatalf("m5.large pricing not found: %v", pricing)
+	} else if !reflect.DeepEqual(*specs, expectedOfferFileEc2Specs) {
+		t.Errorf("Specs are %v instead of %v", *specs, expectedOfferFileEc2Specs)
+	}
+}
+
+func TestReadJsonOfferFile(t *testing.T) {
+	testReadEc2OfferFile(t, func(reader *strings.Reader, filter offerFilter, handler offerItemHandler) error {
+		return readJsonOfferFile(reader, filter, handler)
+	}, testEc2JsonOfferFile)
+}
+
+func TestReadCsvOfferFile(t *testing.T) {
+	testReadEc2OfferFile(t, func(reader *strings.Reader, filter offerFilter, handler offerItemHandler) error {
+		return readCsvOfferFile(reader, filter, handler)
+	}, testEc2CsvOfferFile)
+}
+
+func TestReadCsvOfferFileWithoutHeader(t *testing.T) {
+	err := readCsvOfferFile(strings.NewReader("\"FormatVersion\",\"v1.0\"\n"), ec2OfferFilter, func(string, aws.JSONValue) {})
+	if err == nil {
+		t.Errorf("Reading an offer file without header should fail")
+	}
+}
+
+func writeTestOfferFile(t *testing.T, directory, name, content string) {
+	if err := ioutil.WriteFile(filepath.Join(directory, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write offer file: %s", err.Error())
+	}
+}
+
+func TestImportPricings(t *testing.T) {
+	directory, err := ioutil.TempDir("", "offerFiles")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %s", err.Error())
+	}
+	defer os.RemoveAll(directory)
+	writeTestOfferFile(t, directory, "AmazonEC2.json", testEc2JsonOfferFile)
+	writeTestOfferFile(t, directory, "AmazonRDS.csv", testRdsCsvOfferFile)
+	ctx := context.Background()
+	if ec2Pricing, err := ImportEc2Pricings(ctx, directory); err != nil {
+		t.Errorf("Failed to import EC2 pricings: %s", err.Error())
+	} else if specs, err := ec2Pricing.GetSpecs("us-east-1", "Linux/UNIX", EC2TenancyShared, "m5.large", ""); err != nil {
+		t.Errorf("Failed to get EC2 specs: %s", err.Error())
+	} else if !reflect.DeepEqual(specs, expectedOfferFileEc2Specs) {
+		t.Errorf("EC2 specs are %v instead of %v", specs, expectedOfferFileEc2Specs)
+	}
+	if rdsPricing, err := ImportRdsPricings(ctx, directory); err != nil {
+		t.Errorf("Failed to import RDS pricings: %s", err.Error())
+	} else if len(rdsPricing.Region) != 1 {
+		t.Errorf("RDS pricings have %d regions instead of 1", len(rdsPricing.Region))
+	} else if specs, err := rdsPricing.GetSpecs("eu-west-1", "MySQL/Multi-AZ", "db.m4.large"); err != nil {
+		t.Errorf("Failed to get RDS specs: %s", err.Error())
+	} else if specs.OnDemandHourlyCost != 0.171 || len(specs.Reservations) != 1 ||
+		specs.Reservations[0] != (ReservationOffer{"1yr", "standard", "Partial Upfront", 500, 0.057}) {
+		t.Errorf("Unexpected RDS specs: %v", specs)
+	}
+	if _, err := ImportEsPricings(ctx, directory); err == nil {
+		t.Errorf("Importing pricings without offer file should fail")
+	}
+}
@@ -20,11 +20,15 @@ import (
 	"github.com/aws/aws-sdk-go/service/pricing"
 )
 
-// getPricingClient creates a pricing client using the instance role
-func getPricingClient() *pricing.Pricing {
-	mySession := session.Must(session.NewSession(&aws.Config{
+// getPricingSession creates a session using the instance role
+func getPricingSession() *session.Session {
+	return session.Must(session.NewSession(&aws.Config{
 		CredentialsChainVerboseErrors: aws.Bool(true),
 		Region:                        aws.String(PricingApiEndpointRegion),
 	}))
-	return pricing.New(mySession)
+}
+
+// getPricingClient creates a pricing client using the instance role
+func getPricingClient() *pricing.Pricing {
+	return pricing.New(getPricingSession())
 }
@@ -23,6 +23,7 @@ import (
 	"github.com/trackit/jsonlog"
 
 	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/aws/s3"
 	"github.com/trackit/trackit/db"
 	"github.com/trackit/trackit/es"
 	"github.com/trackit/trackit/models"
@@ -71,6 +72,9 @@ func deleteAwsAccount(r *http.Request, a routes.Arguments) (int, interface{}) {
 	go func() {
 		for _, br := range dbAwsBillRepositories {
 			err = es.CleanByBillRepositoryId(context.Background(), aa.UserId, br.ID)
+			if err == nil {
+				err = s3.CleanBillRepositoryIndices(context.Background(), aa.UserId, br.ID)
+			}
 			if err != nil {
 				l.Error("Failed to clean ES data for bill repository", err.Error())
 			}
@@ -38,7 +38,7 @@ func init() {
 			users.RequireAuthenticatedUser{users.ViewerAsParent},
 			routes.Documentation{
 				Summary:     "get user's bill repositories and info about their update status",
-				Description: "Gets the list of the user's bill repositories and info about when they have updated or will update.",
+				Description: "Gets the list of the user's bill repositories and info about when they have updated or will update. The progress of a pending update gives the manifests, report keys, bytes and line items ingested so far, the rates and an estimated completion time.",
 			},
 		),
 	}.H().With(
@@ -64,6 +64,82 @@ type BillRepositoryUpdateInfo struct {
 	LastStarted      *time.Time `json:"lastStarted"`
 	LastFinished     *time.Time `json:"lastFinished"`
 	LastError        *string    `json:"lastError"`
+	// Progress is the progress of the pending update, if any.
+	Progress *BillRepositoryUpdateProgress `json:"progress"`
+}
+
+// BillRepositoryUpdateProgress is the progress of a bill repository update.
+// Totals only cover what remains to be ingested when the update starts.
+// Updated is the last time the counters changed: an update whose counters
+// have not changed for long is likely stuck.
+type BillRepositoryUpdateProgress struct {
+	ManifestsTotal     int        `json:"manifestsTotal"`
+	ManifestsDone      int        `json:"manifestsDone"`
+	KeysTotal          int        `json:"keysTotal"`
+	KeysDone           int        `json:"keysDone"`
+	BytesTotal         int64      `json:"bytesTotal"`
+	BytesDone          int64      `json:"bytesDone"`
+	LineItems          int64      `json:"lineItems"`
+	Started            time.Time  `json:"started"`
+	Updated            time.Time  `json:"updated"`
+	BytesPerSecond     float64    `json:"bytesPerSecond"`
+	LineItemsPerSecond float64    `json:"lineItemsPerSecond"`
+	Eta                *time.Time `json:"eta"`
+}
+
+// pendingUpdateProgress holds the columns of the pending update of a bill
+// repository, which are NULL if there is none.
+type pendingUpdateProgress struct {
+	ManifestsTotal *int
+	ManifestsDone  *int
+	KeysTotal      *int
+	KeysDone       *int
+	BytesTotal     *int64
+	BytesDone      *int64
+	LineItems      *int64
+	Started        *time.Time
+	Updated        *time.Time
+}
+
+// progress returns the BillRepositoryUpdateProgress of the pending update,
+// or nil if there is none. The rates are averages since the start of the
+// update, and the ETA assumes the remaining bytes, or keys if their sizes
+// are unknown, are ingested at the same rate.
+func (pup pendingUpdateProgress) progress() *BillRepositoryUpdateProgress {
+	if pup.Started == nil || pup.Updated == nil || pup.ManifestsTotal == nil {
+		return nil
+	}
+	p := BillRepositoryUpdateProgress{
+		ManifestsTotal: *pup.ManifestsTotal,
+		ManifestsDone:  *pup.ManifestsDone,
+		KeysTotal:      *pup.KeysTotal,
+		KeysDone:       *pup.KeysDone,
+		BytesTotal:     *pup.BytesTotal,
+		BytesDone:      *pup.BytesDone,
+		LineItems:      *pup.LineItems,
+		Started:        *pup.Started,
+		Updated:        *pup.Updated,
+	}
+	elapsed := p.Updated.Sub(p.Started)
+	if elapsed <= 0 {
+		return &p
+	}
+	p.BytesPerSecond = float64(p.BytesDone) / elapsed.Seconds()
+	p.LineItemsPerSecond = float64(p.LineItems) / elapsed.Seconds()
+	var remaining float64
+	if p.BytesTotal > 0 && p.BytesDone > 0 {
+		remaining = float64(p.BytesTotal-p.BytesDone) / float64(p.BytesDone)
+	} else if p.KeysTotal > 0 && p.KeysDone > 0 {
+		remaining = float64(p.KeysTotal-p.KeysDone) / float64(p.KeysDone)
+	} else {
+		return &p
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+	eta := p.Updated.Add(time.Duration(remaining * float64(elapsed)))
+	p.Eta = &eta
+	return &p
 }
 
 func getBillRepositoryUpdates(r *http.Request, a routes.Arguments) (int, interface{}) {
@@ -95,7 +171,16 @@ func BillRepositoryUpdates(db dbAccessor, userId int) ([]BillRepositoryUpdateInf
 		  (last_pending.id IS NOT NULL)      AS next_pending,
 		  last_completed.created             AS last_started,
 		  last_completed.completed           AS last_finished,
-		  last_completed.error               AS last_error
+		  last_completed.error               AS last_error,
+		  last_pending.manifests_total       AS manifests_total,
+		  last_pending.manifests_done        AS manifests_done,
+		  last_pending.keys_total            AS keys_total,
+		  last_pending.keys_done             AS keys_done,
+		  last_pending.bytes_total           AS bytes_total,
+		  last_pending.bytes_done            AS bytes_done,
+		  last_pending.line_items            AS line_items,
+		  last_pending.created               AS pending_started,
+		  last_pending.modified              AS pending_updated
 		FROM aws_bill_repository
 		INNER JOIN aws_account ON
 		  aws_bill_repository.aws_account_id = aws_account.id
@@ -133,6 +218,7 @@ func BillRepositoryUpdates(db dbAccessor, userId int) ([]BillRepositoryUpdateInf
 	var res []BillRepositoryUpdateInfo
 	var i int
 	for i = 0; q.Next(); i++ {
+		var pup pendingUpdateProgress
 		res = append(res, BillRepositoryUpdateInfo{})
 		err = q.Scan(
 			&res[i].BillRepositoryId,
@@ -145,10 +231,20 @@ func BillRepositoryUpdates(db dbAccessor, userId int) ([]BillRepositoryUpdateInf
 			&res[i].LastStarted,
 			&res[i].LastFinished,
 			&res[i].LastError,
+			&pup.ManifestsTotal,
+			&pup.ManifestsDone,
+			&pup.KeysTotal,
+			&pup.KeysDone,
+			&pup.BytesTotal,
+			&pup.BytesDone,
+			&pup.LineItems,
+			&pup.Started,
+			&pup.Updated,
 		)
 		if err != nil {
 			return nil, err
 		}
+		res[i].Progress = pup.progress()
 	}
 	return res[:i], nil
 }
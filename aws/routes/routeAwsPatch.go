@@ -18,9 +18,11 @@ import (
 	"database/sql"
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/trackit/jsonlog"
 
+	"github.com/trackit/trackit/anomaliesDetection"
 	"github.com/trackit/trackit/aws"
 	"github.com/trackit/trackit/db"
 	"github.com/trackit/trackit/routes"
@@ -29,14 +31,18 @@ import (
 
 // patchAwsAccountRequestBody is all the possible bodies for the
 // patchAwsAccount request handler.
+// The anomaly detector is left unchanged if it is not in the body, an empty
+// string selects the default anomaly detector.
 type patchAwsAccountRequestBody struct {
-	Pretty  string `json:"pretty"`
-	Payer   bool   `json:"payer"`
-	RoleArn string `json:"roleArn"`
+	Pretty          string  `json:"pretty"`
+	Payer           bool    `json:"payer"`
+	RoleArn         string  `json:"roleArn"`
+	AnomalyDetector *string `json:"anomalyDetector"`
 }
 
 var (
-	errFailUpdateAccount = errors.New("failed to update AWS account")
+	errFailUpdateAccount      = errors.New("failed to update AWS account")
+	errUnknownAnomalyDetector = errors.New("unknown anomaly detector, available detectors are: " + strings.Join(anomalies.DetectorNames(), ", "))
 )
 
 // patchAwsAccount is a route handler which lets the user update AwsAccounts from
@@ -59,8 +65,14 @@ func patchAwsAccount(r *http.Request, a routes.Arguments) (int, interface{}) {
 func patchAwsAccountWithValidBody(r *http.Request, tx *sql.Tx, user users.User, body patchAwsAccountRequestBody, id int) (int, interface{}) {
 	ctx := r.Context()
 	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	if body.AnomalyDetector != nil && *body.AnomalyDetector != "" && !anomalies.IsDetector(*body.AnomalyDetector) {
+		return http.StatusBadRequest, errUnknownAnomalyDetector
+	}
 	awsAccount, err := aws.GetAwsAccountWithIdFromUser(user, id, tx)
 	if err == nil {
+		if body.AnomalyDetector != nil {
+			awsAccount.AnomalyDetector = *body.AnomalyDetector
+		}
 		awsAccount.Pretty = body.Pretty
 		awsAccount.Payer = body.Payer
 		awsAccount.RoleArn = body.RoleArn
@@ -55,7 +55,7 @@ func init() {
 			routes.QueryArgs{routes.AwsAccountIdQueryArg},
 			routes.Documentation{
 				Summary:     "edit an aws account",
-				Description: "Edits an AWS account from the user's list of accounts.",
+				Description: "Edits an AWS account from the user's list of accounts. The anomalyDetector selects the anomaly detection algorithm of the account: bollinger, weekday-seasonal, ewma or mad.",
 			},
 		),
 		http.MethodDelete: routes.H(deleteAwsAccount).With(
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
 	"time"
 
@@ -48,8 +49,9 @@ func init() {
 			aws.RequireAwsAccountId{},
 			routes.RequestContentType{"application/json"},
 			routes.RequestBody{postBillRepositoryBody{
-				Bucket: "my-bucket",
-				Prefix: "bills/",
+				Bucket:  "my-bucket",
+				Prefix:  "bills/",
+				Backend: BillRepositoryBackendS3,
 			}},
 			routes.Documentation{
 				Summary:     "add a new bill repository to an aws account",
@@ -62,8 +64,9 @@ func init() {
 			routes.RequestContentType{"application/json"},
 			routes.QueryArgs{routes.BillPositoryQueryArg},
 			routes.RequestBody{postBillRepositoryBody{
-				Bucket: "my-bucket",
-				Prefix: "bills/",
+				Bucket:  "my-bucket",
+				Prefix:  "bills/",
+				Backend: BillRepositoryBackendS3,
 			}},
 			routes.Documentation{
 				Summary:     "add a new bill repository to an aws account",
@@ -85,7 +88,7 @@ func init() {
 		routes.QueryArgs{routes.AwsAccountIdQueryArg},
 		routes.Documentation{
 			Summary:     "interact with aws account's bill repositories",
-			Description: "A bill repository is an S3 location (bucket+prefix) where Cost And Usage Reports can be found.",
+			Description: "A bill repository is a location (bucket+prefix) where Cost And Usage Reports can be found. Its backend is either AWS S3 (s3), an S3-compatible service read with static credentials (s3-compatible) or a directory of the server (local), in which case the bucket is the name of a directory the operator created for the AWS account.",
 		},
 	).Register("/aws/billrepository")
 }
@@ -96,7 +99,22 @@ const (
 	reportUpdateVariationBefore = 2 * time.Hour
 )
 
+const (
+	// BillRepositoryBackendS3 is the backend of bill repositories in AWS S3,
+	// read through the role of their AWS account.
+	BillRepositoryBackendS3 = "s3"
+	// BillRepositoryBackendS3Compatible is the backend of bill repositories
+	// in an S3-compatible service such as MinIO, read with static
+	// credentials.
+	BillRepositoryBackendS3Compatible = "s3-compatible"
+	// BillRepositoryBackendLocal is the backend of bill repositories in a
+	// directory of the server, under the directory of their AWS account in
+	// config.LocalBillRepositories.
+	BillRepositoryBackendLocal = "local"
+)
+
 // BillRepository is a location where the server may look for bill objects.
+// For local bill repositories, Bucket is the name of the directory.
 type BillRepository struct {
 	Id                   int       `json:"id"`
 	AwsAccountId         int       `json:"awsAccountId"`
@@ -105,22 +123,32 @@ type BillRepository struct {
 	Error                string    `json:"error"`
 	LastImportedManifest time.Time `json:"lastImportedManifest"`
 	NextUpdate           time.Time `json:"nextUpdate"`
+	Backend              string    `json:"backend"`
+	Endpoint             string    `json:"endpoint"`
+	AccessKeyId          string    `json:"accessKeyId"`
+	SecretAccessKey      string    `json:"-"`
 }
 
 // CreateBillRepository creates a BillRepository for an AwsAccount. It does
 // not perform checks on the repository.
 func CreateBillRepository(aa aws.AwsAccount, br BillRepository, tx *sql.Tx) (BillRepository, error) {
+	secretAccessKey, err := encryptSecretAccessKey(br.SecretAccessKey)
+	if err != nil {
+		return BillRepository{}, err
+	}
 	dbbr := models.AwsBillRepository{
-		Prefix:       br.Prefix,
-		Bucket:       br.Bucket,
-		AwsAccountID: aa.Id,
+		Prefix:          br.Prefix,
+		Bucket:          br.Bucket,
+		AwsAccountID:    aa.Id,
+		Backend:         br.Backend,
+		Endpoint:        br.Endpoint,
+		AccessKeyID:     br.AccessKeyId,
+		SecretAccessKey: secretAccessKey,
 	}
-	var out BillRepository
-	err := dbbr.Insert(tx)
-	if err == nil {
-		out = billRepoFromDbBillRepo(dbbr)
+	if err = dbbr.Insert(tx); err != nil {
+		return BillRepository{}, err
 	}
-	return out, err
+	return billRepoFromDbBillRepo(dbbr)
 }
 
 // UpdateBillRepository updates a BillRepository in the database
@@ -131,25 +159,37 @@ func UpdateBillRepositorySafe(dbBr *models.AwsBillRepository, br BillRepository,
 	dbBr.NextUpdate = br.NextUpdate
 	dbBr.LastImportedManifest = br.LastImportedManifest
 	dbBr.Error = br.Error
-	var out BillRepository
-	err := dbBr.Update(tx)
-	if err == nil {
-		out = billRepoFromDbBillRepo(*dbBr)
+	dbBr.Backend = br.Backend
+	dbBr.Endpoint = br.Endpoint
+	dbBr.AccessKeyID = br.AccessKeyId
+	secretAccessKey, err := encryptSecretAccessKey(br.SecretAccessKey)
+	if err != nil {
+		return BillRepository{}, err
 	}
-	return out, err
+	dbBr.SecretAccessKey = secretAccessKey
+	if err = dbBr.Update(tx); err != nil {
+		return BillRepository{}, err
+	}
+	return billRepoFromDbBillRepo(*dbBr)
 }
 
 // UpdateBillRepository updates a BillRepository in the database. No checks are
 // performed.
 func UpdateBillRepository(br BillRepository, tx *sql.Tx) error {
-	dbAwsBillRepository := dbBillRepoFromBillRepo(br)
+	dbAwsBillRepository, err := dbBillRepoFromBillRepo(br)
+	if err != nil {
+		return err
+	}
 	return dbAwsBillRepository.UpdateUnsafe(tx)
 }
 
 // UpdateBillRepositoryWithoutContext updates a BillRepository in the database.
 // No checks are performed.
 func UpdateBillRepositoryWithoutContext(br BillRepository, db models.XODB) error {
-	dbAwsBillRepository := dbBillRepoFromBillRepo(br)
+	dbAwsBillRepository, err := dbBillRepoFromBillRepo(br)
+	if err != nil {
+		return err
+	}
 	return dbAwsBillRepository.UpdateUnsafe(db)
 }
 
@@ -160,7 +200,9 @@ func GetBillRepositoriesForAwsAccount(aa aws.AwsAccount, tx *sql.Tx) ([]BillRepo
 	if err == nil {
 		out := make([]BillRepository, len(dbAwsBillRepositories))
 		for i := range out {
-			out[i] = billRepoFromDbBillRepo(*dbAwsBillRepositories[i])
+			if out[i], err = billRepoFromDbBillRepo(*dbAwsBillRepositories[i]); err != nil {
+				return nil, err
+			}
 		}
 		return out, nil
 	} else {
@@ -175,8 +217,8 @@ func GetBillRepositoryForAwsAccountById(aa aws.AwsAccount, brId int, tx *sql.Tx)
 	var err error
 	dbAwsBillRepository, err := models.AwsBillRepositoryByID(tx, brId)
 	if err == nil {
-		out = billRepoFromDbBillRepo(*dbAwsBillRepository)
-		if out.AwsAccountId != aa.Id {
+		out, err = billRepoFromDbBillRepo(*dbAwsBillRepository)
+		if err == nil && out.AwsAccountId != aa.Id {
 			err = errors.New("bill repository does not belong to aws account")
 		}
 	}
@@ -192,12 +234,20 @@ func GetAwsBillRepositoriesWithDueUpdate(tx *sql.Tx) ([]BillRepository, error) {
 	}
 	brs := make([]BillRepository, len(dbbrs))
 	for i := range dbbrs {
-		brs[i] = billRepoFromDbBillRepo(*dbbrs[i])
+		if brs[i], err = billRepoFromDbBillRepo(*dbbrs[i]); err != nil {
+			return nil, err
+		}
 	}
 	return brs, nil
 }
 
-func billRepoFromDbBillRepo(dbBillRepo models.AwsBillRepository) BillRepository {
+// billRepoFromDbBillRepo converts a models.AwsBillRepository to a
+// BillRepository, decrypting its secret access key.
+func billRepoFromDbBillRepo(dbBillRepo models.AwsBillRepository) (BillRepository, error) {
+	secretAccessKey, err := decryptSecretAccessKey(dbBillRepo.SecretAccessKey)
+	if err != nil {
+		return BillRepository{}, err
+	}
 	return BillRepository{
 		Id:                   dbBillRepo.ID,
 		Bucket:               dbBillRepo.Bucket,
@@ -206,10 +256,20 @@ func billRepoFromDbBillRepo(dbBillRepo models.AwsBillRepository) BillRepository
 		AwsAccountId:         dbBillRepo.AwsAccountID,
 		LastImportedManifest: dbBillRepo.LastImportedManifest,
 		NextUpdate:           dbBillRepo.NextUpdate,
-	}
+		Backend:              dbBillRepo.Backend,
+		Endpoint:             dbBillRepo.Endpoint,
+		AccessKeyId:          dbBillRepo.AccessKeyID,
+		SecretAccessKey:      secretAccessKey,
+	}, nil
 }
 
-func dbBillRepoFromBillRepo(br BillRepository) models.AwsBillRepository {
+// dbBillRepoFromBillRepo converts a BillRepository to a
+// models.AwsBillRepository, encrypting its secret access key.
+func dbBillRepoFromBillRepo(br BillRepository) (models.AwsBillRepository, error) {
+	secretAccessKey, err := encryptSecretAccessKey(br.SecretAccessKey)
+	if err != nil {
+		return models.AwsBillRepository{}, err
+	}
 	return models.AwsBillRepository{
 		ID:                   br.Id,
 		Bucket:               br.Bucket,
@@ -218,12 +278,37 @@ func dbBillRepoFromBillRepo(br BillRepository) models.AwsBillRepository {
 		AwsAccountID:         br.AwsAccountId,
 		LastImportedManifest: br.LastImportedManifest,
 		NextUpdate:           br.NextUpdate,
-	}
+		Backend:              br.Backend,
+		Endpoint:             br.Endpoint,
+		AccessKeyID:          br.AccessKeyId,
+		SecretAccessKey:      secretAccessKey,
+	}, nil
 }
 
 type postBillRepositoryBody struct {
-	Prefix string `json:"prefix" req:""`
-	Bucket string `json:"bucket" req:"nonzero"`
+	Prefix          string `json:"prefix" req:""`
+	Bucket          string `json:"bucket" req:"nonzero"`
+	Backend         string `json:"backend" req:""`
+	Endpoint        string `json:"endpoint" req:""`
+	AccessKeyId     string `json:"accessKeyId" req:""`
+	SecretAccessKey string `json:"secretAccessKey" req:""`
+}
+
+// billRepository returns the BillRepository described by the body. The
+// backend defaults to BillRepositoryBackendS3.
+func (body postBillRepositoryBody) billRepository() BillRepository {
+	br := BillRepository{
+		Bucket:          body.Bucket,
+		Prefix:          body.Prefix,
+		Backend:         body.Backend,
+		Endpoint:        body.Endpoint,
+		AccessKeyId:     body.AccessKeyId,
+		SecretAccessKey: body.SecretAccessKey,
+	}
+	if br.Backend == "" {
+		br.Backend = BillRepositoryBackendS3
+	}
+	return br
 }
 
 func postBillRepository(r *http.Request, a routes.Arguments) (int, interface{}) {
@@ -246,7 +331,7 @@ func postBillRepositoryWithValidBody(
 	aa aws.AwsAccount,
 	body postBillRepositoryBody,
 ) (int, interface{}) {
-	br, err := CreateBillRepository(aa, BillRepository{Bucket: body.Bucket, Prefix: body.Prefix}, tx)
+	br, err := CreateBillRepository(aa, body.billRepository(), tx)
 	if err == nil {
 		go UpdateReport(context.Background(), aa, br)
 		return http.StatusOK, br
@@ -290,10 +375,16 @@ func patchBillRepositoryWithValidBody(
 		})
 		return http.StatusNotFound, errors.New("failed to find bill repository to update")
 	}
-	br, err := UpdateBillRepositorySafe(dbBillingRepo, BillRepository{Id: brId, AwsAccountId: aa.Id, Bucket: body.Bucket, Prefix: body.Prefix}, tx)
+	br := body.billRepository()
+	br.Id = brId
+	br.AwsAccountId = aa.Id
+	br, err = UpdateBillRepositorySafe(dbBillingRepo, br, tx)
 	if err == nil {
 		go func() {
 			err = es.CleanByBillRepositoryId(context.Background(), aa.UserId, br.Id)
+			if err == nil {
+				err = CleanBillRepositoryIndices(context.Background(), aa.UserId, br.Id)
+			}
 			if err != nil {
 				l.Error("Failed to clean ES data for bill repository", map[string]interface{}{
 					"billRepository": br,
@@ -316,14 +407,31 @@ const noTwoDotsInBucketNameRegex = `^[a-z-](?:[a-z0-9.-]?[a-z0-9-])+$`
 
 var noTwoDotsInBucketName = regexp.MustCompile(noTwoDotsInBucketNameRegex)
 
-func isBillRepositoryValid(br postBillRepositoryBody) error {
-	if err := isBucketNameValid(br.Bucket); err != nil {
-		return err
-	} else if err := isPrefixValid(br.Prefix); err != nil {
-		return err
-	} else {
-		return nil
+func isBillRepositoryValid(body postBillRepositoryBody) error {
+	br := body.billRepository()
+	switch br.Backend {
+	case BillRepositoryBackendS3:
+		if err := isBucketNameValid(br.Bucket); err != nil {
+			return err
+		}
+	case BillRepositoryBackendS3Compatible:
+		if err := isBucketNameValid(br.Bucket); err != nil {
+			return err
+		} else if err := isEndpointValid(br.Endpoint); err != nil {
+			return err
+		} else if br.AccessKeyId == "" || br.SecretAccessKey == "" {
+			return errors.New("access key id and secret access key shall be set")
+		} else if len(br.SecretAccessKey) > maxSecretAccessKeyLength {
+			return fmt.Errorf("secret access key shall be no longer than %d chars", maxSecretAccessKeyLength)
+		}
+	case BillRepositoryBackendLocal:
+		if err := isDirectoryNameValid(br.Bucket); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("backend shall be one of %s, %s or %s", BillRepositoryBackendS3, BillRepositoryBackendS3Compatible, BillRepositoryBackendLocal)
 	}
+	return isPrefixValid(br.Prefix)
 }
 
 func isBucketNameValid(bn string) error {
@@ -338,6 +446,30 @@ func isBucketNameValid(bn string) error {
 	}
 }
 
+var directoryName = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+func isDirectoryNameValid(dn string) error {
+	if len(dn) > 255 {
+		return errors.New("directory name shall be no longer than 255 chars")
+	} else if !directoryName.MatchString(dn) {
+		return errors.New("directory name shall only contain letters, digits, '.', '_' and '-', and shall not start with '.'")
+	} else {
+		return nil
+	}
+}
+
+func isEndpointValid(e string) error {
+	if u, err := url.Parse(e); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("endpoint shall be an http or https URL")
+	} else if len(e) > 255 {
+		return errors.New("endpoint shall be no longer than 255 chars")
+	} else if err := checkEndpointHost(u); err != nil {
+		return errors.New("endpoint shall not be a local or private address")
+	} else {
+		return nil
+	}
+}
+
 func isPrefixValid(p string) error {
 	l := len([]byte(p))
 	if l > 1024 {
@@ -349,7 +481,7 @@ func isPrefixValid(p string) error {
 
 func isBillRepositoryAccessible(ctx context.Context, aa aws.AwsAccount, body postBillRepositoryBody) error {
 	l := jsonlog.LoggerFromContextOrDefault(ctx)
-	_, _, err := getServiceForRepository(ctx, aa, BillRepository{Bucket: body.Bucket, Prefix: body.Prefix})
+	_, err := getStorageForRepository(ctx, aa, body.billRepository())
 	if err != nil {
 		l.Warning("Trying to add a bad bill location.", err.Error())
 		return errors.New("Couldn't access to this bill location.")
@@ -376,6 +508,9 @@ func deleteBillRepository(r *http.Request, a routes.Arguments) (int, interface{}
 	if err == nil {
 		go func() {
 			err = es.CleanByBillRepositoryId(context.Background(), aa.UserId, brId)
+			if err == nil {
+				err = CleanBillRepositoryIndices(context.Background(), aa.UserId, brId)
+			}
 			if err != nil {
 				l.Error("Failed to clean ES data for bill repository", map[string]interface{}{
 					"error": err.Error(),
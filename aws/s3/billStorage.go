@@ -0,0 +1,234 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/trackit/jsonlog"
+
+	taws "github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/config"
+)
+
+var (
+	ErrLocalBillRepositoriesDisabled = errors.New("local bill repositories are disabled")
+	ErrKeyOutsideBillRepository      = errors.New("key is outside of the bill repository")
+)
+
+// awsIdentity matches the AWS account IDs, which name the directories of the
+// local bill repositories.
+var awsIdentity = regexp.MustCompile(`^[0-9]{12}$`)
+
+// billStorage gives access to the objects of a bill repository, wherever it
+// resides.
+type billStorage interface {
+	// keys returns a channel where the keys of the repository which may
+	// hold new bills will be sent.
+	keys(ctx context.Context) <-chan BillKey
+	// object returns a reader for the object at key s in the repository.
+	object(ctx context.Context, s string) (io.ReadCloser, error)
+}
+
+// readObject reads the object at key s in a billStorage to a buffer.
+func readObject(ctx context.Context, bs billStorage, s string) ([]byte, error) {
+	reader, err := bs.object(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	bufWrapper := bytes.NewBuffer(make([]byte, 0, 0x8000))
+	if err = readToWriter(reader, bufWrapper); err != nil {
+		return nil, err
+	}
+	return bufWrapper.Bytes(), nil
+}
+
+// s3BillStorage is a billStorage for bill repositories in AWS S3 or in an
+// S3-compatible service.
+type s3BillStorage struct {
+	svc   *s3.S3
+	s3mgr dumbS3Manager
+	brr   billRepositoryWithRegion
+	// client is the HTTP client used to get the objects. httpClient is
+	// used if it is nil.
+	client *http.Client
+}
+
+func (bs s3BillStorage) keys(ctx context.Context) <-chan BillKey {
+	return getKeys(ctx, bs.svc, bs.brr)
+}
+
+func (bs s3BillStorage) object(ctx context.Context, s string) (io.ReadCloser, error) {
+	client := bs.client
+	if client == nil {
+		client = &httpClient
+	}
+	return bs.s3mgr.rawS3GetObjectToReader(ctx, client, bs.brr.Region, bs.brr.Bucket, s)
+}
+
+// localBillStorage is a billStorage for bill repositories in a subdirectory
+// of the directory of their AWS account in config.LocalBillRepositories. Keys
+// are the slash separated paths of the files relative to that subdirectory.
+type localBillStorage struct {
+	dir string
+	br  BillRepository
+}
+
+func (bs localBillStorage) keys(ctx context.Context) <-chan BillKey {
+	c := make(chan BillKey)
+	l := jsonlog.LoggerFromContextOrDefault(ctx)
+	l.Debug("Getting manifest files from local repository.", bs.br)
+	go func() {
+		defer close(c)
+		count := 0
+		err := filepath.Walk(bs.dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			key, err := bs.key(path)
+			if err != nil || key == "" {
+				return err
+			} else if info.IsDir() {
+				if !strings.HasPrefix(key+"/", bs.br.Prefix) && !strings.HasPrefix(bs.br.Prefix, key+"/") {
+					return filepath.SkipDir
+				}
+				return nil
+			} else if !strings.HasPrefix(key, bs.br.Prefix) || !bs.br.LastImportedManifest.Before(info.ModTime().AddDate(0, 1, 0)) {
+				return nil
+			} else if count >= MaxCheckedKeysByRepository {
+				l.Warning("Checked maximum amount of keys for repository.", bs.br)
+				return io.EOF
+			}
+			count++
+			select {
+			case c <- BillKey{Bucket: bs.br.Bucket, Key: key, LastModified: info.ModTime(), Size: info.Size()}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil && err != io.EOF {
+			l.Error("Failed to list files from local repository.", err.Error())
+		}
+	}()
+	return c
+}
+
+// key returns the key of the file at path.
+func (bs localBillStorage) key(path string) (string, error) {
+	rel, err := filepath.Rel(bs.dir, path)
+	if err != nil {
+		return "", err
+	} else if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func (bs localBillStorage) object(ctx context.Context, s string) (io.ReadCloser, error) {
+	path := filepath.Join(bs.dir, filepath.FromSlash(s))
+	if !strings.HasPrefix(path, bs.dir+string(filepath.Separator)) {
+		return nil, ErrKeyOutsideBillRepository
+	}
+	return os.Open(path)
+}
+
+// getStorageForRepository returns the billStorage of a BillRepository,
+// according to its backend.
+func getStorageForRepository(ctx context.Context, aa taws.AwsAccount, br BillRepository) (billStorage, error) {
+	switch br.Backend {
+	case BillRepositoryBackendLocal:
+		return getLocalStorage(aa, br)
+	case BillRepositoryBackendS3Compatible:
+		return getS3CompatibleStorage(ctx, br)
+	default:
+		svc, brr, err := getServiceForRepository(ctx, aa, br)
+		if err != nil {
+			return nil, err
+		}
+		var bs = s3BillStorage{svc: svc, brr: brr}
+		bs.s3mgr.init(svc.Client.Config.Credentials)
+		return bs, nil
+	}
+}
+
+// getLocalStorage returns the billStorage of a local BillRepository, whose
+// bucket is a subdirectory of the directory of its AWS account in
+// config.LocalBillRepositories. Users can thus only read the bills the
+// operator put in the directory of their own AWS accounts.
+func getLocalStorage(aa taws.AwsAccount, br BillRepository) (billStorage, error) {
+	if config.LocalBillRepositories == "" {
+		return nil, ErrLocalBillRepositoriesDisabled
+	} else if !awsIdentity.MatchString(aa.AwsIdentity) {
+		return nil, errors.New("aws account identity is unknown")
+	}
+	root, err := filepath.Abs(filepath.Join(config.LocalBillRepositories, aa.AwsIdentity))
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(root, br.Bucket)
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() || filepath.Dir(dir) != root {
+		return nil, errors.New("bill repository is not a directory of the local bill repositories of the aws account")
+	}
+	return localBillStorage{dir, br}, nil
+}
+
+// getS3CompatibleStorage returns the billStorage of a BillRepository in an
+// S3-compatible service such as MinIO, using its static credentials.
+func getS3CompatibleStorage(ctx context.Context, br BillRepository) (billStorage, error) {
+	endpoint, err := url.Parse(br.Endpoint)
+	if err != nil {
+		return nil, err
+	} else if err = checkEndpointHost(endpoint); err != nil {
+		return nil, err
+	}
+	client := endpointHTTPClient(endpoint)
+	creds := credentials.NewStaticCredentials(br.AccessKeyId, br.SecretAccessKey, "")
+	sess := session.New(&aws.Config{
+		Credentials:      creds,
+		Region:           &config.AwsRegion,
+		Endpoint:         &br.Endpoint,
+		S3ForcePathStyle: aws.Bool(true),
+		HTTPClient:       client,
+	})
+	region, err := getBucketRegion(ctx, sess, br)
+	if err != nil {
+		return nil, err
+	}
+	var bs = s3BillStorage{
+		svc:    serviceForBucketRegion(sess, region),
+		brr:    billRepositoryWithRegion{br, region},
+		client: client,
+	}
+	bs.s3mgr.init(creds)
+	bs.s3mgr.endpoint = br.Endpoint
+	return bs, nil
+}
@@ -0,0 +1,138 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+
+	taws "github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/config"
+)
+
+// writeLocalBill writes a file of a local bill repository, creating its
+// directories.
+func writeLocalBill(t *testing.T, path string, content []byte) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %s.", err.Error())
+	}
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("Failed to write file: %s.", err.Error())
+	}
+}
+
+// testLocalAwsAccount is the AWS account the local bill repositories of the
+// tests belong to.
+var testLocalAwsAccount = taws.AwsAccount{Id: 1, UserId: 1, AwsIdentity: "123456789012"}
+
+// withLocalBillRepositories creates a directory for local bill repositories
+// and returns the directory of testLocalAwsAccount in it, and a function
+// removing it.
+func withLocalBillRepositories(t *testing.T) (string, func()) {
+	root, err := ioutil.TempDir("", "trackit-bill-repositories-")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s.", err.Error())
+	}
+	previous := config.LocalBillRepositories
+	config.LocalBillRepositories = root
+	return filepath.Join(root, testLocalAwsAccount.AwsIdentity), func() {
+		config.LocalBillRepositories = previous
+		os.RemoveAll(root)
+	}
+}
+
+func TestLocalBillStorage(t *testing.T) {
+	root, cleanup := withLocalBillRepositories(t)
+	defer cleanup()
+	period := filepath.Join(root, "archive", "bills", "report", "20240101-20240201")
+	writeLocalBill(t, filepath.Join(period, "report-Manifest.json"), []byte(`{
+		"bucket": "customer-bucket",
+		"reportKeys": ["bills/report/20240101-20240201/report-1.csv.gz"],
+		"compression": "GZIP",
+		"billingPeriod": {"start": "20240101T000000.000Z", "end": "20240201T000000.000Z"}
+	}`))
+	file, err := os.Create(filepath.Join(period, "report-1.csv.gz"))
+	if err != nil {
+		t.Fatalf("Failed to create bill: %s.", err.Error())
+	}
+	gz := gzip.NewWriter(file)
+	gz.Write([]byte("identity/LineItemId,lineItem/UsageAmount\nli-1,0.25\nli-2,3\n"))
+	gz.Close()
+	file.Close()
+	writeLocalBill(t, filepath.Join(root, "archive", "other", "report", "20240101-20240201", "report-Manifest.json"), []byte(`{}`))
+
+	bs, err := getLocalStorage(testLocalAwsAccount, BillRepository{Bucket: "archive", Prefix: "bills/"})
+	if err != nil {
+		t.Fatalf("Failed to get local storage: %s.", err.Error())
+	}
+	ctx := context.Background()
+	var manifests []manifest
+	for m := range getManifests(ctx, bs, getManifestKeys(ctx, bs.keys(ctx))) {
+		manifests = append(manifests, m)
+	}
+	if len(manifests) != 1 {
+		t.Fatalf("Should find 1 manifest under the prefix, found %d.", len(manifests))
+	}
+	fi := fakeIngestion{}
+	if err := importManifest(ctx, bs, manifests[0], &fi); err != nil {
+		t.Fatalf("Failed to import manifest: %s.", err.Error())
+	}
+	if fi.lineItems != 2 || fi.ended != 1 {
+		t.Errorf("Should ingest 2 line items and end the manifest, got %d line items and %d ends.", fi.lineItems, fi.ended)
+	}
+	if _, err := bs.object(ctx, "../archive2/secret"); err != ErrKeyOutsideBillRepository {
+		t.Errorf("Keys outside of the repository should be rejected, got %v.", err)
+	}
+}
+
+func TestGetLocalStorage(t *testing.T) {
+	if _, err := getLocalStorage(testLocalAwsAccount, BillRepository{Bucket: "archive"}); err != ErrLocalBillRepositoriesDisabled {
+		t.Errorf("Local bill repositories should be disabled by default, got %v.", err)
+	}
+	root, cleanup := withLocalBillRepositories(t)
+	defer cleanup()
+	os.MkdirAll(filepath.Join(root, "archive", "nested"), 0755)
+	os.MkdirAll(filepath.Join(root, "..", "210987654321", "other"), 0755)
+	for _, bucket := range []string{"", "..", "archive/nested", "missing", "../210987654321/other"} {
+		if _, err := getLocalStorage(testLocalAwsAccount, BillRepository{Bucket: bucket}); err == nil {
+			t.Errorf("Bucket %q should be rejected.", bucket)
+		}
+	}
+	for _, identity := range []string{"210987654321", "", ".."} {
+		aa := taws.AwsAccount{AwsIdentity: identity}
+		if _, err := getLocalStorage(aa, BillRepository{Bucket: "archive"}); err == nil {
+			t.Errorf("Bucket of another AWS account should be rejected for identity %q.", identity)
+		}
+	}
+}
+
+func TestRawS3GetObjectEndpoint(t *testing.T) {
+	var dm dumbS3Manager
+	dm.init(credentials.NewStaticCredentials("id", "secret", ""))
+	dm.endpoint = "http://minio:9000/"
+	req, err := dm.rawS3GetObject("us-east-1", "bills", "/report/report-1.csv.gz")
+	if err != nil {
+		t.Fatalf("Failed to build request: %s.", err.Error())
+	}
+	if url := req.URL.String(); url != "http://minio:9000/bills//report/report-1.csv.gz" {
+		t.Errorf("Request should target the endpoint, targets %s.", url)
+	}
+}
@@ -0,0 +1,62 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"strconv"
+)
+
+// parseCost parses a cost column of a LineItem. Empty or malformed columns
+// are considered to be zero.
+func parseCost(s string) float64 {
+	if cost, err := strconv.ParseFloat(s, 64); err == nil {
+		return cost
+	}
+	return 0
+}
+
+// computeCosts fills the costs of a LineItem which are derived from its raw
+// columns. The amortized cost spreads reservation and savings plan
+// commitments over the usage they cover, the way the AWS Cost Explorer
+// does. The net cost is the unblended cost after discounts.
+func computeCosts(li LineItem) LineItem {
+	unblendedCost := parseCost(li.UnblendedCost)
+	switch li.LineItemType {
+	case "DiscountedUsage":
+		li.AmortizedCost = parseCost(li.ReservationEffectiveCost)
+	case "RIFee":
+		li.AmortizedCost = parseCost(li.ReservationUnusedAmortizedUpfrontFee) + parseCost(li.ReservationUnusedRecurringFee)
+	case "Fee":
+		if li.ReservationArn != "" {
+			li.AmortizedCost = 0
+		} else {
+			li.AmortizedCost = unblendedCost
+		}
+	case "SavingsPlanCoveredUsage":
+		li.AmortizedCost = parseCost(li.SavingsPlanEffectiveCost)
+	case "SavingsPlanRecurringFee":
+		li.AmortizedCost = parseCost(li.SavingsPlanTotalCommitment) - parseCost(li.SavingsPlanUsedCommitment)
+	case "SavingsPlanNegation", "SavingsPlanUpfrontFee":
+		li.AmortizedCost = 0
+	default:
+		li.AmortizedCost = unblendedCost
+	}
+	if li.NetUnblendedCost != "" {
+		li.NetCost = parseCost(li.NetUnblendedCost)
+	} else {
+		li.NetCost = unblendedCost + parseCost(li.TotalDiscount)
+	}
+	return li
+}
@@ -0,0 +1,70 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"testing"
+)
+
+func TestComputeCosts(t *testing.T) {
+	cases := []struct {
+		name      string
+		li        LineItem
+		amortized float64
+		net       float64
+	}{
+		{
+			name:      "usage",
+			li:        LineItem{LineItemType: "Usage", UnblendedCost: "1.5", TotalDiscount: "-0.5"},
+			amortized: 1.5,
+			net:       1.0,
+		},
+		{
+			name:      "reserved usage",
+			li:        LineItem{LineItemType: "DiscountedUsage", UnblendedCost: "0", ReservationEffectiveCost: "0.7"},
+			amortized: 0.7,
+		},
+		{
+			name:      "reservation upfront fee",
+			li:        LineItem{LineItemType: "Fee", UnblendedCost: "1000", ReservationArn: "arn:aws:ec2:us-east-1:123456789012:reserved-instances/ri"},
+			amortized: 0,
+			net:       1000,
+		},
+		{
+			name:      "reservation recurring fee",
+			li:        LineItem{LineItemType: "RIFee", UnblendedCost: "10", ReservationUnusedAmortizedUpfrontFee: "2", ReservationUnusedRecurringFee: "1"},
+			amortized: 3,
+			net:       10,
+		},
+		{
+			name:      "savings plan covered usage",
+			li:        LineItem{LineItemType: "SavingsPlanCoveredUsage", UnblendedCost: "2", SavingsPlanEffectiveCost: "1.2", NetUnblendedCost: "1.9"},
+			amortized: 1.2,
+			net:       1.9,
+		},
+		{
+			name:      "savings plan negation",
+			li:        LineItem{LineItemType: "SavingsPlanNegation", UnblendedCost: "-2"},
+			amortized: 0,
+			net:       -2,
+		},
+	}
+	for _, c := range cases {
+		li := computeCosts(c.li)
+		if li.AmortizedCost != c.amortized || li.NetCost != c.net {
+			t.Errorf("%s: expected amortized %v and net %v but got %v and %v", c.name, c.amortized, c.net, li.AmortizedCost, li.NetCost)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// curSchema is the schema of the columns of a cost and usage report.
+type curSchema int
+
+const (
+	// curSchemaLegacy is the schema of legacy Cost and Usage Reports, with
+	// columns such as lineItem/UsageAccountId in CSV reports and
+	// line_item_usage_account_id in Parquet reports, and a column by user
+	// tag.
+	curSchemaLegacy curSchema = iota
+	// curSchemaDataExports is the schema of CUR 2.0 reports from Data
+	// Exports, with snake_case columns and the tags in the map-typed
+	// resource_tags column.
+	curSchemaDataExports
+)
+
+const (
+	// dataExportsTagsColumn is the map-typed column holding the tags in
+	// Data Exports reports. In CSV reports the map is encoded in JSON.
+	dataExportsTagsColumn = "resource_tags"
+
+	// dataExportsTagPrefix is the prefix of the user tags in the
+	// resource_tags column.
+	dataExportsTagPrefix = "user_"
+)
+
+// dataExportsColumnNames maps the Data Exports columns which were renamed from
+// the legacy reports to the column names of legacy CSV reports.
+var dataExportsColumnNames = map[string]string{
+	"product_region_code": "product/region",
+}
+
+// dataExportsCompressions maps the extensions of Data Exports files to the
+// compression names of legacy manifests.
+var dataExportsCompressions = map[string]string{
+	".parquet": "Parquet",
+	".gz":      "GZIP",
+	".zip":     "ZIP",
+}
+
+// schema detects the schema of the reports described by a manifest. Data
+// Exports manifests list S3 URIs in dataFiles where legacy ones list keys in
+// reportKeys.
+func (m manifest) schema() curSchema {
+	if m.ExportArn != "" || len(m.DataFiles) > 0 {
+		return curSchemaDataExports
+	} else {
+		return curSchemaLegacy
+	}
+}
+
+// normalizeManifest fills the fields of a Data Exports manifest which are
+// only present in legacy manifests, so that both are read the same way.
+func normalizeManifest(m manifest) manifest {
+	if m.schema() != curSchemaDataExports {
+		return m
+	}
+	if m.ReportName == "" {
+		m.ReportName = m.ExportName
+	}
+	m.ReportKeys = make([]string, 0, len(m.DataFiles))
+	for _, uri := range m.DataFiles {
+		bucket, key := splitS3Uri(uri)
+		if m.Bucket == "" {
+			m.Bucket = bucket
+		}
+		m.ReportKeys = append(m.ReportKeys, key)
+		for extension, compression := range dataExportsCompressions {
+			if m.Compression == "" && strings.HasSuffix(key, extension) {
+				m.Compression = compression
+			}
+		}
+	}
+	return m
+}
+
+// splitS3Uri splits an s3://bucket/key URI into its bucket and key. A value
+// which is not an URI is considered to be a key.
+func splitS3Uri(uri string) (string, string) {
+	if !strings.HasPrefix(uri, "s3://") {
+		return "", uri
+	} else if parts := strings.SplitN(strings.TrimPrefix(uri, "s3://"), "/", 2); len(parts) == 2 {
+		return parts[0], parts[1]
+	} else {
+		return parts[0], ""
+	}
+}
+
+// curColumnName returns the legacy CSV column name, as used in the csv tags
+// of LineItem, for a column of a report. Unknown columns keep their name and
+// end up in LineItem.Any.
+func curColumnName(schema curSchema, name string) string {
+	if csvName, ok := dataExportsColumnNames[name]; ok && schema == curSchemaDataExports {
+		return csvName
+	} else if csvName, ok := parquetColumnNames[name]; ok {
+		return csvName
+	} else if strings.HasPrefix(name, parquetTagPrefix) {
+		return tagPrefix + strings.TrimPrefix(name, parquetTagPrefix)
+	} else {
+		return name
+	}
+}
+
+// curColumnNames maps the header of a report with curColumnName.
+func curColumnNames(schema curSchema, header []string) []string {
+	names := make([]string, len(header))
+	for i, name := range header {
+		names[i] = curColumnName(schema, name)
+	}
+	return names
+}
+
+// expandResourceTags moves the user tags of the resource_tags column of Data
+// Exports reports to the columns legacy reports would have used, so that they
+// are extracted the same way.
+func expandResourceTags(li LineItem) LineItem {
+	raw, ok := li.Any[dataExportsTagsColumn]
+	if !ok {
+		return li
+	}
+	delete(li.Any, dataExportsTagsColumn)
+	var tags map[string]string
+	if raw == "" || json.Unmarshal([]byte(raw), &tags) != nil {
+		return li
+	}
+	for k, v := range tags {
+		if strings.HasPrefix(k, dataExportsTagPrefix) {
+			li.Any[tagPrefix+strings.TrimPrefix(k, dataExportsTagPrefix)] = v
+		}
+	}
+	return li
+}
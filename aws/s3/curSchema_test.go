@@ -0,0 +1,139 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/trackit/trackit/util/csv"
+)
+
+const dataExportsManifest = `{
+	"version": "2024-01-01",
+	"exportArn": "arn:aws:bcm-data-exports:us-east-1:123456789012:export/cur2-1234",
+	"exportName": "cur2",
+	"billingPeriod": {
+		"start": "2024-01-01T00:00:00.000Z",
+		"end": "2024-02-01T00:00:00.000Z"
+	},
+	"dataFiles": [
+		"s3://billing-bucket/exports/cur2/data/BILLING_PERIOD=2024-01/cur2-00001.snappy.parquet",
+		"s3://billing-bucket/exports/cur2/data/BILLING_PERIOD=2024-01/cur2-00002.snappy.parquet"
+	]
+}`
+
+func TestDataExportsManifest(t *testing.T) {
+	var m manifest
+	if err := json.Unmarshal([]byte(dataExportsManifest), &m); err != nil {
+		t.Fatalf("Failed to parse manifest: %s.", err.Error())
+	}
+	m = normalizeManifest(m)
+	if m.schema() != curSchemaDataExports {
+		t.Errorf("Manifest should be detected as a Data Exports manifest.")
+	}
+	if m.Bucket != "billing-bucket" || m.Compression != "Parquet" || m.ReportName != "cur2" {
+		t.Errorf("Manifest should be normalized, got bucket %s, compression %s and name %s.", m.Bucket, m.Compression, m.ReportName)
+	}
+	if len(m.ReportKeys) != 2 || m.ReportKeys[0] != "exports/cur2/data/BILLING_PERIOD=2024-01/cur2-00001.snappy.parquet" {
+		t.Errorf("Report keys should be taken from the data files, got %#v.", m.ReportKeys)
+	}
+	if end := time.Time(m.BillingPeriod.End); !end.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Billing period should end on 2024-02-01, ends on %s.", end)
+	}
+}
+
+func TestLegacyManifest(t *testing.T) {
+	var m manifest
+	raw := `{"bucket": "b", "reportKeys": ["k.csv.gz"], "compression": "GZIP", "billingPeriod": {"start": "20240101T000000.000Z", "end": "20240201T000000.000Z"}}`
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatalf("Failed to parse manifest: %s.", err.Error())
+	}
+	if m = normalizeManifest(m); m.schema() != curSchemaLegacy || len(m.ReportKeys) != 1 || m.Compression != "GZIP" {
+		t.Errorf("Legacy manifest should be left as is, got %#v.", m)
+	}
+}
+
+func TestManifestKeyRegex(t *testing.T) {
+	for key, expected := range map[string]bool{
+		"usagecost/report/20240101-20240201/report-Manifest.json":            true,
+		"exports/cur2/metadata/BILLING_PERIOD=2024-01/cur2-Manifest.json":    true,
+		"exports/cur2/data/BILLING_PERIOD=2024-01/cur2-00001.snappy.parquet": false,
+		"usagecost/report/20240101-20240201/abcd-1234/report-Manifest.json":  false,
+	} {
+		if manifestKeyRegex.MatchString(key) != expected {
+			t.Errorf("Key %s should match: %t.", key, expected)
+		}
+	}
+}
+
+func TestDataExportsCsvRecords(t *testing.T) {
+	buf := bytes.NewBufferString(`identity_line_item_id,line_item_usage_account_id,product_region_code,resource_tags
+li-1,123456789012,eu-west-1,"{""user_name"":""web"",""aws_createdBy"":""root""}"
+li-2,123456789012,us-east-1,
+`)
+	d := csv.NewDecoder(buf)
+	var lineItems []LineItem
+	lis, errPromise := records(context.Background(), &d, curSchemaDataExports)
+	err := forEachLineItem(lis, errPromise, func(li LineItem) {
+		lineItems = append(lineItems, extractTags(li))
+	})
+	if err != nil {
+		t.Fatalf("Failed to read line items: %s.", err.Error())
+	}
+	if len(lineItems) != 2 {
+		t.Fatalf("Should read 2 line items, read %d instead.", len(lineItems))
+	}
+	if lineItems[0].LineItemId != "li-1" || lineItems[0].UsageAccountId != "123456789012" || lineItems[0].Region != "eu-west-1" {
+		t.Errorf("Columns should be mapped to the legacy ones, got %#v.", lineItems[0])
+	}
+	if len(lineItems[0].Tags) != 1 || lineItems[0].Tags[0] != (LineItemTags{"name", "web"}) {
+		t.Errorf("User tags should be extracted, got %#v.", lineItems[0].Tags)
+	}
+	if len(lineItems[1].Tags) != 0 {
+		t.Errorf("Line item without tags should have no tags, got %#v.", lineItems[1].Tags)
+	}
+}
+
+// testdata/dataExports.parquet is a Data Exports report written by another
+// Parquet implementation with GZIP compression and version 2 data pages. It
+// holds three line items: li-1 in eu-west-1 tagged with user_name web and
+// user_team ops, li-2 in us-east-1 without tags, and li-3 in eu-west-1 tagged
+// with user_name db.
+func TestDataExportsParquetRecords(t *testing.T) {
+	lineItems := readParquetTestFile(t, "dataExports.parquet", curSchemaDataExports)
+	if len(lineItems) != 3 {
+		t.Fatalf("Should read 3 line items, read %d instead.", len(lineItems))
+	}
+	if lineItems[0].LineItemId != "li-1" || lineItems[0].Region != "eu-west-1" || lineItems[1].Region != "us-east-1" {
+		t.Errorf("Columns should be mapped to the legacy ones, got %#v.", lineItems)
+	}
+	tags := make(map[string]string)
+	for _, tag := range lineItems[0].Tags {
+		tags[tag.Key] = tag.Tag
+	}
+	if len(tags) != 2 || tags["name"] != "web" || tags["team"] != "ops" {
+		t.Errorf("User tags should be extracted from the map, got %#v.", lineItems[0].Tags)
+	}
+	if len(lineItems[1].Tags) != 0 {
+		t.Errorf("Line item without tags should have no tags, got %#v.", lineItems[1].Tags)
+	}
+	if len(lineItems[2].Tags) != 1 || lineItems[2].Tags[0] != (LineItemTags{"name", "db"}) {
+		t.Errorf("Tags should not leak between line items, got %#v.", lineItems[2].Tags)
+	}
+}
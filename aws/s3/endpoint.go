@@ -0,0 +1,59 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/trackit/trackit/config"
+	"github.com/trackit/trackit/util"
+)
+
+var ErrEndpointNotAllowed = errors.New("endpoint does not resolve to a public address")
+
+// isEndpointAllowed returns true if the host of an endpoint was allowed by the
+// operator to resolve to a private address.
+func isEndpointAllowed(u *url.URL) bool {
+	for _, host := range config.S3CompatibleEndpoints {
+		if strings.EqualFold(u.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEndpointHost rejects the endpoints of S3-compatible services whose host
+// is obviously not public. Hostnames resolving to private addresses are
+// rejected when connecting, by endpointHTTPClient.
+func checkEndpointHost(u *url.URL) error {
+	if isEndpointAllowed(u) || util.IsPublicHost(u.Hostname()) {
+		return nil
+	}
+	return ErrEndpointNotAllowed
+}
+
+// endpointHTTPClient returns the HTTP client used to reach the endpoint of an
+// S3-compatible service. Unless its host is one of config.S3CompatibleEndpoints,
+// the client only connects to public addresses and does not use any proxy, so
+// that bill repositories cannot be used to reach internal services.
+func endpointHTTPClient(u *url.URL) *http.Client {
+	if isEndpointAllowed(u) {
+		return &http.Client{}
+	}
+	return &http.Client{Transport: util.PublicHTTPTransport()}
+}
@@ -0,0 +1,59 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/trackit/trackit/config"
+)
+
+func TestIsEndpointValid(t *testing.T) {
+	for _, endpoint := range []string{
+		"http://169.254.169.254",
+		"http://localhost:9000",
+		"http://127.0.0.1:9000",
+		"http://10.0.0.12",
+		"http://[::1]:9000",
+		"http://[::ffff:169.254.169.254]",
+		"http://minio:9000",
+	} {
+		config.S3CompatibleEndpoints = nil
+		if endpoint == "http://minio:9000" {
+			if err := isEndpointValid(endpoint); err != nil {
+				t.Errorf("Endpoint %s should be accepted, got %s.", endpoint, err.Error())
+			}
+		} else if err := isEndpointValid(endpoint); err == nil {
+			t.Errorf("Endpoint %s should be rejected.", endpoint)
+		}
+	}
+}
+
+func TestIsEndpointAllowed(t *testing.T) {
+	previous := config.S3CompatibleEndpoints
+	defer func() { config.S3CompatibleEndpoints = previous }()
+	config.S3CompatibleEndpoints = append(config.S3CompatibleEndpoints[:0:0], "MinIO.internal")
+	if err := isEndpointValid("http://minio.internal:9000"); err != nil {
+		t.Errorf("Allowed endpoint should be accepted, got %s.", err.Error())
+	}
+	u, _ := url.Parse("http://minio.internal:9000")
+	if client := endpointHTTPClient(u); client.Transport != nil {
+		t.Errorf("Allowed endpoint should be reached without restrictions.")
+	}
+	if err := isEndpointValid("http://127.0.0.1:9000"); err == nil {
+		t.Errorf("Endpoints which are not allowed should still be rejected.")
+	}
+}
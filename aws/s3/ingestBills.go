@@ -106,8 +106,13 @@ func UpdateDueReports(ctx context.Context, tx *sql.Tx) ([]ReportUpdateConclusion
 // contextKey is a key in a context, to prevent collision with other modules.
 type contextKey uint
 
-// ingestionContextKey is used to store an 'ingestionId' in a context.
-const ingestionContextKey = contextKey(iota)
+const (
+	// ingestionContextKey is used to store an 'ingestionId' in a context.
+	ingestionContextKey = contextKey(iota)
+	// updateJobContextKey is used to store the ID of the
+	// aws_bill_update_job row of an ingestion in a context.
+	updateJobContextKey
+)
 
 // contextWithIngestionId returns a context configured so that its logger logs
 // an 'ingestionId'.
@@ -129,21 +134,7 @@ func UpdateReport(ctx context.Context, aa aws.AwsAccount, br BillRepository) (la
 		"awsAccount":     aa,
 		"billRepository": br,
 	})
-	if bp, err := getBulkProcessor(ctx); err != nil {
-		logger.Error("Failed to get bulk processor.", err.Error())
-		return latestManifest, err
-	} else {
-		index := es.IndexNameForUserId(aa.UserId, IndexPrefixLineItem)
-		latestManifest, err = ReadBills(
-			ctx,
-			aa,
-			br,
-			ingestLineItems(ctx, bp, index, br),
-			manifestsModifiedAfter(br.LastImportedManifest),
-		)
-		logger.Info("Done ingesting data.", nil)
-		return latestManifest, err
-	}
+	return ingestReports(ctx, aa, br, manifestsModifiedAfter(br.LastImportedManifest))
 }
 
 // UpdateReportLimit updates the elasticsearch database with new data from usage and
@@ -156,17 +147,27 @@ func UpdateReportLimit(ctx context.Context, aa aws.AwsAccount, br BillRepository
 		"billRepository": br,
 		"upperDate":      dateUpperLimit,
 	})
-	if bp, err := getBulkProcessor(ctx); err != nil {
+	return ingestReports(ctx, aa, br, manifestModifedAfterAndBefore(br.LastImportedManifest, dateUpperLimit))
+}
+
+// ingestReports ingests the manifests matching `mp` in staging indices which
+// are swapped into the lineitems alias of the user once complete.
+func ingestReports(ctx context.Context, aa aws.AwsAccount, br BillRepository, mp ManifestPredicate) (latestManifest time.Time, err error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	if err = migrateLineItemsIndex(ctx, aa.UserId); err != nil {
+		logger.Error("Failed to migrate line items index.", err.Error())
+		return
+	} else if bp, err := getBulkProcessor(ctx); err != nil {
 		logger.Error("Failed to get bulk processor.", err.Error())
 		return latestManifest, err
 	} else {
-		index := es.IndexNameForUserId(aa.UserId, IndexPrefixLineItem)
+		defer bp.Close()
 		latestManifest, err = ReadBills(
 			ctx,
 			aa,
 			br,
-			ingestLineItems(ctx, bp, index, br),
-			manifestModifedAfterAndBefore(br.LastImportedManifest, dateUpperLimit),
+			&stagedIngestion{ctx: ctx, bp: bp, aa: aa, br: br},
+			mp,
 		)
 		logger.Info("Done ingesting data.", nil)
 		return latestManifest, err
@@ -179,36 +180,12 @@ func getBulkProcessor(ctx context.Context) (*elastic.BulkProcessor, error) {
 	bps = bps.BulkActions(-1)
 	bps = bps.BulkSize(esBulkInsertSize)
 	bps = bps.Workers(esBulkInsertWorkers)
+	bps = bps.Stats(true)
 	bps = bps.Before(beforeBulk(ctx))
 	bps = bps.After(afterBulk(ctx))
 	return bps.Do(context.Background()) // use of background context is not an error
 }
 
-// ingestLineItems returns an OnLineItem handler which ingests LineItems in an
-// ElasticSearch index.
-func ingestLineItems(ctx context.Context, bp *elastic.BulkProcessor, index string, br BillRepository) OnLineItem {
-	return func(li LineItem, ok bool) {
-		if ok {
-			if li.LineItemType == "Tax" {
-				li.AvailabilityZone = "taxes"
-				li.Region = "taxes"
-			}
-			li.BillRepositoryId = br.Id
-			li = extractTags(li)
-			rq := elastic.NewBulkIndexRequest()
-			rq = rq.Index(index)
-			rq = rq.OpType(opTypeCreate)
-			rq = rq.Type(TypeLineItem)
-			rq = rq.Id(li.EsId())
-			rq = rq.Doc(li)
-			bp.Add(rq)
-		} else {
-			bp.Flush()
-			bp.Close()
-		}
-	}
-}
-
 // manifestsStartingAfter returns a manifest predicate which is true for all
 // manifests starting after a given date.
 func manifestsModifiedAfter(t time.Time) ManifestPredicate {
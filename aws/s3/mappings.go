@@ -41,8 +41,8 @@ func init() {
 
 const TemplateLineItem = `
 {
-	"template": "*-lineitems",
-	"version": 8,
+	"index_patterns": ["*-lineitems", "*-lineitems-*"],
+	"version": 11,
 	"mappings": {
 		"lineitem": {
 			"properties": {
@@ -113,6 +113,82 @@ const TemplateLineItem = `
 					"type": "keyword",
 					"norms": false
 				},
+				"reservationArn": {
+					"type": "keyword",
+					"norms": false
+				},
+				"savingsPlanArn": {
+					"type": "keyword",
+					"norms": false
+				},
+				"savingsPlanOfferingType": {
+					"type": "keyword",
+					"norms": false
+				},
+				"savingsPlanPurchaseTerm": {
+					"type": "keyword",
+					"norms": false
+				},
+				"savingsPlanPaymentOption": {
+					"type": "keyword",
+					"norms": false
+				},
+				"savingsPlanRegion": {
+					"type": "keyword",
+					"norms": false
+				},
+				"savingsPlanStartTime": {
+					"type": "keyword",
+					"norms": false
+				},
+				"savingsPlanEndTime": {
+					"type": "keyword",
+					"norms": false
+				},
+				"blendedCost": {
+					"type": "float",
+					"index": false
+				},
+				"netUnblendedCost": {
+					"type": "float",
+					"index": false
+				},
+				"publicOnDemandCost": {
+					"type": "float",
+					"index": false
+				},
+				"reservationEffectiveCost": {
+					"type": "float",
+					"index": false
+				},
+				"reservationAmortizedUpfrontFee": {
+					"type": "float",
+					"index": false
+				},
+				"savingsPlanEffectiveCost": {
+					"type": "float",
+					"index": false
+				},
+				"savingsPlanTotalCommitment": {
+					"type": "float",
+					"index": false
+				},
+				"savingsPlanUsedCommitment": {
+					"type": "float",
+					"index": false
+				},
+				"totalDiscount": {
+					"type": "float",
+					"index": false
+				},
+				"amortizedCost": {
+					"type": "float",
+					"index": false
+				},
+				"netCost": {
+					"type": "float",
+					"index": false
+				},
 				"usageStartDate": {
 					"type": "date"
 				},
@@ -0,0 +1,256 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/models"
+)
+
+// progressSaveInterval is the interval at which the progress of an
+// ingestion is logged and saved to its aws_bill_update_job row.
+const progressSaveInterval = 10 * time.Second
+
+// ContextWithUpdateJob returns a context in which the progress of bill
+// ingestions is saved to the aws_bill_update_job row with ID updateId. The
+// progress is logged every progressSaveInterval in any case.
+func ContextWithUpdateJob(ctx context.Context, updateId int64) context.Context {
+	return context.WithValue(ctx, updateJobContextKey, updateId)
+}
+
+// ingestionProgress holds the progress counters of an ingestion. They are
+// updated atomically by the ingestion pipeline. Totals only cover what
+// remains to be ingested: report keys ingested by a previous run are
+// subtracted from them.
+type ingestionProgress struct {
+	manifestsTotal int64
+	manifestsDone  int64
+	keysTotal      int64
+	keysDone       int64
+	bytesTotal     int64
+	bytesDone      int64
+	lineItems      int64
+	// sizes holds the sizes of the keys listed from the repository. It is
+	// guarded by sizesMutex since keys are still being listed while the
+	// manifests are read.
+	sizes      map[string]int64
+	sizesMutex sync.Mutex
+}
+
+func newIngestionProgress() *ingestionProgress {
+	return &ingestionProgress{sizes: make(map[string]int64)}
+}
+
+// recordSizes records the sizes of the BillKeys sent to `in` before
+// forwarding them to the returned channel.
+func (p *ingestionProgress) recordSizes(in <-chan BillKey) <-chan BillKey {
+	out := make(chan BillKey)
+	go func() {
+		defer close(out)
+		for bk := range in {
+			p.sizesMutex.Lock()
+			p.sizes[bk.Key] = bk.Size
+			p.sizesMutex.Unlock()
+			out <- bk
+		}
+	}()
+	return out
+}
+
+// countManifests waits for all manifests sent to `in` to count them and
+// their report keys, then forwards them to the returned channel. The totals
+// are only computed once `in` is closed, when the listing of the keys whose
+// sizes are summed is complete.
+func (p *ingestionProgress) countManifests(in <-chan manifest) <-chan manifest {
+	out := make(chan manifest)
+	go func() {
+		defer close(out)
+		var manifests []manifest
+		for m := range in {
+			manifests = append(manifests, m)
+		}
+		for _, m := range manifests {
+			atomic.AddInt64(&p.manifestsTotal, 1)
+			p.addTotals(m.ReportKeys)
+		}
+		for _, m := range manifests {
+			out <- m
+		}
+	}()
+	return out
+}
+
+// addTotals adds the count and the size of `keys` to the totals.
+func (p *ingestionProgress) addTotals(keys []string) {
+	atomic.AddInt64(&p.keysTotal, int64(len(keys)))
+	atomic.AddInt64(&p.bytesTotal, p.sizeOf(keys))
+}
+
+// skipKeys subtracts the count and the size of `keys` from the totals.
+func (p *ingestionProgress) skipKeys(keys []string) {
+	atomic.AddInt64(&p.keysTotal, -int64(len(keys)))
+	atomic.AddInt64(&p.bytesTotal, -p.sizeOf(keys))
+}
+
+// sizeOf returns the sum of the sizes of `keys`. Keys which were not listed
+// from the repository are ignored.
+func (p *ingestionProgress) sizeOf(keys []string) int64 {
+	p.sizesMutex.Lock()
+	defer p.sizesMutex.Unlock()
+	var sum int64
+	for _, s := range keys {
+		sum += p.sizes[s]
+	}
+	return sum
+}
+
+// job returns the counters as the progress of the aws_bill_update_job row
+// with ID id.
+func (p *ingestionProgress) job(id int) models.AwsBillUpdateJob {
+	return models.AwsBillUpdateJob{
+		ID:             id,
+		ManifestsTotal: int(atomic.LoadInt64(&p.manifestsTotal)),
+		ManifestsDone:  int(atomic.LoadInt64(&p.manifestsDone)),
+		KeysTotal:      int(atomic.LoadInt64(&p.keysTotal)),
+		KeysDone:       int(atomic.LoadInt64(&p.keysDone)),
+		BytesTotal:     atomic.LoadInt64(&p.bytesTotal),
+		BytesDone:      atomic.LoadInt64(&p.bytesDone),
+		LineItems:      atomic.LoadInt64(&p.lineItems),
+	}
+}
+
+// report logs the progress and saves it to the aws_bill_update_job row
+// stored in the context, if any, every progressSaveInterval. It returns a
+// function which stops the reporting after a last save.
+func (p *ingestionProgress) report(ctx context.Context) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(progressSaveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.save(ctx)
+			case <-done:
+				p.save(ctx)
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// save logs the progress and saves it to the aws_bill_update_job row stored
+// in the context, if any.
+func (p *ingestionProgress) save(ctx context.Context) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	updateId, _ := ctx.Value(updateJobContextKey).(int64)
+	job := p.job(int(updateId))
+	logger.Info("Ingestion progress.", job)
+	if updateId == 0 {
+	} else if err := job.UpdateProgress(db.Db); err != nil {
+		logger.Warning("Failed to save ingestion progress.", map[string]interface{}{
+			"updateId": updateId,
+			"error":    err.Error(),
+		})
+	}
+}
+
+// progressIngestion is a reportIngestion which counts the progress of the
+// reportIngestion it wraps.
+type progressIngestion struct {
+	reportIngestion
+	p *ingestionProgress
+}
+
+func (pi progressIngestion) startManifest(m manifest) (map[string]bool, bool, error) {
+	done, ingested, err := pi.reportIngestion.startManifest(m)
+	if err != nil {
+	} else if ingested {
+		pi.p.skipKeys(m.ReportKeys)
+		atomic.AddInt64(&pi.p.manifestsDone, 1)
+	} else {
+		var ingestedKeys []string
+		for _, s := range m.ReportKeys {
+			if done[s] {
+				ingestedKeys = append(ingestedKeys, s)
+			}
+		}
+		pi.p.skipKeys(ingestedKeys)
+	}
+	return done, ingested, err
+}
+
+func (pi progressIngestion) onLineItem(m manifest, li LineItem) {
+	pi.reportIngestion.onLineItem(m, li)
+	atomic.AddInt64(&pi.p.lineItems, 1)
+}
+
+func (pi progressIngestion) endReportKey(m manifest, s string, count int) error {
+	err := pi.reportIngestion.endReportKey(m, s, count)
+	if err == nil {
+		atomic.AddInt64(&pi.p.keysDone, 1)
+	}
+	return err
+}
+
+func (pi progressIngestion) endManifest(m manifest) error {
+	err := pi.reportIngestion.endManifest(m)
+	if err == nil {
+		atomic.AddInt64(&pi.p.manifestsDone, 1)
+	}
+	return err
+}
+
+// progressStorage is a billStorage which counts the bytes read from the
+// objects of the billStorage it wraps.
+type progressStorage struct {
+	billStorage
+	p *ingestionProgress
+}
+
+func (ps progressStorage) object(ctx context.Context, s string) (io.ReadCloser, error) {
+	reader, err := ps.billStorage.object(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	return progressReader{reader, &ps.p.bytesDone}, nil
+}
+
+// progressReader is an io.ReadCloser which adds the count of bytes read to
+// a counter.
+type progressReader struct {
+	io.ReadCloser
+	count *int64
+}
+
+func (pr progressReader) Read(b []byte) (int, error) {
+	n, err := pr.ReadCloser.Read(b)
+	atomic.AddInt64(pr.count, int64(n))
+	return n, err
+}
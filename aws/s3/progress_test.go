@@ -0,0 +1,111 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// gzipBill returns a gzip compressed CSV bill with `lineItems` line items.
+func gzipBill(lineItems int) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("identity/LineItemId,lineItem/UsageAmount\n"))
+	for i := 0; i < lineItems; i++ {
+		gz.Write([]byte("li,1\n"))
+	}
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestIngestionProgress(t *testing.T) {
+	root, cleanup := withLocalBillRepositories(t)
+	defer cleanup()
+	period := filepath.Join(root, "archive", "report", "20240101-20240201")
+	first, second := gzipBill(3), gzipBill(5)
+	writeLocalBill(t, filepath.Join(period, "report-Manifest.json"), []byte(`{
+		"reportKeys": ["report/20240101-20240201/report-1.csv.gz", "report/20240101-20240201/report-2.csv.gz"],
+		"compression": "GZIP",
+		"billingPeriod": {"start": "20240101T000000.000Z", "end": "20240201T000000.000Z"}
+	}`))
+	writeLocalBill(t, filepath.Join(period, "report-1.csv.gz"), first)
+	writeLocalBill(t, filepath.Join(period, "report-2.csv.gz"), second)
+	bs, err := getLocalStorage(testLocalAwsAccount, BillRepository{Bucket: "archive"})
+	if err != nil {
+		t.Fatalf("Failed to get local storage: %s.", err.Error())
+	}
+
+	ctx := context.Background()
+	p := newIngestionProgress()
+	fi := fakeIngestion{done: map[string]bool{"report/20240101-20240201/report-1.csv.gz": true}}
+	mc := p.countManifests(getManifests(ctx, bs, getManifestKeys(ctx, p.recordSizes(bs.keys(ctx)))))
+	if err := importBills(ctx, progressStorage{bs, p}, mc, progressIngestion{&fi, p}); err != nil {
+		t.Fatalf("Failed to import bills: %s.", err.Error())
+	}
+	expected := p.job(0)
+	expected.ManifestsTotal, expected.ManifestsDone = 1, 1
+	expected.KeysTotal, expected.KeysDone = 1, 1
+	expected.BytesTotal, expected.BytesDone = int64(len(second)), int64(len(second))
+	expected.LineItems = 5
+	if job := p.job(0); job != expected {
+		t.Errorf("Progress should only count the key which was not checkpointed, expected %#v, got %#v.", expected, job)
+	}
+}
+
+func TestIngestionProgressPeriods(t *testing.T) {
+	root, cleanup := withLocalBillRepositories(t)
+	defer cleanup()
+	var bytesTotal int64
+	for i := 0; i < 6; i++ {
+		begin := month(2024, time.January).AddDate(0, i, 0)
+		end := begin.AddDate(0, 1, 0)
+		dir := fmt.Sprintf("%s-%s", begin.Format("20060102"), end.Format("20060102"))
+		writeLocalBill(t, filepath.Join(root, "archive", "report", dir, "report-Manifest.json"), []byte(fmt.Sprintf(`{
+			"reportKeys": ["report/%[1]s/report-1.csv.gz", "report/%[1]s/report-2.csv.gz"],
+			"compression": "GZIP",
+			"billingPeriod": {"start": "%[2]s", "end": "%[3]s"}
+		}`, dir, begin.Format("20060102T150405.000Z"), end.Format("20060102T150405.000Z"))))
+		for j, bill := range [][]byte{gzipBill(i + 1), gzipBill(2 * (i + 1))} {
+			writeLocalBill(t, filepath.Join(root, "archive", "report", dir, fmt.Sprintf("report-%d.csv.gz", j+1)), bill)
+			bytesTotal += int64(len(bill))
+		}
+	}
+	bs, err := getLocalStorage(testLocalAwsAccount, BillRepository{Bucket: "archive"})
+	if err != nil {
+		t.Fatalf("Failed to get local storage: %s.", err.Error())
+	}
+
+	ctx := context.Background()
+	p := newIngestionProgress()
+	fi := fakeIngestion{}
+	mc := p.countManifests(getManifests(ctx, bs, getManifestKeys(ctx, p.recordSizes(bs.keys(ctx)))))
+	if err := importBills(ctx, progressStorage{bs, p}, mc, progressIngestion{&fi, p}); err != nil {
+		t.Fatalf("Failed to import bills: %s.", err.Error())
+	}
+	expected := p.job(0)
+	expected.ManifestsTotal, expected.ManifestsDone = 6, 6
+	expected.KeysTotal, expected.KeysDone = 12, 12
+	expected.BytesTotal, expected.BytesDone = bytesTotal, bytesTotal
+	expected.LineItems = 63
+	if job := p.job(0); job != expected {
+		t.Errorf("Progress should count every billing period, expected %#v, got %#v.", expected, job)
+	}
+}
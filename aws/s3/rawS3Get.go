@@ -2,11 +2,11 @@ package s3
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -20,6 +20,9 @@ import (
 type dumbS3Manager struct {
 	creds  *credentials.Credentials
 	signer *v4.Signer
+	// endpoint is the URL of an S3-compatible service. AWS S3 is used if
+	// it is empty.
+	endpoint string
 }
 
 // readToWriter reads everything from a reader and writes it to a writer.
@@ -35,22 +38,6 @@ func (dm *dumbS3Manager) init(creds *credentials.Credentials) {
 	dm.signer = v4.NewSigner(creds)
 }
 
-// rawS3GetObjectToBuffer reads an S3 object to a buffer.
-func (dm dumbS3Manager) rawS3GetObjectToBuffer(
-	ctx context.Context, client *http.Client, region, bucket, key string,
-) ([]byte, error) {
-	body, err := dm.rawS3GetObjectToReader(ctx, client, region, bucket, key)
-	if err != nil {
-		return nil, err
-	}
-	bufWrapper := bytes.NewBuffer(make([]byte, 0, 0x8000))
-	err = readToWriter(body, bufWrapper)
-	if err != nil {
-		return nil, err
-	}
-	return bufWrapper.Bytes(), nil
-}
-
 // rawS3GetObjectToReader returns the reader for an S3 object.
 func (dm dumbS3Manager) rawS3GetObjectToReader(ctx context.Context, client *http.Client, region, bucket, key string) (io.ReadCloser, error) {
 	req, err := dm.rawS3GetObject(region, bucket, key)
@@ -73,6 +60,14 @@ func (dm dumbS3Manager) rawS3GetObject(region, bucket, key string) (*http.Reques
 		bucket,
 		rest.EscapePath(key, false),
 	)
+	if dm.endpoint != "" {
+		url = fmt.Sprintf(
+			"%s/%s/%s",
+			strings.TrimRight(dm.endpoint, "/"),
+			bucket,
+			rest.EscapePath(key, false),
+		)
+	}
 	request, err := http.NewRequest(
 		http.MethodGet,
 		url,
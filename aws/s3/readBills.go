@@ -15,14 +15,18 @@
 package s3
 
 import (
+	"archive/zip"
 	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -32,7 +36,6 @@ import (
 
 	taws "github.com/trackit/trackit/aws"
 	"github.com/trackit/trackit/config"
-	"github.com/trackit/trackit/es"
 	"github.com/trackit/trackit/util/csv"
 )
 
@@ -49,6 +52,8 @@ const (
 
 var (
 	ErrUnsupportedCompression = errors.New("unsupported compression")
+	ErrNoCsvInZip             = errors.New("no CSV file in ZIP archive")
+	ErrIncompleteIngestion    = errors.New("some bills could not be ingested")
 	httpClient                = http.Client{}
 )
 
@@ -56,10 +61,18 @@ const maxManifestSize = 0x8000
 
 type billTime time.Time
 
-const billTimeFormat = `"20060102T150405Z"`
+const (
+	billTimeFormat = `"20060102T150405Z"`
+	// dataExportsBillTimeFormat is the time format of Data Exports
+	// manifests.
+	dataExportsBillTimeFormat = `"` + time.RFC3339 + `"`
+)
 
 func (t *billTime) UnmarshalJSON(b []byte) error {
 	tt, err := time.Parse(billTimeFormat, string(b))
+	if err != nil {
+		tt, err = time.Parse(dataExportsBillTimeFormat, string(b))
+	}
 	if err == nil {
 		*t = billTime(tt)
 	}
@@ -73,6 +86,9 @@ type manifest struct {
 	Compression   string   `json:"compression"`
 	ReportName    string   `json:"reportName"`
 	Account       string   `json:"account"`
+	ExportArn     string   `json:"exportArn"`
+	ExportName    string   `json:"exportName"`
+	DataFiles     []string `json:"dataFiles"`
 	BillingPeriod struct {
 		Start billTime `json:"start"`
 		End   billTime `json:"end"`
@@ -86,6 +102,7 @@ type BillKey struct {
 	Bucket       string
 	Key          string
 	LastModified time.Time
+	Size         int64
 }
 
 type billRepositoryWithRegion struct {
@@ -94,29 +111,50 @@ type billRepositoryWithRegion struct {
 }
 
 type LineItem struct {
-	BillRepositoryId   int               `csv:"-"                            json:"billRepositoryId"`
-	LineItemId         string            `csv:"identity/LineItemId"          json:"lineItemId"`
-	TimeInterval       string            `csv:"identity/TimeInterval"        json:"-"`
-	InvoiceId          string            `csv:"bill/InvoiceId"               json:"invoiceId"`
-	BillingPeriodStart string            `csv:"bill/BillingPeriodStartDate"  json:"-"`
-	BillingPeriodEnd   string            `csv:"bill/BillingPeriodEndDate"    json:"-"`
-	UsageAccountId     string            `csv:"lineItem/UsageAccountId"      json:"usageAccountId"`
-	LineItemType       string            `csv:"lineItem/LineItemType"        json:"lineItemType"`
-	UsageStartDate     string            `csv:"lineItem/UsageStartDate"      json:"usageStartDate"`
-	UsageEndDate       string            `csv:"lineItem/UsageEndDate"        json:"usageEndDate""`
-	ProductCode        string            `csv:"lineItem/ProductCode"         json:"productCode"`
-	UsageType          string            `csv:"lineItem/UsageType"           json:"usageType"`
-	Operation          string            `csv:"lineItem/Operation"           json:"operation"`
-	AvailabilityZone   string            `csv:"lineItem/AvailabilityZone"    json:"availabilityZone"`
-	Region             string            `csv:"product/region"               json:"region"`
-	ResourceId         string            `csv:"lineItem/ResourceId"          json:"resourceId"`
-	UsageAmount        string            `csv:"lineItem/UsageAmount"         json:"usageAmount"`
-	ServiceCode        string            `csv:"product/servicecode"          json:"serviceCode"`
-	CurrencyCode       string            `csv:"lineItem/CurrencyCode"        json:"currencyCode"`
-	UnblendedCost      string            `csv:"lineItem/UnblendedCost"       json:"unblendedCost"`
-	TaxType            string            `csv:"lineItem/TaxType"             json:"taxType"`
-	Any                map[string]string `csv:",any"                         json:"-"`
-	Tags               []LineItemTags    `csv:"-"                            json:"tags,omitempty"`
+	BillRepositoryId                     int               `csv:"-"                                                     json:"billRepositoryId"`
+	LineItemId                           string            `csv:"identity/LineItemId"                                   json:"lineItemId"`
+	TimeInterval                         string            `csv:"identity/TimeInterval"                                 json:"-"`
+	InvoiceId                            string            `csv:"bill/InvoiceId"                                        json:"invoiceId"`
+	BillingPeriodStart                   string            `csv:"bill/BillingPeriodStartDate"                           json:"-"`
+	BillingPeriodEnd                     string            `csv:"bill/BillingPeriodEndDate"                             json:"-"`
+	UsageAccountId                       string            `csv:"lineItem/UsageAccountId"                               json:"usageAccountId"`
+	LineItemType                         string            `csv:"lineItem/LineItemType"                                 json:"lineItemType"`
+	UsageStartDate                       string            `csv:"lineItem/UsageStartDate"                               json:"usageStartDate"`
+	UsageEndDate                         string            `csv:"lineItem/UsageEndDate"                                 json:"usageEndDate""`
+	ProductCode                          string            `csv:"lineItem/ProductCode"                                  json:"productCode"`
+	UsageType                            string            `csv:"lineItem/UsageType"                                    json:"usageType"`
+	Operation                            string            `csv:"lineItem/Operation"                                    json:"operation"`
+	AvailabilityZone                     string            `csv:"lineItem/AvailabilityZone"                             json:"availabilityZone"`
+	Region                               string            `csv:"product/region"                                        json:"region"`
+	ResourceId                           string            `csv:"lineItem/ResourceId"                                   json:"resourceId"`
+	UsageAmount                          string            `csv:"lineItem/UsageAmount"                                  json:"usageAmount"`
+	ServiceCode                          string            `csv:"product/servicecode"                                   json:"serviceCode"`
+	CurrencyCode                         string            `csv:"lineItem/CurrencyCode"                                 json:"currencyCode"`
+	UnblendedCost                        string            `csv:"lineItem/UnblendedCost"                                json:"unblendedCost"`
+	TaxType                              string            `csv:"lineItem/TaxType"                                      json:"taxType"`
+	BlendedCost                          string            `csv:"lineItem/BlendedCost"                                  json:"blendedCost,omitempty"`
+	NetUnblendedCost                     string            `csv:"lineItem/NetUnblendedCost"                             json:"netUnblendedCost,omitempty"`
+	PublicOnDemandCost                   string            `csv:"pricing/publicOnDemandCost"                            json:"publicOnDemandCost,omitempty"`
+	ReservationArn                       string            `csv:"reservation/ReservationARN"                            json:"reservationArn,omitempty"`
+	ReservationEffectiveCost             string            `csv:"reservation/EffectiveCost"                             json:"reservationEffectiveCost,omitempty"`
+	ReservationAmortizedUpfrontFee       string            `csv:"reservation/AmortizedUpfrontFeeForBillingPeriod"       json:"reservationAmortizedUpfrontFee,omitempty"`
+	ReservationUnusedAmortizedUpfrontFee string            `csv:"reservation/UnusedAmortizedUpfrontFeeForBillingPeriod" json:"-"`
+	ReservationUnusedRecurringFee        string            `csv:"reservation/UnusedRecurringFee"                        json:"-"`
+	SavingsPlanArn                       string            `csv:"savingsPlan/SavingsPlanARN"                            json:"savingsPlanArn,omitempty"`
+	SavingsPlanOfferingType              string            `csv:"savingsPlan/OfferingType"                              json:"savingsPlanOfferingType,omitempty"`
+	SavingsPlanPurchaseTerm              string            `csv:"savingsPlan/PurchaseTerm"                              json:"savingsPlanPurchaseTerm,omitempty"`
+	SavingsPlanPaymentOption             string            `csv:"savingsPlan/PaymentOption"                             json:"savingsPlanPaymentOption,omitempty"`
+	SavingsPlanRegion                    string            `csv:"savingsPlan/Region"                                    json:"savingsPlanRegion,omitempty"`
+	SavingsPlanStartTime                 string            `csv:"savingsPlan/StartTime"                                 json:"savingsPlanStartTime,omitempty"`
+	SavingsPlanEndTime                   string            `csv:"savingsPlan/EndTime"                                   json:"savingsPlanEndTime,omitempty"`
+	SavingsPlanEffectiveCost             string            `csv:"savingsPlan/SavingsPlanEffectiveCost"                  json:"savingsPlanEffectiveCost,omitempty"`
+	SavingsPlanTotalCommitment           string            `csv:"savingsPlan/TotalCommitmentToDate"                     json:"savingsPlanTotalCommitment,omitempty"`
+	SavingsPlanUsedCommitment            string            `csv:"savingsPlan/UsedCommitment"                            json:"savingsPlanUsedCommitment,omitempty"`
+	TotalDiscount                        string            `csv:"discount/TotalDiscount"                                json:"totalDiscount,omitempty"`
+	AmortizedCost                        float64           `csv:"-"                                                     json:"amortizedCost"`
+	NetCost                              float64           `csv:"-"                                                     json:"netCost"`
+	Any                                  map[string]string `csv:",any"                                                  json:"-"`
+	Tags                                 []LineItemTags    `csv:"-"                                                     json:"tags,omitempty"`
 }
 
 type LineItemTags struct {
@@ -128,25 +166,46 @@ func (li LineItem) EsId() string {
 	return fmt.Sprintf("%s/%s", li.TimeInterval, li.LineItemId)
 }
 
-type OnLineItem func(LineItem, bool)
 type ManifestPredicate func(manifest, bool) bool
 
-// ReadBills reads all LineItems from new bills in a BillRepository, and runs
-// `oli` for each one.
-func ReadBills(ctx context.Context, aa taws.AwsAccount, br BillRepository, oli OnLineItem, mp ManifestPredicate) (time.Time, error) {
+// reportIngestion ingests the LineItems read from the manifests of a
+// BillRepository. The report keys of a manifest are ingested one at a time so
+// that an interrupted ingestion can resume from the last completed one.
+type reportIngestion interface {
+	// startManifest prepares the ingestion of a manifest. It returns the
+	// report keys which were already ingested, or true if the whole manifest
+	// was already ingested.
+	startManifest(m manifest) (map[string]bool, bool, error)
+	// onLineItem ingests a LineItem from a report key of the manifest.
+	onLineItem(m manifest, li LineItem)
+	// endReportKey is called once all LineItems of a report key were
+	// ingested.
+	endReportKey(m manifest, s string, count int) error
+	// endManifest is called once all report keys of a manifest were
+	// ingested.
+	endManifest(m manifest) error
+}
+
+// ReadBills reads all LineItems from new bills in a BillRepository, and
+// ingests them with `ri`. It returns ErrIncompleteIngestion if any manifest
+// could not be fully ingested.
+// Its progress is reported as described by ContextWithUpdateJob.
+func ReadBills(ctx context.Context, aa taws.AwsAccount, br BillRepository, ri reportIngestion, mp ManifestPredicate) (time.Time, error) {
 	var lastManifest time.Time
-	s3svc, brr, err := getServiceForRepository(ctx, aa, br)
+	bs, err := getStorageForRepository(ctx, aa, br)
 	if err != nil {
 		return lastManifest, err
 	}
-	jsonlog.LoggerFromContextOrDefault(ctx).Debug("Obtained S3 service to read bills.", map[string]interface{}{"account": aa, "billRepository": br})
-	mck := getKeys(ctx, s3svc, brr)
+	jsonlog.LoggerFromContextOrDefault(ctx).Debug("Obtained storage to read bills.", map[string]interface{}{"account": aa, "billRepository": br})
+	p := newIngestionProgress()
+	defer p.report(ctx)()
+	mck := p.recordSizes(bs.keys(ctx))
 	mck = getManifestKeys(ctx, mck)
-	mc := getManifests(ctx, s3svc, mck)
+	mc := getManifests(ctx, bs, mck)
 	mc, lastManifestPromise := selectManifests(mp, mc)
-	es.CleanCurrentMonthBillByBillRepositoryId(ctx, aa.UserId, br.Id)
-	importBills(ctx, s3svc, mc, oli, mp)
-	return <-lastManifestPromise, nil
+	mc = p.countManifests(mc)
+	err = importBills(ctx, progressStorage{bs, p}, mc, progressIngestion{ri, p})
+	return <-lastManifestPromise, err
 }
 
 // selectManifests returns a channel of all AWS manifest files which match
@@ -172,84 +231,119 @@ func selectManifests(mp ManifestPredicate, mc <-chan manifest) (<-chan manifest,
 }
 
 // importBills imports LineItems for bill files described in manifests sent to
-// the `manifests` channel.
-func importBills(ctx context.Context, s3svc *s3.S3, manifests <-chan manifest, oli OnLineItem, mp ManifestPredicate) {
+// the `manifests` channel. A manifest which fails to be ingested does not stop
+// the ingestion of the others.
+func importBills(ctx context.Context, bs billStorage, manifests <-chan manifest, ri reportIngestion) error {
 	l := jsonlog.LoggerFromContextOrDefault(ctx)
-	outs, out := mergecdLineItem()
+	var err error
 	for m := range manifests {
 		l.Debug("Will attempt ingesting bills.", m)
-		for _, s := range m.ReportKeys {
-			l.Debug("Will attempt ingesting bill part.", map[string]interface{}{"key": s, "manifest": m})
-			outs <- importBill(ctx, s3svc, s, m, mp)
+		if mErr := importManifest(ctx, bs, m, ri); mErr != nil {
+			l.Error("Failed to ingest bills.", map[string]interface{}{"manifest": m, "error": mErr.Error()})
+			err = ErrIncompleteIngestion
 		}
 	}
-	close(outs)
-	for lineItem := range out {
-		oli(lineItem, true)
-	}
-	oli(LineItem{}, false)
+	return err
 }
 
-// importBill imports LineItems for a single bill file.
-func importBill(ctx context.Context, s3svc *s3.S3, s string, m manifest, mp ManifestPredicate) <-chan LineItem {
-	outs, out := mergecdLineItem()
-	go func() {
-		defer close(outs)
-		ctx, cancel := context.WithCancel(ctx)
-		l := jsonlog.LoggerFromContextOrDefault(ctx)
-		reader, err := getBillReader(ctx, s3svc, s, m)
+// importManifest imports LineItems for the report keys of a manifest which
+// were not ingested yet. It stops at the first report key which fails to be
+// ingested.
+func importManifest(ctx context.Context, bs billStorage, m manifest, ri reportIngestion) error {
+	l := jsonlog.LoggerFromContextOrDefault(ctx)
+	done, ingested, err := ri.startManifest(m)
+	if err != nil {
+		return err
+	} else if ingested {
+		l.Debug("Bills were already ingested.", m)
+		return nil
+	}
+	for _, s := range m.ReportKeys {
+		if done[s] {
+			l.Debug("Bill part was already ingested.", map[string]interface{}{"key": s, "manifest": m})
+			continue
+		}
+		l.Debug("Will attempt ingesting bill part.", map[string]interface{}{"key": s, "manifest": m})
+		var count int
+		err := importBill(ctx, bs, s, m, func(li LineItem) {
+			count++
+			ri.onLineItem(m, li)
+		})
 		if err != nil {
-			l.Error("Failed to read bill.", err.Error())
-		} else {
-			l.Debug("Reading bill.", map[string]interface{}{"key": s, "manifest": m})
-			outs <- readBill(ctx, cancel, reader, s, m, mp)
+			return err
+		} else if err = ri.endReportKey(m, s, count); err != nil {
+			return err
 		}
-	}()
-	return out
+	}
+	return ri.endManifest(m)
 }
 
-// readBill returns a channel of all LineItems in a single bill file.
-func readBill(ctx context.Context, cancel context.CancelFunc, reader io.ReadCloser, s string, m manifest, mp ManifestPredicate) <-chan LineItem {
-	out := make(chan LineItem)
-	go func() {
+// importBill imports LineItems for a single bill file, running `oli` for each
+// one.
+func importBill(ctx context.Context, bs billStorage, s string, m manifest, oli func(LineItem)) error {
+	l := jsonlog.LoggerFromContextOrDefault(ctx)
+	if isParquetBill(s, m) {
+		file, err := downloadBill(ctx, bs, s, m)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		l.Debug("Reading Parquet bill.", map[string]interface{}{"key": s, "manifest": m})
+		lineItems, errPromise := parquetRecords(ctx, file, file.size, m.schema())
+		return forEachLineItem(lineItems, errPromise, oli)
+	} else if reader, err := getBillReader(ctx, bs, s, m); err != nil {
+		return err
+	} else {
 		defer reader.Close()
-		defer close(out)
+		l.Debug("Reading bill.", map[string]interface{}{"key": s, "manifest": m})
 		csvDecoder := csv.NewDecoder(reader)
-		for r := range records(ctx, &csvDecoder) {
-			if mp(m, false) || r.InvoiceId == "" {
-				out <- r
-			}
-		}
-	}()
-	return out
+		lineItems, errPromise := records(ctx, &csvDecoder, m.schema())
+		return forEachLineItem(lineItems, errPromise, oli)
+	}
 }
 
-func records(ctx context.Context, d *csv.Decoder) <-chan LineItem {
+// forEachLineItem runs `oli` for each LineItem sent to `lineItems`, then
+// returns the error sent to `errPromise` once they were all read.
+func forEachLineItem(lineItems <-chan LineItem, errPromise <-chan error, oli func(LineItem)) error {
+	for li := range lineItems {
+		oli(expandResourceTags(li))
+	}
+	return <-errPromise
+}
+
+// records returns a channel of all LineItems in a CSV report, and a channel
+// where the error which stopped the reading, if any, is sent once they were
+// all read. The columns of the header are mapped to the legacy CSV column
+// names for the schema.
+func records(ctx context.Context, d *csv.Decoder, schema curSchema) (<-chan LineItem, <-chan error) {
 	out := make(chan LineItem)
-	log := jsonlog.LoggerFromContextOrDefault(ctx)
+	errOut := make(chan error, 1)
 	go func() {
+		defer close(errOut)
 		defer close(out)
 		if err := d.ReadHeader(); err != nil {
-			log.Error("Failed to read CSV header.", err.Error())
+			errOut <- err
 			return
 		}
+		d.SetHeader(curColumnNames(schema, d.Header()))
 		for {
 			record, err := decodeRecord(d)
 			if err == io.EOF {
 				return // EOF was reached
 			} else if err != nil {
-				log.Error("Error reading CSV record.", err.Error())
+				errOut <- err
 				return
 			} else {
 				select {
 				case out <- record:
 				case <-ctx.Done():
+					errOut <- ctx.Err()
 					return
 				}
 			}
 		}
 	}()
-	return out
+	return out, errOut
 }
 
 // decodeRecord decodes a LineItem from a csv.Reader.
@@ -261,10 +355,12 @@ func decodeRecord(d *csv.Decoder) (LineItem, error) {
 
 // getBillReader returns a ReadCloser for a const and usage report. It will use
 // the object described by the key s and the manifest m.
-func getBillReader(ctx context.Context, s3svc *s3.S3, s string, m manifest) (io.ReadCloser, error) {
+func getBillReader(ctx context.Context, bs billStorage, s string, m manifest) (io.ReadCloser, error) {
 	switch m.Compression {
 	case "GZIP":
-		return getGzipBillReader(ctx, s3svc, s, m)
+		return getGzipBillReader(ctx, bs, s, m)
+	case "ZIP":
+		return getZipBillReader(ctx, bs, s, m)
 	default:
 		jsonlog.LoggerFromContextOrDefault(ctx).Error("Unsupported  compression scheme.", map[string]interface{}{"key": s, "manifest": m})
 		return nil, ErrUnsupportedCompression
@@ -273,35 +369,110 @@ func getBillReader(ctx context.Context, s3svc *s3.S3, s string, m manifest) (io.
 
 // getGzipBillReader returns a ReadCloser for a GZIP-compressed S3 object which
 // is downloaded on the fly.
-func getGzipBillReader(ctx context.Context, s3svc *s3.S3, s string, m manifest) (io.ReadCloser, error) {
-	if reader, err := getRawBillReader(ctx, s3svc, s, m); err == nil {
+func getGzipBillReader(ctx context.Context, bs billStorage, s string, m manifest) (io.ReadCloser, error) {
+	if reader, err := getRawBillReader(ctx, bs, s, m); err == nil {
 		return gzip.NewReader(reader)
 	} else {
 		return nil, err
 	}
 }
 
+// getZipBillReader returns a ReadCloser for the CSV file in a ZIP-compressed
+// S3 object. Since a ZIP archive can only be read with random access, the
+// object is first downloaded to a temporary file.
+func getZipBillReader(ctx context.Context, bs billStorage, s string, m manifest) (io.ReadCloser, error) {
+	if file, err := downloadBill(ctx, bs, s, m); err != nil {
+		return nil, err
+	} else {
+		return openZipBill(file)
+	}
+}
+
+// openZipBill returns a ReadCloser for the first CSV file in a downloaded ZIP
+// archive. The archive is removed once the reader is closed.
+func openZipBill(file *billFile) (io.ReadCloser, error) {
+	archive, err := zip.NewReader(file, file.size)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	for _, f := range archive.File {
+		if strings.HasSuffix(strings.ToLower(f.Name), ".csv") {
+			if reader, err := f.Open(); err != nil {
+				file.Close()
+				return nil, err
+			} else {
+				return zipBillReader{reader, file}, nil
+			}
+		}
+	}
+	file.Close()
+	return nil, ErrNoCsvInZip
+}
+
+// zipBillReader reads a CSV file from a downloaded ZIP archive, which is
+// removed once the reader is closed.
+type zipBillReader struct {
+	io.ReadCloser
+	file *billFile
+}
+
+func (r zipBillReader) Close() error {
+	r.ReadCloser.Close()
+	return r.file.Close()
+}
+
+// isParquetBill checks whether the bill file at key s is a Parquet file.
+func isParquetBill(s string, m manifest) bool {
+	return strings.EqualFold(m.Compression, "Parquet") || strings.HasSuffix(s, ".parquet")
+}
+
+// billFile is a bill downloaded to a temporary file, which is removed once it
+// is closed.
+type billFile struct {
+	*os.File
+	size int64
+}
+
+func (f *billFile) Close() error {
+	f.File.Close()
+	return os.Remove(f.Name())
+}
+
+// downloadBill downloads the bill file at key s to a temporary file, for the
+// formats which cannot be read as a stream.
+func downloadBill(ctx context.Context, bs billStorage, s string, m manifest) (*billFile, error) {
+	reader, err := getRawBillReader(ctx, bs, s, m)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	file, err := ioutil.TempFile("", "trackit-bill-")
+	if err != nil {
+		return nil, err
+	}
+	bf := &billFile{File: file}
+	if bf.size, err = io.Copy(file, reader); err != nil {
+		bf.Close()
+		return nil, err
+	}
+	return bf, nil
+}
+
 // getRawBillReader gets an io.ReadCloser for the raw data from a billing
 // file.
-func getRawBillReader(ctx context.Context, s3svc *s3.S3, s string, m manifest) (io.ReadCloser, error) {
-	var bucket = m.Bucket
-	var key = s
-	var region = *s3svc.Client.Config.Region
-	var dumbS3mgr dumbS3Manager
-	dumbS3mgr.init(s3svc.Client.Config.Credentials)
-	return dumbS3mgr.rawS3GetObjectToReader(ctx, &httpClient, region, bucket, key)
+func getRawBillReader(ctx context.Context, bs billStorage, s string, m manifest) (io.ReadCloser, error) {
+	return bs.object(ctx, s)
 }
 
 // getManifests downloads the manifest whose keys are sent to the in channel.
 // It immediately returns with a channel where manifest objects will be sent.
-func getManifests(ctx context.Context, s3svc *s3.S3, in <-chan BillKey) <-chan manifest {
+func getManifests(ctx context.Context, bs billStorage, in <-chan BillKey) <-chan manifest {
 	outs, out := mergecdManifest()
 	go func() {
 		defer close(outs)
-		var s3mgr dumbS3Manager
-		s3mgr.init(s3svc.Client.Config.Credentials)
 		for bk := range in {
-			outs <- readManifest(ctx, &s3mgr, bk)
+			outs <- readManifest(ctx, bs, bk)
 		}
 	}()
 	return out
@@ -310,12 +481,12 @@ func getManifests(ctx context.Context, s3svc *s3.S3, in <-chan BillKey) <-chan m
 // readManifest downloads and parses a manifest file asynchronously. Returns a
 // channel where at most one manifest object will be sent, then the channel
 // will be closed.
-func readManifest(ctx context.Context, s3mgr *dumbS3Manager, bk BillKey) <-chan manifest {
+func readManifest(ctx context.Context, bs billStorage, bk BillKey) <-chan manifest {
 	out := make(chan manifest)
 	go func() {
 		defer close(out)
 		logger := jsonlog.LoggerFromContextOrDefault(ctx)
-		buf, err := s3mgr.rawS3GetObjectToBuffer(ctx, &httpClient, bk.Region, bk.Bucket, bk.Key)
+		buf, err := readObject(ctx, bs, bk.Key)
 		if err != nil {
 			logger.Error("Failed to download usage and cost manifest.", map[string]interface{}{"billKey": bk, "error": err.Error()})
 			return
@@ -326,6 +497,7 @@ func readManifest(ctx context.Context, s3mgr *dumbS3Manager, bk BillKey) <-chan
 				logger.Error("Failed to parse usage and cost manifest.", map[string]interface{}{"billKey": bk, "error": err.Error()})
 				return
 			} else {
+				m = normalizeManifest(m)
 				m.LastModified = bk.LastModified
 				m.SourceBucket = bk.Bucket
 				out <- m
@@ -376,8 +548,9 @@ func getKeys(ctx context.Context, s3svc *s3.S3, brr billRepositoryWithRegion) <-
 	return c
 }
 
-// manifestKeyRegex matches keys which look like manifest keys.
-var manifestKeyRegex = regexp.MustCompile(`/\d{8}-\d{8}\/[^/]+-Manifest.json$`)
+// manifestKeyRegex matches keys which look like manifest keys, either from
+// legacy Cost and Usage Reports or from Data Exports.
+var manifestKeyRegex = regexp.MustCompile(`(/\d{8}-\d{8}|/metadata/BILLING_PERIOD=\d{4}-\d{2})\/[^/]+-Manifest.json$`)
 
 // getManifestKeys filters a channel of BillKey to only keep those which seem to
 // be Cost And Usage manifests.
@@ -421,6 +594,7 @@ func listBillsFromRepositoryPage(
 					Bucket:       brr.Bucket,
 					Region:       brr.Region,
 					LastModified: *o.LastModified,
+					Size:         aws.Int64Value(o.Size),
 				}:
 				case <-ctx.Done():
 					return false
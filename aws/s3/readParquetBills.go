@@ -0,0 +1,271 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/trackit/trackit/util/csv"
+	"github.com/trackit/trackit/util/parquet"
+)
+
+const (
+	// parquetTagPrefix is the prefix of user tag columns in Parquet
+	// reports, where AWS renames resourceTags/user:Name to
+	// resource_tags_user_name.
+	parquetTagPrefix = "resource_tags_user_"
+
+	// julianDayOfUnixEpoch is the julian day of 1970-01-01, used to decode
+	// INT96 timestamps.
+	julianDayOfUnixEpoch = 2440588
+)
+
+// parquetColumnNames maps the column names of Parquet reports to the column
+// names of CSV reports, as used in the csv tags of LineItem.
+var parquetColumnNames = getParquetColumnNames()
+
+// getParquetColumnNames builds parquetColumnNames from the csv tags of
+// LineItem.
+func getParquetColumnNames() map[string]string {
+	names := make(map[string]string)
+	t := reflect.TypeOf(LineItem{})
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("csv")
+		if name != "" && name != "-" && !strings.HasPrefix(name, ",") {
+			names[parquetColumnName(name)] = name
+		}
+	}
+	return names
+}
+
+// parquetColumnName returns the name AWS gives in Parquet reports to a CSV
+// report column: the category and the name are joined with an underscore and
+// each uppercase letter is lowered and preceded by an underscore, so
+// reservation/ReservationARN becomes reservation_reservation_a_r_n.
+func parquetColumnName(name string) string {
+	var b bytes.Buffer
+	var previous rune = '/'
+	for _, c := range name {
+		if c == '/' {
+			b.WriteRune('_')
+		} else if unicode.IsUpper(c) {
+			if previous != '/' {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(c))
+		} else {
+			b.WriteRune(c)
+		}
+		previous = c
+	}
+	return b.String()
+}
+
+// parquetValueFormatter formats a Parquet value the way it would appear in a
+// CSV report.
+type parquetValueFormatter func(parquet.Value) string
+
+// getParquetValueFormatter returns the formatter for the values of a leaf
+// column of a Parquet report.
+func getParquetValueFormatter(column parquet.Column) parquetValueFormatter {
+	if unit := column.TimeUnit; unit != 0 {
+		return func(v parquet.Value) string {
+			return formatBillTime(time.Unix(0, v.Int64()*int64(unit)))
+		}
+	}
+	switch column.Type {
+	case parquet.Boolean:
+		return func(v parquet.Value) string { return strconv.FormatBool(v.Boolean()) }
+	case parquet.Int32:
+		return func(v parquet.Value) string { return strconv.FormatInt(int64(v.Int32()), 10) }
+	case parquet.Int64:
+		return func(v parquet.Value) string { return strconv.FormatInt(v.Int64(), 10) }
+	case parquet.Int96:
+		return func(v parquet.Value) string { return formatBillTime(int96ToTime(v.Int96())) }
+	case parquet.Float:
+		return func(v parquet.Value) string { return strconv.FormatFloat(float64(v.Float()), 'f', -1, 32) }
+	case parquet.Double:
+		return func(v parquet.Value) string { return strconv.FormatFloat(v.Double(), 'f', -1, 64) }
+	default:
+		return func(v parquet.Value) string { return string(v.ByteArray()) }
+	}
+}
+
+// int96ToTime decodes a legacy INT96 timestamp, made of the nanoseconds in
+// the day followed by the julian day.
+func int96ToTime(i [3]uint32) time.Time {
+	nanoseconds := int64(i[1])<<32 | int64(i[0])
+	days := int64(i[2]) - julianDayOfUnixEpoch
+	return time.Unix(days*86400, nanoseconds)
+}
+
+// formatBillTime formats a time the way CSV reports do.
+func formatBillTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// parquetColumn describes how the values of a leaf column of a Parquet report
+// are decoded.
+type parquetColumn struct {
+	// field is the index of the column in the decoded records.
+	field int
+	// format formats the values of the column.
+	format parquetValueFormatter
+	// mapKeys is, for the values of a map-typed column, the index of the
+	// leaf column holding the keys. It is -1 for other columns.
+	mapKeys int
+	// isMapKey is true for the keys of a map-typed column.
+	isMapKey bool
+}
+
+// getParquetColumns returns the header of the records decoded from a Parquet
+// report, and how to decode each of its leaf columns. Map-typed columns such
+// as resource_tags are decoded as a single JSON-encoded field, the way they
+// appear in CSV reports.
+func getParquetColumns(leaves []parquet.Column, cs curSchema) ([]string, []parquetColumn) {
+	var header []string
+	columns := make([]parquetColumn, len(leaves))
+	mapFields := make(map[string]int)
+	mapKeys := make(map[string]int)
+	for i, leaf := range leaves {
+		path := leaf.Path
+		columns[i] = parquetColumn{format: getParquetValueFormatter(leaf), mapKeys: -1}
+		if name, ok := parquetMapName(path); ok {
+			if _, ok := mapFields[name]; !ok {
+				mapFields[name] = len(header)
+				header = append(header, curColumnName(cs, name))
+			}
+			columns[i].field = mapFields[name]
+			if path[len(path)-1] == "key" {
+				columns[i].isMapKey = true
+				mapKeys[name] = i
+			} else if keys, ok := mapKeys[name]; ok {
+				columns[i].mapKeys = keys
+			}
+		} else {
+			columns[i].field = len(header)
+			header = append(header, curColumnName(cs, strings.Join(path, ".")))
+		}
+	}
+	return header, columns
+}
+
+// parquetMapName returns the name of the map-typed column a leaf column
+// belongs to, if any. The leaves of map-typed columns have paths such as
+// resource_tags.key_value.key and resource_tags.key_value.value.
+func parquetMapName(path []string) (string, bool) {
+	if len(path) == 3 && (path[2] == "key" || path[2] == "value") {
+		return path[0], true
+	}
+	return "", false
+}
+
+// parquetRecords returns a channel of all LineItems in a Parquet report, and
+// a channel where the error which stopped the reading, if any, is sent once
+// they were all read. The values are formatted the way they would be in a CSV
+// report so that the LineItems are the same whatever the format of the
+// report.
+func parquetRecords(ctx context.Context, r io.ReaderAt, size int64, cs curSchema) (<-chan LineItem, <-chan error) {
+	out := make(chan LineItem)
+	errOut := make(chan error, 1)
+	go func() {
+		defer close(errOut)
+		defer close(out)
+		file, err := parquet.Open(r, size)
+		if err != nil {
+			errOut <- err
+			return
+		}
+		var d csv.Decoder
+		header, columns := getParquetColumns(file.Columns(), cs)
+		d.SetHeader(header)
+		for i := 0; i < file.NumRowGroups(); i++ {
+			if err := readParquetRowGroup(ctx, file, i, &d, columns, out); err != nil {
+				errOut <- err
+				return
+			}
+		}
+	}()
+	return out, errOut
+}
+
+// readParquetRowGroup sends the LineItems of a Parquet row group to out. The
+// columns of the row group are read side by side, one row at a time.
+func readParquetRowGroup(ctx context.Context, file *parquet.File, rowGroup int, d *csv.Decoder, columns []parquetColumn, out chan<- LineItem) error {
+	readers := make([]*parquet.ColumnReader, len(columns))
+	for i := range readers {
+		readers[i] = file.ColumnReader(rowGroup, i)
+	}
+	row := make([][]parquet.Value, len(columns))
+	for n := file.NumRows(rowGroup); n > 0; n-- {
+		for i, reader := range readers {
+			var err error
+			if row[i], err = reader.ReadRow(row[i][:0]); err != nil {
+				return err
+			}
+		}
+		record, err := decodeParquetRow(d, row, columns)
+		if err != nil {
+			return err
+		}
+		select {
+		case out <- record:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// decodeParquetRow decodes a LineItem from the values of each leaf column in
+// a Parquet row. The keys of a map are read before its values, and the n-th
+// value of a map goes with its n-th key.
+func decodeParquetRow(d *csv.Decoder, row [][]parquet.Value, columns []parquetColumn) (LineItem, error) {
+	var record LineItem
+	values := make([]string, len(d.Header()))
+	maps := make(map[int]map[string]string)
+	for i, c := range columns {
+		if c.isMapKey {
+			continue
+		} else if c.mapKeys >= 0 {
+			keys := row[c.mapKeys]
+			for j, v := range row[i] {
+				if j < len(keys) && !keys[j].IsNull() && !v.IsNull() {
+					if maps[c.field] == nil {
+						maps[c.field] = make(map[string]string)
+					}
+					maps[c.field][columns[c.mapKeys].format(keys[j])] = c.format(v)
+				}
+			}
+		} else if len(row[i]) > 0 && !row[i][0].IsNull() {
+			values[c.field] = c.format(row[i][0])
+		}
+	}
+	for field, m := range maps {
+		if encoded, err := json.Marshal(m); err == nil {
+			values[field] = string(encoded)
+		}
+	}
+	err := d.DecodeRecord(values, &record)
+	return record, err
+}
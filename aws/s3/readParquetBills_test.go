@@ -0,0 +1,133 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/trackit/trackit/util/csv"
+)
+
+// parquetTestRows are the rows of testdata/legacy.parquet, a legacy Cost and
+// Usage Report written by another Parquet implementation with Snappy
+// compression and dictionary encoding. It also holds a resource_tags_user_name
+// column and an unknown_column.
+var parquetTestRows = []struct {
+	LineItemId     string
+	TimeInterval   string
+	ReservationArn string
+}{
+	{"li-1", "2019-01-01T00:00:00Z/2019-01-01T01:00:00Z", ""},
+	{"li-2", "2019-01-01T01:00:00Z/2019-01-01T02:00:00Z", "arn:aws:ec2:reserved"},
+}
+
+// readParquetTestFile reads the line items of a Parquet file of testdata.
+func readParquetTestFile(t *testing.T, name string, cs curSchema) []LineItem {
+	data, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("Failed to read Parquet file: %s.", err.Error())
+	}
+	var lineItems []LineItem
+	lis, errPromise := parquetRecords(context.Background(), bytes.NewReader(data), int64(len(data)), cs)
+	err = forEachLineItem(lis, errPromise, func(li LineItem) {
+		lineItems = append(lineItems, extractTags(li))
+	})
+	if err != nil {
+		t.Fatalf("Failed to read line items: %s.", err.Error())
+	}
+	return lineItems
+}
+
+func TestParquetColumnName(t *testing.T) {
+	for csvName, expected := range map[string]string{
+		"identity/LineItemId":                             "identity_line_item_id",
+		"lineItem/UsageStartDate":                         "line_item_usage_start_date",
+		"product/servicecode":                             "product_servicecode",
+		"reservation/ReservationARN":                      "reservation_reservation_a_r_n",
+		"reservation/AmortizedUpfrontFeeForBillingPeriod": "reservation_amortized_upfront_fee_for_billing_period",
+		"savingsPlan/SavingsPlanEffectiveCost":            "savings_plan_savings_plan_effective_cost",
+	} {
+		if name := parquetColumnName(csvName); name != expected {
+			t.Errorf("Parquet column name for %s should be %s, is %s instead.", csvName, expected, name)
+		}
+		if name := curColumnName(curSchemaLegacy, expected); name != csvName {
+			t.Errorf("CSV column name for %s should be %s, is %s instead.", expected, csvName, name)
+		}
+	}
+	if name := curColumnName(curSchemaLegacy, "resource_tags_user_name"); name != "resourceTags/user:name" {
+		t.Errorf("CSV column name for a tag should be resourceTags/user:name, is %s instead.", name)
+	}
+}
+
+func TestParquetRecords(t *testing.T) {
+	rows := parquetTestRows
+	lineItems := readParquetTestFile(t, "legacy.parquet", curSchemaLegacy)
+	if len(lineItems) != len(rows) {
+		t.Fatalf("Should read %d line items, read %d instead.", len(rows), len(lineItems))
+	}
+	first, second := lineItems[0], lineItems[1]
+	if first.LineItemId != "li-1" || first.TimeInterval != rows[0].TimeInterval {
+		t.Errorf("Identity should be read, got %s and %s.", first.LineItemId, first.TimeInterval)
+	}
+	if first.UsageStartDate != "2019-01-01T00:00:00Z" || second.UsageStartDate != "2019-01-01T01:00:00Z" {
+		t.Errorf("Timestamps should be formatted like CSV reports, got %s and %s.", first.UsageStartDate, second.UsageStartDate)
+	}
+	if first.UsageAmount != "0.25" || second.UsageAmount != "3" {
+		t.Errorf("Numbers should be formatted like CSV reports, got %s and %s.", first.UsageAmount, second.UsageAmount)
+	}
+	if first.ReservationArn != "" || second.ReservationArn != "arn:aws:ec2:reserved" {
+		t.Errorf("Optional values should be read, got %q and %q.", first.ReservationArn, second.ReservationArn)
+	}
+	if first.ServiceCode != "AmazonEC2" || second.ServiceCode != "AmazonS3" {
+		t.Errorf("Product columns should be read, got %s and %s.", first.ServiceCode, second.ServiceCode)
+	}
+	if len(first.Tags) != 1 || first.Tags[0] != (LineItemTags{"name", "web"}) {
+		t.Errorf("Tags should be extracted, got %#v.", first.Tags)
+	}
+}
+
+func TestOpenZipBill(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	w, _ := archive.Create("report-1.csv")
+	w.Write([]byte("identity/LineItemId,lineItem/UsageAmount\nli-1,0.25\nli-2,3\n"))
+	archive.Close()
+	file, err := ioutil.TempFile("", "trackit-bill-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temporary file: %s.", err.Error())
+	}
+	file.Write(buf.Bytes())
+	reader, err := openZipBill(&billFile{file, int64(buf.Len())})
+	if err != nil {
+		t.Fatalf("Failed to open ZIP bill: %s.", err.Error())
+	}
+	defer reader.Close()
+	d := csv.NewDecoder(reader)
+	var lineItems []LineItem
+	lis, errPromise := records(context.Background(), &d, curSchemaLegacy)
+	err = forEachLineItem(lis, errPromise, func(li LineItem) {
+		lineItems = append(lineItems, li)
+	})
+	if err != nil {
+		t.Fatalf("Failed to read line items: %s.", err.Error())
+	}
+	if len(lineItems) != 2 || lineItems[0].LineItemId != "li-1" || lineItems[1].UsageAmount != "3" {
+		t.Errorf("ZIP bill should contain both line items, got %#v.", lineItems)
+	}
+}
@@ -0,0 +1,90 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/trackit/trackit/config"
+)
+
+// encryptedSecretPrefix prefixes the secret access keys encrypted in the
+// database. Keys stored before they were encrypted do not have it.
+const encryptedSecretPrefix = "aes-gcm:"
+
+// maxSecretAccessKeyLength is the length of the longest secret access key
+// whose encrypted form fits in the database.
+const maxSecretAccessKeyLength = 128
+
+var ErrInvalidEncryptedSecret = errors.New("encrypted secret access key is invalid")
+
+// secretCipher returns the AES-GCM cipher whose key is derived from
+// config.BillRepositorySecret.
+func secretCipher() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(config.BillRepositorySecret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptSecretAccessKey encrypts the secret access key of a bill repository
+// before it is stored in the database.
+func encryptSecretAccessKey(secret string) (string, error) {
+	if secret == "" {
+		return "", nil
+	}
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(secret), nil)
+	return encryptedSecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecretAccessKey decrypts the secret access key of a bill repository
+// read from the database. Keys stored before they were encrypted are returned
+// as is, and are encrypted the next time the bill repository is updated.
+func decryptSecretAccessKey(stored string) (string, error) {
+	if !strings.HasPrefix(stored, encryptedSecretPrefix) {
+		return stored, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, encryptedSecretPrefix))
+	if err != nil {
+		return "", ErrInvalidEncryptedSecret
+	}
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	} else if len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidEncryptedSecret
+	}
+	secret, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrInvalidEncryptedSecret
+	}
+	return string(secret), nil
+}
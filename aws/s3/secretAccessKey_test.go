@@ -0,0 +1,54 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/trackit/trackit/config"
+)
+
+func TestSecretAccessKeyEncryption(t *testing.T) {
+	secret := strings.Repeat("s", maxSecretAccessKeyLength)
+	encrypted, err := encryptSecretAccessKey(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(encrypted, secret[:16]) || len(encrypted) > 255 {
+		t.Errorf("Secret access key should be encrypted and fit in the database, got %s.", encrypted)
+	}
+	if other, _ := encryptSecretAccessKey(secret); other == encrypted {
+		t.Errorf("Encrypting a secret access key twice should use different nonces.")
+	}
+	if decrypted, err := decryptSecretAccessKey(encrypted); err != nil || decrypted != secret {
+		t.Errorf("Secret access key should be decrypted, got %s and %v.", decrypted, err)
+	}
+	previous := config.BillRepositorySecret
+	defer func() { config.BillRepositorySecret = previous }()
+	config.BillRepositorySecret = "other"
+	if _, err := decryptSecretAccessKey(encrypted); err != ErrInvalidEncryptedSecret {
+		t.Errorf("Decrypting with another secret should fail, got %v.", err)
+	}
+}
+
+func TestSecretAccessKeyNotEncrypted(t *testing.T) {
+	if decrypted, err := decryptSecretAccessKey("plaintext"); err != nil || decrypted != "plaintext" {
+		t.Errorf("Secret access keys stored before encryption should be read as is, got %s and %v.", decrypted, err)
+	}
+	if encrypted, err := encryptSecretAccessKey(""); err != nil || encrypted != "" {
+		t.Errorf("Empty secret access keys should stay empty, got %s and %v.", encrypted, err)
+	}
+}
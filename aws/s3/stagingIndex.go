@@ -0,0 +1,424 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/es"
+	"github.com/trackit/trackit/models"
+)
+
+// lineItemsBaseIndexSuffix is the suffix of the index holding the LineItems
+// which were ingested before each billing period got its own index.
+const lineItemsBaseIndexSuffix = "base"
+
+// stagingIndexSettings are the settings of the staging indices. A billing
+// period index only holds a month of LineItems of a BillRepository, so a single
+// shard is enough and keeps the shard count of a user proportional to the
+// number of billing periods.
+const stagingIndexSettings = `{"settings": {"number_of_shards": 1}}`
+
+var ErrFailedBulkRequests = errors.New("failed to index some line items")
+
+// lineItemsAliasLockTimeout is the number of seconds to wait for the lock on
+// the lineitems alias of a user.
+const lineItemsAliasLockTimeout = 600
+
+var ErrLineItemsAliasLocked = errors.New("failed to lock the lineitems alias")
+
+// stagedIngestion is a reportIngestion which ingests each manifest of a
+// BillRepository in its own staging index. Each ingested report key is
+// checkpointed in the database so that an interrupted ingestion resumes from
+// the last completed one. Once all report keys are ingested, the staging index
+// is swapped into the lineitems alias in place of the previous index of the
+// billing period, so that a billing period is never partially visible.
+type stagedIngestion struct {
+	ctx    context.Context
+	bp     *elastic.BulkProcessor
+	aa     aws.AwsAccount
+	br     BillRepository
+	index  string
+	failed int64
+}
+
+// lineItemsAlias returns the name of the alias the LineItems of a user are
+// read from.
+func lineItemsAlias(userId int) string {
+	return es.IndexNameForUserId(userId, IndexPrefixLineItem)
+}
+
+// lineItemsBaseIndex returns the name of the base index of a user.
+func lineItemsBaseIndex(userId int) string {
+	return fmt.Sprintf("%s-%s", lineItemsAlias(userId), lineItemsBaseIndexSuffix)
+}
+
+// stagingIndexName returns the name of the staging index for a version of a
+// manifest. It only depends on the manifest so that an interrupted ingestion
+// resumes in the same index.
+func stagingIndexName(userId, brId int, m manifest) string {
+	return fmt.Sprintf("%s-%d-%s-%d", lineItemsAlias(userId), brId, billingPeriod(m).Format("200601"), m.LastModified.Unix())
+}
+
+// billRepositoryIndexPattern returns the pattern matching all the staging and
+// billing period indices of a BillRepository.
+func billRepositoryIndexPattern(userId, brId int) string {
+	return fmt.Sprintf("%s-%d-*", lineItemsAlias(userId), brId)
+}
+
+// billingPeriodIndexPattern returns the pattern matching all the staging
+// indices of a billing period of a BillRepository.
+func billingPeriodIndexPattern(userId, brId int, period time.Time) string {
+	return fmt.Sprintf("%s-%d-%s-*", lineItemsAlias(userId), brId, period.Format("200601"))
+}
+
+// lockLineItemsAlias takes a database lock on the lineitems alias of a user,
+// since the filter on the base index depends on all the billing periods of the
+// user, which may be ingested by several workers. The returned function
+// releases the lock.
+func lockLineItemsAlias(ctx context.Context, userId int) (func(), error) {
+	name := fmt.Sprintf("trackit.%s", lineItemsAlias(userId))
+	conn, err := db.Db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var acquired sql.NullInt64
+	if err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, lineItemsAliasLockTimeout).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, err
+	} else if acquired.Int64 != 1 {
+		conn.Close()
+		return nil, ErrLineItemsAliasLocked
+	}
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name); err != nil {
+			jsonlog.LoggerFromContextOrDefault(ctx).Warning("Failed to release the lineitems alias lock.", map[string]interface{}{"lock": name, "error": err.Error()})
+		}
+		conn.Close()
+	}, nil
+}
+
+// billingPeriod returns the start of the billing period of a manifest.
+func billingPeriod(m manifest) time.Time {
+	return time.Time(m.BillingPeriod.Start).UTC()
+}
+
+func (si *stagedIngestion) startManifest(m manifest) (map[string]bool, bool, error) {
+	l := jsonlog.LoggerFromContextOrDefault(si.ctx)
+	si.index = stagingIndexName(si.aa.UserId, si.br.Id, m)
+	current := ""
+	if pi, err := models.AwsBillPeriodIndexByAwsBillRepositoryIDBillingPeriod(db.Db, si.br.Id, billingPeriod(m)); err == nil && pi.EsIndex == si.index {
+		return nil, true, nil
+	} else if err == nil {
+		current = pi.EsIndex
+	} else if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+	if err := deleteStagingIndices(si.ctx, si.br.Id, billingPeriodIndexPattern(si.aa.UserId, si.br.Id, billingPeriod(m)), si.index, current); err != nil {
+		l.Warning("Failed to delete stale staging indices.", map[string]interface{}{"index": si.index, "error": err.Error()})
+	}
+	if exists, err := es.Client.IndexExists(si.index).Do(si.ctx); err != nil {
+		return nil, false, err
+	} else if !exists {
+		if _, err := es.Client.CreateIndex(si.index).BodyString(stagingIndexSettings).Do(si.ctx); err != nil {
+			return nil, false, err
+		}
+	}
+	checkpoints, err := models.AwsBillCheckpointsByAwsBillRepositoryIDEsIndex(db.Db, si.br.Id, si.index)
+	if err != nil {
+		return nil, false, err
+	}
+	done := make(map[string]bool, len(checkpoints))
+	for _, c := range checkpoints {
+		done[c.ReportKey] = true
+	}
+	if len(done) > 0 {
+		l.Info("Resuming ingestion from checkpoints.", map[string]interface{}{
+			"index":      si.index,
+			"ingested":   len(done),
+			"reportKeys": len(m.ReportKeys),
+		})
+	}
+	return done, false, nil
+}
+
+func (si *stagedIngestion) onLineItem(m manifest, li LineItem) {
+	if li.LineItemType == "Tax" {
+		li.AvailabilityZone = "taxes"
+		li.Region = "taxes"
+	}
+	li.BillRepositoryId = si.br.Id
+	li = extractTags(li)
+	li = computeCosts(li)
+	rq := elastic.NewBulkIndexRequest()
+	rq = rq.Index(si.index)
+	rq = rq.OpType(opTypeIndex)
+	rq = rq.Type(TypeLineItem)
+	rq = rq.Id(li.EsId())
+	rq = rq.Doc(li)
+	si.bp.Add(rq)
+}
+
+// endReportKey flushes the LineItems of the report key and checkpoints it if
+// they were all indexed.
+func (si *stagedIngestion) endReportKey(m manifest, s string, count int) error {
+	if err := si.bp.Flush(); err != nil {
+		return err
+	} else if failed := si.bp.Stats().Failed; failed > si.failed {
+		si.failed = failed
+		return ErrFailedBulkRequests
+	}
+	checkpoint := models.AwsBillCheckpoint{
+		AwsBillRepositoryID: si.br.Id,
+		EsIndex:             si.index,
+		ReportKey:           s,
+		LineItems:           count,
+	}
+	return checkpoint.Insert(db.Db)
+}
+
+// endManifest swaps the staging index into the lineitems alias.
+func (si *stagedIngestion) endManifest(m manifest) error {
+	if _, err := es.Client.Refresh(si.index).Do(si.ctx); err != nil {
+		return err
+	}
+	return swapBillingPeriodIndex(si.ctx, si.aa.UserId, si.br.Id, billingPeriod(m), si.index)
+}
+
+// swapBillingPeriodIndex atomically replaces the index of a billing period of
+// a BillRepository in the lineitems alias, and hides the billing period from
+// the base index. The previous index is then deleted.
+func swapBillingPeriodIndex(ctx context.Context, userId, brId int, period time.Time, index string) error {
+	unlock, err := lockLineItemsAlias(ctx, userId)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	l := jsonlog.LoggerFromContextOrDefault(ctx)
+	alias := lineItemsAlias(userId)
+	base := lineItemsBaseIndex(userId)
+	pi, err := models.AwsBillPeriodIndexByAwsBillRepositoryIDBillingPeriod(db.Db, brId, period)
+	if err == sql.ErrNoRows {
+		pi = &models.AwsBillPeriodIndex{AwsBillRepositoryID: brId, BillingPeriod: period}
+	} else if err != nil {
+		return err
+	}
+	previous := pi.EsIndex
+	pi.EsIndex = index
+	periods, err := models.AwsBillPeriodIndexesByUserID(db.Db, userId)
+	if err != nil {
+		return err
+	}
+	actions := []elastic.AliasAction{elastic.NewAliasAddAction(alias).Index(index)}
+	if previous != "" && previous != index {
+		actions = append(actions, elastic.NewAliasRemoveAction(alias).Index(previous))
+	}
+	if exists, err := es.Client.IndexExists(base).Do(ctx); err != nil {
+		return err
+	} else if exists {
+		filter := baseIndexFilter(append(periods, pi))
+		actions = append(actions, elastic.NewAliasAddAction(alias).Index(base).Filter(filter))
+	}
+	if _, err := es.Client.Alias().Action(actions...).Do(ctx); err != nil {
+		return err
+	} else if err := pi.Save(db.Db); err != nil {
+		return err
+	}
+	l.Info("Swapped billing period index.", map[string]interface{}{
+		"billRepositoryId": brId,
+		"billingPeriod":    period,
+		"index":            index,
+		"previousIndex":    previous,
+	})
+	if err := deleteStagingIndices(ctx, brId, billingPeriodIndexPattern(userId, brId, period), index); err != nil {
+		l.Warning("Failed to delete previous billing period indices.", map[string]interface{}{"index": previous, "error": err.Error()})
+	}
+	return models.DeleteAwsBillCheckpointsByAwsBillRepositoryIDEsIndex(db.Db, brId, index)
+}
+
+// staleIndices returns the indices which are not kept.
+func staleIndices(indices []string, keep ...string) []string {
+	stale := make([]string, 0, len(indices))
+	for _, index := range indices {
+		kept := false
+		for _, k := range keep {
+			kept = kept || index == k
+		}
+		if !kept {
+			stale = append(stale, index)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// deleteStagingIndices deletes the indices of a BillRepository matching
+// pattern, except the kept ones, along with their checkpoints. They are left
+// by interrupted ingestions of manifests which were since superseded.
+func deleteStagingIndices(ctx context.Context, brId int, pattern string, keep ...string) error {
+	res, err := es.Client.IndexGet(pattern).Do(ctx)
+	if elastic.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	indices := make([]string, 0, len(res))
+	for index := range res {
+		indices = append(indices, index)
+	}
+	stale := staleIndices(indices, keep...)
+	if len(stale) == 0 {
+		return nil
+	}
+	jsonlog.LoggerFromContextOrDefault(ctx).Info("Deleting stale line items indices.", map[string]interface{}{
+		"billRepositoryId": brId,
+		"indices":          stale,
+	})
+	if _, err := es.Client.DeleteIndex(stale...).Do(ctx); err != nil {
+		return err
+	}
+	for _, index := range stale {
+		if err := models.DeleteAwsBillCheckpointsByAwsBillRepositoryIDEsIndex(db.Db, brId, index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CleanBillRepositoryIndices deletes the billing period and staging indices of
+// a BillRepository and its checkpoints, and removes its billing periods from
+// the filter of the lineitems alias on the base index. It is called when the
+// BillRepository is deleted or changed, since its LineItems are then removed.
+func CleanBillRepositoryIndices(ctx context.Context, userId, brId int) error {
+	unlock, err := lockLineItemsAlias(ctx, userId)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := models.DeleteAwsBillPeriodIndexesByAwsBillRepositoryID(db.Db, brId); err != nil {
+		return err
+	} else if err := models.DeleteAwsBillCheckpointsByAwsBillRepositoryID(db.Db, brId); err != nil {
+		return err
+	} else if err := deleteStagingIndices(ctx, brId, billRepositoryIndexPattern(userId, brId)); err != nil {
+		return err
+	}
+	base := lineItemsBaseIndex(userId)
+	if exists, err := es.Client.IndexExists(base).Do(ctx); err != nil || !exists {
+		return err
+	}
+	periods, err := models.AwsBillPeriodIndexesByUserID(db.Db, userId)
+	if err != nil {
+		return err
+	}
+	_, err = es.Client.Alias().Action(
+		elastic.NewAliasAddAction(lineItemsAlias(userId)).Index(base).Filter(baseIndexFilter(periods)),
+	).Do(ctx)
+	return err
+}
+
+// periodRange is a range of consecutive billing periods of a BillRepository.
+type periodRange struct {
+	billRepositoryId int
+	start            time.Time
+	end              time.Time
+}
+
+// getPeriodRanges merges the consecutive billing periods of each
+// BillRepository into ranges. A BillRepository may appear more than once in
+// periods, in which case the last one wins.
+func getPeriodRanges(periods []*models.AwsBillPeriodIndex) []periodRange {
+	unique := make(map[string]*models.AwsBillPeriodIndex, len(periods))
+	for _, p := range periods {
+		unique[fmt.Sprintf("%d-%s", p.AwsBillRepositoryID, p.BillingPeriod.UTC().Format("200601"))] = p
+	}
+	sorted := make([]*models.AwsBillPeriodIndex, 0, len(unique))
+	for _, p := range unique {
+		sorted = append(sorted, p)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].AwsBillRepositoryID != sorted[j].AwsBillRepositoryID {
+			return sorted[i].AwsBillRepositoryID < sorted[j].AwsBillRepositoryID
+		}
+		return sorted[i].BillingPeriod.Before(sorted[j].BillingPeriod)
+	})
+	var ranges []periodRange
+	for _, p := range sorted {
+		start := p.BillingPeriod.UTC()
+		end := start.AddDate(0, 1, 0)
+		if last := len(ranges) - 1; last >= 0 && ranges[last].billRepositoryId == p.AwsBillRepositoryID && ranges[last].end.Equal(start) {
+			ranges[last].end = end
+		} else {
+			ranges = append(ranges, periodRange{p.AwsBillRepositoryID, start, end})
+		}
+	}
+	return ranges
+}
+
+// baseIndexFilter returns the filter of the lineitems alias on the base index,
+// which hides the billing periods which have their own index.
+func baseIndexFilter(periods []*models.AwsBillPeriodIndex) elastic.Query {
+	query := elastic.NewBoolQuery()
+	for _, r := range getPeriodRanges(periods) {
+		query = query.MustNot(elastic.NewBoolQuery().Filter(
+			elastic.NewTermQuery("billRepositoryId", r.billRepositoryId),
+			elastic.NewRangeQuery("usageStartDate").Gte(r.start).Lt(r.end),
+		))
+	}
+	return query
+}
+
+// migrateLineItemsIndex turns the lineitems index of a user, created before
+// the billing periods were ingested in their own index, into the base index
+// of the lineitems alias. The alias replaces the index atomically.
+func migrateLineItemsIndex(ctx context.Context, userId int) error {
+	unlock, err := lockLineItemsAlias(ctx, userId)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	alias := lineItemsAlias(userId)
+	base := lineItemsBaseIndex(userId)
+	indices, err := es.Client.IndexGet(alias).Do(ctx)
+	if elastic.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	} else if _, ok := indices[alias]; !ok {
+		return nil
+	}
+	jsonlog.LoggerFromContextOrDefault(ctx).Info("Migrating line items index to an alias.", map[string]interface{}{
+		"index":     alias,
+		"baseIndex": base,
+	})
+	_, err = es.Client.Reindex().SourceIndex(alias).DestinationIndex(base).WaitForCompletion(true).Refresh("true").Do(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = es.Client.Alias().Action(
+		elastic.NewAliasAddAction(alias).Index(base),
+		elastic.NewAliasRemoveIndexAction(alias),
+	).Do(ctx)
+	return err
+}
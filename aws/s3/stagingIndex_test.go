@@ -0,0 +1,144 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/trackit/trackit/models"
+)
+
+// fakeIngestion is a reportIngestion which records the calls it receives.
+type fakeIngestion struct {
+	done      map[string]bool
+	ingested  bool
+	lineItems int
+	ended     int
+}
+
+func (fi *fakeIngestion) startManifest(m manifest) (map[string]bool, bool, error) {
+	return fi.done, fi.ingested, nil
+}
+
+func (fi *fakeIngestion) onLineItem(m manifest, li LineItem) {
+	fi.lineItems++
+}
+
+func (fi *fakeIngestion) endReportKey(m manifest, s string, count int) error {
+	return nil
+}
+
+func (fi *fakeIngestion) endManifest(m manifest) error {
+	fi.ended++
+	return nil
+}
+
+func month(year int, month time.Month) time.Time {
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestImportManifestResumes(t *testing.T) {
+	m := manifest{ReportKeys: []string{"a.csv.gz", "b.csv.gz"}}
+	fi := fakeIngestion{done: map[string]bool{"a.csv.gz": true, "b.csv.gz": true}}
+	if err := importManifest(context.Background(), nil, m, &fi); err != nil {
+		t.Fatalf("Import should succeed, failed with %s.", err.Error())
+	}
+	if fi.lineItems != 0 || fi.ended != 1 {
+		t.Errorf("Checkpointed keys should be skipped and the manifest ended, got %d line items and %d ends.", fi.lineItems, fi.ended)
+	}
+	fi = fakeIngestion{ingested: true}
+	if err := importManifest(context.Background(), nil, m, &fi); err != nil {
+		t.Fatalf("Import should succeed, failed with %s.", err.Error())
+	}
+	if fi.ended != 0 {
+		t.Errorf("An ingested manifest should not be ended again.")
+	}
+}
+
+func TestStagingIndexName(t *testing.T) {
+	var m manifest
+	m.BillingPeriod.Start = billTime(month(2024, time.March))
+	m.LastModified = time.Unix(1710000000, 0)
+	if name := stagingIndexName(42, 7, m); name != "000042-lineitems-7-202403-1710000000" {
+		t.Errorf("Staging index name should be 000042-lineitems-7-202403-1710000000, is %s.", name)
+	}
+	if name := lineItemsBaseIndex(42); name != "000042-lineitems-base" {
+		t.Errorf("Base index name should be 000042-lineitems-base, is %s.", name)
+	}
+}
+
+func TestIndexPatterns(t *testing.T) {
+	if pattern := billRepositoryIndexPattern(42, 7); pattern != "000042-lineitems-7-*" {
+		t.Errorf("Bill repository index pattern should be 000042-lineitems-7-*, is %s.", pattern)
+	}
+	if pattern := billingPeriodIndexPattern(42, 7, month(2024, time.March)); pattern != "000042-lineitems-7-202403-*" {
+		t.Errorf("Billing period index pattern should be 000042-lineitems-7-202403-*, is %s.", pattern)
+	}
+}
+
+func TestStaleIndices(t *testing.T) {
+	indices := []string{
+		"000042-lineitems-7-202403-1710000300",
+		"000042-lineitems-7-202403-1710000000",
+		"000042-lineitems-7-202403-1710000200",
+	}
+	stale := staleIndices(indices, "000042-lineitems-7-202403-1710000300", "")
+	if len(stale) != 2 || stale[0] != "000042-lineitems-7-202403-1710000000" || stale[1] != "000042-lineitems-7-202403-1710000200" {
+		t.Errorf("All the indices but the kept one should be stale, got %v.", stale)
+	}
+	if stale := staleIndices(indices, indices...); len(stale) != 0 {
+		t.Errorf("Kept indices should not be stale, got %v.", stale)
+	}
+}
+
+func TestGetPeriodRanges(t *testing.T) {
+	ranges := getPeriodRanges([]*models.AwsBillPeriodIndex{
+		{AwsBillRepositoryID: 2, BillingPeriod: month(2024, time.January)},
+		{AwsBillRepositoryID: 1, BillingPeriod: month(2024, time.February)},
+		{AwsBillRepositoryID: 1, BillingPeriod: month(2024, time.January)},
+		{AwsBillRepositoryID: 1, BillingPeriod: month(2024, time.April)},
+		{AwsBillRepositoryID: 1, BillingPeriod: month(2024, time.April), EsIndex: "new"},
+	})
+	expected := []periodRange{
+		{1, month(2024, time.January), month(2024, time.March)},
+		{1, month(2024, time.April), month(2024, time.May)},
+		{2, month(2024, time.January), month(2024, time.February)},
+	}
+	if len(ranges) != len(expected) {
+		t.Fatalf("Should get %d ranges, got %#v.", len(expected), ranges)
+	}
+	for i := range expected {
+		if ranges[i].billRepositoryId != expected[i].billRepositoryId || !ranges[i].start.Equal(expected[i].start) || !ranges[i].end.Equal(expected[i].end) {
+			t.Errorf("Range %d should be %#v, is %#v.", i, expected[i], ranges[i])
+		}
+	}
+}
+
+func TestBaseIndexFilter(t *testing.T) {
+	source, err := baseIndexFilter([]*models.AwsBillPeriodIndex{
+		{AwsBillRepositoryID: 1, BillingPeriod: month(2024, time.January)},
+	}).Source()
+	if err != nil {
+		t.Fatalf("Filter should be valid, failed with %s.", err.Error())
+	}
+	raw, _ := json.Marshal(source)
+	expected := `{"bool":{"must_not":{"bool":{"filter":[{"term":{"billRepositoryId":1}},{"range":{"usageStartDate":{"from":"2024-01-01T00:00:00Z","include_lower":true,"include_upper":false,"to":"2024-02-01T00:00:00Z"}}}]}}}}`
+	if string(raw) != expected {
+		t.Errorf("Filter should be %s, is %s.", expected, raw)
+	}
+}
@@ -70,6 +70,9 @@ func GetBillRepositoryWithPendingForAwsAccount(tx *sql.Tx, awsAccountId int) ([]
 		  aws_bill_repository.error                  AS error,
 		  aws_bill_repository.last_imported_manifest AS last_imported_manifest,
 		  aws_bill_repository.next_update            AS next_update,
+		  aws_bill_repository.backend                AS backend,
+		  aws_bill_repository.endpoint               AS endpoint,
+		  aws_bill_repository.access_key_id          AS access_key_id,
 		  (last_pending.id IS NOT NULL)              AS next_pending
 		FROM aws_bill_repository
 		LEFT OUTER JOIN (
@@ -98,6 +101,9 @@ func GetBillRepositoryWithPendingForAwsAccount(tx *sql.Tx, awsAccountId int) ([]
 			&res[i].Error,
 			&res[i].LastImportedManifest,
 			&res[i].NextUpdate,
+			&res[i].Backend,
+			&res[i].Endpoint,
+			&res[i].AccessKeyId,
 			&res[i].NextPending,
 		)
 		if err != nil {
@@ -0,0 +1,106 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package savingsplans calls the AWS Savings Plans API. The version of the AWS
+// SDK the server is built with predates Savings Plans, so the requests are
+// built and signed here.
+package savingsplans
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+const (
+	// serviceName is the name the requests are signed for.
+	serviceName = "savingsplans"
+	// signingRegion is the region of the global endpoint of the API.
+	signingRegion = "us-east-1"
+	// requestTimeout is the maximum duration of a request to the API.
+	requestTimeout = 30 * time.Second
+)
+
+// Endpoint is the URL of the Savings Plans API.
+var Endpoint = "https://savingsplans.amazonaws.com"
+
+// Client calls the Savings Plans API with a set of credentials.
+type Client struct {
+	signer     *v4.Signer
+	httpClient *http.Client
+}
+
+// New returns a Client signing its requests with creds.
+func New(creds *credentials.Credentials) *Client {
+	return &Client{
+		signer:     v4.NewSigner(creds),
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// errorResponse is the body of the responses of the API to failed requests.
+type errorResponse struct {
+	Message string `json:"message"`
+}
+
+// call sends the input of an operation and decodes its output.
+func (c *Client) call(ctx context.Context, operation string, input, output interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, Endpoint+"/"+operation, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if _, err = c.signer.Sign(request, bytes.NewReader(body), serviceName, signingRegion, time.Now()); err != nil {
+		return err
+	}
+	response, err := c.httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return decodeError(response, responseBody)
+	}
+	return json.Unmarshal(responseBody, output)
+}
+
+// decodeError returns the error described by the response of the API to a
+// failed request.
+func decodeError(response *http.Response, body []byte) error {
+	code := strings.SplitN(response.Header.Get("X-Amzn-Errortype"), ":", 2)[0]
+	if code == "" {
+		code = http.StatusText(response.StatusCode)
+	}
+	var errResponse errorResponse
+	if err := json.Unmarshal(body, &errResponse); err != nil || errResponse.Message == "" {
+		errResponse.Message = string(body)
+	}
+	return awserr.NewRequestFailure(awserr.New(code, errResponse.Message, nil), response.StatusCode, response.Header.Get("X-Amzn-Requestid"))
+}
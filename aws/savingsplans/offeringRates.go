@@ -0,0 +1,92 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package savingsplans
+
+import (
+	"context"
+)
+
+// describeSavingsPlansOfferingRatesMaxResults is the maximum number of rates
+// returned by a DescribeSavingsPlansOfferingRates request.
+const describeSavingsPlansOfferingRatesMaxResults = 1000
+
+type (
+	// DescribeSavingsPlansOfferingRatesInput is the input of
+	// DescribeSavingsPlansOfferingRates.
+	DescribeSavingsPlansOfferingRatesInput struct {
+		SavingsPlanTypes          []string `json:"savingsPlanTypes,omitempty"`
+		SavingsPlanPaymentOptions []string `json:"savingsPlanPaymentOptions,omitempty"`
+		Products                  []string `json:"products,omitempty"`
+		UsageTypes                []string `json:"usageTypes,omitempty"`
+		Operations                []string `json:"operations,omitempty"`
+		NextToken                 string   `json:"nextToken,omitempty"`
+		MaxResults                int      `json:"maxResults,omitempty"`
+	}
+
+	// DescribeSavingsPlansOfferingRatesOutput is the output of
+	// DescribeSavingsPlansOfferingRates.
+	DescribeSavingsPlansOfferingRatesOutput struct {
+		SearchResults []SavingsPlanOfferingRate `json:"searchResults"`
+		NextToken     string                    `json:"nextToken"`
+	}
+
+	// SavingsPlanOfferingRate is the price of a unit of usage with a Savings
+	// Plan offering. The rate is a decimal string, as returned by the API.
+	SavingsPlanOfferingRate struct {
+		SavingsPlanOffering SavingsPlanOffering `json:"savingsPlanOffering"`
+		Rate                string              `json:"rate"`
+		Unit                string              `json:"unit"`
+		ProductType         string              `json:"productType"`
+		ServiceCode         string              `json:"serviceCode"`
+		UsageType           string              `json:"usageType"`
+		Operation           string              `json:"operation"`
+	}
+
+	// SavingsPlanOffering describes the term and the payment option of a
+	// Savings Plan which can be bought.
+	SavingsPlanOffering struct {
+		OfferingId      string `json:"offeringId"`
+		PaymentOption   string `json:"paymentOption"`
+		PlanType        string `json:"planType"`
+		DurationSeconds int64  `json:"durationSeconds"`
+		Currency        string `json:"currency"`
+		PlanDescription string `json:"planDescription"`
+	}
+)
+
+// DescribeSavingsPlansOfferingRates returns a page of the rates of the
+// Savings Plans offerings.
+func (c *Client) DescribeSavingsPlansOfferingRates(ctx context.Context, input DescribeSavingsPlansOfferingRatesInput) (output DescribeSavingsPlansOfferingRatesOutput, err error) {
+	err = c.call(ctx, "DescribeSavingsPlansOfferingRates", input, &output)
+	return
+}
+
+// DescribeAllSavingsPlansOfferingRates returns all the rates of the Savings
+// Plans offerings matching input.
+func (c *Client) DescribeAllSavingsPlansOfferingRates(ctx context.Context, input DescribeSavingsPlansOfferingRatesInput) ([]SavingsPlanOfferingRate, error) {
+	var rates []SavingsPlanOfferingRate
+	input.MaxResults = describeSavingsPlansOfferingRatesMaxResults
+	for {
+		output, err := c.DescribeSavingsPlansOfferingRates(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, output.SearchResults...)
+		if output.NextToken == "" {
+			return rates, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
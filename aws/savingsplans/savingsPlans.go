@@ -0,0 +1,87 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package savingsplans
+
+import (
+	"context"
+)
+
+// describeSavingsPlansMaxResults is the maximum number of Savings Plans
+// returned by a DescribeSavingsPlans request.
+const describeSavingsPlansMaxResults = 1000
+
+type (
+	// DescribeSavingsPlansInput is the input of DescribeSavingsPlans.
+	DescribeSavingsPlansInput struct {
+		States     []string `json:"states,omitempty"`
+		NextToken  string   `json:"nextToken,omitempty"`
+		MaxResults int      `json:"maxResults,omitempty"`
+	}
+
+	// DescribeSavingsPlansOutput is the output of DescribeSavingsPlans.
+	DescribeSavingsPlansOutput struct {
+		SavingsPlans []SavingsPlan `json:"savingsPlans"`
+		NextToken    string        `json:"nextToken"`
+	}
+
+	// SavingsPlan describes a Savings Plan. The amounts are decimal strings
+	// and the dates are ISO 8601 strings, as returned by the API.
+	SavingsPlan struct {
+		SavingsPlanId          string   `json:"savingsPlanId"`
+		SavingsPlanArn         string   `json:"savingsPlanArn"`
+		Description            string   `json:"description"`
+		Start                  string   `json:"start"`
+		End                    string   `json:"end"`
+		State                  string   `json:"state"`
+		Region                 string   `json:"region"`
+		Ec2InstanceFamily      string   `json:"ec2InstanceFamily"`
+		SavingsPlanType        string   `json:"savingsPlanType"`
+		PaymentOption          string   `json:"paymentOption"`
+		ProductTypes           []string `json:"productTypes"`
+		Currency               string   `json:"currency"`
+		Commitment             string   `json:"commitment"`
+		UpfrontPaymentAmount   string   `json:"upfrontPaymentAmount"`
+		RecurringPaymentAmount string   `json:"recurringPaymentAmount"`
+		TermDurationInSeconds  int64    `json:"termDurationInSeconds"`
+		OfferingId             string   `json:"offeringId"`
+	}
+)
+
+// DescribeSavingsPlans returns a page of the Savings Plans of the account.
+func (c *Client) DescribeSavingsPlans(ctx context.Context, input DescribeSavingsPlansInput) (output DescribeSavingsPlansOutput, err error) {
+	err = c.call(ctx, "DescribeSavingsPlans", input, &output)
+	return
+}
+
+// DescribeAllSavingsPlans returns all the Savings Plans of the account in one
+// of states, or in any state if states is empty.
+func (c *Client) DescribeAllSavingsPlans(ctx context.Context, states ...string) ([]SavingsPlan, error) {
+	var savingsPlans []SavingsPlan
+	input := DescribeSavingsPlansInput{
+		States:     states,
+		MaxResults: describeSavingsPlansMaxResults,
+	}
+	for {
+		output, err := c.DescribeSavingsPlans(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		savingsPlans = append(savingsPlans, output.SavingsPlans...)
+		if output.NextToken == "" {
+			return savingsPlans, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
@@ -0,0 +1,112 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package savingsplans
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// newTestClient returns a Client calling handler instead of the API.
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*Client, func()) {
+	server := httptest.NewServer(handler)
+	previous := Endpoint
+	Endpoint = server.URL
+	client := New(credentials.NewStaticCredentials("AKID", "SECRET", ""))
+	return client, func() {
+		Endpoint = previous
+		server.Close()
+	}
+}
+
+func TestDescribeAllSavingsPlans(t *testing.T) {
+	var requests []DescribeSavingsPlansInput
+	client, closeClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/DescribeSavingsPlans" || !strings.Contains(r.Header.Get("Authorization"), "/us-east-1/savingsplans/aws4_request") {
+			t.Errorf("Unexpected request to %s with authorization %s.", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var input DescribeSavingsPlansInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Fatal(err)
+		}
+		requests = append(requests, input)
+		if input.NextToken == "" {
+			w.Write([]byte(`{"savingsPlans": [{"savingsPlanId": "sp-1", "commitment": "10.0", "termDurationInSeconds": 31536000}], "nextToken": "page-2"}`))
+		} else {
+			w.Write([]byte(`{"savingsPlans": [{"savingsPlanId": "sp-2", "state": "active"}]}`))
+		}
+	})
+	defer closeClient()
+	savingsPlans, err := client.DescribeAllSavingsPlans(context.Background(), "active")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(savingsPlans) != 2 || savingsPlans[0].Commitment != "10.0" || savingsPlans[0].TermDurationInSeconds != 31536000 || savingsPlans[1].SavingsPlanId != "sp-2" {
+		t.Errorf("Unexpected Savings Plans %+v", savingsPlans)
+	}
+	if len(requests) != 2 || requests[1].NextToken != "page-2" || len(requests[0].States) != 1 || requests[0].States[0] != "active" {
+		t.Errorf("Unexpected requests %+v", requests)
+	}
+}
+
+func TestDescribeSavingsPlansError(t *testing.T) {
+	client, closeClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amzn-Errortype", "AccessDeniedException:http://internal.amazon.com/coral/com.amazon.coral.service/")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "not authorized to perform savingsplans:DescribeSavingsPlans"}`))
+	})
+	defer closeClient()
+	_, err := client.DescribeAllSavingsPlans(context.Background())
+	if awsErr, ok := err.(awserr.RequestFailure); !ok || awsErr.Code() != "AccessDeniedException" || awsErr.StatusCode() != http.StatusForbidden {
+		t.Errorf("Expected an access denied error, got %v.", err)
+	}
+}
+
+func TestDescribeAllSavingsPlansOfferingRates(t *testing.T) {
+	pages := 0
+	client, closeClient := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var input DescribeSavingsPlansOfferingRatesInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Fatal(err)
+		}
+		if r.URL.Path != "/DescribeSavingsPlansOfferingRates" || len(input.UsageTypes) != 1 || input.MaxResults != describeSavingsPlansOfferingRatesMaxResults {
+			t.Errorf("Unexpected request to %s with %+v.", r.URL.Path, input)
+		}
+		pages++
+		if input.NextToken == "" {
+			w.Write([]byte(`{"searchResults": [{"rate": "0.0655", "usageType": "BoxUsage:m5.large", "operation": "RunInstances", "savingsPlanOffering": {"paymentOption": "No Upfront", "planType": "Compute", "durationSeconds": 31536000}}], "nextToken": "page-2"}`))
+		} else {
+			w.Write([]byte(`{"searchResults": [{"rate": "0.0451", "usageType": "BoxUsage:m5.large", "operation": "RunInstances", "savingsPlanOffering": {"paymentOption": "All Upfront", "planType": "Compute", "durationSeconds": 94608000}}]}`))
+		}
+	})
+	defer closeClient()
+	rates, err := client.DescribeAllSavingsPlansOfferingRates(context.Background(), DescribeSavingsPlansOfferingRatesInput{
+		SavingsPlanTypes: []string{"Compute"},
+		UsageTypes:       []string{"BoxUsage:m5.large"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages != 2 || len(rates) != 2 || rates[0].Rate != "0.0655" || rates[1].SavingsPlanOffering.DurationSeconds != 94608000 {
+		t.Errorf("Unexpected rates %+v", rates)
+	}
+}
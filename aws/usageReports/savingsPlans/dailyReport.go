@@ -0,0 +1,359 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package savingsPlans
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/trackit/jsonlog"
+
+	taws "github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/aws/savingsplans"
+	"github.com/trackit/trackit/aws/usageReports"
+	"github.com/trackit/trackit/es"
+)
+
+// secondsPerYear is the duration of a year in the terms of the Savings Plans.
+const secondsPerYear = 365 * 24 * 60 * 60
+
+type (
+	// ResponseSavingsPlans allows to parse the ES response on the lineitems
+	ResponseSavingsPlans struct {
+		Plans struct {
+			Arns struct {
+				Buckets []struct {
+					Arn   string `json:"key"`
+					Hours struct {
+						Buckets []struct {
+							Hour            float64 `json:"key"`
+							TotalCommitment esValue `json:"totalCommitment"`
+							UsedCommitment  esValue `json:"usedCommitment"`
+						} `json:"buckets"`
+					} `json:"hours"`
+					Details esTopHits `json:"details"`
+				} `json:"buckets"`
+			} `json:"arns"`
+		} `json:"plans"`
+		Coverage struct {
+			Products struct {
+				Buckets []struct {
+					Product string `json:"key"`
+					Hours   struct {
+						Buckets []struct {
+							Hour  float64 `json:"key"`
+							Types struct {
+								Buckets []struct {
+									Type string  `json:"key"`
+									Cost esValue `json:"cost"`
+								} `json:"buckets"`
+							} `json:"types"`
+						} `json:"buckets"`
+					} `json:"hours"`
+				} `json:"buckets"`
+			} `json:"products"`
+		} `json:"coverage"`
+	}
+
+	esValue struct {
+		Value float64 `json:"value"`
+	}
+
+	esTopHits struct {
+		Hits struct {
+			Hits []struct {
+				Source savingsPlanDetails `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+
+	// savingsPlanDetails are the savingsPlan/* columns of a lineitem
+	savingsPlanDetails struct {
+		OfferingType  string `json:"savingsPlanOfferingType"`
+		PurchaseTerm  string `json:"savingsPlanPurchaseTerm"`
+		PaymentOption string `json:"savingsPlanPaymentOption"`
+		Region        string `json:"savingsPlanRegion"`
+		StartTime     string `json:"savingsPlanStartTime"`
+		EndTime       string `json:"savingsPlanEndTime"`
+	}
+)
+
+// percentage returns the percentage represented by part in total, or 0 if
+// total is zero.
+func percentage(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return part / total * 100
+}
+
+// parseMillis converts an ES date aggregation value to a time.Time.
+func parseMillis(millis float64) time.Time {
+	return time.Unix(0, int64(millis)*int64(time.Millisecond)).UTC()
+}
+
+// parseSavingsPlanTime parses a savingsPlan/StartTime or savingsPlan/EndTime
+// column, or a date returned by the Savings Plans API. Malformed dates are
+// considered to be zero.
+func parseSavingsPlanTime(s string) time.Time {
+	if date, err := time.Parse(time.RFC3339, s); err == nil {
+		return date.UTC()
+	}
+	return time.Time{}
+}
+
+// parseAmount parses an amount returned by the Savings Plans API. Malformed
+// amounts are considered to be zero.
+func parseAmount(s string) float64 {
+	if amount, err := strconv.ParseFloat(s, 64); err == nil {
+		return amount
+	}
+	return 0
+}
+
+// purchaseTerm returns the term of a Savings Plan the way it is written in the
+// savingsPlan/PurchaseTerm column, e.g. "1yr".
+func purchaseTerm(termDurationInSeconds int64) string {
+	if termDurationInSeconds <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%dyr", int64(math.Floor(float64(termDurationInSeconds)/secondsPerYear+0.5)))
+}
+
+// savingsPlanFromInventory builds a SavingsPlan from its description by the
+// Savings Plans API. The hourly commitment is the one of the plan, and not
+// the one computed from the billing data.
+func savingsPlanFromInventory(description savingsplans.SavingsPlan) SavingsPlan {
+	return SavingsPlan{
+		Id:                     description.SavingsPlanId,
+		Arn:                    description.SavingsPlanArn,
+		State:                  description.State,
+		OfferingType:           description.SavingsPlanType + "SavingsPlans",
+		Ec2InstanceFamily:      description.Ec2InstanceFamily,
+		PurchaseTerm:           purchaseTerm(description.TermDurationInSeconds),
+		PaymentOption:          description.PaymentOption,
+		Region:                 description.Region,
+		Start:                  parseSavingsPlanTime(description.Start),
+		End:                    parseSavingsPlanTime(description.End),
+		UpfrontPaymentAmount:   parseAmount(description.UpfrontPaymentAmount),
+		RecurringPaymentAmount: parseAmount(description.RecurringPaymentAmount),
+		HourlyCommitment:       parseAmount(description.Commitment),
+	}
+}
+
+// getInventory returns the Savings Plans of an inventory which were active
+// at some point between begin and end.
+func getInventory(inventory []savingsplans.SavingsPlan, begin, end time.Time) []SavingsPlan {
+	savingsPlans := make([]SavingsPlan, 0, len(inventory))
+	for _, description := range inventory {
+		savingsPlan := savingsPlanFromInventory(description)
+		if savingsPlan.Start.Before(end) && savingsPlan.End.After(begin) {
+			savingsPlans = append(savingsPlans, savingsPlan)
+		}
+	}
+	return savingsPlans
+}
+
+// addHourlyUtilization fills the utilization of a Savings Plan from the hours
+// it was billed for. An hour is underutilized if some of its commitment was
+// not used.
+func addHourlyUtilization(savingsPlan *SavingsPlan, hours []HourlyUtilization) {
+	savingsPlan.Hourly = hours
+	savingsPlan.Hours = float64(len(hours))
+	for _, hour := range hours {
+		savingsPlan.TotalCommitment += hour.Commitment
+		savingsPlan.UsedCommitment += hour.UsedCommitment
+		if hour.UsedCommitment < hour.Commitment {
+			savingsPlan.UnderutilizedHours++
+		}
+	}
+	savingsPlan.UnusedCommitment = savingsPlan.TotalCommitment - savingsPlan.UsedCommitment
+	savingsPlan.Utilization = percentage(savingsPlan.UsedCommitment, savingsPlan.TotalCommitment)
+	if savingsPlan.HourlyCommitment == 0 && savingsPlan.Hours > 0 {
+		savingsPlan.HourlyCommitment = savingsPlan.TotalCommitment / savingsPlan.Hours
+	}
+}
+
+// getSavingsPlans builds the list of Savings Plans with their commitment
+// utilization from the inventory of the Savings Plans and the ES response.
+// The Savings Plans which are only found in the billing data are described
+// by their savingsPlan/* columns.
+func getSavingsPlans(inventory []SavingsPlan, response ResponseSavingsPlans) []SavingsPlan {
+	savingsPlans := make([]SavingsPlan, len(inventory), len(inventory)+len(response.Plans.Arns.Buckets))
+	copy(savingsPlans, inventory)
+	indexes := make(map[string]int, len(savingsPlans))
+	for i, savingsPlan := range savingsPlans {
+		indexes[savingsPlan.Arn] = i
+	}
+	for _, bucket := range response.Plans.Arns.Buckets {
+		i, ok := indexes[bucket.Arn]
+		if !ok {
+			savingsPlan := SavingsPlan{Arn: bucket.Arn}
+			if len(bucket.Details.Hits.Hits) > 0 {
+				details := bucket.Details.Hits.Hits[0].Source
+				savingsPlan.OfferingType = details.OfferingType
+				savingsPlan.PurchaseTerm = details.PurchaseTerm
+				savingsPlan.PaymentOption = details.PaymentOption
+				savingsPlan.Region = details.Region
+				savingsPlan.Start = parseSavingsPlanTime(details.StartTime)
+				savingsPlan.End = parseSavingsPlanTime(details.EndTime)
+			}
+			i = len(savingsPlans)
+			indexes[bucket.Arn] = i
+			savingsPlans = append(savingsPlans, savingsPlan)
+		}
+		hours := make([]HourlyUtilization, 0, len(bucket.Hours.Buckets))
+		for _, hour := range bucket.Hours.Buckets {
+			hours = append(hours, HourlyUtilization{
+				Hour:           parseMillis(hour.Hour),
+				Commitment:     hour.TotalCommitment.Value,
+				UsedCommitment: hour.UsedCommitment.Value,
+				Utilization:    percentage(hour.UsedCommitment.Value, hour.TotalCommitment.Value),
+			})
+		}
+		addHourlyUtilization(&savingsPlans[i], hours)
+	}
+	sort.Slice(savingsPlans, func(i, j int) bool {
+		return savingsPlans[i].Arn < savingsPlans[j].Arn
+	})
+	return savingsPlans
+}
+
+// addCost adds the cost of eligible usage to the covered or to the on demand
+// cost, depending on its lineitem type.
+func addCost(coveredCost, onDemandCost *float64, lineItemType string, cost float64) {
+	switch lineItemType {
+	case "SavingsPlanCoveredUsage":
+		*coveredCost += cost
+	case "Usage":
+		*onDemandCost += cost
+	}
+}
+
+// getCoverage builds the coverage of the eligible usage from the hourly costs
+// of the ES response. The cost of the usage covered by a Savings Plan is its
+// on demand equivalent.
+func getCoverage(response ResponseSavingsPlans) Coverage {
+	coverage := Coverage{
+		Services: make([]ServiceCoverage, 0, len(response.Coverage.Products.Buckets)),
+	}
+	hourly := make(map[float64]*HourlyCoverage)
+	for _, product := range response.Coverage.Products.Buckets {
+		service := ServiceCoverage{Service: product.Product}
+		for _, hourBucket := range product.Hours.Buckets {
+			hour, ok := hourly[hourBucket.Hour]
+			if !ok {
+				hour = &HourlyCoverage{Hour: parseMillis(hourBucket.Hour)}
+				hourly[hourBucket.Hour] = hour
+			}
+			for _, lineItemType := range hourBucket.Types.Buckets {
+				addCost(&service.CoveredCost, &service.OnDemandCost, lineItemType.Type, lineItemType.Cost.Value)
+				addCost(&hour.CoveredCost, &hour.OnDemandCost, lineItemType.Type, lineItemType.Cost.Value)
+			}
+		}
+		service.Coverage = percentage(service.CoveredCost, service.CoveredCost+service.OnDemandCost)
+		coverage.CoveredCost += service.CoveredCost
+		coverage.OnDemandCost += service.OnDemandCost
+		coverage.Services = append(coverage.Services, service)
+	}
+	sort.Slice(coverage.Services, func(i, j int) bool {
+		return coverage.Services[i].Service < coverage.Services[j].Service
+	})
+	coverage.Hourly = make([]HourlyCoverage, 0, len(hourly))
+	for _, hour := range hourly {
+		hour.Coverage = percentage(hour.CoveredCost, hour.CoveredCost+hour.OnDemandCost)
+		coverage.Hourly = append(coverage.Hourly, *hour)
+	}
+	sort.Slice(coverage.Hourly, func(i, j int) bool {
+		return coverage.Hourly[i].Hour.Before(coverage.Hourly[j].Hour)
+	})
+	coverage.Coverage = percentage(coverage.CoveredCost, coverage.CoveredCost+coverage.OnDemandCost)
+	return coverage
+}
+
+// parseSavingsPlansResponse unmarshals the aggregations of the ES response.
+func parseSavingsPlansResponse(res *elastic.SearchResult) (response ResponseSavingsPlans, err error) {
+	if err = json.Unmarshal(*res.Aggregations["plans"], &response.Plans); err != nil {
+		return
+	}
+	err = json.Unmarshal(*res.Aggregations["coverage"], &response.Coverage)
+	return
+}
+
+// fetchSavingsPlansInventory fetches the Savings Plans of an AwsAccount which
+// were active at some point between begin and end from the Savings Plans API.
+func fetchSavingsPlansInventory(ctx context.Context, aa taws.AwsAccount, begin, end time.Time) ([]SavingsPlan, error) {
+	creds, err := taws.GetTemporaryCredentials(aa, MonitorSavingsPlansStsSessionName)
+	if err != nil {
+		return nil, err
+	}
+	inventory, err := savingsplans.New(creds).DescribeAllSavingsPlans(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return getInventory(inventory, begin, end), nil
+}
+
+// FetchDailySavingsPlansStats lists the Savings Plans of an AwsAccount and computes their hourly
+// utilization and the hourly coverage of the eligible usage since the beginning of the month, from
+// the billing data, and imports them in ElasticSearch. If the Savings Plans cannot be listed, the
+// report only holds the Savings Plans found in the billing data and the error is returned once the
+// report is imported.
+func FetchDailySavingsPlansStats(ctx context.Context, aa taws.AwsAccount) error {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	logger.Info("Fetching Savings Plans stats", map[string]interface{}{"awsAccountId": aa.Id})
+	now := time.Now().UTC()
+	begin := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	inventory, inventoryErr := fetchSavingsPlansInventory(ctx, aa, begin, now)
+	if inventoryErr != nil {
+		logger.Error("Failed to list Savings Plans", inventoryErr.Error())
+	}
+	var response ResponseSavingsPlans
+	index := es.IndexNameForUserId(aa.UserId, es.IndexPrefixLineItems)
+	res, err := getElasticSearchParams(aa.AwsIdentity, begin, now, es.Client, index).Do(ctx)
+	if err == nil {
+		if response, err = parseSavingsPlansResponse(res); err != nil {
+			logger.Error("Failed to parse Savings Plans data", err.Error())
+			return err
+		}
+	} else if elastic.IsNotFound(err) {
+		logger.Warning("Query execution failed, ES index does not exists", map[string]interface{}{
+			"index": index,
+			"error": err.Error(),
+		})
+	} else {
+		logger.Error("Failed to get Savings Plans data from ES", err.Error())
+		return err
+	}
+	report := SavingsPlansReport{
+		ReportBase: utils.ReportBase{
+			Account:    aa.AwsIdentity,
+			ReportDate: now,
+			ReportType: "daily",
+		},
+		SavingsPlans: getSavingsPlans(inventory, response),
+		Coverage:     getCoverage(response),
+	}
+	if err = importSavingsPlansReportToEs(ctx, aa, report); err != nil {
+		return err
+	}
+	return inventoryErr
+}
@@ -0,0 +1,255 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package savingsPlans
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/trackit/trackit/aws/savingsplans"
+)
+
+// responseSavingsPlans holds two hours of billing data, from 2019-12-01T00:00
+// (1575158400000) to 2019-12-01T01:00 (1575162000000). The first Savings Plan
+// is fully used on the first hour and half used on the second one. The second
+// Savings Plan is not in the inventory.
+const responseSavingsPlans = `{
+	"plans": {
+		"arns": {
+			"buckets": [
+				{
+					"key": "arn:aws:savingsplans::123456789012:savingsplan/4f2c",
+					"hours": {
+						"buckets": [
+							{ "key": 1575158400000, "totalCommitment": { "value": 10 }, "usedCommitment": { "value": 10 } },
+							{ "key": 1575162000000, "totalCommitment": { "value": 10 }, "usedCommitment": { "value": 5 } }
+						]
+					},
+					"details": { "hits": { "hits": [] } }
+				},
+				{
+					"key": "arn:aws:savingsplans::123456789012:savingsplan/9a1b",
+					"hours": {
+						"buckets": [
+							{ "key": 1575158400000, "totalCommitment": { "value": 2 }, "usedCommitment": { "value": 2 } }
+						]
+					},
+					"details": {
+						"hits": {
+							"hits": [
+								{
+									"_source": {
+										"savingsPlanOfferingType": "EC2InstanceSavingsPlans",
+										"savingsPlanPurchaseTerm": "3yr",
+										"savingsPlanPaymentOption": "All Upfront",
+										"savingsPlanRegion": "EU (Ireland)",
+										"savingsPlanStartTime": "2019-11-01T00:00:00.000Z",
+										"savingsPlanEndTime": "2022-10-31T23:59:59.000Z"
+									}
+								}
+							]
+						}
+					}
+				}
+			]
+		}
+	},
+	"coverage": {
+		"products": {
+			"buckets": [
+				{
+					"key": "AmazonEC2",
+					"hours": {
+						"buckets": [
+							{
+								"key": 1575158400000,
+								"types": {
+									"buckets": [
+										{ "key": "SavingsPlanCoveredUsage", "cost": { "value": 200 } }
+									]
+								}
+							},
+							{
+								"key": 1575162000000,
+								"types": {
+									"buckets": [
+										{ "key": "SavingsPlanCoveredUsage", "cost": { "value": 100 } },
+										{ "key": "Usage", "cost": { "value": 100 } }
+									]
+								}
+							}
+						]
+					}
+				},
+				{
+					"key": "AWSLambda",
+					"hours": {
+						"buckets": [
+							{
+								"key": 1575162000000,
+								"types": {
+									"buckets": [
+										{ "key": "Usage", "cost": { "value": 100 } }
+									]
+								}
+							}
+						]
+					}
+				}
+			]
+		}
+	}
+}`
+
+// testInventory are the Savings Plans of the account: one active during the
+// billing data, and one which expired before.
+var testInventory = []savingsplans.SavingsPlan{
+	{
+		SavingsPlanId:          "4f2c",
+		SavingsPlanArn:         "arn:aws:savingsplans::123456789012:savingsplan/4f2c",
+		Start:                  "2019-11-01T00:00:00.000Z",
+		End:                    "2020-10-31T23:59:59.000Z",
+		State:                  "active",
+		SavingsPlanType:        "Compute",
+		PaymentOption:          "Partial Upfront",
+		Commitment:             "10.0",
+		UpfrontPaymentAmount:   "43800.0",
+		RecurringPaymentAmount: "5.0",
+		TermDurationInSeconds:  31536000,
+	},
+	{
+		SavingsPlanId:         "0e3d",
+		SavingsPlanArn:        "arn:aws:savingsplans::123456789012:savingsplan/0e3d",
+		Start:                 "2016-11-01T00:00:00.000Z",
+		End:                   "2019-10-31T23:59:59.000Z",
+		State:                 "retired",
+		SavingsPlanType:       "Compute",
+		Commitment:            "1.0",
+		TermDurationInSeconds: 94608000,
+	},
+}
+
+func floatEquals(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func getTestResponse(t *testing.T) ResponseSavingsPlans {
+	var response ResponseSavingsPlans
+	if err := json.Unmarshal([]byte(responseSavingsPlans), &response); err != nil {
+		t.Fatalf("Failed to unmarshal the test response: %s", err.Error())
+	}
+	return response
+}
+
+func TestGetInventory(t *testing.T) {
+	begin := time.Date(2019, time.December, 1, 0, 0, 0, 0, time.UTC)
+	inventory := getInventory(testInventory, begin, begin.AddDate(0, 1, 0))
+	if len(inventory) != 1 {
+		t.Fatalf("Expected only the active Savings Plan, got %+v.", inventory)
+	}
+	savingsPlan := inventory[0]
+	if savingsPlan.Id != "4f2c" || savingsPlan.OfferingType != "ComputeSavingsPlans" || savingsPlan.PurchaseTerm != "1yr" || savingsPlan.State != "active" {
+		t.Errorf("Savings Plan details were not filled: %+v", savingsPlan)
+	}
+	if !floatEquals(savingsPlan.HourlyCommitment, 10) || !floatEquals(savingsPlan.UpfrontPaymentAmount, 43800) || !floatEquals(savingsPlan.RecurringPaymentAmount, 5) {
+		t.Errorf("Savings Plan amounts were not parsed: %+v", savingsPlan)
+	}
+	if !savingsPlan.Start.Equal(time.Date(2019, time.November, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected start to be 2019-11-01, got %s.", savingsPlan.Start)
+	}
+}
+
+func TestPurchaseTerm(t *testing.T) {
+	for seconds, term := range map[int64]string{
+		31536000: "1yr",
+		94608000: "3yr",
+		94694400: "3yr",
+		0:        "",
+	} {
+		if purchaseTerm(seconds) != term {
+			t.Errorf("Expected %d seconds to be %q, got %q.", seconds, term, purchaseTerm(seconds))
+		}
+	}
+}
+
+func TestGetSavingsPlans(t *testing.T) {
+	begin := time.Date(2019, time.December, 1, 0, 0, 0, 0, time.UTC)
+	inventory := getInventory(testInventory, begin, begin.AddDate(0, 1, 0))
+	savingsPlans := getSavingsPlans(inventory, getTestResponse(t))
+	if len(savingsPlans) != 2 {
+		t.Fatalf("Expected 2 Savings Plans, got %d.", len(savingsPlans))
+	}
+	savingsPlan := savingsPlans[0]
+	if savingsPlan.Id != "4f2c" || savingsPlan.PaymentOption != "Partial Upfront" {
+		t.Errorf("Savings Plan should be described by the inventory: %+v", savingsPlan)
+	}
+	if !floatEquals(savingsPlan.Hours, 2) || !floatEquals(savingsPlan.HourlyCommitment, 10) {
+		t.Errorf("Expected 2 hours with a commitment of 10, got %f and %f.", savingsPlan.Hours, savingsPlan.HourlyCommitment)
+	}
+	if !floatEquals(savingsPlan.UnusedCommitment, 5) || !floatEquals(savingsPlan.Utilization, 75) {
+		t.Errorf("Expected an unused commitment of 5 and a utilization of 75%%, got %f and %f.", savingsPlan.UnusedCommitment, savingsPlan.Utilization)
+	}
+	if savingsPlan.UnderutilizedHours != 1 || len(savingsPlan.Hourly) != 2 || !floatEquals(savingsPlan.Hourly[1].Utilization, 50) {
+		t.Errorf("Expected the second hour to be underutilized, got %+v.", savingsPlan.Hourly)
+	}
+	if !savingsPlan.Hourly[1].Hour.Equal(time.Date(2019, time.December, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the second hour to be 2019-12-01T01:00, got %s.", savingsPlan.Hourly[1].Hour)
+	}
+	billed := savingsPlans[1]
+	if billed.Id != "" || billed.OfferingType != "EC2InstanceSavingsPlans" || billed.PurchaseTerm != "3yr" || !floatEquals(billed.HourlyCommitment, 2) {
+		t.Errorf("Savings Plan missing from the inventory should be described by the billing data: %+v", billed)
+	}
+}
+
+func TestGetSavingsPlansWithoutBillingData(t *testing.T) {
+	begin := time.Date(2019, time.December, 1, 0, 0, 0, 0, time.UTC)
+	savingsPlans := getSavingsPlans(getInventory(testInventory, begin, begin.AddDate(0, 1, 0)), ResponseSavingsPlans{})
+	if len(savingsPlans) != 1 || !floatEquals(savingsPlans[0].HourlyCommitment, 10) || savingsPlans[0].Hours != 0 {
+		t.Errorf("Savings Plans should be listed without billing data, got %+v.", savingsPlans)
+	}
+}
+
+func TestGetCoverage(t *testing.T) {
+	coverage := getCoverage(getTestResponse(t))
+	if !floatEquals(coverage.CoveredCost, 300) || !floatEquals(coverage.OnDemandCost, 200) {
+		t.Errorf("Expected 300 covered and 200 on demand, got %f and %f.", coverage.CoveredCost, coverage.OnDemandCost)
+	}
+	if !floatEquals(coverage.Coverage, 60) {
+		t.Errorf("Expected a coverage of 60%%, got %f.", coverage.Coverage)
+	}
+	if len(coverage.Services) != 2 {
+		t.Fatalf("Expected 2 services, got %d.", len(coverage.Services))
+	}
+	if coverage.Services[0].Service != "AWSLambda" || !floatEquals(coverage.Services[0].Coverage, 0) {
+		t.Errorf("Unexpected Lambda coverage: %+v", coverage.Services[0])
+	}
+	if coverage.Services[1].Service != "AmazonEC2" || !floatEquals(coverage.Services[1].Coverage, 75) {
+		t.Errorf("Unexpected EC2 coverage: %+v", coverage.Services[1])
+	}
+	if len(coverage.Hourly) != 2 {
+		t.Fatalf("Expected 2 hours, got %d.", len(coverage.Hourly))
+	}
+	if !floatEquals(coverage.Hourly[0].Coverage, 100) || !floatEquals(coverage.Hourly[1].Coverage, 100.0/3) {
+		t.Errorf("Expected an hourly coverage of 100%% then 33%%, got %+v.", coverage.Hourly)
+	}
+}
+
+func TestPercentageOfZero(t *testing.T) {
+	if percentage(10, 0) != 0 {
+		t.Errorf("Expected a percentage of zero to be 0.")
+	}
+}
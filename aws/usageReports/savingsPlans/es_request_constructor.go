@@ -0,0 +1,81 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package savingsPlans
+
+import (
+	"time"
+
+	"github.com/olivere/elastic"
+
+	"github.com/trackit/trackit/aws/usageReports"
+)
+
+// savingsPlanDetailsFields are the lineitem fields describing a Savings Plan.
+// They are identical on every SavingsPlanRecurringFee line of a plan.
+var savingsPlanDetailsFields = []string{
+	"savingsPlanArn",
+	"savingsPlanOfferingType",
+	"savingsPlanPurchaseTerm",
+	"savingsPlanPaymentOption",
+	"savingsPlanRegion",
+	"savingsPlanStartTime",
+	"savingsPlanEndTime",
+}
+
+// eligibleUsageQueries associates the products which can be covered by a
+// Savings Plan with a pattern matching their eligible usage types.
+var eligibleUsageQueries = [][2]string{
+	{"AmazonEC2", "*BoxUsage*"},
+	{"AWSLambda", "*Lambda-GB-Second*"},
+	{"AmazonECS", "*Fargate*"},
+}
+
+// CreateQueryEligibleUsage creates and returns a new *elastic.BoolQuery matching the
+// lineitems which could be covered by a Savings Plan.
+func CreateQueryEligibleUsage() *elastic.BoolQuery {
+	query := elastic.NewBoolQuery().MinimumNumberShouldMatch(1)
+	for _, eligible := range eligibleUsageQueries {
+		query = query.Should(elastic.NewBoolQuery().
+			Filter(elastic.NewTermQuery("productCode", eligible[0])).
+			Filter(elastic.NewWildcardQuery("usageType", eligible[1])))
+	}
+	return elastic.NewBoolQuery().
+		Filter(elastic.NewTermsQuery("lineItemType", "Usage", "SavingsPlanCoveredUsage")).
+		Filter(query)
+}
+
+// getElasticSearchParams is used to construct an ElasticSearch *elastic.SearchService on the lineitems
+// of an account. It aggregates by hour the commitment of each Savings Plan and the cost of the eligible
+// usage, split between the usage covered by a Savings Plan and the on demand usage.
+func getElasticSearchParams(account string, durationBegin, durationEnd time.Time, client *elastic.Client, index string) *elastic.SearchService {
+	query := elastic.NewBoolQuery()
+	query = query.Filter(elastic.NewTermQuery("usageAccountId", account))
+	query = query.Filter(elastic.NewRangeQuery("usageStartDate").
+		From(durationBegin).To(durationEnd))
+	search := client.Search().Index(index).Size(0).Query(query)
+	search.Aggregation("plans", elastic.NewFilterAggregation().Filter(elastic.NewTermQuery("lineItemType", "SavingsPlanRecurringFee")).
+		SubAggregation("arns", elastic.NewTermsAggregation().Field("savingsPlanArn").Size(utils.MaxAggregationSize).
+			SubAggregation("hours", elastic.NewDateHistogramAggregation().Field("usageStartDate").MinDocCount(1).Interval("hour").
+				SubAggregation("totalCommitment", elastic.NewSumAggregation().Field("savingsPlanTotalCommitment")).
+				SubAggregation("usedCommitment", elastic.NewSumAggregation().Field("savingsPlanUsedCommitment"))).
+			SubAggregation("details", elastic.NewTopHitsAggregation().Size(1).
+				FetchSourceContext(elastic.NewFetchSourceContext(true).Include(savingsPlanDetailsFields...)))))
+	search.Aggregation("coverage", elastic.NewFilterAggregation().Filter(CreateQueryEligibleUsage()).
+		SubAggregation("products", elastic.NewTermsAggregation().Field("productCode").Size(utils.MaxAggregationSize).
+			SubAggregation("hours", elastic.NewDateHistogramAggregation().Field("usageStartDate").MinDocCount(1).Interval("hour").
+				SubAggregation("types", elastic.NewTermsAggregation().Field("lineItemType").Size(utils.MaxAggregationSize).
+					SubAggregation("cost", elastic.NewSumAggregation().Field("unblendedCost"))))))
+	return search
+}
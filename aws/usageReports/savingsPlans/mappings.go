@@ -0,0 +1,189 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package savingsPlans
+
+import (
+	"context"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/es"
+)
+
+const TypeSavingsPlansReport = "savings-plans-report"
+const IndexPrefixSavingsPlansReport = "savings-plans-reports"
+const TemplateNameSavingsPlansReport = "savings-plans-reports"
+
+// put the ElasticSearch index for *-savings-plans-reports indices at startup.
+func init() {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	res, err := es.Client.IndexPutTemplate(TemplateNameSavingsPlansReport).BodyString(TemplateSavingsPlansReport).Do(ctx)
+	if err != nil {
+		jsonlog.DefaultLogger.Error("Failed to put ES index Savings Plans Report.", err)
+	} else {
+		jsonlog.DefaultLogger.Info("Put ES index Savings Plans Report.", res)
+		ctxCancel()
+	}
+}
+
+const TemplateSavingsPlansReport = `
+{
+	"template": "*-savings-plans-reports",
+	"version": 2,
+	"mappings": {
+		"savings-plans-report": {
+			"properties": {
+				"account": {
+					"type": "keyword"
+				},
+				"reportDate": {
+					"type": "date"
+				},
+				"reportType": {
+					"type": "keyword"
+				},
+				"savingsPlans": {
+					"properties": {
+						"id": {
+							"type": "keyword"
+						},
+						"arn": {
+							"type": "keyword"
+						},
+						"state": {
+							"type": "keyword"
+						},
+						"offeringType": {
+							"type": "keyword"
+						},
+						"ec2InstanceFamily": {
+							"type": "keyword"
+						},
+						"purchaseTerm": {
+							"type": "keyword"
+						},
+						"paymentOption": {
+							"type": "keyword"
+						},
+						"region": {
+							"type": "keyword"
+						},
+						"start": {
+							"type": "date"
+						},
+						"end": {
+							"type": "date"
+						},
+						"upfrontPaymentAmount": {
+							"type": "double"
+						},
+						"recurringPaymentAmount": {
+							"type": "double"
+						},
+						"hours": {
+							"type": "double"
+						},
+						"hourlyCommitment": {
+							"type": "double"
+						},
+						"totalCommitment": {
+							"type": "double"
+						},
+						"usedCommitment": {
+							"type": "double"
+						},
+						"unusedCommitment": {
+							"type": "double"
+						},
+						"utilization": {
+							"type": "double"
+						},
+						"underutilizedHours": {
+							"type": "integer"
+						},
+						"hourly": {
+							"properties": {
+								"hour": {
+									"type": "date"
+								},
+								"commitment": {
+									"type": "double"
+								},
+								"usedCommitment": {
+									"type": "double"
+								},
+								"utilization": {
+									"type": "double"
+								}
+							}
+						}
+					}
+				},
+				"coverage": {
+					"properties": {
+						"coveredCost": {
+							"type": "double"
+						},
+						"onDemandCost": {
+							"type": "double"
+						},
+						"coverage": {
+							"type": "double"
+						},
+						"services": {
+							"properties": {
+								"service": {
+									"type": "keyword"
+								},
+								"coveredCost": {
+									"type": "double"
+								},
+								"onDemandCost": {
+									"type": "double"
+								},
+								"coverage": {
+									"type": "double"
+								}
+							}
+						},
+						"hourly": {
+							"properties": {
+								"hour": {
+									"type": "date"
+								},
+								"coveredCost": {
+									"type": "double"
+								},
+								"onDemandCost": {
+									"type": "double"
+								},
+								"coverage": {
+									"type": "double"
+								}
+							}
+						}
+					}
+				}
+			},
+			"_all": {
+				"enabled": false
+			},
+			"numeric_detection": false,
+			"date_detection": false
+		}
+	}
+}
+`
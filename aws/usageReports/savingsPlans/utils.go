@@ -0,0 +1,146 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package savingsPlans
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	taws "github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/aws/usageReports"
+	"github.com/trackit/trackit/es"
+)
+
+const MonitorSavingsPlansStsSessionName = "monitor-savings-plans"
+
+type (
+	// SavingsPlansReport is saved in ES to have the utilization of the Savings Plans
+	// of an account and the coverage of its eligible usage
+	SavingsPlansReport struct {
+		utils.ReportBase
+		SavingsPlans []SavingsPlan `json:"savingsPlans"`
+		Coverage     Coverage      `json:"coverage"`
+	}
+
+	// SavingsPlan contains the information and the commitment utilization of a Savings Plan
+	SavingsPlan struct {
+		Id                     string              `json:"id"`
+		Arn                    string              `json:"arn"`
+		State                  string              `json:"state"`
+		OfferingType           string              `json:"offeringType"`
+		Ec2InstanceFamily      string              `json:"ec2InstanceFamily"`
+		PurchaseTerm           string              `json:"purchaseTerm"`
+		PaymentOption          string              `json:"paymentOption"`
+		Region                 string              `json:"region"`
+		Start                  time.Time           `json:"start"`
+		End                    time.Time           `json:"end"`
+		UpfrontPaymentAmount   float64             `json:"upfrontPaymentAmount"`
+		RecurringPaymentAmount float64             `json:"recurringPaymentAmount"`
+		Hours                  float64             `json:"hours"`
+		HourlyCommitment       float64             `json:"hourlyCommitment"`
+		TotalCommitment        float64             `json:"totalCommitment"`
+		UsedCommitment         float64             `json:"usedCommitment"`
+		UnusedCommitment       float64             `json:"unusedCommitment"`
+		Utilization            float64             `json:"utilization"`
+		UnderutilizedHours     int                 `json:"underutilizedHours"`
+		Hourly                 []HourlyUtilization `json:"hourly"`
+	}
+
+	// HourlyUtilization contains the commitment utilization of a Savings Plan during an hour
+	HourlyUtilization struct {
+		Hour           time.Time `json:"hour"`
+		Commitment     float64   `json:"commitment"`
+		UsedCommitment float64   `json:"usedCommitment"`
+		Utilization    float64   `json:"utilization"`
+	}
+
+	// Coverage contains the part of the eligible usage covered by Savings Plans
+	Coverage struct {
+		CoveredCost  float64           `json:"coveredCost"`
+		OnDemandCost float64           `json:"onDemandCost"`
+		Coverage     float64           `json:"coverage"`
+		Services     []ServiceCoverage `json:"services"`
+		Hourly       []HourlyCoverage  `json:"hourly"`
+	}
+
+	// ServiceCoverage contains the coverage of the eligible usage of a service
+	ServiceCoverage struct {
+		Service      string  `json:"service"`
+		CoveredCost  float64 `json:"coveredCost"`
+		OnDemandCost float64 `json:"onDemandCost"`
+		Coverage     float64 `json:"coverage"`
+	}
+
+	// HourlyCoverage contains the coverage of the eligible usage during an hour
+	HourlyCoverage struct {
+		Hour         time.Time `json:"hour"`
+		CoveredCost  float64   `json:"coveredCost"`
+		OnDemandCost float64   `json:"onDemandCost"`
+		Coverage     float64   `json:"coverage"`
+	}
+)
+
+// importSavingsPlansReportToEs imports a Savings Plans report in ElasticSearch.
+func importSavingsPlansReportToEs(ctx context.Context, aa taws.AwsAccount, report SavingsPlansReport) error {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	logger.Info("Updating Savings Plans report for AWS account.", map[string]interface{}{
+		"awsAccount": aa,
+	})
+	index := es.IndexNameForUserId(aa.UserId, IndexPrefixSavingsPlansReport)
+	bp, err := utils.GetBulkProcessor(ctx)
+	if err != nil {
+		logger.Error("Failed to get bulk processor.", err.Error())
+		return err
+	}
+	id, err := generateId(report)
+	if err != nil {
+		logger.Error("Error when marshaling Savings Plans report var", err.Error())
+		return err
+	}
+	bp = utils.AddDocToBulkProcessor(bp, report, TypeSavingsPlansReport, index, id)
+	bp.Flush()
+	err = bp.Close()
+	if err != nil {
+		logger.Error("Failed to put Savings Plans report in ES", err.Error())
+		return err
+	}
+	logger.Info("Savings Plans report put in ES", nil)
+	return nil
+}
+
+// generateId returns the same id for all the reports of an account for a
+// given day, so that running the ingestion twice a day updates the report.
+func generateId(report SavingsPlansReport) (string, error) {
+	ji, err := json.Marshal(struct {
+		Account    string `json:"account"`
+		ReportDate string `json:"reportDate"`
+		Type       string `json:"reportType"`
+	}{
+		report.Account,
+		report.ReportDate.Format("2006-01-02"),
+		report.ReportType,
+	})
+	if err != nil {
+		return "", err
+	}
+	hash := md5.Sum(ji)
+	hash64 := base64.URLEncoding.EncodeToString(hash[:])
+	return hash64, nil
+}
@@ -0,0 +1,163 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package budgets
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/models"
+	"github.com/trackit/trackit/notifications"
+	"github.com/trackit/trackit/users"
+)
+
+// CheckBudgets computes the spending of every budget and alerts their owners
+// when a threshold is crossed for the first time in the current period. Each
+// budget is checked in its own transaction, so that the failure of one does
+// not roll back the alerts recorded for the others.
+func CheckBudgets(ctx context.Context, db *sql.DB) error {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	dbBudgets, err := models.AllBudgets(db)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, dbBudget := range dbBudgets {
+		if err := checkBudget(ctx, db, *dbBudget, now); err != nil {
+			logger.Error("Failed to check budget.", map[string]interface{}{
+				"budgetId": dbBudget.ID,
+				"error":    err.Error(),
+			})
+		}
+	}
+	return nil
+}
+
+// budgetAlert is an alert about a budget which was recorded and has yet to be
+// sent.
+type budgetAlert struct {
+	user      users.User
+	status    BudgetStatus
+	threshold int
+	notifiers []notifications.Notifier
+}
+
+// checkBudget alerts the owner of a budget if its spending crossed
+// thresholds which were not alerted yet. The alert is only sent once it is
+// recorded, so that it is never sent again by the next check.
+func checkBudget(ctx context.Context, db *sql.DB, dbBudget models.Budget, date time.Time) error {
+	alert, err := recordBudgetAlertInTransaction(ctx, db, dbBudget, date)
+	if err != nil || alert == nil {
+		return err
+	}
+	return sendBudgetAlert(ctx, *alert)
+}
+
+// recordBudgetAlertInTransaction runs recordBudgetAlert in a transaction of
+// its own, which is committed before it returns.
+func recordBudgetAlertInTransaction(ctx context.Context, db *sql.DB, dbBudget models.Budget, date time.Time) (alert *budgetAlert, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	return recordBudgetAlert(ctx, tx, dbBudget, date)
+}
+
+// recordBudgetAlert records the thresholds crossed by the spending of a budget
+// which were not alerted yet, and returns the alert to send about the highest
+// one. It returns nil if no threshold was crossed.
+func recordBudgetAlert(ctx context.Context, tx *sql.Tx, dbBudget models.Budget, date time.Time) (*budgetAlert, error) {
+	b, err := budgetFromDbBudget(dbBudget)
+	if err != nil {
+		return nil, err
+	}
+	user, err := users.GetUserWithId(tx, dbBudget.UserID)
+	if err != nil {
+		return nil, err
+	}
+	status, _, err := getBudgetStatus(ctx, tx, user, b, date)
+	if err != nil {
+		return nil, err
+	}
+	crossed, err := getCrossedThresholds(tx, status)
+	if err != nil || len(crossed) == 0 {
+		return nil, err
+	}
+	for _, threshold := range crossed {
+		dbBudgetAlert := models.BudgetAlert{
+			BudgetID:    b.Id,
+			PeriodBegin: status.PeriodBegin,
+			Threshold:   threshold,
+			Spent:       status.Spent,
+		}
+		if err := dbBudgetAlert.Insert(tx); err != nil {
+			return nil, err
+		}
+	}
+	notifiers, err := notifications.GetNotifiers(tx, user, status.AwsAccountId)
+	if err != nil {
+		return nil, err
+	}
+	return &budgetAlert{user, status, crossed[len(crossed)-1], notifiers}, nil
+}
+
+// getCrossedThresholds returns the thresholds, in ascending order, which were
+// reached by the spending of a budget and have not been alerted yet during
+// its current period.
+func getCrossedThresholds(tx *sql.Tx, status BudgetStatus) ([]int, error) {
+	crossed := []int{}
+	for _, threshold := range status.Thresholds {
+		if status.Percentage < float64(threshold) {
+			break
+		}
+		_, err := models.BudgetAlertByBudgetIDPeriodBeginThreshold(tx, status.Id, status.PeriodBegin, threshold)
+		if err == sql.ErrNoRows {
+			crossed = append(crossed, threshold)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return crossed, nil
+}
+
+// sendBudgetAlert notifies the owner of a budget about a crossed threshold.
+func sendBudgetAlert(ctx context.Context, alert budgetAlert) error {
+	status, threshold := alert.status, alert.threshold
+	mailSubject := fmt.Sprintf("Budget %s reached %d%%", status.Name, threshold)
+	mailBody := fmt.Sprintf("Hi, your %s budget \"%s\" reached %d%% of its amount: $%.2f out of $%.2f have been spent since %s. "+
+		"You can connect to your account to see the details: https://re.trackit.io/",
+		status.Period, status.Name, threshold, status.Spent, status.Amount, status.PeriodBegin.Format("2006-01-02"))
+	_, err := notifications.NotifyAll(ctx, alert.user, alert.notifiers, notifications.Notification{
+		Event:   notifications.EventBudget,
+		Subject: mailSubject,
+		Body:    mailBody,
+		Data: map[string]interface{}{
+			"budget":    status,
+			"threshold": threshold,
+		},
+	})
+	return err
+}
@@ -0,0 +1,172 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package budgets lets users plan an amount of spending over a period of time
+// and get alerted when a given share of it has been spent.
+package budgets
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trackit/trackit/models"
+)
+
+const (
+	PeriodMonthly   = "monthly"
+	PeriodQuarterly = "quarterly"
+	PeriodYearly    = "yearly"
+)
+
+var (
+	ErrInvalidPeriod    = errors.New("period must be one of monthly, quarterly or yearly")
+	ErrInvalidAmount    = errors.New("amount must be greater than zero")
+	ErrInvalidThreshold = errors.New("thresholds must be integer percentages greater than zero")
+	ErrInvalidTag       = errors.New("tag filter needs both a key and a value")
+)
+
+// defaultThresholds are the percentages of a budget triggering an alert when
+// none were specified.
+var defaultThresholds = []int{50, 80, 100}
+
+// Budget is an amount of money planned to be spent over a period, either for
+// a single AWS account or for every account of the user. It can be
+// restricted to the line items carrying a tag.
+type Budget struct {
+	Id           int     `json:"id"`
+	Name         string  `json:"name"`
+	AwsAccountId *int    `json:"awsAccountId,omitempty"`
+	TagKey       string  `json:"tagKey"`
+	TagValue     string  `json:"tagValue"`
+	Period       string  `json:"period"`
+	Amount       float64 `json:"amount"`
+	Thresholds   []int   `json:"thresholds"`
+}
+
+// periodBounds returns the first and the last instants of the period
+// containing date.
+func periodBounds(period string, date time.Time) (begin, end time.Time, err error) {
+	date = date.UTC()
+	switch period {
+	case PeriodMonthly:
+		begin = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		end = begin.AddDate(0, 1, 0)
+	case PeriodQuarterly:
+		firstMonth := ((date.Month()-1)/3)*3 + 1
+		begin = time.Date(date.Year(), firstMonth, 1, 0, 0, 0, 0, time.UTC)
+		end = begin.AddDate(0, 3, 0)
+	case PeriodYearly:
+		begin = time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		end = begin.AddDate(1, 0, 0)
+	default:
+		return begin, end, ErrInvalidPeriod
+	}
+	end = end.Add(-time.Nanosecond)
+	return
+}
+
+// parseThresholds parses the comma separated list of percentages stored in
+// the database.
+func parseThresholds(raw string) ([]int, error) {
+	thresholds := []int{}
+	for _, s := range strings.Split(raw, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		threshold, err := strconv.Atoi(s)
+		if err != nil || threshold <= 0 {
+			return nil, ErrInvalidThreshold
+		}
+		thresholds = append(thresholds, threshold)
+	}
+	sort.Ints(thresholds)
+	return thresholds, nil
+}
+
+// formatThresholds formats thresholds the way they are stored in the
+// database.
+func formatThresholds(thresholds []int) string {
+	s := make([]string, len(thresholds))
+	for i, threshold := range thresholds {
+		s[i] = strconv.Itoa(threshold)
+	}
+	return strings.Join(s, ",")
+}
+
+// validate checks a budget is consistent and fills its defaults.
+func (b *Budget) validate() error {
+	if b.Period == "" {
+		b.Period = PeriodMonthly
+	}
+	if _, _, err := periodBounds(b.Period, time.Now()); err != nil {
+		return err
+	} else if b.Amount <= 0 {
+		return ErrInvalidAmount
+	} else if (b.TagKey == "") != (b.TagValue == "") {
+		return ErrInvalidTag
+	}
+	if len(b.Thresholds) == 0 {
+		b.Thresholds = defaultThresholds
+	}
+	for _, threshold := range b.Thresholds {
+		if threshold <= 0 {
+			return ErrInvalidThreshold
+		}
+	}
+	sort.Ints(b.Thresholds)
+	return nil
+}
+
+// budgetFromDbBudget builds a Budget from its database representation.
+func budgetFromDbBudget(dbBudget models.Budget) (Budget, error) {
+	thresholds, err := parseThresholds(dbBudget.Thresholds)
+	if err != nil {
+		return Budget{}, fmt.Errorf("budget %d: %s", dbBudget.ID, err.Error())
+	}
+	b := Budget{
+		Id:         dbBudget.ID,
+		Name:       dbBudget.Name,
+		TagKey:     dbBudget.TagKey,
+		TagValue:   dbBudget.TagValue,
+		Period:     dbBudget.Period,
+		Amount:     dbBudget.Amount,
+		Thresholds: thresholds,
+	}
+	if dbBudget.AwsAccountID.Valid {
+		aaId := int(dbBudget.AwsAccountID.Int64)
+		b.AwsAccountId = &aaId
+	}
+	return b, nil
+}
+
+// updateDbBudget copies the content of a Budget into its database
+// representation.
+func updateDbBudget(dbBudget *models.Budget, b Budget) {
+	dbBudget.Name = b.Name
+	dbBudget.TagKey = b.TagKey
+	dbBudget.TagValue = b.TagValue
+	dbBudget.Period = b.Period
+	dbBudget.Amount = b.Amount
+	dbBudget.Thresholds = formatThresholds(b.Thresholds)
+	if b.AwsAccountId != nil {
+		dbBudget.AwsAccountID = sql.NullInt64{Int64: int64(*b.AwsAccountId), Valid: true}
+	} else {
+		dbBudget.AwsAccountID = sql.NullInt64{}
+	}
+}
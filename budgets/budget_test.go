@@ -0,0 +1,80 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package budgets
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPeriodBounds(t *testing.T) {
+	date := time.Date(2019, time.May, 17, 12, 30, 0, 0, time.UTC)
+	cases := []struct {
+		period string
+		begin  time.Time
+		end    time.Time
+	}{
+		{PeriodMonthly, time.Date(2019, time.May, 1, 0, 0, 0, 0, time.UTC), time.Date(2019, time.May, 31, 23, 59, 59, 999999999, time.UTC)},
+		{PeriodQuarterly, time.Date(2019, time.April, 1, 0, 0, 0, 0, time.UTC), time.Date(2019, time.June, 30, 23, 59, 59, 999999999, time.UTC)},
+		{PeriodYearly, time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(2019, time.December, 31, 23, 59, 59, 999999999, time.UTC)},
+	}
+	for _, c := range cases {
+		begin, end, err := periodBounds(c.period, date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !begin.Equal(c.begin) || !end.Equal(c.end) {
+			t.Errorf("%s: expected %v - %v but got %v - %v", c.period, c.begin, c.end, begin, end)
+		}
+	}
+	if _, _, err := periodBounds("weekly", date); err != ErrInvalidPeriod {
+		t.Errorf("Expected %v but got %v", ErrInvalidPeriod, err)
+	}
+}
+
+func TestParseThresholds(t *testing.T) {
+	thresholds, err := parseThresholds("100, 50,80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int{50, 80, 100}; !reflect.DeepEqual(thresholds, expected) {
+		t.Errorf("Expected %v but got %v", expected, thresholds)
+	}
+	if formatted := formatThresholds(thresholds); formatted != "50,80,100" {
+		t.Errorf("Expected 50,80,100 but got %s", formatted)
+	}
+	if _, err := parseThresholds("50,-1"); err != ErrInvalidThreshold {
+		t.Errorf("Expected %v but got %v", ErrInvalidThreshold, err)
+	}
+}
+
+func TestValidateBudget(t *testing.T) {
+	b := Budget{Name: "test", Amount: 100}
+	if err := b.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if b.Period != PeriodMonthly || !reflect.DeepEqual(b.Thresholds, defaultThresholds) {
+		t.Errorf("Expected defaults to be filled but got %+v", b)
+	}
+	b = Budget{Name: "test", Amount: 100, TagKey: "project"}
+	if err := b.validate(); err != ErrInvalidTag {
+		t.Errorf("Expected %v but got %v", ErrInvalidTag, err)
+	}
+	b = Budget{Name: "test", Amount: 0}
+	if err := b.validate(); err != ErrInvalidAmount {
+		t.Errorf("Expected %v but got %v", ErrInvalidAmount, err)
+	}
+}
@@ -0,0 +1,242 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package budgets
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/models"
+	"github.com/trackit/trackit/routes"
+	"github.com/trackit/trackit/users"
+)
+
+// budgetBody is the body expected when creating or updating a budget.
+type budgetBody struct {
+	Name         string  `json:"name"         req:"nonzero"`
+	AwsAccountId *int    `json:"awsAccountId"`
+	TagKey       string  `json:"tagKey"`
+	TagValue     string  `json:"tagValue"`
+	Period       string  `json:"period"`
+	Amount       float64 `json:"amount"       req:"nonzero"`
+	Thresholds   []int   `json:"thresholds"`
+}
+
+var (
+	// budgetIdQueryArg allows to get the DB id of a budget in the URL
+	// parameters.
+	budgetIdQueryArg = routes.QueryArg{
+		Name:        "budget-id",
+		Type:        routes.QueryArgInt{},
+		Description: "The DB ID of the budget.",
+	}
+
+	exampleBudgetBody = budgetBody{
+		Name:       "Project X",
+		TagKey:     "project",
+		TagValue:   "x",
+		Period:     PeriodMonthly,
+		Amount:     10000,
+		Thresholds: []int{50, 80, 100},
+	}
+)
+
+func init() {
+	routes.MethodMuxer{
+		http.MethodGet: routes.H(getBudgets).With(
+			users.RequireAuthenticatedUser{users.ViewerAsParent},
+			routes.Documentation{
+				Summary:     "get the budgets",
+				Description: "Responds with the budgets of the user and what has been spent during their current period.",
+			},
+		),
+		http.MethodPost: routes.H(postBudget).With(
+			users.RequireAuthenticatedUser{users.ViewerCannot},
+			routes.RequestContentType{"application/json"},
+			routes.RequestBody{exampleBudgetBody},
+			routes.Documentation{
+				Summary:     "create a budget",
+				Description: "Creates a budget for an AWS account, or for all of them, optionally restricted to a tag.",
+			},
+		),
+		http.MethodPatch: routes.H(patchBudget).With(
+			users.RequireAuthenticatedUser{users.ViewerCannot},
+			routes.RequestContentType{"application/json"},
+			routes.QueryArgs{budgetIdQueryArg},
+			routes.RequestBody{exampleBudgetBody},
+			routes.Documentation{
+				Summary:     "update a budget",
+				Description: "Updates the budget whose ID is passed in query args.",
+			},
+		),
+		http.MethodDelete: routes.H(deleteBudget).With(
+			users.RequireAuthenticatedUser{users.ViewerCannot},
+			routes.QueryArgs{budgetIdQueryArg},
+			routes.Documentation{
+				Summary:     "delete a budget",
+				Description: "Deletes the budget whose ID is passed in query args.",
+			},
+		),
+	}.H().With(
+		db.RequestTransaction{db.Db},
+		routes.Documentation{
+			Summary:     "interact with budgets",
+			Description: "A budget is an amount planned to be spent over a month, a quarter or a year. Alerts are sent when thresholds are crossed.",
+		},
+	).Register("/budgets")
+}
+
+// getBudgets returns the budgets of the user along with their status.
+func getBudgets(r *http.Request, a routes.Arguments) (int, interface{}) {
+	l := jsonlog.LoggerFromContextOrDefault(r.Context())
+	user := a[users.AuthenticatedUser].(users.User)
+	tx := a[db.Transaction].(*sql.Tx)
+	dbBudgets, err := models.BudgetsByUserID(tx, user.Id)
+	if err != nil {
+		l.Error("Failed to get budgets.", map[string]interface{}{
+			"userId": user.Id,
+			"error":  err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("failed to get budgets")
+	}
+	now := time.Now().UTC()
+	res := make([]BudgetStatus, 0, len(dbBudgets))
+	for _, dbBudget := range dbBudgets {
+		b, err := budgetFromDbBudget(*dbBudget)
+		if err != nil {
+			l.Error("Failed to read budget.", err.Error())
+			continue
+		}
+		status, returnCode, err := getBudgetStatus(r.Context(), tx, user, b, now)
+		if err != nil {
+			return returnCode, err
+		}
+		res = append(res, status)
+	}
+	return http.StatusOK, res
+}
+
+// budgetFromBody validates a budgetBody and converts it to a Budget.
+func budgetFromBody(tx *sql.Tx, user users.User, body budgetBody) (Budget, error) {
+	b := Budget{
+		Name:         body.Name,
+		AwsAccountId: body.AwsAccountId,
+		TagKey:       body.TagKey,
+		TagValue:     body.TagValue,
+		Period:       body.Period,
+		Amount:       body.Amount,
+		Thresholds:   body.Thresholds,
+	}
+	if err := b.validate(); err != nil {
+		return b, err
+	}
+	if b.AwsAccountId != nil {
+		if _, err := aws.GetAwsAccountWithIdFromUser(user, *b.AwsAccountId, tx); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+// postBudget creates a budget for the user.
+func postBudget(r *http.Request, a routes.Arguments) (int, interface{}) {
+	l := jsonlog.LoggerFromContextOrDefault(r.Context())
+	user := a[users.AuthenticatedUser].(users.User)
+	tx := a[db.Transaction].(*sql.Tx)
+	var body budgetBody
+	routes.MustRequestBody(a, &body)
+	b, err := budgetFromBody(tx, user, body)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("Body is invalid (%s).", err.Error())
+	}
+	dbBudget := models.Budget{UserID: user.Id}
+	updateDbBudget(&dbBudget, b)
+	if err := dbBudget.Insert(tx); err != nil {
+		l.Error("Failed to create budget.", map[string]interface{}{
+			"budget": b,
+			"error":  err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("failed to create budget")
+	}
+	b.Id = dbBudget.ID
+	return http.StatusOK, b
+}
+
+// getUserDbBudget retrieves a budget from the database, ensuring it belongs
+// to the user.
+func getUserDbBudget(tx *sql.Tx, user users.User, budgetId int) (*models.Budget, error) {
+	dbBudget, err := models.BudgetByID(tx, budgetId)
+	if err != nil {
+		return nil, err
+	} else if dbBudget.UserID != user.Id {
+		return nil, errors.New("budget does not belong to the user")
+	}
+	return dbBudget, nil
+}
+
+// patchBudget updates a budget of the user.
+func patchBudget(r *http.Request, a routes.Arguments) (int, interface{}) {
+	l := jsonlog.LoggerFromContextOrDefault(r.Context())
+	user := a[users.AuthenticatedUser].(users.User)
+	tx := a[db.Transaction].(*sql.Tx)
+	budgetId := a[budgetIdQueryArg].(int)
+	var body budgetBody
+	routes.MustRequestBody(a, &body)
+	b, err := budgetFromBody(tx, user, body)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("Body is invalid (%s).", err.Error())
+	}
+	dbBudget, err := getUserDbBudget(tx, user, budgetId)
+	if err != nil {
+		return http.StatusNotFound, errors.New("budget not found")
+	}
+	updateDbBudget(dbBudget, b)
+	if err := dbBudget.Update(tx); err != nil {
+		l.Error("Failed to update budget.", map[string]interface{}{
+			"budget": b,
+			"error":  err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("failed to update budget")
+	}
+	b.Id = dbBudget.ID
+	return http.StatusOK, b
+}
+
+// deleteBudget deletes a budget of the user.
+func deleteBudget(r *http.Request, a routes.Arguments) (int, interface{}) {
+	l := jsonlog.LoggerFromContextOrDefault(r.Context())
+	user := a[users.AuthenticatedUser].(users.User)
+	tx := a[db.Transaction].(*sql.Tx)
+	budgetId := a[budgetIdQueryArg].(int)
+	dbBudget, err := getUserDbBudget(tx, user, budgetId)
+	if err != nil {
+		return http.StatusNotFound, errors.New("budget not found")
+	}
+	if err := dbBudget.Delete(tx); err != nil {
+		l.Error("Failed to delete budget.", map[string]interface{}{
+			"budgetId": budgetId,
+			"error":    err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("failed to delete budget")
+	}
+	return http.StatusOK, nil
+}
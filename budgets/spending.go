@@ -0,0 +1,83 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package budgets
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/aws/s3"
+	"github.com/trackit/trackit/costs"
+	"github.com/trackit/trackit/es"
+	"github.com/trackit/trackit/users"
+)
+
+// BudgetStatus is a Budget along with what has already been spent during its
+// current period.
+type BudgetStatus struct {
+	Budget
+	PeriodBegin time.Time `json:"periodBegin"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	Spent       float64   `json:"spent"`
+	Percentage  float64   `json:"percentage"`
+}
+
+// getBudgetStatus computes the spending of a budget over the period
+// containing date, using the costs aggregated from the line items.
+func getBudgetStatus(ctx context.Context, tx *sql.Tx, user users.User, b Budget, date time.Time) (BudgetStatus, int, error) {
+	status := BudgetStatus{Budget: b}
+	begin, end, err := periodBounds(b.Period, date)
+	if err != nil {
+		return status, http.StatusInternalServerError, err
+	}
+	status.PeriodBegin, status.PeriodEnd = begin, end
+	accountList := []string{}
+	if b.AwsAccountId != nil {
+		aa, err := aws.GetAwsAccountWithIdFromUser(user, *b.AwsAccountId, tx)
+		if err != nil {
+			return status, http.StatusBadRequest, err
+		}
+		accountList = append(accountList, aa.AwsIdentity)
+	}
+	accountsAndIndexes, returnCode, err := es.GetAccountsAndIndexes(accountList, user, tx, s3.IndexPrefixLineItem)
+	if err != nil {
+		return status, returnCode, err
+	}
+	params := costs.EsQueryParams{
+		DateBegin:         begin,
+		DateEnd:           end,
+		AccountList:       accountsAndIndexes.Accounts,
+		IndexList:         accountsAndIndexes.Indexes,
+		AggregationParams: []string{"month"},
+	}
+	if b.TagKey != "" {
+		params.TagFilters = []costs.TagFilter{{Key: b.TagKey, Value: b.TagValue}}
+	}
+	esCosts, returnCode, err := costs.MakeElasticSearchRequestAndParseIt(ctx, params)
+	if err != nil {
+		if returnCode == http.StatusOK {
+			return status, returnCode, nil
+		}
+		return status, returnCode, err
+	}
+	for _, month := range esCosts.Children {
+		status.Spent += month.Value
+	}
+	status.Percentage = status.Spent * 100 / b.Amount
+	return status, http.StatusOK, nil
+}
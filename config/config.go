@@ -41,6 +41,9 @@ var (
 	ReportsBucket string
 	// ReportsCover is the URL where the report cover is stored.
 	ReportsCover string
+	// ReportsEmail is true if the generated reports should be mailed to the
+	// owners of the AWS accounts.
+	ReportsEmail bool
 	// DefaultRole is the role added by default to new user accounts
 	DefaultRole string
 	// DefaultRoleName is the pretty name for the role added by default
@@ -51,6 +54,16 @@ var (
 	DefaultRoleBucket string
 	// DefaultRoleBucketPrefix is the billing prefix for the role added by default
 	DefaultRoleBucketPrefix string
+	// LocalBillRepositories is the directory holding the local bill
+	// repositories, in a subdirectory per AWS account ID holding one
+	// subdirectory per bill repository.
+	LocalBillRepositories string
+	// S3CompatibleEndpoints are the hosts of the S3-compatible services bill
+	// repositories may use even if they resolve to private addresses.
+	S3CompatibleEndpoints stringArray
+	// BillRepositorySecret is the secret from which the key encrypting the
+	// secret access keys of the bill repositories is derived.
+	BillRepositorySecret string
 	// PrettyJsonResponses, if set, indicates JSON HTTP responses should be pretty.
 	PrettyJsonResponses bool
 	// EsAuth is the authentication used to connect to the ElasticSearch database.
@@ -74,6 +87,15 @@ var (
 	SmtpPassword string
 	// SmtpSender is the mail address used to send mails.
 	SmtpSender string
+	// SmtpTls is the way TLS is used to connect to the SMTP server: starttls,
+	// opportunistic or implicit.
+	SmtpTls string
+	// SmtpCaBundle is the path of the PEM file holding the certificate
+	// authorities trusted to verify the certificate of the SMTP server.
+	SmtpCaBundle string
+	// SmtpInsecureSkipVerify is true if the certificate of the SMTP server
+	// should not be verified.
+	SmtpInsecureSkipVerify bool
 	// UrlEc2Pricing is the URL used by downloadJson to fetch the EC2 pricing.
 	UrlEc2Pricing string
 	// Task is the task to be run. "server", by default.
@@ -82,12 +104,36 @@ var (
 	Periodics bool
 	// Aws Market place product code
 	MarketPlaceProductCode string
+	// AnomalyDetectionDefaultDetector is the anomaly detection algorithm used for the AWS accounts which did not select one.
+	AnomalyDetectionDefaultDetector string
+	// AnomalyDetectionDimensions are the dimensions on which anomalies are detected. Example: "product,usageType,region,linkedAccount".
+	AnomalyDetectionDimensions string
+	// AnomalyDetectionTagKeys are the tag keys whose values are dimensions on which anomalies are detected. Example: "team,project".
+	AnomalyDetectionTagKeys string
 	// AnomalyDetectionBollingerBandPeriod is the period in day used to generate the upper band.
 	AnomalyDetectionBollingerBandPeriod int
 	// AnomalyDetectionBollingerBandStandardDeviationCoefficient is the coefficient applied to the standard deviation used to generate the upper band.
 	AnomalyDetectionBollingerBandStandardDeviationCoefficient float64
 	// AnomalyDetectionBollingerBandUpperBandCoefficient is the coefficient applied to the upper band.
 	AnomalyDetectionBollingerBandUpperBandCoefficient float64
+	// AnomalyDetectionWeekdaySeasonalWeeks is the number of previous weeks whose same weekday is used to generate the upper band.
+	AnomalyDetectionWeekdaySeasonalWeeks int
+	// AnomalyDetectionWeekdaySeasonalStandardDeviationCoefficient is the coefficient applied to the standard deviation used to generate the upper band.
+	AnomalyDetectionWeekdaySeasonalStandardDeviationCoefficient float64
+	// AnomalyDetectionWeekdaySeasonalUpperBandCoefficient is the coefficient applied to the upper band.
+	AnomalyDetectionWeekdaySeasonalUpperBandCoefficient float64
+	// AnomalyDetectionEwmaAlpha is the weight of the latest cost in the exponentially weighted moving average, between 0 and 1.
+	AnomalyDetectionEwmaAlpha float64
+	// AnomalyDetectionEwmaStandardDeviationCoefficient is the coefficient applied to the standard deviation used to generate the upper band.
+	AnomalyDetectionEwmaStandardDeviationCoefficient float64
+	// AnomalyDetectionEwmaUpperBandCoefficient is the coefficient applied to the upper band.
+	AnomalyDetectionEwmaUpperBandCoefficient float64
+	// AnomalyDetectionMadPeriod is the period in day used to compute the median and the median absolute deviation.
+	AnomalyDetectionMadPeriod int
+	// AnomalyDetectionMadThreshold is the robust z-score a cost has to exceed to be an anomaly.
+	AnomalyDetectionMadThreshold float64
+	// AnomalyDetectionMadUpperBandCoefficient is the coefficient applied to the median used to generate the upper band.
+	AnomalyDetectionMadUpperBandCoefficient float64
 	// AnomalyDetectionDisturbanceCleaningMinPercentOfDailyBill is the percentage of the daily bill an anomaly has to exceed. Otherwise, it's considered as a disturbance.
 	AnomalyDetectionDisturbanceCleaningMinPercentOfDailyBill float64
 	// AnomalyDetectionDisturbanceCleaningMinAbsoluteCost is the cost an anomaly has to exceed. Otherwise, it's considered as a disturbance.
@@ -116,11 +162,15 @@ func init() {
 	flag.StringVar(&BackendId, "backend-id", "", "The ID to be sent to clients through the 'X-Backend-ID' field. Generated if left empty.")
 	flag.StringVar(&ReportsBucket, "reports-bucket", "", "The bucket name where the reports are stored. The feature is disabled if left empty.")
 	flag.StringVar(&ReportsCover, "reports-cover", "https://s3-us-west-2.amazonaws.com/trackit-private-artifacts/spreadsheet/introduction.jpg", "The URL where the report cover is stored.")
+	flag.BoolVar(&ReportsEmail, "reports-email", false, "The generated reports should be mailed to the owners of the AWS accounts.")
 	flag.StringVar(&DefaultRole, "default-role", "", "The default role added to new user accounts. No role is added if left empty.")
 	flag.StringVar(&DefaultRoleName, "default-role-name", "Demo", "The pretty name for the default role.")
 	flag.StringVar(&DefaultRoleExternal, "default-role-external", "defaultroleexternal", "The external ID for the default role.")
 	flag.StringVar(&DefaultRoleBucket, "default-role-bucket", "", "The bucket name for the default role.")
 	flag.StringVar(&DefaultRoleBucketPrefix, "default-role-bucket-prefix", "", "The billing prefix for the default role.")
+	flag.StringVar(&LocalBillRepositories, "local-bill-repositories", "", "The directory holding the local bill repositories, in a subdirectory per AWS account ID holding one subdirectory per bill repository. Local bill repositories are disabled if left empty.")
+	flag.Var(&S3CompatibleEndpoints, "s3-compatible-endpoint", "A host of an S3-compatible service bill repositories may use even if it resolves to a private address. Can be repeated.")
+	flag.StringVar(&BillRepositorySecret, "bill-repository-secret", "trackitdefaultbillrepositorysecret", "The secret used to encrypt the secret access keys of the bill repositories in the database.")
 	flag.StringVar(&EsAuthentication, "es-auth", "basic:elastic:changeme", "The authentication to use to connect to the ElasticSearch database.")
 	flag.Var(&EsAddress, "es-address", "The address of the ElasticSearch database.")
 	flag.StringVar(&RedisAddress, "redis-address", "127.0.0.1:6379", "The address of the Redis database.")
@@ -133,12 +183,27 @@ func init() {
 	flag.StringVar(&SmtpUser, "smtp-user", "", "The user for the SMTP server.")
 	flag.StringVar(&SmtpPassword, "smtp-password", "", "The password for the SMTP server.")
 	flag.StringVar(&SmtpSender, "smtp-sender", "", "The mail address used to send mails.")
+	flag.StringVar(&SmtpTls, "smtp-tls", "starttls", "The way TLS is used with the SMTP server: starttls (required), opportunistic (STARTTLS if available) or implicit (SMTPS).")
+	flag.StringVar(&SmtpCaBundle, "smtp-ca-bundle", "", "The PEM file of the certificate authorities trusted for the SMTP server. The system ones are used if left empty.")
+	flag.BoolVar(&SmtpInsecureSkipVerify, "smtp-insecure-skip-verify", false, "The certificate of the SMTP server should not be verified.")
 	flag.StringVar(&Task, "task", "server", "The task to be run.")
 	flag.BoolVar(&Periodics, "periodics", true, "Periodic jobs should be run by the process.")
 	flag.StringVar(&MarketPlaceProductCode, "market-place-product-code", "productcode", "Aws market place product code.")
+	flag.StringVar(&AnomalyDetectionDefaultDetector, "anomaly-detection-default-detector", "bollinger", "Anomaly detection algorithm used by default: bollinger, weekday-seasonal, ewma or mad.")
+	flag.StringVar(&AnomalyDetectionDimensions, "anomaly-detection-dimensions", "product,usageType,region,linkedAccount", "Comma separated dimensions on which anomalies are detected: product, usageType, region or linkedAccount.")
+	flag.StringVar(&AnomalyDetectionTagKeys, "anomaly-detection-tag-keys", "", "Comma separated tag keys whose values are dimensions on which anomalies are detected.")
 	flag.IntVar(&AnomalyDetectionBollingerBandPeriod, "anomaly-detection-bollinger-band-period", 3, "Period used by the Bollinger Band algorithm.")
 	flag.Float64Var(&AnomalyDetectionBollingerBandStandardDeviationCoefficient, "anomaly-detection-bollinger-band-standard-deviation-coefficient", 3.0, "Coefficient used by the Bollinger Band algorithm to generate the standard deviation.")
 	flag.Float64Var(&AnomalyDetectionBollingerBandUpperBandCoefficient, "anomaly-detection-bollinger-band-upper-band-coefficient", 1.05, "Coefficient used by the Bollinger Band algorithm to generate the upper band.")
+	flag.IntVar(&AnomalyDetectionWeekdaySeasonalWeeks, "anomaly-detection-weekday-seasonal-weeks", 4, "Number of previous weeks used by the weekday seasonal algorithm.")
+	flag.Float64Var(&AnomalyDetectionWeekdaySeasonalStandardDeviationCoefficient, "anomaly-detection-weekday-seasonal-standard-deviation-coefficient", 3.0, "Coefficient used by the weekday seasonal algorithm to generate the standard deviation.")
+	flag.Float64Var(&AnomalyDetectionWeekdaySeasonalUpperBandCoefficient, "anomaly-detection-weekday-seasonal-upper-band-coefficient", 1.05, "Coefficient used by the weekday seasonal algorithm to generate the upper band.")
+	flag.Float64Var(&AnomalyDetectionEwmaAlpha, "anomaly-detection-ewma-alpha", 0.3, "Weight of the latest cost used by the EWMA algorithm.")
+	flag.Float64Var(&AnomalyDetectionEwmaStandardDeviationCoefficient, "anomaly-detection-ewma-standard-deviation-coefficient", 3.0, "Coefficient used by the EWMA algorithm to generate the standard deviation.")
+	flag.Float64Var(&AnomalyDetectionEwmaUpperBandCoefficient, "anomaly-detection-ewma-upper-band-coefficient", 1.05, "Coefficient used by the EWMA algorithm to generate the upper band.")
+	flag.IntVar(&AnomalyDetectionMadPeriod, "anomaly-detection-mad-period", 14, "Period used by the median absolute deviation algorithm.")
+	flag.Float64Var(&AnomalyDetectionMadThreshold, "anomaly-detection-mad-threshold", 3.5, "Robust z-score a cost has to exceed for the median absolute deviation algorithm.")
+	flag.Float64Var(&AnomalyDetectionMadUpperBandCoefficient, "anomaly-detection-mad-upper-band-coefficient", 1.05, "Coefficient used by the median absolute deviation algorithm to generate the upper band.")
 	flag.Float64Var(&AnomalyDetectionDisturbanceCleaningMinPercentOfDailyBill, "anomaly-detection-disturbance-cleaning-min-percent-of-daily-bill", 5.0, "Percentage of the daily bill an anomaly has to exceed.")
 	flag.Float64Var(&AnomalyDetectionDisturbanceCleaningMinAbsoluteCost, "anomaly-detection-disturbance-cleaning-absolute-cost", 20.0, "Absolute cost an anomaly has to exceed.")
 	flag.IntVar(&AnomalyDetectionDisturbanceCleaningHighestSpendingMinRank, "anomaly-detection-disturbance-cleaning-highest-spending-min-rank", 5, "Minimum rank of the service where the anomaly has been detected.")
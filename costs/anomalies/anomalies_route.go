@@ -20,7 +20,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
@@ -29,7 +28,6 @@ import (
 
 	"github.com/trackit/trackit/anomaliesDetection"
 	"github.com/trackit/trackit/cache"
-	"github.com/trackit/trackit/config"
 	"github.com/trackit/trackit/costs/anomalies/anomalyFilters"
 	"github.com/trackit/trackit/costs/anomalies/anomalyType"
 	"github.com/trackit/trackit/db"
@@ -43,16 +41,19 @@ import (
 type (
 	// esProductAnomalyTypedResult is used to store the raw ElasticSearch response.
 	esProductAnomalyTypedResult struct {
-		Id        string `json:"-"`
-		Account   string `json:"account"`
-		Date      string `json:"date"`
-		Product   string `json:"product"`
-		Abnormal  bool   `json:"abnormal"`
-		Recurrent bool   `json:"recurrent"`
-		Cost      struct {
+		Id             string `json:"-"`
+		Account        string `json:"account"`
+		Date           string `json:"date"`
+		Product        string `json:"product"`
+		Dimension      string `json:"dimension"`
+		DimensionValue string `json:"dimensionValue"`
+		Abnormal       bool   `json:"abnormal"`
+		Recurrent      bool   `json:"recurrent"`
+		Cost           struct {
 			Value       float64 `json:"value"`
 			MaxExpected float64 `json:"maxExpected"`
 		} `json:"cost"`
+		Detector anomalyType.Detector `json:"detector"`
 	}
 )
 
@@ -61,6 +62,12 @@ var anomalyQueryArgs = []routes.QueryArg{
 	routes.AwsAccountsOptionalQueryArg,
 	routes.DateBeginQueryArg,
 	routes.DateEndQueryArg,
+	routes.QueryArg{
+		Name:        "dimension",
+		Description: "Dimension of the anomalies: product (default), usageType, region, linkedAccount or tag:<key>.",
+		Type:        routes.QueryArgString{},
+		Optional:    true,
+	},
 }
 
 func init() {
@@ -85,16 +92,23 @@ func init() {
 // be returned, but instead of having a 500 status code, it will return the provided status code
 // with empty data
 func makeElasticSearchRequest(ctx context.Context, parsedParams anomalyType.AnomalyEsQueryParams) (*elastic.SearchResult, int, error) {
-	l := jsonlog.LoggerFromContextOrDefault(ctx)
 	index := strings.Join(parsedParams.IndexList, ",")
 	searchService := getElasticSearchParams(
 		parsedParams.AccountList,
 		parsedParams.DateBegin,
 		parsedParams.DateEnd,
+		parsedParams.Dimension,
 		es.Client,
 		index,
 		parsedParams.AnomalyType,
 	)
+	return doElasticSearchRequest(ctx, searchService, index)
+}
+
+// doElasticSearchRequest runs the request on index and handles its errors
+// as described in makeElasticSearchRequest.
+func doElasticSearchRequest(ctx context.Context, searchService *elastic.SearchService, index string) (*elastic.SearchResult, int, error) {
+	l := jsonlog.LoggerFromContextOrDefault(ctx)
 	res, err := searchService.Do(ctx)
 	if err != nil {
 		if elastic.IsNotFound(err) {
@@ -129,24 +143,26 @@ func getSnoozedAnomalies(userId int, tx *sql.Tx) (map[string]bool, error) {
 	}
 }
 
-// getAnomalyLevel get anomaly level depending on their cost.
-func getAnomalyLevel(typedDocument esProductAnomalyTypedResult) (int, string) {
+// getAnomalyLevel get anomaly level depending on their cost and the levels
+// of the settings.
+func getAnomalyLevel(typedDocument esProductAnomalyTypedResult, settings anomalies.Settings) (int, string) {
 	if !typedDocument.Abnormal {
 		return 0, ""
 	}
-	prettyLevels := strings.Split(config.AnomalyDetectionPrettyLevels, ",")
-	percent := (typedDocument.Cost.Value * 100) / typedDocument.Cost.MaxExpected
-	levels := strings.Split(config.AnomalyDetectionLevels, ",")
-	for i, level := range levels[1:] {
-		l, _ := strconv.ParseFloat(level, 64)
-		if percent < l {
-			return i, prettyLevels[i]
-		}
+	return settings.Level(typedDocument.Cost.Value, typedDocument.Cost.MaxExpected)
+}
+
+// getValue returns the value of the dimension of an anomaly.
+// The anomalies ingested before the other dimensions were added have no
+// dimension and are product anomalies.
+func (typedDocument esProductAnomalyTypedResult) getValue() string {
+	if typedDocument.Dimension == "" {
+		return typedDocument.Product
 	}
-	return len(levels) - 1, prettyLevels[len(levels)-1]
+	return typedDocument.DimensionValue
 }
 
-func formatAnomaliesData(raw *elastic.SearchResult, snoozedAnomalies map[string]bool, ctx context.Context) (anomalyType.AnomaliesDetectionResponse, error) {
+func formatAnomaliesData(raw *elastic.SearchResult, snoozedAnomalies map[string]bool, states map[string]anomalyType.AnomalyTriage, settings map[string]anomalies.Settings, ctx context.Context) (anomalyType.AnomaliesDetectionResponse, error) {
 	logger := jsonlog.LoggerFromContextOrDefault(ctx)
 	res := make(anomalyType.AnomaliesDetectionResponse)
 	for i := range raw.Hits.Hits {
@@ -159,12 +175,21 @@ func formatAnomaliesData(raw *elastic.SearchResult, snoozedAnomalies map[string]
 		if _, ok := res[typedDocument.Account]; !ok {
 			res[typedDocument.Account] = make(anomalyType.ProductAnomalies)
 		}
-		if _, ok := res[typedDocument.Account][typedDocument.Product]; !ok {
-			res[typedDocument.Account][typedDocument.Product] = make([]anomalyType.ProductAnomaly, 0)
+		value := typedDocument.getValue()
+		if _, ok := res[typedDocument.Account][value]; !ok {
+			res[typedDocument.Account][value] = make([]anomalyType.ProductAnomaly, 0)
+		}
+		accountSettings, ok := settings[typedDocument.Account]
+		if !ok {
+			accountSettings = anomalies.DefaultSettings()
+		}
+		level, prettyLevel := getAnomalyLevel(typedDocument, accountSettings)
+		state, ok := states[typedDocument.Id]
+		if !ok {
+			state.State = anomalyType.AnomalyStateOpen
 		}
-		level, prettyLevel := getAnomalyLevel(typedDocument)
 		if date, err := time.Parse("2006-01-02T15:04:05.000Z", typedDocument.Date); err == nil {
-			res[typedDocument.Account][typedDocument.Product] = append(res[typedDocument.Account][typedDocument.Product], anomalyType.ProductAnomaly{
+			res[typedDocument.Account][value] = append(res[typedDocument.Account][value], anomalyType.ProductAnomaly{
 				Id:          typedDocument.Id,
 				Date:        date,
 				Cost:        typedDocument.Cost.Value,
@@ -173,8 +198,11 @@ func formatAnomaliesData(raw *elastic.SearchResult, snoozedAnomalies map[string]
 				Recurrent:   typedDocument.Recurrent,
 				Filtered:    false,
 				Snoozed:     snoozedAnomalies[typedDocument.Id],
+				Detector:    typedDocument.Detector,
 				Level:       level,
 				PrettyLevel: prettyLevel,
+				State:       state.State,
+				Assignee:    state.Assignee,
 			})
 		}
 	}
@@ -232,10 +260,17 @@ func getAnomaliesData(request *http.Request, a routes.Arguments) (int, interface
 		AccountList: []string{},
 		DateBegin:   a[anomalyQueryArgs[1]].(time.Time),
 		DateEnd:     a[anomalyQueryArgs[2]].(time.Time).Add(time.Hour*time.Duration(23) + time.Minute*time.Duration(59) + time.Second*time.Duration(59)),
+		Dimension:   anomalies.DimensionProduct,
 	}
 	if a[anomalyQueryArgs[0]] != nil {
 		parsedParams.AccountList = a[anomalyQueryArgs[0]].([]string)
 	}
+	if a[anomalyQueryArgs[3]] != nil {
+		parsedParams.Dimension = a[anomalyQueryArgs[3]].(string)
+		if !anomalies.IsDimension(parsedParams.Dimension) {
+			return http.StatusBadRequest, fmt.Errorf("Unknown dimension: %s.", parsedParams.Dimension)
+		}
+	}
 	tx := a[db.Transaction].(*sql.Tx)
 	accountsAndIndexes, returnCode, err := es.GetAccountsAndIndexes(parsedParams.AccountList, user, tx, anomalies.IndexPrefixAnomaliesDetection)
 	if err != nil {
@@ -256,7 +291,15 @@ func getAnomaliesData(request *http.Request, a routes.Arguments) (int, interface
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
-	res, err := formatAnomaliesData(raw, snoozedAnomalies, request.Context())
+	states, err := getAnomaliesStates(tx, user, parsedParams.AccountList)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	settings, err := getAccountsSettings(tx, user, parsedParams.AccountList)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	res, err := formatAnomaliesData(raw, snoozedAnomalies, states, settings, request.Context())
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
@@ -0,0 +1,344 @@
+package anomalyFilters
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+)
+
+type (
+	// expression will only show entries matching
+	// the given boolean expression.
+	// Comparisons between a field and a value can be combined
+	// with "and", "or", "not" and parentheses.
+	// Fields are product (string), cost, expected_cost,
+	// level, week_day and month_day (numbers).
+	// Operators are =, !=, <, <=, > and >=, strings only
+	// support = and != and are compared case-insensitively.
+	//
+	// Format (string):
+	// "(product = \"AmazonEC2\" and level >= 2) or cost > 500"
+	expression struct{}
+
+	// exprToken is a token produced by the lexer of the expressions.
+	exprToken struct {
+		kind  exprTokenKind
+		value string
+		pos   int
+	}
+
+	exprTokenKind int
+
+	// exprNode is a node of a parsed expression.
+	exprNode interface {
+		eval(an anomalyType.ProductAnomaly, product string) bool
+	}
+
+	exprAnd struct{ left, right exprNode }
+	exprOr  struct{ left, right exprNode }
+	exprNot struct{ operand exprNode }
+
+	// exprComparison compares a field of an anomaly to a value.
+	exprComparison struct {
+		field    exprField
+		operator string
+		number   float64
+		str      string
+	}
+
+	// exprField describes a field which can be used in an expression.
+	// Exactly one of number and str is set.
+	exprField struct {
+		name   string
+		number func(an anomalyType.ProductAnomaly) float64
+		str    func(product string) string
+	}
+
+	// exprParser is a recursive descent parser for the expressions.
+	exprParser struct {
+		tokens []exprToken
+		cur    int
+	}
+)
+
+const (
+	exprTokenEnd exprTokenKind = iota
+	exprTokenIdent
+	exprTokenNumber
+	exprTokenString
+	exprTokenOperator
+	exprTokenLeftParen
+	exprTokenRightParen
+)
+
+var exprFields = map[string]exprField{
+	"product": {name: "product", str: func(product string) string { return product }},
+	"cost": {name: "cost", number: func(an anomalyType.ProductAnomaly) float64 {
+		return an.Cost
+	}},
+	"expected_cost": {name: "expected_cost", number: func(an anomalyType.ProductAnomaly) float64 {
+		return an.UpperBand
+	}},
+	"level": {name: "level", number: func(an anomalyType.ProductAnomaly) float64 {
+		return float64(an.Level)
+	}},
+	"week_day": {name: "week_day", number: func(an anomalyType.ProductAnomaly) float64 {
+		return float64((an.Date.Weekday() + 6) % 7)
+	}},
+	"month_day": {name: "month_day", number: func(an anomalyType.ProductAnomaly) float64 {
+		return float64(an.Date.Day())
+	}},
+}
+
+func init() {
+	registerFilter("expression", expression{})
+}
+
+// valid verifies the validity of the data
+func (f expression) valid(data interface{}) error {
+	if typed, ok := data.(string); !ok {
+		return fmt.Errorf("%s: not a string", filtersName[f])
+	} else if _, err := parseExpression(typed); err != nil {
+		return fmt.Errorf("%s: %s", filtersName[f], err.Error())
+	}
+	return nil
+}
+
+// apply applies the filter to the anomaly and returns the result.
+func (f expression) apply(data interface{}, an anomalyType.ProductAnomaly, product string) bool {
+	if typed, ok := data.(string); !ok {
+	} else if node, err := parseExpression(typed); err != nil {
+	} else {
+		return !node.eval(an, product)
+	}
+	return false
+}
+
+// parseExpression parses an expression and returns its root node.
+func parseExpression(expr string) (exprNode, error) {
+	tokens, err := lexExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := exprParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	} else if tok := p.peek(); tok.kind != exprTokenEnd {
+		return nil, fmt.Errorf("unexpected \"%s\" at position %d", tok.value, tok.pos)
+	}
+	return node, nil
+}
+
+// lexExpression splits an expression into tokens.
+func lexExpression(expr string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, exprToken{exprTokenLeftParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, exprToken{exprTokenRightParen, ")", i})
+			i++
+		case c == '=':
+			tokens = append(tokens, exprToken{exprTokenOperator, "=", i})
+			i++
+		case c == '!' || c == '<' || c == '>':
+			op := string(c)
+			if i+1 < len(expr) && expr[i+1] == '=' {
+				op += "="
+			} else if c == '!' {
+				return nil, fmt.Errorf("unexpected \"!\" at position %d", i)
+			}
+			tokens = append(tokens, exprToken{exprTokenOperator, op, i})
+			i += len(op)
+		case c == '"':
+			end := i + 1
+			for end < len(expr) && expr[end] != '"' {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			value, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d", i)
+			}
+			tokens = append(tokens, exprToken{exprTokenString, value, i})
+			i = end + 1
+		case c == '-' || c == '.' || unicode.IsDigit(c):
+			end := i + 1
+			for end < len(expr) && (expr[end] == '.' || unicode.IsDigit(rune(expr[end]))) {
+				end++
+			}
+			tokens = append(tokens, exprToken{exprTokenNumber, expr[i:end], i})
+			i = end
+		case c == '_' || unicode.IsLetter(c):
+			end := i + 1
+			for end < len(expr) && (expr[end] == '_' || unicode.IsLetter(rune(expr[end])) || unicode.IsDigit(rune(expr[end]))) {
+				end++
+			}
+			tokens = append(tokens, exprToken{exprTokenIdent, expr[i:end], i})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected \"%c\" at position %d", c, i)
+		}
+	}
+	return append(tokens, exprToken{exprTokenEnd, "end of expression", len(expr)}), nil
+}
+
+// peek returns the current token without consuming it.
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.cur]
+}
+
+// next consumes and returns the current token.
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.cur]
+	if tok.kind != exprTokenEnd {
+		p.cur++
+	}
+	return tok
+}
+
+// isKeyword checks if the current token is the given keyword.
+func (p *exprParser) isKeyword(keyword string) bool {
+	tok := p.peek()
+	return tok.kind == exprTokenIdent && strings.EqualFold(tok.value, keyword)
+}
+
+// parseOr parses a sequence of operands separated by "or".
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.isKeyword("or") {
+		p.next()
+		var right exprNode
+		if right, err = p.parseAnd(); err == nil {
+			left = exprOr{left, right}
+		}
+	}
+	return left, err
+}
+
+// parseAnd parses a sequence of operands separated by "and".
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	for err == nil && p.isKeyword("and") {
+		p.next()
+		var right exprNode
+		if right, err = p.parseNot(); err == nil {
+			left = exprAnd{left, right}
+		}
+	}
+	return left, err
+}
+
+// parseNot parses an operand optionally preceded by "not".
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.isKeyword("not") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return exprNot{operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses a parenthesized expression or a comparison.
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	if p.peek().kind == exprTokenLeftParen {
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		} else if tok := p.next(); tok.kind != exprTokenRightParen {
+			return nil, fmt.Errorf("expected \")\" at position %d", tok.pos)
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses a comparison between a field and a value.
+func (p *exprParser) parseComparison() (exprNode, error) {
+	tok := p.next()
+	if tok.kind != exprTokenIdent {
+		return nil, fmt.Errorf("expected a field at position %d", tok.pos)
+	}
+	field, ok := exprFields[strings.ToLower(tok.value)]
+	if !ok {
+		return nil, fmt.Errorf("unknown field \"%s\" at position %d", tok.value, tok.pos)
+	}
+	op := p.next()
+	if op.kind != exprTokenOperator {
+		return nil, fmt.Errorf("expected an operator at position %d", op.pos)
+	}
+	value := p.next()
+	comparison := exprComparison{field: field, operator: op.value}
+	if field.str != nil {
+		if value.kind != exprTokenString {
+			return nil, fmt.Errorf("%s: expected a string at position %d", field.name, value.pos)
+		} else if op.value != "=" && op.value != "!=" {
+			return nil, fmt.Errorf("%s: operator \"%s\" not supported on strings", field.name, op.value)
+		}
+		comparison.str = value.value
+	} else if value.kind != exprTokenNumber {
+		return nil, fmt.Errorf("%s: expected a number at position %d", field.name, value.pos)
+	} else if number, err := strconv.ParseFloat(value.value, 64); err != nil {
+		return nil, fmt.Errorf("%s: invalid number at position %d", field.name, value.pos)
+	} else {
+		comparison.number = number
+	}
+	return comparison, nil
+}
+
+func (n exprAnd) eval(an anomalyType.ProductAnomaly, product string) bool {
+	return n.left.eval(an, product) && n.right.eval(an, product)
+}
+
+func (n exprOr) eval(an anomalyType.ProductAnomaly, product string) bool {
+	return n.left.eval(an, product) || n.right.eval(an, product)
+}
+
+func (n exprNot) eval(an anomalyType.ProductAnomaly, product string) bool {
+	return !n.operand.eval(an, product)
+}
+
+func (n exprComparison) eval(an anomalyType.ProductAnomaly, product string) bool {
+	if n.field.str != nil {
+		equal := strings.EqualFold(n.field.str(product), n.str)
+		return equal == (n.operator == "=")
+	}
+	return compareNumbers(n.field.number(an), n.operator, n.number)
+}
+
+// compareNumbers compares two numbers with the given operator.
+func compareNumbers(a float64, operator string, b float64) bool {
+	switch operator {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package anomalyFilters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+)
+
+func TestExpressionValid(t *testing.T) {
+	valid := []interface{}{
+		`(product = "AmazonEC2" and level >= 2) or cost > 500`,
+		`not (week_day = 5 or week_day = 6) AND month_day != 1`,
+		`expected_cost <= 10.5 and not not product != "AmazonES"`,
+	}
+	invalid := []interface{}{
+		42.0,
+		``,
+		`cost >`,
+		`cost > "500"`,
+		`product > "AmazonEC2"`,
+		`product = 2`,
+		`unknown = 2`,
+		`(cost > 500`,
+		`cost > 500)`,
+		`cost > 500 level = 2`,
+		`product = "AmazonEC2`,
+		`cost ! 500`,
+	}
+	for _, data := range valid {
+		if err := Valid("expression", data); err != nil {
+			t.Errorf("Expression %v should be valid, got error %s.", data, err.Error())
+		}
+	}
+	for _, data := range invalid {
+		if err := Valid("expression", data); err == nil {
+			t.Errorf("Expression %v should be invalid.", data)
+		}
+	}
+}
+
+func TestExpressionApply(t *testing.T) {
+	an := anomalyType.ProductAnomaly{
+		Date:      time.Date(2019, time.May, 11, 0, 0, 0, 0, time.UTC),
+		Cost:      300,
+		UpperBand: 120,
+		Level:     2,
+	}
+	cases := []struct {
+		expr     string
+		product  string
+		filtered bool
+	}{
+		{`(product = "AmazonEC2" and level >= 2) or cost > 500`, "AmazonEC2", false},
+		{`(product = "AmazonEC2" and level >= 2) or cost > 500`, "AmazonES", true},
+		{`product = "AmazonEC2" and (level >= 3 or cost > 500)`, "AmazonEC2", true},
+		{`product = "amazonec2" and expected_cost < cost`, "AmazonEC2", false},
+		{`not product = "AmazonEC2"`, "AmazonEC2", true},
+		{`level = 3 or level = 2 and cost < 100`, "AmazonEC2", true},
+		{`week_day = 5 and month_day = 11`, "AmazonEC2", false},
+	}
+	for _, c := range cases {
+		if res := (expression{}).apply(c.expr, an, c.product); res != c.filtered {
+			t.Errorf("Expression %s on %s: expected filtered to be %t, got %t.", c.expr, c.product, c.filtered, res)
+		}
+	}
+}
@@ -4,6 +4,21 @@ import (
 	"time"
 )
 
+// States of the anomalies in the triage workflow.
+const (
+	AnomalyStateOpen         = "open"
+	AnomalyStateAcknowledged = "acknowledged"
+	AnomalyStateExpected     = "expected"
+	AnomalyStateResolved     = "resolved"
+)
+
+// Types of the events of the triage history of an anomaly.
+const (
+	AnomalyEventState      = "state"
+	AnomalyEventAssignment = "assignment"
+	AnomalyEventComment    = "comment"
+)
+
 type (
 	// AnomalyEsQueryParams will store the parsed query params
 	AnomalyEsQueryParams struct {
@@ -12,6 +27,37 @@ type (
 		AccountList []string
 		IndexList   []string
 		AnomalyType string
+		Dimension   string
+	}
+
+	// Detector describes the anomaly detection algorithm which analysed a cost
+	// and the parameters it used.
+	Detector struct {
+		Name       string             `json:"name"`
+		Parameters map[string]float64 `json:"parameters"`
+	}
+
+	// Cleaning contains the thresholds used to clear the anomalies which are
+	// disturbances or recurrent expenses.
+	Cleaning struct {
+		MinPercentOfDailyBill  float64 `json:"minPercentOfDailyBill"`
+		MinAbsoluteCost        float64 `json:"minAbsoluteCost"`
+		HighestSpendingMinRank int     `json:"highestSpendingMinRank"`
+		RecurrenceThreshold    float64 `json:"recurrenceThreshold"`
+	}
+
+	// Settings overrides the anomaly detection settings for a user or an AWS
+	// account. Unset settings are inherited from the user, then from config.
+	// DetectorParameters keys are the names of the detectors.
+	Settings struct {
+		DetectorParameters     map[string]map[string]float64 `json:"detectorParameters,omitempty"`
+		MinPercentOfDailyBill  *float64                      `json:"minPercentOfDailyBill,omitempty"`
+		MinAbsoluteCost        *float64                      `json:"minAbsoluteCost,omitempty"`
+		HighestSpendingMinRank *int                          `json:"highestSpendingMinRank,omitempty"`
+		RecurrenceThreshold    *float64                      `json:"recurrenceThreshold,omitempty"`
+		Levels                 []float64                     `json:"levels,omitempty"`
+		PrettyLevels           []string                      `json:"prettyLevels,omitempty"`
+		EmailingMinLevel       *int                          `json:"emailingMinLevel,omitempty"`
 	}
 
 	// ProductAnomaly represents one anomaly returned.
@@ -26,16 +72,78 @@ type (
 		Snoozed     bool      `json:"snoozed"`
 		Level       int       `json:"level"`
 		PrettyLevel string    `json:"pretty_level"`
+		Detector    Detector  `json:"detector"`
+		State       string    `json:"state"`
+		Assignee    string    `json:"assignee,omitempty"`
 	}
 
 	// ProductAnomalies is used to respond to the request.
-	// Key is a product name.
+	// Key is a value of the requested dimension, a product name by default.
 	ProductAnomalies map[string][]ProductAnomaly
 
 	// AnomaliesDetectionResponse is used to respond to the request.
 	// Key is an AWS Account Identity.
 	AnomaliesDetectionResponse map[string]ProductAnomalies
 
+	// AnomalyContributor is a value of a criterion (e.g. a usage type) whose
+	// cost changed between the baseline and the day of an anomaly.
+	// BaselineCost is the average daily cost during the baseline.
+	AnomalyContributor struct {
+		Key          string  `json:"key,omitempty"`
+		Value        string  `json:"value"`
+		Cost         float64 `json:"cost"`
+		BaselineCost float64 `json:"baseline_cost"`
+		Delta        float64 `json:"delta"`
+	}
+
+	// AnomalyExplanation is used to respond to the explain request.
+	// Contributors keys are the criteria: usageType, resource, region and tag.
+	AnomalyExplanation struct {
+		Id            string                          `json:"id"`
+		Account       string                          `json:"account"`
+		Date          time.Time                       `json:"date"`
+		Dimension     string                          `json:"dimension"`
+		Value         string                          `json:"value"`
+		Cost          float64                         `json:"cost"`
+		BaselineCost  float64                         `json:"baseline_cost"`
+		BaselineBegin time.Time                       `json:"baseline_begin"`
+		BaselineEnd   time.Time                       `json:"baseline_end"`
+		Contributors  map[string][]AnomalyContributor `json:"contributors"`
+	}
+
+	// AnomaliesBacktest is used to respond to the backtest request.
+	// Anomalies keys are the dimensions, then the values of the dimensions.
+	AnomaliesBacktest struct {
+		Account   string                      `json:"account"`
+		DateBegin time.Time                   `json:"dateBegin"`
+		DateEnd   time.Time                   `json:"dateEnd"`
+		Detector  Detector                    `json:"detector"`
+		Cleaning  Cleaning                    `json:"cleaning"`
+		Anomalies map[string]ProductAnomalies `json:"anomalies"`
+	}
+
+	// AnomalyEvent is an event of the triage history of an anomaly: a change
+	// of state, an assignment or a comment. User is the email of the user who
+	// made the change.
+	AnomalyEvent struct {
+		Date     time.Time `json:"date"`
+		User     string    `json:"user"`
+		Type     string    `json:"type"`
+		State    string    `json:"state,omitempty"`
+		Assignee string    `json:"assignee,omitempty"`
+		Comment  string    `json:"comment,omitempty"`
+	}
+
+	// AnomalyTriage is used to respond to the triage request.
+	// It is shared between all the users of the AWS account of the anomaly.
+	AnomalyTriage struct {
+		Id       string         `json:"id"`
+		Account  string         `json:"account"`
+		State    string         `json:"state"`
+		Assignee string         `json:"assignee,omitempty"`
+		History  []AnomalyEvent `json:"history"`
+	}
+
 	// Filter represents a filter.
 	// A filter contains the rule and the associated data.
 	Filter struct {
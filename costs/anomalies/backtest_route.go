@@ -0,0 +1,173 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/anomaliesDetection"
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/routes"
+	"github.com/trackit/trackit/users"
+)
+
+// maxBacktestMonths is the maximum number of months of a backtest.
+const maxBacktestMonths = 6
+
+type (
+	// backtestBody is the body expected when backtesting the anomaly
+	// detection. The detector, its parameters and the cleaning thresholds
+	// which are not set are the ones of the settings of the AWS account.
+	backtestBody struct {
+		Detector   string             `json:"detector"`
+		Parameters map[string]float64 `json:"parameters"`
+		Cleaning   cleaningBody       `json:"cleaning"`
+	}
+
+	// cleaningBody contains the cleaning thresholds to override.
+	cleaningBody struct {
+		MinPercentOfDailyBill  *float64 `json:"minPercentOfDailyBill"`
+		MinAbsoluteCost        *float64 `json:"minAbsoluteCost"`
+		HighestSpendingMinRank *int     `json:"highestSpendingMinRank"`
+		RecurrenceThreshold    *float64 `json:"recurrenceThreshold"`
+	}
+)
+
+var exampleBacktestBody = backtestBody{
+	Detector: anomalies.DetectorBollinger,
+	Parameters: map[string]float64{
+		"period":                       7,
+		"standardDeviationCoefficient": 2.5,
+	},
+}
+
+func init() {
+	routes.MethodMuxer{
+		http.MethodPost: routes.H(postAnomaliesBacktest).With(
+			db.RequestTransaction{Db: db.Db},
+			users.RequireAuthenticatedUser{users.ViewerAsParent},
+			routes.QueryArgs{
+				routes.AwsAccountIdQueryArg,
+				routes.DateBeginQueryArg,
+				routes.DateEndQueryArg,
+			},
+			aws.RequireAwsAccountId{},
+			routes.RequestContentType{"application/json"},
+			routes.RequestBody{exampleBacktestBody},
+			routes.Documentation{
+				Summary:     "backtest the anomaly detection",
+				Description: "Runs the anomaly detection and the cleaning steps on the costs of the AWS account between the dates passed in query args with the detector, parameters and cleaning thresholds of the body, defaulting to the settings of the AWS account, and responds with the anomalies it would have produced. Nothing is saved.",
+			},
+		),
+	}.H().Register("/costs/anomalies/backtest")
+}
+
+// getCleaning overrides the cleaning thresholds with the ones of the body.
+func (body cleaningBody) getCleaning(cleaning anomalyType.Cleaning) (anomalyType.Cleaning, error) {
+	if body.MinPercentOfDailyBill != nil {
+		cleaning.MinPercentOfDailyBill = *body.MinPercentOfDailyBill
+	}
+	if body.MinAbsoluteCost != nil {
+		cleaning.MinAbsoluteCost = *body.MinAbsoluteCost
+	}
+	if body.HighestSpendingMinRank != nil {
+		cleaning.HighestSpendingMinRank = *body.HighestSpendingMinRank
+	}
+	if body.RecurrenceThreshold != nil {
+		cleaning.RecurrenceThreshold = *body.RecurrenceThreshold
+	}
+	if cleaning.MinPercentOfDailyBill < 0 || cleaning.MinAbsoluteCost < 0 || cleaning.RecurrenceThreshold < 0 {
+		return cleaning, errors.New("cleaning thresholds must be positive")
+	} else if cleaning.HighestSpendingMinRank <= 0 {
+		return cleaning, errors.New("highest spending min rank must be strictly positive")
+	}
+	return cleaning, nil
+}
+
+// setBacktestLevels sets the level of the backtested anomalies with the
+// levels of the settings.
+func setBacktestLevels(res map[string]anomalyType.ProductAnomalies, settings anomalies.Settings) {
+	for dimension := range res {
+		for value := range res[dimension] {
+			for i, an := range res[dimension][value] {
+				var typedDocument esProductAnomalyTypedResult
+				typedDocument.Abnormal = an.Abnormal
+				typedDocument.Cost.Value = an.Cost
+				typedDocument.Cost.MaxExpected = an.UpperBand
+				res[dimension][value][i].Level, res[dimension][value][i].PrettyLevel = getAnomalyLevel(typedDocument, settings)
+			}
+		}
+	}
+}
+
+// postAnomaliesBacktest checks the request and returns the anomalies the
+// detection would have produced.
+func postAnomaliesBacktest(request *http.Request, a routes.Arguments) (int, interface{}) {
+	l := jsonlog.LoggerFromContextOrDefault(request.Context())
+	account := a[aws.AwsAccountSelection].(aws.AwsAccount)
+	begin := a[routes.DateBeginQueryArg].(time.Time)
+	end := a[routes.DateEndQueryArg].(time.Time).Add(time.Hour*time.Duration(23) + time.Minute*time.Duration(59) + time.Second*time.Duration(59))
+	if !begin.Before(end) {
+		return http.StatusBadRequest, errors.New("the begin date must be before the end date")
+	} else if end.After(begin.AddDate(0, maxBacktestMonths, 0)) {
+		return http.StatusBadRequest, fmt.Errorf("the backtested period cannot exceed %d months", maxBacktestMonths)
+	}
+	var body backtestBody
+	routes.MustRequestBody(a, &body)
+	settings, err := anomalies.GetSettings(a[db.Transaction].(*sql.Tx), account)
+	if err != nil {
+		l.Error("Failed to get anomaly detection settings.", map[string]interface{}{
+			"awsAccount": account.Id,
+			"error":      err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("failed to backtest the anomaly detection")
+	}
+	if body.Detector == "" {
+		body.Detector = account.AnomalyDetector
+	}
+	detector, err := settings.Detector(body.Detector, body.Parameters)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("Body is invalid (%s).", err.Error())
+	}
+	cleaning, err := body.Cleaning.getCleaning(settings.Cleaning)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("Body is invalid (%s).", err.Error())
+	}
+	res, err := anomalies.Backtest(request.Context(), account, begin, end, detector, cleaning)
+	if err != nil {
+		l.Error("Failed to backtest the anomaly detection.", map[string]interface{}{
+			"awsAccount": account.Id,
+			"error":      err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("failed to backtest the anomaly detection")
+	}
+	setBacktestLevels(res, settings)
+	return http.StatusOK, anomalyType.AnomaliesBacktest{
+		Account:   account.AwsIdentity,
+		DateBegin: begin,
+		DateEnd:   end,
+		Detector:  detector.Description(),
+		Cleaning:  cleaning,
+		Anomalies: res,
+	}
+}
@@ -18,13 +18,25 @@ import (
 	"time"
 
 	"github.com/olivere/elastic"
+
+	"github.com/trackit/trackit/anomaliesDetection"
 )
 
 const (
 	// queryMaxSize is the maximum size of an Elastic Search Query
 	queryMaxSize = 10000
+	// aggregationMaxSize is the maximum size of an Elastic Search Aggregation
+	aggregationMaxSize = 0x7FFFFFFF
 )
 
+// explainCriteria maps the criteria of an anomaly explanation, except the
+// tags, to the line item fields they aggregate.
+var explainCriteria = map[string]string{
+	"usageType": "usageType",
+	"resource":  "resourceId",
+	"region":    "region",
+}
+
 // createQueryAccountFilter creates and return a new *elastic.TermsQuery on the accountList array
 func createQueryAccountFilter(accountList []string) *elastic.TermsQuery {
 	accountListFormatted := make([]interface{}, len(accountList))
@@ -41,12 +53,26 @@ func createQueryTimeRange(durationBegin time.Time, durationEnd time.Time) *elast
 		From(durationBegin).To(durationEnd)
 }
 
+// createQueryDimensionFilter creates and return a new elastic.Query on the dimension.
+// The anomalies ingested before the other dimensions were added have no
+// dimension and are product anomalies.
+func createQueryDimensionFilter(dimension string) elastic.Query {
+	if dimension == anomalies.DimensionProduct {
+		return elastic.NewBoolQuery().
+			Should(elastic.NewTermQuery("dimension", dimension)).
+			Should(elastic.NewBoolQuery().MustNot(elastic.NewExistsQuery("dimension"))).
+			MinimumNumberShouldMatch(1)
+	}
+	return elastic.NewTermQuery("dimension", dimension)
+}
+
 // getElasticSearchParams is used to construct an ElasticSearch *elastic.SearchService
 // used to retrieve the anomalies.
 // It takes as parameters :
 // 	- accountList []string : A slice of string representing aws account number
 //	- durationBeing time.Time : A time.Time struct representing the begining of the time range in the query
 //	- durationEnd time.Time : A time.Time struct representing the end of the time range in the query
+//	- dimension string : The dimension of the anomalies, e.g. "product" or "tag:team"
 //	- client *elastic.Client : an instance of *elastic.Client that represent an Elastic Search client.
 //	- index string : The Elastic Search index on which to execute the query.
 // This function excepts arguments passed to it to be sanitize. If they are not, the following cases will make
@@ -54,12 +80,73 @@ func createQueryTimeRange(durationBegin time.Time, durationEnd time.Time) *elast
 //	- If the client is nil or malconfigured, it will crash
 //	- If the index is not an index present in the ES, it will crash
 func getElasticSearchParams(accountList []string, durationBegin time.Time,
-	durationEnd time.Time, client *elastic.Client, index string, anomalyType string) *elastic.SearchService {
+	durationEnd time.Time, dimension string, client *elastic.Client, index string, anomalyType string) *elastic.SearchService {
 	query := elastic.NewBoolQuery()
 	if len(accountList) > 0 {
 		query = query.Filter(createQueryAccountFilter(accountList))
 	}
 	query = query.Filter(createQueryTimeRange(durationBegin, durationEnd))
+	query = query.Filter(createQueryDimensionFilter(dimension))
 	search := client.Search().Index(index).Type(anomalyType).Size(queryMaxSize).Sort("date", false).Query(query)
 	return search
 }
+
+// getAnomalyByIdElasticSearchParams is used to construct an ElasticSearch *elastic.SearchService
+// used to retrieve an anomaly by its id.
+// It takes as parameters :
+// 	- accountList []string : A slice of string representing the aws account numbers the anomaly can belong to
+//	- id string : The id of the anomaly
+//	- client *elastic.Client : an instance of *elastic.Client that represent an Elastic Search client.
+//	- index string : The Elastic Search index on which to execute the query.
+func getAnomalyByIdElasticSearchParams(accountList []string, id string,
+	client *elastic.Client, index string, anomalyType string) *elastic.SearchService {
+	query := elastic.NewBoolQuery()
+	query = query.Filter(createQueryAccountFilter(accountList))
+	query = query.Filter(elastic.NewIdsQuery(anomalyType).Ids(id))
+	search := client.Search().Index(index).Type(anomalyType).Size(1).Query(query)
+	return search
+}
+
+// createPeriodsAggregation creates and return a new *elastic.DateRangeAggregation
+// summing the cost of the line items during the baseline and during the day
+// of the anomaly.
+func createPeriodsAggregation(baselineBegin, anomalyDate time.Time) *elastic.DateRangeAggregation {
+	return elastic.NewDateRangeAggregation().Field("usageStartDate").Keyed(true).
+		AddRangeWithKey("baseline", baselineBegin, anomalyDate).
+		AddRangeWithKey("anomaly", anomalyDate, anomalyDate.AddDate(0, 0, 1)).
+		SubAggregation("cost", elastic.NewSumAggregation().Field("unblendedCost"))
+}
+
+// getExplainElasticSearchParams is used to construct an ElasticSearch *elastic.SearchService
+// used to retrieve the cost of every usage type, resource, region and tag value
+// during the baseline and the day of an anomaly.
+// It takes as parameters :
+// 	- lineItemsFilter elastic.Query : The query selecting the line items whose cost was analysed by the anomaly
+//	- anomalyDate time.Time : The day of the anomaly
+//	- baselineDays int : The number of days preceding the anomaly used as baseline
+//	- client *elastic.Client : an instance of *elastic.Client that represent an Elastic Search client.
+//	- index string : The Elastic Search index on which to execute the query. In this context the default value
+//	should be "lineitems"
+// This function excepts arguments passed to it to be sanitize. If they are not, the following cases will make
+// it crash :
+//	- If the client is nil or malconfigured, it will crash
+//	- If the index is not an index present in the ES, it will crash
+func getExplainElasticSearchParams(lineItemsFilter elastic.Query, anomalyDate time.Time, baselineDays int,
+	client *elastic.Client, index string) *elastic.SearchService {
+	baselineBegin := anomalyDate.AddDate(0, 0, -baselineDays)
+	query := elastic.NewBoolQuery()
+	query = query.Filter(lineItemsFilter)
+	query = query.Filter(elastic.NewRangeQuery("usageStartDate").Gte(baselineBegin).Lt(anomalyDate.AddDate(0, 0, 1)))
+	search := client.Search().Index(index).Size(0).Query(query)
+	search.Aggregation("total", createPeriodsAggregation(baselineBegin, anomalyDate))
+	for criterion, field := range explainCriteria {
+		search.Aggregation(criterion, elastic.NewTermsAggregation().Field(field).Size(aggregationMaxSize).
+			SubAggregation("periods", createPeriodsAggregation(baselineBegin, anomalyDate)))
+	}
+	search.Aggregation("tag", elastic.NewNestedAggregation().Path("tags").
+		SubAggregation("keys", elastic.NewTermsAggregation().Field("tags.key").Size(aggregationMaxSize).
+			SubAggregation("values", elastic.NewTermsAggregation().Field("tags.tag").Size(aggregationMaxSize).
+				SubAggregation("lineItems", elastic.NewReverseNestedAggregation().
+					SubAggregation("periods", createPeriodsAggregation(baselineBegin, anomalyDate))))))
+	return search
+}
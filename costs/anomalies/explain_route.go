@@ -0,0 +1,283 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/anomaliesDetection"
+	"github.com/trackit/trackit/aws/s3"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/es"
+	"github.com/trackit/trackit/routes"
+	"github.com/trackit/trackit/users"
+)
+
+const (
+	// defaultExplainBaselineDays is the number of days preceding an anomaly
+	// used as baseline when none is specified.
+	defaultExplainBaselineDays = 14
+	// maxExplainBaselineDays is the maximum number of days of the baseline.
+	maxExplainBaselineDays = 90
+	// defaultExplainLimit is the number of contributors returned by criterion
+	// when none is specified.
+	defaultExplainLimit = 10
+)
+
+type (
+	// esExplainPeriodCost is used to store the raw ElasticSearch response.
+	esExplainPeriodCost struct {
+		Cost struct {
+			Value float64 `json:"value"`
+		} `json:"cost"`
+	}
+
+	// esExplainPeriods is used to store the cost during the baseline and the
+	// day of the anomaly from the raw ElasticSearch response.
+	esExplainPeriods struct {
+		Buckets struct {
+			Baseline esExplainPeriodCost `json:"baseline"`
+			Anomaly  esExplainPeriodCost `json:"anomaly"`
+		} `json:"buckets"`
+	}
+
+	// esExplainCriterion is used to store the raw ElasticSearch response.
+	esExplainCriterion struct {
+		Buckets []struct {
+			Key     string           `json:"key"`
+			Periods esExplainPeriods `json:"periods"`
+		} `json:"buckets"`
+	}
+
+	// esExplainTagCriterion is used to store the raw ElasticSearch response.
+	esExplainTagCriterion struct {
+		Keys struct {
+			Buckets []struct {
+				Key    string `json:"key"`
+				Values struct {
+					Buckets []struct {
+						Key       string `json:"key"`
+						LineItems struct {
+							Periods esExplainPeriods `json:"periods"`
+						} `json:"lineItems"`
+					} `json:"buckets"`
+				} `json:"values"`
+			} `json:"buckets"`
+		} `json:"keys"`
+	}
+)
+
+// explainQueryArgs allows to get required queryArgs params
+var explainQueryArgs = []routes.QueryArg{
+	routes.QueryArg{
+		Name:        "id",
+		Description: "Id of the anomaly to explain",
+		Type:        routes.QueryArgString{},
+		Optional:    false,
+	},
+	routes.QueryArg{
+		Name:        "baseline",
+		Description: "Number of days preceding the anomaly used as baseline, 14 by default",
+		Type:        routes.QueryArgInt{},
+		Optional:    true,
+	},
+	routes.QueryArg{
+		Name:        "limit",
+		Description: "Number of contributors returned by criterion, 10 by default",
+		Type:        routes.QueryArgInt{},
+		Optional:    true,
+	},
+}
+
+func init() {
+	routes.MethodMuxer{
+		http.MethodGet: routes.H(getAnomalyExplanation).With(
+			db.RequestTransaction{Db: db.Db},
+			users.RequireAuthenticatedUser{users.ViewerAsParent},
+			routes.QueryArgs(explainQueryArgs),
+			routes.Documentation{
+				Summary:     "explain a cost anomaly",
+				Description: "Responds with the usage types, resources, regions and tag values which contributed the most to the anomaly whose id is passed in query args, ranked by the difference between their cost the day of the anomaly and their average daily cost during the baseline",
+			},
+		),
+	}.H().Register("/costs/anomalies/explain")
+}
+
+// getAnomalyById returns the anomaly with the id id if it belongs to one of the accounts.
+// It returns nil if there is no such anomaly.
+func getAnomalyById(ctx context.Context, accountsAndIndexes es.AccountsAndIndexes, id string) (*esProductAnomalyTypedResult, int, error) {
+	index := strings.Join(accountsAndIndexes.Indexes, ",")
+	searchService := getAnomalyByIdElasticSearchParams(
+		accountsAndIndexes.Accounts,
+		id,
+		es.Client,
+		index,
+		anomalies.TypeProductAnomaliesDetection,
+	)
+	raw, returnCode, err := doElasticSearchRequest(ctx, searchService, index)
+	if err != nil {
+		if returnCode == http.StatusOK {
+			return nil, http.StatusOK, nil
+		}
+		return nil, returnCode, err
+	} else if len(raw.Hits.Hits) == 0 {
+		return nil, http.StatusOK, nil
+	}
+	var typedDocument esProductAnomalyTypedResult
+	typedDocument.Id = raw.Hits.Hits[0].Id
+	if err := json.Unmarshal(*raw.Hits.Hits[0].Source, &typedDocument); err != nil {
+		jsonlog.LoggerFromContextOrDefault(ctx).Error("Failed to parse elasticsearch document.", err.Error())
+		return nil, http.StatusInternalServerError, err
+	}
+	return &typedDocument, http.StatusOK, nil
+}
+
+// getContributor returns the contributor of a value of a criterion from the
+// costs of its periods.
+func getContributor(key, value string, periods esExplainPeriods, baselineDays int) anomalyType.AnomalyContributor {
+	baselineCost := periods.Buckets.Baseline.Cost.Value / float64(baselineDays)
+	return anomalyType.AnomalyContributor{
+		Key:          key,
+		Value:        value,
+		Cost:         periods.Buckets.Anomaly.Cost.Value,
+		BaselineCost: baselineCost,
+		Delta:        periods.Buckets.Anomaly.Cost.Value - baselineCost,
+	}
+}
+
+// rankContributors sorts the contributors by decreasing delta and keeps the limit first ones.
+func rankContributors(contributors []anomalyType.AnomalyContributor, limit int) []anomalyType.AnomalyContributor {
+	sort.SliceStable(contributors, func(i, j int) bool {
+		return contributors[i].Delta > contributors[j].Delta
+	})
+	if len(contributors) > limit {
+		contributors = contributors[:limit]
+	}
+	return contributors
+}
+
+// formatAnomalyExplanation parses the raw ElasticSearch response and ranks
+// the contributors of every criterion.
+func formatAnomalyExplanation(raw *elastic.SearchResult, explanation anomalyType.AnomalyExplanation, baselineDays, limit int) (anomalyType.AnomalyExplanation, error) {
+	var total esExplainPeriods
+	if err := json.Unmarshal(*raw.Aggregations["total"], &total); err != nil {
+		return explanation, err
+	}
+	explanation.Cost = total.Buckets.Anomaly.Cost.Value
+	explanation.BaselineCost = total.Buckets.Baseline.Cost.Value / float64(baselineDays)
+	for criterion := range explainCriteria {
+		var typedCriterion esExplainCriterion
+		if err := json.Unmarshal(*raw.Aggregations[criterion], &typedCriterion); err != nil {
+			return explanation, err
+		}
+		contributors := make([]anomalyType.AnomalyContributor, 0, len(typedCriterion.Buckets))
+		for _, bucket := range typedCriterion.Buckets {
+			contributors = append(contributors, getContributor("", bucket.Key, bucket.Periods, baselineDays))
+		}
+		explanation.Contributors[criterion] = rankContributors(contributors, limit)
+	}
+	var tags esExplainTagCriterion
+	if err := json.Unmarshal(*raw.Aggregations["tag"], &tags); err != nil {
+		return explanation, err
+	}
+	contributors := make([]anomalyType.AnomalyContributor, 0)
+	for _, key := range tags.Keys.Buckets {
+		for _, value := range key.Values.Buckets {
+			contributors = append(contributors, getContributor(key.Key, value.Key, value.LineItems.Periods, baselineDays))
+		}
+	}
+	explanation.Contributors["tag"] = rankContributors(contributors, limit)
+	return explanation, nil
+}
+
+// getAnomalyExplanation checks the request and returns the top contributors to an anomaly.
+func getAnomalyExplanation(request *http.Request, a routes.Arguments) (int, interface{}) {
+	user := a[users.AuthenticatedUser].(users.User)
+	tx := a[db.Transaction].(*sql.Tx)
+	ctx := request.Context()
+	id := a[explainQueryArgs[0]].(string)
+	baselineDays := defaultExplainBaselineDays
+	if a[explainQueryArgs[1]] != nil {
+		baselineDays = a[explainQueryArgs[1]].(int)
+	}
+	if baselineDays <= 0 || baselineDays > maxExplainBaselineDays {
+		return http.StatusBadRequest, errors.New("The baseline must be between 1 and 90 days.")
+	}
+	limit := defaultExplainLimit
+	if a[explainQueryArgs[2]] != nil {
+		limit = a[explainQueryArgs[2]].(int)
+	}
+	if limit <= 0 {
+		return http.StatusBadRequest, errors.New("The limit must be positive.")
+	}
+	accountsAndIndexes, returnCode, err := es.GetAccountsAndIndexes(nil, user, tx, anomalies.IndexPrefixAnomaliesDetection)
+	if err != nil {
+		return returnCode, err
+	}
+	anomaly, returnCode, err := getAnomalyById(ctx, accountsAndIndexes, id)
+	if err != nil {
+		return returnCode, err
+	} else if anomaly == nil {
+		return http.StatusNotFound, errors.New("Anomaly not found.")
+	}
+	date, err := time.Parse("2006-01-02T15:04:05.000Z", anomaly.Date)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	explanation := anomalyType.AnomalyExplanation{
+		Id:            anomaly.Id,
+		Account:       anomaly.Account,
+		Date:          date,
+		Dimension:     anomaly.Dimension,
+		Value:         anomaly.getValue(),
+		BaselineBegin: date.AddDate(0, 0, -baselineDays),
+		BaselineEnd:   date.AddDate(0, 0, -1),
+		Contributors:  make(map[string][]anomalyType.AnomalyContributor),
+	}
+	if explanation.Dimension == "" {
+		explanation.Dimension = anomalies.DimensionProduct
+	}
+	lineItemsFilter, err := anomalies.GetLineItemsFilter(explanation.Account, explanation.Dimension, explanation.Value)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	lineItemsAccountsAndIndexes, returnCode, err := es.GetAccountsAndIndexes([]string{explanation.Account}, user, tx, s3.IndexPrefixLineItem)
+	if err != nil {
+		return returnCode, err
+	}
+	index := strings.Join(lineItemsAccountsAndIndexes.Indexes, ",")
+	searchService := getExplainElasticSearchParams(lineItemsFilter, date, baselineDays, es.Client, index)
+	raw, returnCode, err := doElasticSearchRequest(ctx, searchService, index)
+	if err != nil {
+		return returnCode, err
+	}
+	explanation, err = formatAnomalyExplanation(raw, explanation, baselineDays, limit)
+	if err != nil {
+		jsonlog.LoggerFromContextOrDefault(ctx).Error("Failed to parse elasticsearch document.", err.Error())
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, explanation
+}
@@ -0,0 +1,63 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/olivere/elastic"
+
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+)
+
+func periodsJson(baseline, anomaly string) string {
+	return `{"buckets":{"baseline":{"cost":{"value":` + baseline + `}},"anomaly":{"cost":{"value":` + anomaly + `}}}}`
+}
+
+func TestFormatAnomalyExplanation(t *testing.T) {
+	aggregations := map[string]string{
+		"total":     periodsJson("100", "40"),
+		"usageType": `{"buckets":[{"key":"BoxUsage","periods":` + periodsJson("80", "12") + `},{"key":"DataTransfer-Out-Bytes","periods":` + periodsJson("20", "28") + `}]}`,
+		"resource":  `{"buckets":[{"key":"i-1","periods":` + periodsJson("40", "4") + `},{"key":"i-2","periods":` + periodsJson("40", "8") + `},{"key":"i-3","periods":` + periodsJson("0", "2") + `}]}`,
+		"region":    `{"buckets":[{"key":"us-east-1","periods":` + periodsJson("100", "40") + `}]}`,
+		"tag":       `{"keys":{"buckets":[{"key":"team","values":{"buckets":[{"key":"web","lineItems":{"periods":` + periodsJson("10", "3") + `}}]}}]}}`,
+	}
+	raw := &elastic.SearchResult{Aggregations: make(elastic.Aggregations)}
+	for name, aggregation := range aggregations {
+		rawAggregation := json.RawMessage(aggregation)
+		raw.Aggregations[name] = &rawAggregation
+	}
+	explanation := anomalyType.AnomalyExplanation{Contributors: make(map[string][]anomalyType.AnomalyContributor)}
+	explanation, err := formatAnomalyExplanation(raw, explanation, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if explanation.Cost != 40 || explanation.BaselineCost != 10 {
+		t.Errorf("Expected cost 40 and baseline cost 10 but got %f and %f", explanation.Cost, explanation.BaselineCost)
+	}
+	usageTypes := explanation.Contributors["usageType"]
+	if len(usageTypes) != 2 || usageTypes[0].Value != "DataTransfer-Out-Bytes" || usageTypes[0].Delta != 26 || usageTypes[1].Delta != 4 {
+		t.Errorf("Unexpected usage type contributors %v", usageTypes)
+	}
+	resources := explanation.Contributors["resource"]
+	if len(resources) != 2 || resources[0].Value != "i-2" || resources[1].Value != "i-3" {
+		t.Errorf("Unexpected resource contributors %v", resources)
+	}
+	tags := explanation.Contributors["tag"]
+	if len(tags) != 1 || tags[0].Key != "team" || tags[0].Value != "web" || tags[0].Delta != 2 {
+		t.Errorf("Unexpected tag contributors %v", tags)
+	}
+}
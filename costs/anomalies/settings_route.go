@@ -0,0 +1,252 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/anomaliesDetection"
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/cache"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/models"
+	"github.com/trackit/trackit/routes"
+	"github.com/trackit/trackit/users"
+)
+
+// settingsResponse is used to respond to the settings requests. Effective
+// are the settings used for the AWS account, or for the AWS accounts without
+// settings if none is selected.
+type settingsResponse struct {
+	User       anomalyType.Settings  `json:"user"`
+	AwsAccount *anomalyType.Settings `json:"awsAccount,omitempty"`
+	Effective  anomalies.Settings    `json:"effective"`
+}
+
+var (
+	// settingsAwsAccountIdQueryArg allows to select the AWS account whose
+	// settings are requested. The settings of the user are requested if
+	// it is not set.
+	settingsAwsAccountIdQueryArg = routes.QueryArg{
+		Name:        "account-id",
+		Type:        routes.QueryArgInt{},
+		Description: "The DB ID of the AWS account, the settings of the user are used if it is not set.",
+		Optional:    true,
+	}
+
+	exampleSettingsBody = anomalyType.Settings{
+		DetectorParameters: map[string]map[string]float64{
+			anomalies.DetectorBollinger: {"period": 7},
+		},
+		Levels:       []float64{0, 150, 300},
+		PrettyLevels: []string{"low", "high", "critical"},
+	}
+)
+
+func init() {
+	routes.MethodMuxer{
+		http.MethodGet: routes.H(getAnomaliesSettings).With(
+			users.RequireAuthenticatedUser{users.ViewerAsParent},
+			routes.Documentation{
+				Summary:     "get the anomaly detection settings",
+				Description: "Responds with the anomaly detection settings of the user, or of the AWS account passed in query args, and the settings actually used.",
+			},
+		),
+		http.MethodPost: routes.H(postAnomaliesSettings).With(
+			users.RequireAuthenticatedUser{users.ViewerCannot},
+			routes.RequestContentType{"application/json"},
+			routes.RequestBody{exampleSettingsBody},
+			routes.Documentation{
+				Summary:     "edit the anomaly detection settings",
+				Description: "Replaces the anomaly detection settings of the user, or of the AWS account passed in query args. Unset settings are inherited from the user, then from the server configuration.",
+			},
+		),
+	}.H().With(
+		db.RequestTransaction{Db: db.Db},
+		routes.QueryArgs{settingsAwsAccountIdQueryArg},
+		routes.Documentation{
+			Summary:     "interact with the anomaly detection settings",
+			Description: "The anomaly detection settings override the detector parameters, the cleaning thresholds, the levels and the emailing min level of the server configuration for a user or one of their AWS accounts.",
+		},
+	).Register("/costs/anomalies/settings")
+}
+
+// getAccountsSettings returns the anomaly detection settings of the AWS
+// accounts of the user among accounts, by AWS identity.
+func getAccountsSettings(tx *sql.Tx, user users.User, accounts []string) (map[string]anomalies.Settings, error) {
+	awsAccounts, err := aws.GetAwsAccountsFromUser(user, tx)
+	if err != nil {
+		return nil, err
+	}
+	requested := make(map[string]bool, len(accounts))
+	for _, account := range accounts {
+		requested[account] = true
+	}
+	res := make(map[string]anomalies.Settings)
+	for _, awsAccount := range awsAccounts {
+		if !requested[awsAccount.AwsIdentity] {
+			continue
+		}
+		settings, err := anomalies.GetSettings(tx, awsAccount)
+		if err != nil {
+			return nil, err
+		}
+		res[awsAccount.AwsIdentity] = settings
+	}
+	return res, nil
+}
+
+// getSettingsResponse returns the settings of the user and of the AWS
+// account if it is not nil.
+func getSettingsResponse(tx *sql.Tx, dbUser *models.User, awsAccount *aws.AwsAccount) (settingsResponse, error) {
+	var res settingsResponse
+	var err error
+	if res.User, err = anomalies.ParseSettings(dbUser.AnomaliesSettings); err != nil {
+		return res, err
+	}
+	if awsAccount == nil {
+		res.Effective = anomalies.DefaultSettings().Override(res.User)
+		return res, nil
+	}
+	dbAwsAccount, err := models.AwsAccountByID(tx, awsAccount.Id)
+	if err != nil {
+		return res, err
+	}
+	awsAccountSettings, err := anomalies.ParseSettings(dbAwsAccount.AnomaliesSettings)
+	if err != nil {
+		return res, err
+	}
+	res.AwsAccount = &awsAccountSettings
+	res.Effective = anomalies.DefaultSettings().Override(res.User).Override(awsAccountSettings)
+	return res, nil
+}
+
+// getSettingsAwsAccount returns the AWS account selected in query args, or
+// nil if none is selected.
+func getSettingsAwsAccount(tx *sql.Tx, user users.User, a routes.Arguments) (*aws.AwsAccount, error) {
+	if a[settingsAwsAccountIdQueryArg] == nil {
+		return nil, nil
+	}
+	awsAccount, err := aws.GetAwsAccountWithIdFromUser(user, a[settingsAwsAccountIdQueryArg].(int), tx)
+	if err != nil {
+		return nil, err
+	}
+	return &awsAccount, nil
+}
+
+// getAnomaliesSettings is a route handler which returns the anomaly
+// detection settings of the user or of one of their AWS accounts.
+func getAnomaliesSettings(r *http.Request, a routes.Arguments) (int, interface{}) {
+	l := jsonlog.LoggerFromContextOrDefault(r.Context())
+	tx := a[db.Transaction].(*sql.Tx)
+	user := a[users.AuthenticatedUser].(users.User)
+	awsAccount, err := getSettingsAwsAccount(tx, user, a)
+	if err != nil {
+		return http.StatusNotFound, errors.New("AWS account not found")
+	}
+	dbUser, err := models.UserByID(tx, user.Id)
+	if err != nil {
+		l.Error("Failed to get user with id", map[string]interface{}{
+			"userId": user.Id,
+			"error":  err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("failed to retrieve anomaly detection settings")
+	}
+	res, err := getSettingsResponse(tx, dbUser, awsAccount)
+	if err != nil {
+		l.Error("Failed to read anomaly detection settings", map[string]interface{}{
+			"userId": user.Id,
+			"error":  err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("failed to retrieve anomaly detection settings")
+	}
+	return http.StatusOK, res
+}
+
+// postAnomaliesSettings is a route handler which lets the user replace the
+// anomaly detection settings of their own or of one of their AWS accounts.
+func postAnomaliesSettings(r *http.Request, a routes.Arguments) (int, interface{}) {
+	l := jsonlog.LoggerFromContextOrDefault(r.Context())
+	var body anomalyType.Settings
+	routes.MustRequestBody(a, &body)
+	tx := a[db.Transaction].(*sql.Tx)
+	user := a[users.AuthenticatedUser].(users.User)
+	if err := anomalies.ValidSettings(body); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("Body is invalid (%s).", err.Error())
+	}
+	awsAccount, err := getSettingsAwsAccount(tx, user, a)
+	if err != nil {
+		return http.StatusNotFound, errors.New("AWS account not found")
+	}
+	raw, err := json.Marshal(body)
+	if err != nil {
+		l.Error("Failed to marshal anomaly detection settings", map[string]interface{}{
+			"userId": user.Id,
+			"error":  err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("failed to update anomaly detection settings")
+	}
+	dbUser, err := models.UserByID(tx, user.Id)
+	if err == nil {
+		if awsAccount == nil {
+			dbUser.AnomaliesSettings = raw
+			err = dbUser.Update(tx)
+		} else if dbAwsAccount, dbErr := models.AwsAccountByID(tx, awsAccount.Id); dbErr != nil {
+			err = dbErr
+		} else {
+			dbAwsAccount.AnomaliesSettings = raw
+			err = dbAwsAccount.Update(tx)
+		}
+	}
+	if err != nil {
+		l.Error("Failed to save anomaly detection settings", map[string]interface{}{
+			"userId": user.Id,
+			"error":  err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("failed to update anomaly detection settings")
+	}
+	removeSettingsCache(tx, user, awsAccount, l)
+	res, err := getSettingsResponse(tx, dbUser, awsAccount)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("failed to retrieve anomaly detection settings")
+	}
+	return http.StatusOK, res
+}
+
+// removeSettingsCache removes the cached anomalies of the AWS accounts whose
+// settings changed, so that their levels are up to date.
+func removeSettingsCache(tx *sql.Tx, user users.User, awsAccount *aws.AwsAccount, l jsonlog.Logger) {
+	var identities []string
+	if awsAccount != nil {
+		identities = []string{awsAccount.AwsIdentity}
+	} else if awsAccounts, err := aws.GetAwsAccountsFromUser(user, tx); err == nil {
+		for _, aa := range awsAccounts {
+			identities = append(identities, aa.AwsIdentity)
+		}
+	}
+	if err := cache.RemoveMatchingCache([]string{"/costs/anomalies"}, identities, l); err != nil {
+		l.Error("Failed to remove cache", map[string]interface{}{
+			"userId": user.Id,
+			"error":  err.Error(),
+		})
+	}
+}
@@ -0,0 +1,408 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/anomaliesDetection"
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/cache"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/es"
+	"github.com/trackit/trackit/models"
+	"github.com/trackit/trackit/routes"
+	"github.com/trackit/trackit/users"
+	"github.com/trackit/trackit/users/shared_account"
+)
+
+// maxCommentLength is the maximum length of the comments of an anomaly.
+const maxCommentLength = 4096
+
+// triageBody is the expected body for the triage route handler.
+// State and Assignee are left unchanged if they are omitted. Assignee is the
+// email of a user with access to the AWS account, or an empty string to
+// unassign the anomaly.
+type triageBody struct {
+	State    *string `json:"state"`
+	Assignee *string `json:"assignee"`
+	Comment  string  `json:"comment"`
+}
+
+// anomalyStateTransitions lists the states an anomaly can be moved to from
+// each state.
+var anomalyStateTransitions = map[string][]string{
+	anomalyType.AnomalyStateOpen:         {anomalyType.AnomalyStateAcknowledged, anomalyType.AnomalyStateExpected, anomalyType.AnomalyStateResolved},
+	anomalyType.AnomalyStateAcknowledged: {anomalyType.AnomalyStateOpen, anomalyType.AnomalyStateExpected, anomalyType.AnomalyStateResolved},
+	anomalyType.AnomalyStateExpected:     {anomalyType.AnomalyStateOpen, anomalyType.AnomalyStateResolved},
+	anomalyType.AnomalyStateResolved:     {anomalyType.AnomalyStateOpen},
+}
+
+var (
+	errAssigneeNotFound = errors.New("The assignee does not exist.")
+	errAssigneeNoAccess = errors.New("The assignee has no access to the AWS account of the anomaly.")
+)
+
+// triageQueryArgs allows to get required queryArgs params
+var triageQueryArgs = []routes.QueryArg{
+	routes.QueryArg{
+		Name:        "id",
+		Description: "ID of the anomaly.",
+		Type:        routes.QueryArgString{},
+	},
+}
+
+func init() {
+	exampleState := anomalyType.AnomalyStateAcknowledged
+	exampleAssignee := "finops@example.com"
+	routes.MethodMuxer{
+		http.MethodGet: routes.H(getAnomalyTriage).With(
+			db.RequestTransaction{Db: db.Db},
+			users.RequireAuthenticatedUser{users.ViewerAsParent},
+			routes.QueryArgs(triageQueryArgs),
+			routes.Documentation{
+				Summary:     "get the triage of an anomaly",
+				Description: "Responds with the state, the assignee and the history of the anomaly whose id is passed in query args",
+			},
+		),
+		http.MethodPut: routes.H(updateAnomalyTriage).With(
+			db.RequestTransaction{Db: db.Db},
+			users.RequireAuthenticatedUser{users.ViewerCannot},
+			routes.RequestContentType{"application/json"},
+			routes.RequestBody{triageBody{&exampleState, &exampleAssignee, "Looking into it."}},
+			routes.QueryArgs(triageQueryArgs),
+			routes.Documentation{
+				Summary:     "triage an anomaly",
+				Description: "Changes the state or the assignee of the anomaly whose id is passed in query args, or comments it. The changes are shared with all the users of its AWS account and recorded in its history.",
+			},
+		),
+	}.H().Register("/costs/anomalies/triage")
+}
+
+// isValidStateTransition returns true if an anomaly can be moved from a state to another.
+func isValidStateTransition(from, to string) bool {
+	for _, state := range anomalyStateTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// getAnomalyAwsAccount returns an anomaly along with the AWS account it
+// belongs to, among the ones the user has access to. The anomaly is nil if
+// it was not found.
+func getAnomalyAwsAccount(ctx context.Context, tx *sql.Tx, user users.User, id string) (*esProductAnomalyTypedResult, aws.AwsAccount, int, error) {
+	accountsAndIndexes, returnCode, err := es.GetAccountsAndIndexes(nil, user, tx, anomalies.IndexPrefixAnomaliesDetection)
+	if err != nil {
+		return nil, aws.AwsAccount{}, returnCode, err
+	}
+	anomaly, returnCode, err := getAnomalyById(ctx, accountsAndIndexes, id)
+	if err != nil || anomaly == nil {
+		return nil, aws.AwsAccount{}, returnCode, err
+	}
+	awsAccounts, err := aws.GetAwsAccountsFromUser(user, tx)
+	if err != nil {
+		return nil, aws.AwsAccount{}, http.StatusInternalServerError, err
+	}
+	for _, awsAccount := range awsAccounts {
+		if awsAccount.AwsIdentity == anomaly.Account {
+			return anomaly, awsAccount, http.StatusOK, nil
+		}
+	}
+	return nil, aws.AwsAccount{}, http.StatusOK, nil
+}
+
+// getAnomalyState returns the triage state of an anomaly. An anomaly which
+// was never triaged is open and its state is not in the database yet.
+func getAnomalyState(tx *sql.Tx, awsAccountId int, anomalyId string) (*models.AnomalyState, error) {
+	dbAnomalyState, err := models.AnomalyStateByAwsAccountIDAnomalyID(tx, awsAccountId, anomalyId)
+	if err == sql.ErrNoRows {
+		return &models.AnomalyState{
+			AwsAccountID: awsAccountId,
+			AnomalyID:    anomalyId,
+			State:        anomalyType.AnomalyStateOpen,
+		}, nil
+	}
+	return dbAnomalyState, err
+}
+
+// userEmails resolves the ids of the users to their emails.
+type userEmails map[int]string
+
+// get returns the email of a user, or an empty string if there is none.
+func (emails userEmails) get(tx *sql.Tx, id sql.NullInt64) (string, error) {
+	if !id.Valid {
+		return "", nil
+	} else if email, ok := emails[int(id.Int64)]; ok {
+		return email, nil
+	}
+	dbUser, err := models.UserByID(tx, int(id.Int64))
+	if err == sql.ErrNoRows {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	emails[dbUser.ID] = dbUser.Email
+	return dbUser.Email, nil
+}
+
+// formatAnomalyTriage returns the triage of an anomaly with its history
+// sorted chronologically.
+func formatAnomalyTriage(tx *sql.Tx, account string, dbAnomalyState *models.AnomalyState) (anomalyType.AnomalyTriage, error) {
+	emails := make(userEmails)
+	assignee, err := emails.get(tx, dbAnomalyState.AssigneeID)
+	if err != nil {
+		return anomalyType.AnomalyTriage{}, err
+	}
+	res := anomalyType.AnomalyTriage{
+		Id:       dbAnomalyState.AnomalyID,
+		Account:  account,
+		State:    dbAnomalyState.State,
+		Assignee: assignee,
+		History:  []anomalyType.AnomalyEvent{},
+	}
+	if !dbAnomalyState.Exists() {
+		return res, nil
+	}
+	dbAnomalyEvents, err := models.AnomalyEventsByAnomalyStateID(tx, dbAnomalyState.ID)
+	if err != nil {
+		return res, err
+	}
+	sort.Slice(dbAnomalyEvents, func(i, j int) bool {
+		return dbAnomalyEvents[i].ID < dbAnomalyEvents[j].ID
+	})
+	for _, dbAnomalyEvent := range dbAnomalyEvents {
+		user, err := emails.get(tx, dbAnomalyEvent.UserID)
+		if err != nil {
+			return res, err
+		}
+		assignee, err := emails.get(tx, dbAnomalyEvent.AssigneeID)
+		if err != nil {
+			return res, err
+		}
+		res.History = append(res.History, anomalyType.AnomalyEvent{
+			Date:     dbAnomalyEvent.Created,
+			User:     user,
+			Type:     dbAnomalyEvent.Type,
+			State:    dbAnomalyEvent.State,
+			Assignee: assignee,
+			Comment:  dbAnomalyEvent.Comment,
+		})
+	}
+	return res, nil
+}
+
+// getAssignee returns the id of the user with an email if they have access
+// to an AWS account. An empty email unassigns the anomaly.
+func getAssignee(tx *sql.Tx, awsAccount aws.AwsAccount, email string) (sql.NullInt64, error) {
+	if email == "" {
+		return sql.NullInt64{}, nil
+	}
+	dbUser, err := models.UserByEmail(tx, email)
+	if err == sql.ErrNoRows {
+		return sql.NullInt64{}, errAssigneeNotFound
+	} else if err != nil {
+		return sql.NullInt64{}, err
+	} else if dbUser.ID == awsAccount.UserId {
+		return sql.NullInt64{Int64: int64(dbUser.ID), Valid: true}, nil
+	}
+	dbSharedAccounts, err := models.SharedAccountsByAccountID(tx, awsAccount.Id)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	for _, dbSharedAccount := range dbSharedAccounts {
+		if dbSharedAccount.UserID == dbUser.ID && dbSharedAccount.SharingAccepted {
+			return sql.NullInt64{Int64: int64(dbUser.ID), Valid: true}, nil
+		}
+	}
+	return sql.NullInt64{}, errAssigneeNoAccess
+}
+
+// getAnomaliesStates returns the triage states of the anomalies of AWS
+// accounts by anomaly id, without their history.
+func getAnomaliesStates(tx *sql.Tx, user users.User, accounts []string) (map[string]anomalyType.AnomalyTriage, error) {
+	awsAccounts, err := aws.GetAwsAccountsFromUser(user, tx)
+	if err != nil {
+		return nil, err
+	}
+	requested := make(map[string]bool, len(accounts))
+	for _, account := range accounts {
+		requested[account] = true
+	}
+	emails := make(userEmails)
+	res := make(map[string]anomalyType.AnomalyTriage)
+	for _, awsAccount := range awsAccounts {
+		if !requested[awsAccount.AwsIdentity] {
+			continue
+		}
+		requested[awsAccount.AwsIdentity] = false
+		dbAnomalyStates, err := models.AnomalyStatesByAwsAccountID(tx, awsAccount.Id)
+		if err != nil {
+			return nil, err
+		}
+		for _, dbAnomalyState := range dbAnomalyStates {
+			assignee, err := emails.get(tx, dbAnomalyState.AssigneeID)
+			if err != nil {
+				return nil, err
+			}
+			res[dbAnomalyState.AnomalyID] = anomalyType.AnomalyTriage{
+				Id:       dbAnomalyState.AnomalyID,
+				Account:  awsAccount.AwsIdentity,
+				State:    dbAnomalyState.State,
+				Assignee: assignee,
+			}
+		}
+	}
+	return res, nil
+}
+
+// getAnomalyTriage checks the request and returns the AnomalyTriage.
+func getAnomalyTriage(request *http.Request, a routes.Arguments) (int, interface{}) {
+	user := a[users.AuthenticatedUser].(users.User)
+	tx := a[db.Transaction].(*sql.Tx)
+	id := a[triageQueryArgs[0]].(string)
+	anomaly, awsAccount, returnCode, err := getAnomalyAwsAccount(request.Context(), tx, user, id)
+	if err != nil {
+		return returnCode, err
+	} else if anomaly == nil {
+		return http.StatusNotFound, errors.New("Anomaly not found.")
+	}
+	dbAnomalyState, err := getAnomalyState(tx, awsAccount.Id, anomaly.Id)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	res, err := formatAnomalyTriage(tx, anomaly.Account, dbAnomalyState)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, res
+}
+
+// getTriageEvents applies the changes of the body to the state of an
+// anomaly and returns the events recording them.
+func getTriageEvents(tx *sql.Tx, awsAccount aws.AwsAccount, dbAnomalyState *models.AnomalyState, body triageBody) ([]models.AnomalyEvent, int, error) {
+	events := make([]models.AnomalyEvent, 0, 3)
+	if body.State != nil && *body.State != dbAnomalyState.State {
+		if _, ok := anomalyStateTransitions[*body.State]; !ok {
+			return nil, http.StatusBadRequest, fmt.Errorf("Unknown state: %s.", *body.State)
+		} else if !isValidStateTransition(dbAnomalyState.State, *body.State) {
+			return nil, http.StatusBadRequest, fmt.Errorf("An anomaly cannot be moved from %s to %s.", dbAnomalyState.State, *body.State)
+		}
+		dbAnomalyState.State = *body.State
+		events = append(events, models.AnomalyEvent{
+			Type:  anomalyType.AnomalyEventState,
+			State: *body.State,
+		})
+	}
+	if body.Assignee != nil {
+		assignee, err := getAssignee(tx, awsAccount, *body.Assignee)
+		if err == errAssigneeNotFound || err == errAssigneeNoAccess {
+			return nil, http.StatusBadRequest, err
+		} else if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		if assignee != dbAnomalyState.AssigneeID {
+			dbAnomalyState.AssigneeID = assignee
+			events = append(events, models.AnomalyEvent{
+				Type:       anomalyType.AnomalyEventAssignment,
+				AssigneeID: assignee,
+			})
+		}
+	}
+	if body.Comment != "" {
+		if len(body.Comment) > maxCommentLength {
+			return nil, http.StatusBadRequest, fmt.Errorf("Comments cannot be longer than %d characters.", maxCommentLength)
+		}
+		events = append(events, models.AnomalyEvent{
+			Type:    anomalyType.AnomalyEventComment,
+			Comment: body.Comment,
+		})
+	}
+	return events, http.StatusOK, nil
+}
+
+// updateAnomalyTriage checks the request, updates the state of the anomaly
+// and records the changes in its history.
+func updateAnomalyTriage(request *http.Request, a routes.Arguments) (int, interface{}) {
+	l := jsonlog.LoggerFromContextOrDefault(request.Context())
+	user := a[users.AuthenticatedUser].(users.User)
+	tx := a[db.Transaction].(*sql.Tx)
+	id := a[triageQueryArgs[0]].(string)
+	var body triageBody
+	routes.MustRequestBody(a, &body)
+	if body.State == nil && body.Assignee == nil && body.Comment == "" {
+		return http.StatusBadRequest, errors.New("The state, the assignee or a comment is required.")
+	}
+	anomaly, awsAccount, returnCode, err := getAnomalyAwsAccount(request.Context(), tx, user, id)
+	if err != nil {
+		return returnCode, err
+	} else if anomaly == nil {
+		return http.StatusNotFound, errors.New("Anomaly not found.")
+	} else if !awsAccount.AccountOwner && awsAccount.UserPermission == shared_account.ReadLevel {
+		return http.StatusForbidden, errors.New("You do not have permission to triage the anomalies of this AWS account.")
+	}
+	dbAnomalyState, err := getAnomalyState(tx, awsAccount.Id, anomaly.Id)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	events, returnCode, err := getTriageEvents(tx, awsAccount, dbAnomalyState, body)
+	if err != nil {
+		return returnCode, err
+	}
+	now := time.Now().UTC()
+	if !dbAnomalyState.Exists() {
+		dbAnomalyState.Created = now
+	}
+	if err := dbAnomalyState.Save(tx); err != nil {
+		l.Error("Failed to save anomaly state", map[string]interface{}{
+			"anomalyId": anomaly.Id,
+			"error":     err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("Failed to save anomaly state.")
+	}
+	for _, event := range events {
+		event.Created = now
+		event.AnomalyStateID = dbAnomalyState.ID
+		event.UserID = sql.NullInt64{Int64: int64(user.Id), Valid: true}
+		if err := event.Insert(tx); err != nil {
+			l.Error("Failed to insert anomaly event", map[string]interface{}{
+				"anomalyId": anomaly.Id,
+				"error":     err.Error(),
+			})
+			return http.StatusInternalServerError, errors.New("Failed to save anomaly history.")
+		}
+	}
+	if err := cache.RemoveMatchingCache([]string{"/costs/anomalies"}, []string{awsAccount.AwsIdentity}, l); err != nil {
+		l.Error("Failed to remove cache", map[string]interface{}{
+			"userId": user.Id,
+			"error":  err.Error(),
+		})
+	}
+	res, err := formatAnomalyTriage(tx, anomaly.Account, dbAnomalyState)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, res
+}
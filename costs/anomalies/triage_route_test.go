@@ -0,0 +1,86 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package anomalies
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/costs/anomalies/anomalyType"
+	"github.com/trackit/trackit/models"
+)
+
+func TestIsValidStateTransition(t *testing.T) {
+	for _, c := range []struct {
+		from     string
+		to       string
+		expected bool
+	}{
+		{anomalyType.AnomalyStateOpen, anomalyType.AnomalyStateAcknowledged, true},
+		{anomalyType.AnomalyStateOpen, anomalyType.AnomalyStateResolved, true},
+		{anomalyType.AnomalyStateAcknowledged, anomalyType.AnomalyStateExpected, true},
+		{anomalyType.AnomalyStateExpected, anomalyType.AnomalyStateAcknowledged, false},
+		{anomalyType.AnomalyStateResolved, anomalyType.AnomalyStateOpen, true},
+		{anomalyType.AnomalyStateResolved, anomalyType.AnomalyStateExpected, false},
+		{anomalyType.AnomalyStateOpen, "closed", false},
+	} {
+		if res := isValidStateTransition(c.from, c.to); res != c.expected {
+			t.Errorf("Transition from %s to %s: expected %t, got %t", c.from, c.to, c.expected, res)
+		}
+	}
+}
+
+func TestGetTriageEvents(t *testing.T) {
+	acknowledged := anomalyType.AnomalyStateAcknowledged
+	state := &models.AnomalyState{State: anomalyType.AnomalyStateOpen}
+	events, returnCode, err := getTriageEvents(nil, aws.AwsAccount{}, state, triageBody{State: &acknowledged, Comment: "Looking into it."})
+	if err != nil || returnCode != http.StatusOK {
+		t.Fatalf("Unexpected error: %d %v", returnCode, err)
+	}
+	if state.State != anomalyType.AnomalyStateAcknowledged {
+		t.Errorf("Expected state %s, got %s", anomalyType.AnomalyStateAcknowledged, state.State)
+	}
+	if len(events) != 2 || events[0].Type != anomalyType.AnomalyEventState || events[0].State != acknowledged ||
+		events[1].Type != anomalyType.AnomalyEventComment || events[1].Comment != "Looking into it." {
+		t.Errorf("Unexpected events: %v", events)
+	}
+	events, _, err = getTriageEvents(nil, aws.AwsAccount{}, state, triageBody{State: &acknowledged})
+	if err != nil || len(events) != 0 {
+		t.Errorf("Expected no event when the state is unchanged, got %v (%v)", events, err)
+	}
+}
+
+func TestGetTriageEventsErrors(t *testing.T) {
+	unknown := "closed"
+	expected := anomalyType.AnomalyStateExpected
+	for _, c := range []struct {
+		state string
+		body  triageBody
+	}{
+		{anomalyType.AnomalyStateOpen, triageBody{State: &unknown}},
+		{anomalyType.AnomalyStateResolved, triageBody{State: &expected}},
+		{anomalyType.AnomalyStateOpen, triageBody{Comment: strings.Repeat("a", maxCommentLength+1)}},
+	} {
+		state := &models.AnomalyState{State: c.state}
+		if _, returnCode, err := getTriageEvents(nil, aws.AwsAccount{}, state, c.body); err == nil || returnCode != http.StatusBadRequest {
+			t.Errorf("Expected a bad request from %s with %v, got %d %v", c.state, c.body, returnCode, err)
+		}
+		if state.State != c.state {
+			t.Errorf("State changed despite the error")
+		}
+	}
+}
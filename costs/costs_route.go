@@ -48,6 +48,19 @@ var simpleCriterionMap = map[string]bool{
 	"availabilityzone": true,
 }
 
+// DefaultCostMetric is the metric used when none is specified.
+const DefaultCostMetric = "unblended"
+
+// CostMetrics maps the metrics which can be summed to the line item fields
+// holding them.
+var CostMetrics = map[string]string{
+	"unblended":        "unblendedCost",
+	"blended":          "blendedCost",
+	"amortized":        "amortizedCost",
+	"net":              "netCost",
+	"public-on-demand": "publicOnDemandCost",
+}
+
 // EsQueryParams will store the parsed query params
 type EsQueryParams struct {
 	DateBegin         time.Time
@@ -55,6 +68,15 @@ type EsQueryParams struct {
 	AccountList       []string
 	IndexList         []string
 	AggregationParams []string
+	TagFilters        []TagFilter
+	Metric            string
+}
+
+// TagFilter restricts a costs query to the line items carrying the tag Key
+// with the value Value.
+type TagFilter struct {
+	Key   string
+	Value string
 }
 
 // costQueryArgs allows to get required queryArgs params
@@ -68,6 +90,12 @@ var costsQueryArgs = []routes.QueryArg{
 		Type:        routes.QueryArgStringSlice{},
 		Optional:    false,
 	},
+	routes.QueryArg{
+		Name:        "metric",
+		Description: "Cost metric to sum. Possible values are unblended (default), blended, amortized, net, public-on-demand. Metrics other than unblended are zero for bills ingested before they were added, until these are ingested again after the upgrade",
+		Type:        routes.QueryArgString{},
+		Optional:    true,
+	},
 }
 
 func init() {
@@ -90,8 +118,12 @@ func init() {
 // or, in the case of the special criterion tag, will check if it is in the
 // correct format : 'tag:*' (with no more than one ':')
 // Right now the tags are not enabled and will generate an error if they are
-// used because they are not yet implemented in the new ElasticSearch mapping
+// used because they are not yet implemented in the new ElasticSearch mapping.
+// It also validates the metric, which must be a key of CostMetrics if set.
 func validateCriteriaParam(parsedParams EsQueryParams) error {
+	if _, ok := CostMetrics[parsedParams.Metric]; parsedParams.Metric != "" && !ok {
+		return fmt.Errorf("Error parsing metric : %s", parsedParams.Metric)
+	}
 	for _, criterion := range parsedParams.AggregationParams {
 		if !simpleCriterionMap[criterion] {
 			if len(criterion) >= 5 && criterion[:4] == "tag:" && strings.Count(criterion, ":") == 1 {
@@ -112,8 +144,14 @@ func validateCriteriaParam(parsedParams EsQueryParams) error {
 func MakeElasticSearchRequestAndParseIt(ctx context.Context, parsedParams EsQueryParams) (es.SimplifiedCostsDocument, int, error) {
 	l := jsonlog.LoggerFromContextOrDefault(ctx)
 	index := strings.Join(parsedParams.IndexList, ",")
-	searchService := GetElasticSearchParams(
+	metric := parsedParams.Metric
+	if metric == "" {
+		metric = DefaultCostMetric
+	}
+	searchService := GetFilteredElasticSearchParams(
 		parsedParams.AccountList,
+		parsedParams.TagFilters,
+		metric,
 		parsedParams.DateBegin,
 		parsedParams.DateEnd,
 		parsedParams.AggregationParams,
@@ -158,6 +196,9 @@ func getCostData(request *http.Request, a routes.Arguments) (int, interface{}) {
 	if a[costsQueryArgs[0]] != nil {
 		parsedParams.AccountList = a[costsQueryArgs[0]].([]string)
 	}
+	if a[costsQueryArgs[4]] != nil {
+		parsedParams.Metric = a[costsQueryArgs[4]].(string)
+	}
 	if err := validateCriteriaParam(parsedParams); err != nil {
 		return http.StatusBadRequest, err
 	}
@@ -162,6 +162,7 @@ func TaskDiffData(ctx context.Context, aa aws.AwsAccount, dateRange DateRange, a
 	if tx, err = db.Db.BeginTx(ctx, nil); err != nil {
 		return costDiff{}, err
 	}
+	defer tx.Rollback()
 	user, err := users.GetUserWithId(tx, aa.UserId)
 	if err != nil {
 		return
@@ -172,7 +173,10 @@ func TaskDiffData(ctx context.Context, aa aws.AwsAccount, dateRange DateRange, a
 	}
 	parsedParams.accountList = accountsAndIndexes.Accounts
 	parsedParams.indexList = accountsAndIndexes.Indexes
-	_, diffData := getDiffData(ctx, parsedParams)
+	returnCode, diffData := getDiffData(ctx, parsedParams)
+	if returnCode == http.StatusOK && diffData == nil {
+		return costDiff{}, nil
+	}
 	return convertDiffData(ctx, diffData)
 }
 
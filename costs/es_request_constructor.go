@@ -67,6 +67,14 @@ func createQueryAccountFilter(accountList []string) *elastic.TermsQuery {
 	return elastic.NewTermsQuery("usageAccountId", accountListFormatted...)
 }
 
+// createQueryTagFilter creates and return a new *elastic.NestedQuery matching the
+// line items which carry the tag described by tagFilter
+func createQueryTagFilter(tagFilter TagFilter) *elastic.NestedQuery {
+	return elastic.NewNestedQuery("tags", elastic.NewBoolQuery().
+		Filter(elastic.NewTermQuery("tags.key", tagFilter.Key)).
+		Filter(elastic.NewTermQuery("tags.tag", tagFilter.Value)))
+}
+
 // createQueryTimeRange creates and return a new *elastic.RangeQuery based on the duration
 // defined by durationBegin and durationEnd
 func createQueryTimeRange(durationBegin time.Time, durationEnd time.Time) *elastic.RangeQuery {
@@ -191,12 +199,17 @@ func createAggregationPerTag(paramSplit []string) []paramAggrAndName {
 }
 
 // createCostSumAggregation : Creates and return a new []paramAggrAndName of size 1, which creates a
-// SumAggregation on the field 'cost'
-func createCostSumAggregation(_ []string) []paramAggrAndName {
+// SumAggregation on the field 'unblendedCost', or on the field passed in the parameter 'paramSplit'
+// in the form "cost:<FIELD>"
+func createCostSumAggregation(paramSplit []string) []paramAggrAndName {
+	field := CostMetrics[DefaultCostMetric]
+	if len(paramSplit) > 1 {
+		field = paramSplit[1]
+	}
 	return []paramAggrAndName{
 		paramAggrAndName{
 			name: "value",
-			aggr: elastic.NewSumAggregation().Field("unblendedCost"),
+			aggr: elastic.NewSumAggregation().Field(field),
 		},
 	}
 }
@@ -266,14 +279,25 @@ func nestAggregation(allAggrSlice []paramAggrAndName) elastic.Aggregation {
 //	- If the client is nil or malconfigured, it will crash
 //	- If the index is not an index present in the ES, it will crash
 func GetElasticSearchParams(accountList []string, durationBegin time.Time,
+	durationEnd time.Time, params []string, client *elastic.Client, index string) *elastic.SearchService {
+	return GetFilteredElasticSearchParams(accountList, nil, DefaultCostMetric, durationBegin, durationEnd, params, client, index)
+}
+
+// GetFilteredElasticSearchParams behaves like GetElasticSearchParams but only
+// takes into account the line items matching all the tagFilters, and sums the
+// cost designated by metric, which must be a key of CostMetrics.
+func GetFilteredElasticSearchParams(accountList []string, tagFilters []TagFilter, metric string, durationBegin time.Time,
 	durationEnd time.Time, params []string, client *elastic.Client, index string) *elastic.SearchService {
 	query := elastic.NewBoolQuery()
 	if len(accountList) > 0 {
 		query = query.Filter(createQueryAccountFilter(accountList))
 	}
+	for _, tagFilter := range tagFilters {
+		query = query.Filter(createQueryTagFilter(tagFilter))
+	}
 	query = query.Filter(createQueryTimeRange(durationBegin, durationEnd))
 	search := client.Search().Index(index).Size(0).Query(query)
-	params = append(params, "cost")
+	params = append(params, "cost:"+CostMetrics[metric])
 	var allAggregationSlice []paramAggrAndName
 	for _, paramName := range params {
 		paramNameSplit := strings.Split(paramName, ":")
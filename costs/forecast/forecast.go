@@ -0,0 +1,191 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package forecast projects the costs of the current month and of the
+// following ones from the daily costs of the previous weeks.
+package forecast
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/trackit/trackit/es"
+)
+
+const (
+	// historyDays is the number of days of costs used to build a forecast.
+	historyDays = 60
+	// confidenceCoefficient is the number of standard deviations used for
+	// the confidence bands. 1.96 gives a 95% confidence interval.
+	confidenceCoefficient = 1.96
+	// totalKey is the key under which the forecast is stored when it is
+	// not broken down by any criterion.
+	totalKey = "total"
+)
+
+type (
+	// ForecastedMonth is the projected cost of a month. Actual is the cost
+	// already known for the month and Cost the expected cost when the
+	// month closes. LowerBand and UpperBand delimit the confidence interval
+	// of Cost.
+	ForecastedMonth struct {
+		Month     string  `json:"month"`
+		Actual    float64 `json:"actual"`
+		Cost      float64 `json:"cost"`
+		LowerBand float64 `json:"lowerBand"`
+		UpperBand float64 `json:"upperBand"`
+	}
+
+	// Forecast maps the values of the criterion the costs are broken down
+	// by to their forecasted months.
+	Forecast map[string][]ForecastedMonth
+
+	// dailySeries holds daily costs, the first of them being the cost of
+	// begin.
+	dailySeries struct {
+		begin time.Time
+		costs []float64
+	}
+)
+
+// linearRegression fits costs against their index with the least squares
+// method, returning the intercept, the slope and the standard deviation of
+// the residuals.
+func linearRegression(costs []float64) (intercept, slope, sigma float64) {
+	n := float64(len(costs))
+	if n == 0 {
+		return
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range costs {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	if denominator := n*sumXX - sumX*sumX; denominator != 0 {
+		slope = (n*sumXY - sumX*sumY) / denominator
+	}
+	intercept = (sumY - slope*sumX) / n
+	var sumSquaredResiduals float64
+	for i, y := range costs {
+		residual := y - (intercept + slope*float64(i))
+		sumSquaredResiduals += residual * residual
+	}
+	if n > 2 {
+		sigma = math.Sqrt(sumSquaredResiduals / (n - 2))
+	}
+	return
+}
+
+// forecastSeries projects a daily series until the end of the month of now
+// and for the following months months. The days after lastDay, the last
+// known day, are projected, including those of the current month for which
+// the costs have not been reported yet.
+func forecastSeries(series dailySeries, lastDay, now time.Time, months int) []ForecastedMonth {
+	intercept, slope, sigma := linearRegression(series.costs)
+	monthBegin := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	res := make([]ForecastedMonth, 0, months+1)
+	for m := 0; m <= months; m++ {
+		begin := monthBegin.AddDate(0, m, 0)
+		end := begin.AddDate(0, 1, 0)
+		fm := ForecastedMonth{Month: begin.Format("2006-01")}
+		var projectedDays float64
+		for day := begin; day.Before(end); day = day.AddDate(0, 0, 1) {
+			index := int(day.Sub(series.begin).Hours() / 24)
+			if !day.After(lastDay) {
+				if index >= 0 && index < len(series.costs) {
+					fm.Actual += series.costs[index]
+				}
+			} else {
+				fm.Cost += math.Max(intercept+slope*float64(index), 0)
+				projectedDays++
+			}
+		}
+		band := confidenceCoefficient * sigma * math.Sqrt(projectedDays)
+		fm.Cost += fm.Actual
+		fm.LowerBand = math.Max(fm.Cost-band, fm.Actual)
+		fm.UpperBand = fm.Cost + band
+		res = append(res, fm)
+	}
+	return res
+}
+
+// parseDay parses the key of a daily bucket, such as
+// "2019-05-01T00:00:00.000Z", to the day it starts.
+func parseDay(key string) (time.Time, error) {
+	return time.Parse("2006-01-02", strings.Split(key, "T")[0])
+}
+
+// addDays adds the daily buckets of a costs document to series. Buckets
+// outside of the series are ignored. It returns the updated series and the
+// last day with a nonzero cost, which is zero if there is none.
+func addDays(series dailySeries, days []es.SimplifiedCostsDocument) (dailySeries, time.Time) {
+	var lastDay time.Time
+	for _, day := range days {
+		date, err := parseDay(day.Key)
+		if err != nil {
+			continue
+		}
+		index := int(date.Sub(series.begin).Hours() / 24)
+		if index < 0 || index >= len(series.costs) {
+			continue
+		}
+		series.costs[index] += day.Value
+		if day.Value != 0 && date.After(lastDay) {
+			lastDay = date
+		}
+	}
+	return series, lastDay
+}
+
+// buildForecast builds a Forecast from a costs document aggregated by day,
+// optionally broken down by a criterion beforehand. The costs of the days
+// in [historyBegin, now) are used and the last day holding costs is
+// considered to be the last known day. The month of now is the first
+// forecasted one, even if no costs were reported for it yet.
+func buildForecast(doc es.SimplifiedCostsDocument, brokenDown bool, historyBegin, now time.Time, months int) Forecast {
+	length := int(now.Sub(historyBegin).Hours() / 24)
+	series := map[string]dailySeries{}
+	var lastDay time.Time
+	addKey := func(key string, days []es.SimplifiedCostsDocument) {
+		s, ok := series[key]
+		if !ok {
+			s = dailySeries{historyBegin, make([]float64, length)}
+		}
+		s, keyLastDay := addDays(s, days)
+		if keyLastDay.After(lastDay) {
+			lastDay = keyLastDay
+		}
+		series[key] = s
+	}
+	if brokenDown {
+		for _, child := range doc.Children {
+			addKey(child.Key, child.Children)
+		}
+	} else {
+		addKey(totalKey, doc.Children)
+	}
+	res := Forecast{}
+	if lastDay.IsZero() {
+		return res
+	}
+	for key, s := range series {
+		s.costs = s.costs[:int(lastDay.Sub(historyBegin).Hours()/24)+1]
+		res[key] = forecastSeries(s, lastDay, now, months)
+	}
+	return res
+}
@@ -0,0 +1,123 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package forecast
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/trackit/trackit/aws/s3"
+	"github.com/trackit/trackit/cache"
+	"github.com/trackit/trackit/costs"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/es"
+	"github.com/trackit/trackit/routes"
+	"github.com/trackit/trackit/users"
+)
+
+// maxForecastedMonths is the maximum number of months which can be
+// forecasted after the current one.
+const maxForecastedMonths = 12
+
+// validCriterionMap lists the criteria a forecast can be broken down by.
+var validCriterionMap = map[string]bool{
+	"product": true,
+	"account": true,
+	"region":  true,
+}
+
+// forecastQueryArgs allows to get required queryArgs params
+var forecastQueryArgs = []routes.QueryArg{
+	routes.AwsAccountsOptionalQueryArg,
+	routes.QueryArg{
+		Name:        "by",
+		Description: "Criterion the forecast is broken down by. Possible values are product, account, region",
+		Type:        routes.QueryArgString{},
+		Optional:    true,
+	},
+	routes.QueryArg{
+		Name:        "months",
+		Description: fmt.Sprintf("Number of months to forecast after the current one, up to %d", maxForecastedMonths),
+		Type:        routes.QueryArgInt{},
+		Optional:    true,
+	},
+}
+
+func init() {
+	routes.MethodMuxer{
+		http.MethodGet: routes.H(getForecast).With(
+			db.RequestTransaction{Db: db.Db},
+			users.RequireAuthenticatedUser{users.ViewerAsParent},
+			routes.QueryArgs(forecastQueryArgs),
+			cache.UsersCache{},
+			routes.Documentation{
+				Summary:     "get the costs forecast",
+				Description: "Responds with the projected costs of the current month, and optionally of the following ones, with their confidence bands",
+			},
+		),
+	}.H().Register("/costs/forecast")
+}
+
+// getForecast returns the costs forecast based on the query params, in JSON
+// format.
+func getForecast(request *http.Request, a routes.Arguments) (int, interface{}) {
+	user := a[users.AuthenticatedUser].(users.User)
+	tx := a[db.Transaction].(*sql.Tx)
+	accountList := []string{}
+	if a[forecastQueryArgs[0]] != nil {
+		accountList = a[forecastQueryArgs[0]].([]string)
+	}
+	var by string
+	if a[forecastQueryArgs[1]] != nil {
+		by = a[forecastQueryArgs[1]].(string)
+		if !validCriterionMap[by] {
+			return http.StatusBadRequest, fmt.Errorf("Error parsing criterion : %s", by)
+		}
+	}
+	var months int
+	if a[forecastQueryArgs[2]] != nil {
+		months = a[forecastQueryArgs[2]].(int)
+		if months < 0 || months > maxForecastedMonths {
+			return http.StatusBadRequest, fmt.Errorf("months must be between 0 and %d", maxForecastedMonths)
+		}
+	}
+	accountsAndIndexes, returnCode, err := es.GetAccountsAndIndexes(accountList, user, tx, s3.IndexPrefixLineItem)
+	if err != nil {
+		return returnCode, err
+	}
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	historyBegin := today.AddDate(0, 0, -historyDays)
+	parsedParams := costs.EsQueryParams{
+		DateBegin:         historyBegin,
+		DateEnd:           today.Add(-time.Nanosecond),
+		AccountList:       accountsAndIndexes.Accounts,
+		IndexList:         accountsAndIndexes.Indexes,
+		AggregationParams: []string{"day"},
+	}
+	if by != "" {
+		parsedParams.AggregationParams = []string{by, "day"}
+	}
+	simplifiedCostDocument, returnCode, err := costs.MakeElasticSearchRequestAndParseIt(request.Context(), parsedParams)
+	if err != nil {
+		if returnCode == http.StatusOK {
+			return returnCode, Forecast{}
+		}
+		return returnCode, err
+	}
+	return http.StatusOK, buildForecast(simplifiedCostDocument, by != "", historyBegin, today, months)
+}
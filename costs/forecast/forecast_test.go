@@ -0,0 +1,113 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package forecast
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/trackit/trackit/es"
+)
+
+func TestLinearRegression(t *testing.T) {
+	intercept, slope, sigma := linearRegression([]float64{1, 3, 5, 7, 9})
+	if math.Abs(intercept-1) > 1e-9 || math.Abs(slope-2) > 1e-9 || sigma > 1e-9 {
+		t.Errorf("Expected 1, 2, 0 but got %v, %v, %v", intercept, slope, sigma)
+	}
+}
+
+func dailyDocument(begin time.Time, costs []float64) []es.SimplifiedCostsDocument {
+	days := make([]es.SimplifiedCostsDocument, len(costs))
+	for i, cost := range costs {
+		days[i] = es.SimplifiedCostsDocument{
+			Key:      fmt.Sprintf("%sT00:00:00.000Z", begin.AddDate(0, 0, i).Format("2006-01-02")),
+			HasValue: true,
+			Value:    cost,
+		}
+	}
+	return days
+}
+
+func TestBuildForecastConstantCosts(t *testing.T) {
+	historyBegin := time.Date(2019, time.April, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2019, time.May, 11, 0, 0, 0, 0, time.UTC)
+	costs := make([]float64, 40)
+	for i := range costs {
+		costs[i] = 10
+	}
+	doc := es.SimplifiedCostsDocument{
+		ChildrenKind: "product",
+		Children: []es.SimplifiedCostsDocument{
+			{Key: "AmazonEC2", Children: dailyDocument(historyBegin, costs)},
+		},
+	}
+	res := buildForecast(doc, true, historyBegin, now, 1)
+	months, ok := res["AmazonEC2"]
+	if !ok || len(months) != 2 {
+		t.Fatalf("Expected 2 months for AmazonEC2 but got %v", res)
+	}
+	expected := []ForecastedMonth{
+		{Month: "2019-05", Actual: 100, Cost: 310, LowerBand: 310, UpperBand: 310},
+		{Month: "2019-06", Actual: 0, Cost: 300, LowerBand: 300, UpperBand: 300},
+	}
+	for i, month := range months {
+		if month.Month != expected[i].Month ||
+			math.Abs(month.Actual-expected[i].Actual) > 1e-6 ||
+			math.Abs(month.Cost-expected[i].Cost) > 1e-6 ||
+			math.Abs(month.LowerBand-expected[i].LowerBand) > 1e-6 ||
+			math.Abs(month.UpperBand-expected[i].UpperBand) > 1e-6 {
+			t.Errorf("Expected %+v but got %+v", expected[i], month)
+		}
+	}
+}
+
+func TestBuildForecastReportLag(t *testing.T) {
+	historyBegin := time.Date(2019, time.March, 3, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2019, time.May, 2, 0, 0, 0, 0, time.UTC)
+	costs := make([]float64, 59)
+	for i := range costs {
+		costs[i] = 10
+	}
+	doc := es.SimplifiedCostsDocument{Children: dailyDocument(historyBegin, costs)}
+	res := buildForecast(doc, false, historyBegin, now, 0)
+	months := res[totalKey]
+	if len(months) != 1 {
+		t.Fatalf("Expected 1 month but got %v", res)
+	}
+	expected := ForecastedMonth{Month: "2019-05", Actual: 0, Cost: 310, LowerBand: 310, UpperBand: 310}
+	if month := months[0]; month.Month != expected.Month || math.Abs(month.Cost-expected.Cost) > 1e-6 || month.Actual != 0 {
+		t.Errorf("Expected %+v but got %+v", expected, month)
+	}
+}
+
+func TestBuildForecastBands(t *testing.T) {
+	historyBegin := time.Date(2019, time.April, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2019, time.May, 11, 0, 0, 0, 0, time.UTC)
+	costs := make([]float64, 40)
+	for i := range costs {
+		costs[i] = float64(10 + 5*(i%2))
+	}
+	doc := es.SimplifiedCostsDocument{Children: dailyDocument(historyBegin, costs)}
+	res := buildForecast(doc, false, historyBegin, now, 0)
+	months := res[totalKey]
+	if len(months) != 1 {
+		t.Fatalf("Expected 1 month but got %v", res)
+	}
+	if month := months[0]; !(month.LowerBand < month.Cost && month.Cost < month.UpperBand) || month.LowerBand < month.Actual {
+		t.Errorf("Expected actual <= lower band < cost < upper band but got %+v", month)
+	}
+}
@@ -0,0 +1,41 @@
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+CREATE TABLE budget (
+	id              INTEGER      NOT NULL AUTO_INCREMENT,
+	created         TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	user_id         INTEGER      NOT NULL,
+	aws_account_id  INTEGER      NULL DEFAULT NULL,
+	name            VARCHAR(255) NOT NULL,
+	tag_key         VARCHAR(255) NOT NULL DEFAULT "",
+	tag_value       VARCHAR(255) NOT NULL DEFAULT "",
+	period          VARCHAR(16)  NOT NULL DEFAULT "monthly",
+	amount          DOUBLE       NOT NULL,
+	thresholds      VARCHAR(255) NOT NULL DEFAULT "50,80,100",
+	CONSTRAINT PRIMARY KEY (id),
+	CONSTRAINT foreign_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+	CONSTRAINT foreign_aws_account FOREIGN KEY (aws_account_id) REFERENCES aws_account(id) ON DELETE CASCADE
+);
+
+CREATE TABLE budget_alert (
+	id              INTEGER      NOT NULL AUTO_INCREMENT,
+	created         TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	budget_id       INTEGER      NOT NULL,
+	period_begin    DATETIME     NOT NULL,
+	threshold       INTEGER      NOT NULL,
+	spent           DOUBLE       NOT NULL,
+	CONSTRAINT PRIMARY KEY (id),
+	CONSTRAINT UNIQUE (budget_id, period_begin, threshold),
+	CONSTRAINT foreign_budget FOREIGN KEY (budget_id) REFERENCES budget(id) ON DELETE CASCADE
+);
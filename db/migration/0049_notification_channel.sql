@@ -0,0 +1,28 @@
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+CREATE TABLE notification_channel (
+	id              INTEGER       NOT NULL AUTO_INCREMENT,
+	created         TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	user_id         INTEGER       NOT NULL,
+	aws_account_id  INTEGER       NULL DEFAULT NULL,
+	type            VARCHAR(16)   NOT NULL,
+	name            VARCHAR(255)  NOT NULL,
+	target          VARCHAR(2048) NOT NULL,
+	secret          VARCHAR(255)  NOT NULL DEFAULT "",
+	disabled        BOOLEAN       NOT NULL DEFAULT 0,
+	CONSTRAINT PRIMARY KEY (id),
+	CONSTRAINT foreign_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+	CONSTRAINT foreign_aws_account FOREIGN KEY (aws_account_id) REFERENCES aws_account(id) ON DELETE CASCADE
+);
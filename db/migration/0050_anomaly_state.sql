@@ -0,0 +1,41 @@
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+CREATE TABLE anomaly_state (
+	id              INTEGER       NOT NULL AUTO_INCREMENT,
+	created         TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	aws_account_id  INTEGER       NOT NULL,
+	anomaly_id      VARCHAR(255)  NOT NULL,
+	state           VARCHAR(16)   NOT NULL DEFAULT "open",
+	assignee_id     INTEGER       NULL DEFAULT NULL,
+	CONSTRAINT PRIMARY KEY (id),
+	CONSTRAINT unique_aws_account_anomaly UNIQUE (aws_account_id, anomaly_id),
+	CONSTRAINT foreign_aws_account FOREIGN KEY (aws_account_id) REFERENCES aws_account(id) ON DELETE CASCADE,
+	CONSTRAINT foreign_assignee FOREIGN KEY (assignee_id) REFERENCES user(id) ON DELETE SET NULL
+);
+
+CREATE TABLE anomaly_event (
+	id                INTEGER       NOT NULL AUTO_INCREMENT,
+	created           TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	anomaly_state_id  INTEGER       NOT NULL,
+	user_id           INTEGER       NULL DEFAULT NULL,
+	type              VARCHAR(16)   NOT NULL,
+	state             VARCHAR(16)   NOT NULL DEFAULT "",
+	assignee_id       INTEGER       NULL DEFAULT NULL,
+	comment           TEXT          NOT NULL,
+	CONSTRAINT PRIMARY KEY (id),
+	CONSTRAINT foreign_anomaly_state FOREIGN KEY (anomaly_state_id) REFERENCES anomaly_state(id) ON DELETE CASCADE,
+	CONSTRAINT foreign_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE SET NULL,
+	CONSTRAINT foreign_event_assignee FOREIGN KEY (assignee_id) REFERENCES user(id) ON DELETE SET NULL
+);
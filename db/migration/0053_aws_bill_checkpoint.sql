@@ -0,0 +1,37 @@
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+
+CREATE TABLE aws_bill_period_index (
+	id                      INTEGER       NOT NULL AUTO_INCREMENT,
+	modified                TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	aws_bill_repository_id  INTEGER       NOT NULL,
+	billing_period          DATETIME      NOT NULL,
+	es_index                VARCHAR(255)  NOT NULL,
+	CONSTRAINT PRIMARY KEY (id),
+	CONSTRAINT unique_bill_repository_period UNIQUE (aws_bill_repository_id, billing_period),
+	CONSTRAINT foreign_period_index_bill_repository FOREIGN KEY (aws_bill_repository_id) REFERENCES aws_bill_repository(id) ON DELETE CASCADE
+);
+
+CREATE TABLE aws_bill_checkpoint (
+	id                      INTEGER        NOT NULL AUTO_INCREMENT,
+	created                 TIMESTAMP      NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	aws_bill_repository_id  INTEGER        NOT NULL,
+	es_index                VARCHAR(255)   NOT NULL,
+	report_key              VARCHAR(1024)  NOT NULL,
+	line_items              INTEGER        NOT NULL,
+	CONSTRAINT PRIMARY KEY (id),
+	INDEX index_bill_repository_es_index (aws_bill_repository_id, es_index),
+	CONSTRAINT foreign_checkpoint_bill_repository FOREIGN KEY (aws_bill_repository_id) REFERENCES aws_bill_repository(id) ON DELETE CASCADE
+);
@@ -0,0 +1,22 @@
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+
+ALTER TABLE aws_bill_update_job ADD manifests_total INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE aws_bill_update_job ADD manifests_done  INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE aws_bill_update_job ADD keys_total      INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE aws_bill_update_job ADD keys_done       INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE aws_bill_update_job ADD bytes_total     BIGINT  NOT NULL DEFAULT 0;
+ALTER TABLE aws_bill_update_job ADD bytes_done      BIGINT  NOT NULL DEFAULT 0;
+ALTER TABLE aws_bill_update_job ADD line_items      BIGINT  NOT NULL DEFAULT 0;
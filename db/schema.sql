@@ -972,3 +972,329 @@ ALTER TABLE aws_account ADD next_tags_spreadsheet_report_generation DATETIME NOT
 CREATE OR REPLACE VIEW aws_account_tags_spreadsheets_reports_due_update AS
 SELECT * FROM aws_account WHERE next_tags_spreadsheet_report_generation <= NOW()
 ;
+
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+CREATE TABLE budget (
+	id              INTEGER      NOT NULL AUTO_INCREMENT,
+	created         TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	user_id         INTEGER      NOT NULL,
+	aws_account_id  INTEGER      NULL DEFAULT NULL,
+	name            VARCHAR(255) NOT NULL,
+	tag_key         VARCHAR(255) NOT NULL DEFAULT "",
+	tag_value       VARCHAR(255) NOT NULL DEFAULT "",
+	period          VARCHAR(16)  NOT NULL DEFAULT "monthly",
+	amount          DOUBLE       NOT NULL,
+	thresholds      VARCHAR(255) NOT NULL DEFAULT "50,80,100",
+	CONSTRAINT PRIMARY KEY (id),
+	CONSTRAINT foreign_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+	CONSTRAINT foreign_aws_account FOREIGN KEY (aws_account_id) REFERENCES aws_account(id) ON DELETE CASCADE
+);
+
+CREATE TABLE budget_alert (
+	id              INTEGER      NOT NULL AUTO_INCREMENT,
+	created         TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	budget_id       INTEGER      NOT NULL,
+	period_begin    DATETIME     NOT NULL,
+	threshold       INTEGER      NOT NULL,
+	spent           DOUBLE       NOT NULL,
+	CONSTRAINT PRIMARY KEY (id),
+	CONSTRAINT UNIQUE (budget_id, period_begin, threshold),
+	CONSTRAINT foreign_budget FOREIGN KEY (budget_id) REFERENCES budget(id) ON DELETE CASCADE
+);
+
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+ALTER TABLE aws_account_update_job ADD savingsPlansError VARCHAR(255) NOT NULL DEFAULT "";
+
+ALTER TABLE aws_account_reports_job ADD savingsPlansReportError VARCHAR(255) NOT NULL DEFAULT "";
+
+ALTER TABLE aws_account_master_reports_job ADD savingsPlansReportError VARCHAR(255) NOT NULL DEFAULT "";
+
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+ALTER TABLE aws_account_update_job ADD odToSpError VARCHAR(255) NOT NULL DEFAULT "";
+
+ALTER TABLE aws_account_update_job ADD odToRiRdsError VARCHAR(255) NOT NULL DEFAULT "";
+ALTER TABLE aws_account_update_job ADD odToRiElastiCacheError VARCHAR(255) NOT NULL DEFAULT "";
+ALTER TABLE aws_account_update_job ADD odToRiEsError VARCHAR(255) NOT NULL DEFAULT "";
+
+ALTER TABLE aws_account_reports_job ADD odToRiRdsReportError VARCHAR(255) NOT NULL DEFAULT "";
+ALTER TABLE aws_account_reports_job ADD odToRiElastiCacheReportError VARCHAR(255) NOT NULL DEFAULT "";
+ALTER TABLE aws_account_reports_job ADD odToRiEsReportError VARCHAR(255) NOT NULL DEFAULT "";
+
+ALTER TABLE aws_account_master_reports_job ADD odToRiRdsReportError VARCHAR(255) NOT NULL DEFAULT "";
+ALTER TABLE aws_account_master_reports_job ADD odToRiElastiCacheReportError VARCHAR(255) NOT NULL DEFAULT "";
+ALTER TABLE aws_account_master_reports_job ADD odToRiEsReportError VARCHAR(255) NOT NULL DEFAULT "";
+
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+ALTER TABLE aws_account ADD anomaly_detector VARCHAR(255) NOT NULL DEFAULT "";
+
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+CREATE TABLE notification_channel (
+	id              INTEGER       NOT NULL AUTO_INCREMENT,
+	created         TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	user_id         INTEGER       NOT NULL,
+	aws_account_id  INTEGER       NULL DEFAULT NULL,
+	type            VARCHAR(16)   NOT NULL,
+	name            VARCHAR(255)  NOT NULL,
+	target          VARCHAR(2048) NOT NULL,
+	secret          VARCHAR(255)  NOT NULL DEFAULT "",
+	disabled        BOOLEAN       NOT NULL DEFAULT 0,
+	CONSTRAINT PRIMARY KEY (id),
+	CONSTRAINT foreign_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+	CONSTRAINT foreign_aws_account FOREIGN KEY (aws_account_id) REFERENCES aws_account(id) ON DELETE CASCADE
+);
+
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+CREATE TABLE anomaly_state (
+	id              INTEGER       NOT NULL AUTO_INCREMENT,
+	created         TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	aws_account_id  INTEGER       NOT NULL,
+	anomaly_id      VARCHAR(255)  NOT NULL,
+	state           VARCHAR(16)   NOT NULL DEFAULT "open",
+	assignee_id     INTEGER       NULL DEFAULT NULL,
+	CONSTRAINT PRIMARY KEY (id),
+	CONSTRAINT unique_aws_account_anomaly UNIQUE (aws_account_id, anomaly_id),
+	CONSTRAINT foreign_aws_account FOREIGN KEY (aws_account_id) REFERENCES aws_account(id) ON DELETE CASCADE,
+	CONSTRAINT foreign_assignee FOREIGN KEY (assignee_id) REFERENCES user(id) ON DELETE SET NULL
+);
+
+CREATE TABLE anomaly_event (
+	id                INTEGER       NOT NULL AUTO_INCREMENT,
+	created           TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	anomaly_state_id  INTEGER       NOT NULL,
+	user_id           INTEGER       NULL DEFAULT NULL,
+	type              VARCHAR(16)   NOT NULL,
+	state             VARCHAR(16)   NOT NULL DEFAULT "",
+	assignee_id       INTEGER       NULL DEFAULT NULL,
+	comment           TEXT          NOT NULL,
+	CONSTRAINT PRIMARY KEY (id),
+	CONSTRAINT foreign_anomaly_state FOREIGN KEY (anomaly_state_id) REFERENCES anomaly_state(id) ON DELETE CASCADE,
+	CONSTRAINT foreign_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE SET NULL,
+	CONSTRAINT foreign_event_assignee FOREIGN KEY (assignee_id) REFERENCES user(id) ON DELETE SET NULL
+);
+
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+CREATE TABLE digest_subscription (
+	id              INTEGER       NOT NULL AUTO_INCREMENT,
+	created         TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	user_id         INTEGER       NOT NULL,
+	aws_account_id  INTEGER       NULL DEFAULT NULL,
+	frequency       VARCHAR(16)   NOT NULL DEFAULT "weekly",
+	hour            INTEGER       NOT NULL DEFAULT 8,
+	last_sent       TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT PRIMARY KEY (id),
+	CONSTRAINT foreign_user FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+	CONSTRAINT foreign_aws_account FOREIGN KEY (aws_account_id) REFERENCES aws_account(id) ON DELETE CASCADE
+);
+
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+ALTER TABLE user ADD (
+  anomalies_settings BLOB NULL DEFAULT NULL
+);
+
+ALTER TABLE aws_account ADD (
+  anomalies_settings BLOB NULL DEFAULT NULL
+);
+
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+
+CREATE TABLE aws_bill_period_index (
+	id                      INTEGER       NOT NULL AUTO_INCREMENT,
+	modified                TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	aws_bill_repository_id  INTEGER       NOT NULL,
+	billing_period          DATETIME      NOT NULL,
+	es_index                VARCHAR(255)  NOT NULL,
+	CONSTRAINT PRIMARY KEY (id),
+	CONSTRAINT unique_bill_repository_period UNIQUE (aws_bill_repository_id, billing_period),
+	CONSTRAINT foreign_period_index_bill_repository FOREIGN KEY (aws_bill_repository_id) REFERENCES aws_bill_repository(id) ON DELETE CASCADE
+);
+
+CREATE TABLE aws_bill_checkpoint (
+	id                      INTEGER        NOT NULL AUTO_INCREMENT,
+	created                 TIMESTAMP      NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	aws_bill_repository_id  INTEGER        NOT NULL,
+	es_index                VARCHAR(255)   NOT NULL,
+	report_key              VARCHAR(1024)  NOT NULL,
+	line_items              INTEGER        NOT NULL,
+	CONSTRAINT PRIMARY KEY (id),
+	INDEX index_bill_repository_es_index (aws_bill_repository_id, es_index),
+	CONSTRAINT foreign_checkpoint_bill_repository FOREIGN KEY (aws_bill_repository_id) REFERENCES aws_bill_repository(id) ON DELETE CASCADE
+);
+
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+
+ALTER TABLE aws_bill_repository ADD backend           VARCHAR(16)  NOT NULL DEFAULT "s3";
+ALTER TABLE aws_bill_repository ADD endpoint          VARCHAR(255) NOT NULL DEFAULT "";
+ALTER TABLE aws_bill_repository ADD access_key_id     VARCHAR(255) NOT NULL DEFAULT "";
+ALTER TABLE aws_bill_repository ADD secret_access_key VARCHAR(255) NOT NULL DEFAULT "";
+
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+
+ALTER TABLE aws_bill_update_job ADD manifests_total INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE aws_bill_update_job ADD manifests_done  INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE aws_bill_update_job ADD keys_total      INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE aws_bill_update_job ADD keys_done       INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE aws_bill_update_job ADD bytes_total     BIGINT  NOT NULL DEFAULT 0;
+ALTER TABLE aws_bill_update_job ADD bytes_done      BIGINT  NOT NULL DEFAULT 0;
+ALTER TABLE aws_bill_update_job ADD line_items      BIGINT  NOT NULL DEFAULT 0;
+
+--   Copyright 2019 MSolution.IO
+--
+--   Licensed under the Apache License, Version 2.0 (the "License");
+--   you may not use this file except in compliance with the License.
+--   You may obtain a copy of the License at
+--
+--       http://www.apache.org/licenses/LICENSE-2.0
+--
+--   Unless required by applicable law or agreed to in writing, software
+--   distributed under the License is distributed on an "AS IS" BASIS,
+--   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+--   See the License for the specific language governing permissions and
+--   limitations under the License.
+
+
+-- Line items ingested before the blended, amortized, net and public on-demand
+-- costs were added lack them: import every bill again at the next update.
+UPDATE aws_bill_repository SET last_imported_manifest = "1970-01-01 00:00:00", next_update = NOW();
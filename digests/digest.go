@@ -0,0 +1,222 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package digests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/anomaliesDetection"
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/costs/diff"
+	"github.com/trackit/trackit/mail"
+	"github.com/trackit/trackit/models"
+	"github.com/trackit/trackit/users"
+)
+
+// maxCostMovers is the number of usage types listed as the biggest cost
+// changes of an AWS account.
+const maxCostMovers = 5
+
+var errAwsAccountNotFound = errors.New("aws account not found")
+
+// SendDigests sends the digests which are due to their subscribers. Each
+// digest is recorded as sent in its own transaction, so that the digests
+// already mailed are not sent again if a later one fails or the task stops.
+func SendDigests(ctx context.Context, db *sql.DB) error {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	dbSubscriptions, err := models.AllDigestSubscriptions(db)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, dbSubscription := range dbSubscriptions {
+		schedule := lastSchedule(dbSubscription.Frequency, dbSubscription.Hour, now)
+		if !dbSubscription.LastSent.Before(schedule) {
+			continue
+		}
+		if err := sendDigestInTransaction(ctx, db, *dbSubscription, schedule); err != nil {
+			logger.Error("Failed to send digest.", map[string]interface{}{
+				"subscriptionId": dbSubscription.ID,
+				"error":          err.Error(),
+			})
+		}
+	}
+	return nil
+}
+
+// sendDigestInTransaction sends a digest within its own transaction, which is
+// committed as soon as the digest is sent.
+func sendDigestInTransaction(ctx context.Context, db *sql.DB, dbSubscription models.DigestSubscription, schedule time.Time) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	return sendDigest(ctx, tx, dbSubscription, schedule)
+}
+
+// sendDigest sends the digest scheduled at schedule to the subscriber and
+// records it was sent.
+func sendDigest(ctx context.Context, tx *sql.Tx, dbSubscription models.DigestSubscription, schedule time.Time) error {
+	user, err := users.GetUserWithId(tx, dbSubscription.UserID)
+	if err != nil {
+		return err
+	}
+	awsAccounts, err := getSubscriptionAwsAccounts(tx, user, dbSubscription.AwsAccountID)
+	if err != nil {
+		return err
+	}
+	begin, end := digestPeriod(dbSubscription.Frequency, schedule)
+	data := mail.DigestData{
+		Frequency: dbSubscription.Frequency,
+		Begin:     begin,
+		End:       end.AddDate(0, 0, -1),
+		Accounts:  make([]mail.DigestAccountData, 0, len(awsAccounts)),
+	}
+	for _, aa := range awsAccounts {
+		accountData, err := getAccountDigest(ctx, tx, aa, dbSubscription.Frequency, begin, end)
+		if err != nil {
+			return err
+		}
+		data.Accounts = append(data.Accounts, accountData)
+	}
+	if err := mail.SendTemplate(user.Email, mail.DigestTemplate, data, ctx); err != nil {
+		return err
+	}
+	dbSubscription.LastSent = time.Now().UTC()
+	return dbSubscription.Update(tx)
+}
+
+// getSubscriptionAwsAccounts returns the AWS accounts summarized by a digest:
+// the subscribed one if any, every account of the user otherwise.
+func getSubscriptionAwsAccounts(tx *sql.Tx, user users.User, awsAccountId sql.NullInt64) ([]aws.AwsAccount, error) {
+	awsAccounts, err := aws.GetAwsAccountsFromUser(user, tx)
+	if err != nil || !awsAccountId.Valid {
+		return awsAccounts, err
+	}
+	for _, aa := range awsAccounts {
+		if aa.Id == int(awsAccountId.Int64) {
+			return []aws.AwsAccount{aa}, nil
+		}
+	}
+	return nil, errAwsAccountNotFound
+}
+
+// getAccountDigest returns the summary of an AWS account between begin and
+// end.
+func getAccountDigest(ctx context.Context, tx *sql.Tx, aa aws.AwsAccount, frequency string, begin, end time.Time) (mail.DigestAccountData, error) {
+	data := mail.DigestAccountData{
+		AwsAccount:  aa.Pretty,
+		AwsIdentity: aa.AwsIdentity,
+	}
+	var err error
+	if data.Anomalies, err = anomalies.GetAnomaliesMailData(ctx, tx, aa, begin, end.Add(-time.Second)); err != nil {
+		return data, err
+	}
+	aggregationPeriod := "day"
+	if frequency == FrequencyWeekly {
+		aggregationPeriod = "week"
+	}
+	movers, err := diff.TaskDiffData(ctx, aa, diff.DateRange{
+		Begin: begin.Add(-end.Sub(begin)),
+		End:   end.Add(-time.Second),
+	}, aggregationPeriod)
+	if err != nil {
+		return data, err
+	}
+	data.Movers = getCostMovers(movers, maxCostMovers)
+	lastDay := end.AddDate(0, 0, -1)
+	monthBegin := time.Date(lastDay.Year(), lastDay.Month(), 1, 0, 0, 0, 0, time.UTC)
+	spend, err := diff.TaskDiffData(ctx, aa, diff.DateRange{
+		Begin: monthBegin.AddDate(0, -1, 0),
+		End:   end.Add(-time.Second),
+	}, "day")
+	if err != nil {
+		return data, err
+	}
+	data.MonthToDate, data.PreviousMonthToDate, data.PreviousMonth = getMonthSpend(spend, lastDay)
+	return data, nil
+}
+
+// getCostMovers returns the limit usage types whose cost changed the most
+// between the last two periods of a cost diff.
+func getCostMovers(costDiff map[string][]diff.PricePoint, limit int) []mail.CostMoverData {
+	movers := make([]mail.CostMoverData, 0, len(costDiff))
+	for usageType, pricePoints := range costDiff {
+		if len(pricePoints) < 2 {
+			continue
+		}
+		previous := pricePoints[len(pricePoints)-2].Cost
+		current := pricePoints[len(pricePoints)-1].Cost
+		if current == previous {
+			continue
+		}
+		movers = append(movers, mail.CostMoverData{
+			UsageType:    usageType,
+			Cost:         current,
+			PreviousCost: previous,
+			Delta:        current - previous,
+		})
+	}
+	sort.Slice(movers, func(i, j int) bool {
+		if math.Abs(movers[i].Delta) != math.Abs(movers[j].Delta) {
+			return math.Abs(movers[i].Delta) > math.Abs(movers[j].Delta)
+		}
+		return movers[i].UsageType < movers[j].UsageType
+	})
+	if len(movers) > limit {
+		movers = movers[:limit]
+	}
+	return movers
+}
+
+// getMonthSpend returns the spend of the month of lastDay up to lastDay, the
+// spend of the previous month up to the same day of the month and the spend
+// of the whole previous month, from a daily cost diff.
+func getMonthSpend(costDiff map[string][]diff.PricePoint, lastDay time.Time) (monthToDate, previousMonthToDate, previousMonth float64) {
+	monthBegin := time.Date(lastDay.Year(), lastDay.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, pricePoints := range costDiff {
+		for _, pricePoint := range pricePoints {
+			if len(pricePoint.Date) < 10 {
+				continue
+			}
+			date, err := time.Parse("2006-01-02", pricePoint.Date[:10])
+			if err != nil || date.After(lastDay) {
+				continue
+			} else if !date.Before(monthBegin) {
+				monthToDate += pricePoint.Cost
+			} else if !date.Before(monthBegin.AddDate(0, -1, 0)) {
+				previousMonth += pricePoint.Cost
+				if date.Day() <= lastDay.Day() {
+					previousMonthToDate += pricePoint.Cost
+				}
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,115 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package digests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trackit/trackit/costs/diff"
+)
+
+func day(month time.Month, d, hour int) time.Time {
+	return time.Date(2019, month, d, hour, 0, 0, 0, time.UTC)
+}
+
+func TestLastSchedule(t *testing.T) {
+	cases := []struct {
+		frequency string
+		date      time.Time
+		expected  time.Time
+	}{
+		// 2019-05-20 is a Monday.
+		{FrequencyDaily, day(time.May, 22, 9), day(time.May, 22, 8)},
+		{FrequencyDaily, day(time.May, 22, 7), day(time.May, 21, 8)},
+		{FrequencyWeekly, day(time.May, 20, 8), day(time.May, 20, 8)},
+		{FrequencyWeekly, day(time.May, 20, 7), day(time.May, 13, 8)},
+		{FrequencyWeekly, day(time.May, 26, 23), day(time.May, 20, 8)},
+	}
+	for _, c := range cases {
+		if schedule := lastSchedule(c.frequency, 8, c.date); !schedule.Equal(c.expected) {
+			t.Errorf("%s at %v: expected %v but got %v", c.frequency, c.date, c.expected, schedule)
+		}
+	}
+}
+
+func TestDigestPeriod(t *testing.T) {
+	begin, end := digestPeriod(FrequencyWeekly, day(time.May, 20, 8))
+	if !begin.Equal(day(time.May, 13, 0)) || !end.Equal(day(time.May, 20, 0)) {
+		t.Errorf("weekly: expected %v - %v but got %v - %v", day(time.May, 13, 0), day(time.May, 20, 0), begin, end)
+	}
+	begin, end = digestPeriod(FrequencyDaily, day(time.May, 22, 8))
+	if !begin.Equal(day(time.May, 21, 0)) || !end.Equal(day(time.May, 22, 0)) {
+		t.Errorf("daily: expected %v - %v but got %v - %v", day(time.May, 21, 0), day(time.May, 22, 0), begin, end)
+	}
+}
+
+func TestSubscriptionValidate(t *testing.T) {
+	s := Subscription{Hour: 8}
+	if err := s.validate(); err != nil || s.Frequency != FrequencyWeekly {
+		t.Errorf("Expected a weekly subscription, got %v (%v)", s, err)
+	}
+	if s := (Subscription{Frequency: "monthly"}); s.validate() != ErrInvalidFrequency {
+		t.Errorf("Expected ErrInvalidFrequency")
+	}
+	if s := (Subscription{Frequency: FrequencyDaily, Hour: 24}); s.validate() != ErrInvalidHour {
+		t.Errorf("Expected ErrInvalidHour")
+	}
+}
+
+func TestGetCostMovers(t *testing.T) {
+	costDiff := map[string][]diff.PricePoint{
+		"BoxUsage":      {{Cost: 100}, {Cost: 150}},
+		"DataTransfer":  {{Cost: 80}, {Cost: 10}},
+		"TimedStorage":  {{Cost: 20}, {Cost: 20}},
+		"Requests":      {{Cost: 1}, {Cost: 6}},
+		"NewUsageType":  {{Cost: 0}, {Cost: 5}},
+		"SinglePeriods": {{Cost: 1000}},
+	}
+	movers := getCostMovers(costDiff, 3)
+	expected := []string{"DataTransfer", "BoxUsage", "NewUsageType"}
+	if len(movers) != len(expected) {
+		t.Fatalf("Expected %d movers, got %v", len(expected), movers)
+	}
+	for i, usageType := range expected {
+		if movers[i].UsageType != usageType {
+			t.Errorf("Expected %s at position %d, got %s", usageType, i, movers[i].UsageType)
+		}
+	}
+	if movers[0].Delta != -70 || movers[0].PreviousCost != 80 || movers[0].Cost != 10 {
+		t.Errorf("Unexpected mover: %v", movers[0])
+	}
+}
+
+func TestGetMonthSpend(t *testing.T) {
+	costDiff := map[string][]diff.PricePoint{
+		"BoxUsage": {
+			{Date: "2019-04-01T00:00:00.000Z", Cost: 10},
+			{Date: "2019-04-10T00:00:00.000Z", Cost: 20},
+			{Date: "2019-04-30T00:00:00.000Z", Cost: 40},
+			{Date: "2019-05-01T00:00:00.000Z", Cost: 5},
+			{Date: "2019-05-10T00:00:00.000Z", Cost: 7},
+		},
+		"DataTransfer": {
+			{Date: "2019-04-05T00:00:00.000Z", Cost: 1},
+			{Date: "2019-05-05T00:00:00.000Z", Cost: 2},
+			{Date: "2019-05-11T00:00:00.000Z", Cost: 100},
+		},
+	}
+	monthToDate, previousMonthToDate, previousMonth := getMonthSpend(costDiff, day(time.May, 10, 0))
+	if monthToDate != 14 || previousMonthToDate != 31 || previousMonth != 71 {
+		t.Errorf("Expected 14, 31 and 71 but got %v, %v and %v", monthToDate, previousMonthToDate, previousMonth)
+	}
+}
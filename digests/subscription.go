@@ -0,0 +1,113 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package digests sends daily or weekly summaries of the new anomalies, the
+// biggest cost changes and the month-to-date spend of AWS accounts.
+package digests
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/trackit/trackit/models"
+)
+
+const (
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+)
+
+// defaultHour is the hour, in UTC, at which digests are sent when none was
+// specified.
+const defaultHour = 8
+
+var (
+	ErrInvalidFrequency = errors.New("frequency must be one of daily or weekly")
+	ErrInvalidHour      = errors.New("hour must be between 0 and 23")
+)
+
+// Subscription is a user's subscription to the digests of an AWS account, or
+// of every account they have access to. Digests are sent at Hour UTC, on
+// Mondays for the weekly ones.
+type Subscription struct {
+	Id           int    `json:"id"`
+	AwsAccountId *int   `json:"awsAccountId,omitempty"`
+	Frequency    string `json:"frequency"`
+	Hour         int    `json:"hour"`
+}
+
+// validate checks a subscription is consistent and fills its defaults.
+func (s *Subscription) validate() error {
+	if s.Frequency == "" {
+		s.Frequency = FrequencyWeekly
+	}
+	if s.Frequency != FrequencyDaily && s.Frequency != FrequencyWeekly {
+		return ErrInvalidFrequency
+	} else if s.Hour < 0 || s.Hour > 23 {
+		return ErrInvalidHour
+	}
+	return nil
+}
+
+// lastSchedule returns the last instant, before date, at which a digest was
+// scheduled to be sent.
+func lastSchedule(frequency string, hour int, date time.Time) time.Time {
+	date = date.UTC()
+	schedule := time.Date(date.Year(), date.Month(), date.Day(), hour, 0, 0, 0, time.UTC)
+	if schedule.After(date) {
+		schedule = schedule.AddDate(0, 0, -1)
+	}
+	if frequency == FrequencyWeekly {
+		schedule = schedule.AddDate(0, 0, -(int(schedule.Weekday()-time.Monday)+7)%7)
+	}
+	return schedule
+}
+
+// digestPeriod returns the first day summarized by a digest scheduled at
+// schedule and the day following the last one.
+func digestPeriod(frequency string, schedule time.Time) (begin, end time.Time) {
+	end = time.Date(schedule.Year(), schedule.Month(), schedule.Day(), 0, 0, 0, 0, time.UTC)
+	if frequency == FrequencyWeekly {
+		return end.AddDate(0, 0, -7), end
+	}
+	return end.AddDate(0, 0, -1), end
+}
+
+// subscriptionFromDbSubscription builds a Subscription from its database
+// representation.
+func subscriptionFromDbSubscription(dbSubscription models.DigestSubscription) Subscription {
+	s := Subscription{
+		Id:        dbSubscription.ID,
+		Frequency: dbSubscription.Frequency,
+		Hour:      dbSubscription.Hour,
+	}
+	if dbSubscription.AwsAccountID.Valid {
+		aaId := int(dbSubscription.AwsAccountID.Int64)
+		s.AwsAccountId = &aaId
+	}
+	return s
+}
+
+// updateDbSubscription copies the content of a Subscription into its
+// database representation.
+func updateDbSubscription(dbSubscription *models.DigestSubscription, s Subscription) {
+	dbSubscription.Frequency = s.Frequency
+	dbSubscription.Hour = s.Hour
+	if s.AwsAccountId != nil {
+		dbSubscription.AwsAccountID = sql.NullInt64{Int64: int64(*s.AwsAccountId), Valid: true}
+	} else {
+		dbSubscription.AwsAccountID = sql.NullInt64{}
+	}
+}
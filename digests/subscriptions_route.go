@@ -0,0 +1,228 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package digests
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/models"
+	"github.com/trackit/trackit/routes"
+	"github.com/trackit/trackit/users"
+)
+
+// subscriptionBody is the body expected when creating or updating a digest
+// subscription. Hour defaults to 8 UTC.
+type subscriptionBody struct {
+	AwsAccountId *int   `json:"awsAccountId"`
+	Frequency    string `json:"frequency"`
+	Hour         *int   `json:"hour"`
+}
+
+var (
+	// subscriptionIdQueryArg allows to get the DB id of a digest subscription
+	// in the URL parameters.
+	subscriptionIdQueryArg = routes.QueryArg{
+		Name:        "subscription-id",
+		Type:        routes.QueryArgInt{},
+		Description: "The DB ID of the digest subscription.",
+	}
+
+	exampleHour             = defaultHour
+	exampleSubscriptionBody = subscriptionBody{
+		Frequency: FrequencyWeekly,
+		Hour:      &exampleHour,
+	}
+)
+
+func init() {
+	routes.MethodMuxer{
+		http.MethodGet: routes.H(getSubscriptions).With(
+			users.RequireAuthenticatedUser{users.ViewerAsParent},
+			routes.Documentation{
+				Summary:     "get the digest subscriptions",
+				Description: "Responds with the digest subscriptions of the user.",
+			},
+		),
+		http.MethodPost: routes.H(postSubscription).With(
+			users.RequireAuthenticatedUser{users.ViewerCannot},
+			routes.RequestContentType{"application/json"},
+			routes.RequestBody{exampleSubscriptionBody},
+			routes.Documentation{
+				Summary:     "subscribe to digests",
+				Description: "Subscribes the user to the daily or weekly digests of an AWS account, or of all of them.",
+			},
+		),
+		http.MethodPatch: routes.H(patchSubscription).With(
+			users.RequireAuthenticatedUser{users.ViewerCannot},
+			routes.RequestContentType{"application/json"},
+			routes.QueryArgs{subscriptionIdQueryArg},
+			routes.RequestBody{exampleSubscriptionBody},
+			routes.Documentation{
+				Summary:     "update a digest subscription",
+				Description: "Updates the digest subscription whose ID is passed in query args.",
+			},
+		),
+		http.MethodDelete: routes.H(deleteSubscription).With(
+			users.RequireAuthenticatedUser{users.ViewerCannot},
+			routes.QueryArgs{subscriptionIdQueryArg},
+			routes.Documentation{
+				Summary:     "unsubscribe from digests",
+				Description: "Deletes the digest subscription whose ID is passed in query args.",
+			},
+		),
+	}.H().With(
+		db.RequestTransaction{db.Db},
+		routes.Documentation{
+			Summary:     "interact with digest subscriptions",
+			Description: "A digest is a daily or weekly mail summarizing the new anomalies, the biggest cost changes and the month-to-date spend of AWS accounts.",
+		},
+	).Register("/digests/subscriptions")
+}
+
+// getSubscriptions returns the digest subscriptions of the user.
+func getSubscriptions(r *http.Request, a routes.Arguments) (int, interface{}) {
+	l := jsonlog.LoggerFromContextOrDefault(r.Context())
+	user := a[users.AuthenticatedUser].(users.User)
+	tx := a[db.Transaction].(*sql.Tx)
+	dbSubscriptions, err := models.DigestSubscriptionsByUserID(tx, user.Id)
+	if err != nil {
+		l.Error("Failed to get digest subscriptions.", map[string]interface{}{
+			"userId": user.Id,
+			"error":  err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("failed to get digest subscriptions")
+	}
+	res := make([]Subscription, len(dbSubscriptions))
+	for i, dbSubscription := range dbSubscriptions {
+		res[i] = subscriptionFromDbSubscription(*dbSubscription)
+	}
+	return http.StatusOK, res
+}
+
+// subscriptionFromBody validates a subscriptionBody and converts it to a
+// Subscription.
+func subscriptionFromBody(tx *sql.Tx, user users.User, body subscriptionBody) (Subscription, error) {
+	s := Subscription{
+		AwsAccountId: body.AwsAccountId,
+		Frequency:    body.Frequency,
+		Hour:         defaultHour,
+	}
+	if body.Hour != nil {
+		s.Hour = *body.Hour
+	}
+	if err := s.validate(); err != nil {
+		return s, err
+	}
+	if s.AwsAccountId != nil {
+		if _, err := getSubscriptionAwsAccounts(tx, user, sql.NullInt64{Int64: int64(*s.AwsAccountId), Valid: true}); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// postSubscription subscribes the user to digests.
+func postSubscription(r *http.Request, a routes.Arguments) (int, interface{}) {
+	l := jsonlog.LoggerFromContextOrDefault(r.Context())
+	user := a[users.AuthenticatedUser].(users.User)
+	tx := a[db.Transaction].(*sql.Tx)
+	var body subscriptionBody
+	routes.MustRequestBody(a, &body)
+	s, err := subscriptionFromBody(tx, user, body)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("Body is invalid (%s).", err.Error())
+	}
+	dbSubscription := models.DigestSubscription{
+		UserID:   user.Id,
+		LastSent: time.Now().UTC(),
+	}
+	updateDbSubscription(&dbSubscription, s)
+	if err := dbSubscription.Insert(tx); err != nil {
+		l.Error("Failed to create digest subscription.", map[string]interface{}{
+			"subscription": s,
+			"error":        err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("failed to create digest subscription")
+	}
+	s.Id = dbSubscription.ID
+	return http.StatusOK, s
+}
+
+// getUserDbSubscription retrieves a digest subscription from the database,
+// ensuring it belongs to the user.
+func getUserDbSubscription(tx *sql.Tx, user users.User, subscriptionId int) (*models.DigestSubscription, error) {
+	dbSubscription, err := models.DigestSubscriptionByID(tx, subscriptionId)
+	if err != nil {
+		return nil, err
+	} else if dbSubscription.UserID != user.Id {
+		return nil, errors.New("digest subscription does not belong to the user")
+	}
+	return dbSubscription, nil
+}
+
+// patchSubscription updates a digest subscription of the user.
+func patchSubscription(r *http.Request, a routes.Arguments) (int, interface{}) {
+	l := jsonlog.LoggerFromContextOrDefault(r.Context())
+	user := a[users.AuthenticatedUser].(users.User)
+	tx := a[db.Transaction].(*sql.Tx)
+	subscriptionId := a[subscriptionIdQueryArg].(int)
+	var body subscriptionBody
+	routes.MustRequestBody(a, &body)
+	s, err := subscriptionFromBody(tx, user, body)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("Body is invalid (%s).", err.Error())
+	}
+	dbSubscription, err := getUserDbSubscription(tx, user, subscriptionId)
+	if err != nil {
+		return http.StatusNotFound, errors.New("digest subscription not found")
+	}
+	updateDbSubscription(dbSubscription, s)
+	if err := dbSubscription.Update(tx); err != nil {
+		l.Error("Failed to update digest subscription.", map[string]interface{}{
+			"subscription": s,
+			"error":        err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("failed to update digest subscription")
+	}
+	s.Id = dbSubscription.ID
+	return http.StatusOK, s
+}
+
+// deleteSubscription deletes a digest subscription of the user.
+func deleteSubscription(r *http.Request, a routes.Arguments) (int, interface{}) {
+	l := jsonlog.LoggerFromContextOrDefault(r.Context())
+	user := a[users.AuthenticatedUser].(users.User)
+	tx := a[db.Transaction].(*sql.Tx)
+	subscriptionId := a[subscriptionIdQueryArg].(int)
+	dbSubscription, err := getUserDbSubscription(tx, user, subscriptionId)
+	if err != nil {
+		return http.StatusNotFound, errors.New("digest subscription not found")
+	}
+	if err := dbSubscription.Delete(tx); err != nil {
+		l.Error("Failed to delete digest subscription.", map[string]interface{}{
+			"subscriptionId": subscriptionId,
+			"error":          err.Error(),
+		})
+		return http.StatusInternalServerError, errors.New("failed to delete digest subscription")
+	}
+	return http.StatusOK, nil
+}
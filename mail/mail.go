@@ -17,7 +17,9 @@ package mail
 import (
 	"context"
 	"crypto/tls"
-	"fmt"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
 	"net"
 	"net/smtp"
 
@@ -26,55 +28,138 @@ import (
 	"github.com/trackit/trackit/config"
 )
 
-// Mail contains the data necessary to send a mail.
+// TlsMode is the way TLS is used to connect to the SMTP server.
+type TlsMode string
+
+const (
+	// TlsStartTls upgrades the connection with STARTTLS and fails if the
+	// server does not support it.
+	TlsStartTls = TlsMode("starttls")
+	// TlsOpportunistic upgrades the connection with STARTTLS if the server
+	// supports it and sends the mail in plain text otherwise.
+	TlsOpportunistic = TlsMode("opportunistic")
+	// TlsImplicit connects to the server with TLS, as with SMTPS on port
+	// 465.
+	TlsImplicit = TlsMode("implicit")
+)
+
+// implicitTlsPort is the default port of the SMTP servers when TlsImplicit
+// is used.
+const implicitTlsPort = "465"
+
+var (
+	ErrUnknownTlsMode      = errors.New("unknown SMTP TLS mode")
+	ErrStartTlsUnsupported = errors.New("SMTP server does not support STARTTLS")
+	ErrInvalidCaBundle     = errors.New("SMTP CA bundle has no valid certificate")
+)
+
+// Mail contains the data necessary to send a mail. TlsMode defaults to
+// TlsStartTls and RootCAs to the certificate authorities of the system.
 type Mail struct {
-	SmtpAddress  string
-	SmtpPort     string
-	SmtpUser     string
-	SmtpPassword string
-	Sender       string
-	Recipient    string
-	Subject      string
-	Body         string
+	SmtpAddress        string
+	SmtpPort           string
+	SmtpUser           string
+	SmtpPassword       string
+	TlsMode            TlsMode
+	RootCAs            *x509.CertPool
+	InsecureSkipVerify bool
+	Message
 }
 
-// SendMail is the easiest way to send a mail.
+// SendMail is the easiest way to send a plain text mail.
 // It gets the SMTP information from the config file.
 func SendMail(recipient string, subject, body string, ctx context.Context) error {
+	return SendMessage(Message{
+		To:      []string{recipient},
+		Subject: subject,
+		Text:    body,
+	}, ctx)
+}
+
+// SendMessage sends a message, from the config sender if it has no sender.
+// It gets the SMTP information from the config file.
+func SendMessage(message Message, ctx context.Context) error {
+	if message.From == "" {
+		message.From = config.SmtpSender
+	}
+	rootCAs, err := LoadCaBundle(config.SmtpCaBundle)
+	if err != nil {
+		return err
+	}
 	mail := Mail{
-		config.SmtpAddress,
-		config.SmtpPort,
-		config.SmtpUser,
-		config.SmtpPassword,
-		config.SmtpSender,
-		recipient,
-		subject,
-		body,
+		SmtpAddress:        config.SmtpAddress,
+		SmtpPort:           config.SmtpPort,
+		SmtpUser:           config.SmtpUser,
+		SmtpPassword:       config.SmtpPassword,
+		TlsMode:            TlsMode(config.SmtpTls),
+		RootCAs:            rootCAs,
+		InsecureSkipVerify: config.SmtpInsecureSkipVerify,
+		Message:            message,
 	}
 	return mail.Send(ctx)
 }
 
-func (m Mail) buildMessage() []byte {
-	message := ""
-	message += fmt.Sprintf("From: %s\r\n", m.Sender)
-	message += fmt.Sprintf("To: %s\r\n", m.Recipient)
-	message += fmt.Sprintf("Subject: %s\r\n", m.Subject)
-	message += "\r\n" + m.Body
-	return []byte(message)
+// LoadCaBundle returns the certificate authorities of a PEM file. It returns
+// nil if path is empty, so that the ones of the system are used.
+func LoadCaBundle(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, ErrInvalidCaBundle
+	}
+	return pool, nil
+}
+
+// SendTemplate sends the message rendered by a template with data.
+// It gets the SMTP information from the config file.
+func SendTemplate(recipient string, template Template, data interface{}, ctx context.Context) error {
+	message, err := template.Render(data)
+	if err != nil {
+		return err
+	}
+	message.To = []string{recipient}
+	return SendMessage(message, ctx)
 }
 
-func (m Mail) setTlsConfig(client *smtp.Client) error {
-	tlsconfig := &tls.Config{
-		InsecureSkipVerify: true,
+func (m Mail) buildMessage() ([]byte, error) {
+	return m.Message.Build()
+}
+
+func (m Mail) getTlsConfig() *tls.Config {
+	return &tls.Config{
 		ServerName:         m.SmtpAddress,
+		RootCAs:            m.RootCAs,
+		InsecureSkipVerify: m.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
 	}
-	if err := client.StartTLS(tlsconfig); err != nil {
-		return err
+}
+
+// setTlsConfig upgrades the connection with STARTTLS unless TlsImplicit is
+// used. It fails if the server does not support it, unless TlsOpportunistic
+// is used.
+func (m Mail) setTlsConfig(client *smtp.Client, tlsMode TlsMode) error {
+	if tlsMode == TlsImplicit {
+		return nil
 	}
-	return nil
+	if ok, _ := client.Extension("STARTTLS"); !ok {
+		if tlsMode == TlsOpportunistic {
+			return nil
+		}
+		return ErrStartTlsUnsupported
+	}
+	return client.StartTLS(m.getTlsConfig())
 }
 
 func (m Mail) setAuth(client *smtp.Client) error {
+	if m.SmtpUser == "" {
+		return nil
+	}
 	auth := smtp.PlainAuth(
 		"",
 		m.SmtpUser,
@@ -87,43 +172,72 @@ func (m Mail) setAuth(client *smtp.Client) error {
 	return nil
 }
 
+// dial connects to the SMTP server, with TLS if TlsImplicit is used.
+func (m Mail) dial(ctx context.Context, tlsMode TlsMode) (net.Conn, error) {
+	port := m.SmtpPort
+	if port == "" && tlsMode == TlsImplicit {
+		port = implicitTlsPort
+	}
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.SmtpAddress, port))
+	if err != nil || tlsMode != TlsImplicit {
+		return conn, err
+	}
+	tlsConn := tls.Client(conn, m.getTlsConfig())
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
 // getSmtpClient creates a smtp.Client with information
 // from the Mail structure.
 func (m Mail) getSmtpClient(ctx context.Context) (*smtp.Client, error) {
-
-	conn, err := net.Dial("tcp", m.SmtpAddress+":"+m.SmtpPort)
+	tlsMode := m.TlsMode
+	if tlsMode == "" {
+		tlsMode = TlsStartTls
+	} else if tlsMode != TlsStartTls && tlsMode != TlsOpportunistic && tlsMode != TlsImplicit {
+		return nil, ErrUnknownTlsMode
+	}
+	conn, err := m.dial(ctx, tlsMode)
 	if err != nil {
 		return nil, err
 	}
 	client, err := smtp.NewClient(conn, m.SmtpAddress)
 	if err != nil {
-		return client, err
+		conn.Close()
+		return nil, err
 	}
-	if err := m.setTlsConfig(client); err != nil {
-		return client, err
+	if err := m.setTlsConfig(client, tlsMode); err != nil {
+		client.Close()
+		return nil, err
 	}
 	if err := m.setAuth(client); err != nil {
-		return client, err
+		client.Close()
+		return nil, err
 	}
 	return client, nil
 }
 
 func (m Mail) setAddresses(client *smtp.Client) error {
-	if err := client.Mail(m.Sender); err != nil {
+	if err := client.Mail(m.From); err != nil {
 		return err
 	}
-	if err := client.Rcpt(m.Recipient); err != nil {
-		return err
+	for _, recipient := range m.Recipients() {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (m Mail) setMessage(client *smtp.Client) error {
+func (m Mail) setMessage(client *smtp.Client, message []byte) error {
 	w, err := client.Data()
 	if err != nil {
 		return err
 	}
-	if _, err := w.Write(m.buildMessage()); err != nil {
+	if _, err := w.Write(message); err != nil {
 		return err
 	}
 	if err := w.Close(); err != nil {
@@ -135,18 +249,23 @@ func (m Mail) setMessage(client *smtp.Client) error {
 // Send provides a way to send a mail with SMTP information
 // from the Mail structure.
 func (m Mail) Send(ctx context.Context) error {
-	dataLogged := map[string]interface{}{"subject": m.Subject, "recipient": m.Recipient}
+	dataLogged := map[string]interface{}{"subject": m.Subject, "recipients": m.Recipients()}
 	logger := jsonlog.LoggerFromContextOrDefault(ctx)
 	logger.Info("Sending mail.", dataLogged)
 
+	message, err := m.buildMessage()
+	if err != nil {
+		return err
+	}
 	client, err := m.getSmtpClient(ctx)
 	if err != nil {
 		return err
 	}
+	defer client.Close()
 	if err := m.setAddresses(client); err != nil {
 		return err
 	}
-	if err := m.setMessage(client); err != nil {
+	if err := m.setMessage(client, message); err != nil {
 		return err
 	}
 	if err := client.Quit(); err != nil {
@@ -16,25 +16,46 @@ package mail
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
+var testDate = time.Date(2019, time.March, 4, 10, 30, 0, 0, time.UTC)
+
 func TestSendMail(t *testing.T) {
 	m := Mail{
-		"",
-		"",
-		"",
-		"",
-		"team@msolution.io",
-		"thibaut@trackit.io",
-		"test subject!",
-		"test body!",
-	}
-	msg := m.buildMessage()
+		Message: Message{
+			From:      "team@msolution.io",
+			To:        []string{"thibaut@trackit.io"},
+			Subject:   "test subject!",
+			Text:      "test body!",
+			Date:      testDate,
+			MessageId: "<0123456789@msolution.io>",
+		},
+	}
+	msg, err := m.buildMessage()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
 	template := []byte(
-		"From: " + m.Sender + "\r\n" +
-			"To: " + m.Recipient + "\r\n" +
-			"Subject: " + m.Subject + "\r\n" +
+		"From: team@msolution.io\r\n" +
+			"To: thibaut@trackit.io\r\n" +
+			"Subject: test subject!\r\n" +
+			"Date: Mon, 04 Mar 2019 10:30:00 +0000\r\n" +
+			"Message-ID: <0123456789@msolution.io>\r\n" +
+			"MIME-Version: 1.0\r\n" +
+			"Content-Type: text/plain; charset=utf-8\r\n" +
+			"Content-Transfer-Encoding: quoted-printable\r\n" +
 			"\r\n" +
 			"test body!",
 	)
@@ -42,3 +63,252 @@ func TestSendMail(t *testing.T) {
 		t.Fatalf("Unexcepted message: (%s) instead of (%s)", msg, template)
 	}
 }
+
+func TestBuildNoRecipient(t *testing.T) {
+	if _, err := (Message{From: "team@msolution.io"}).Build(); err != ErrNoRecipient {
+		t.Fatalf("Expected ErrNoRecipient, got %v", err)
+	}
+}
+
+func TestBuildRecipients(t *testing.T) {
+	m := Message{
+		From: "team@msolution.io",
+		To:   []string{"a@trackit.io", "b@trackit.io"},
+		Cc:   []string{"c@trackit.io"},
+		Bcc:  []string{"d@trackit.io"},
+		Text: "body",
+	}
+	if recipients := m.Recipients(); len(recipients) != 4 {
+		t.Fatalf("Expected 4 recipients, got %v", recipients)
+	}
+	raw, err := m.Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %s", err.Error())
+	}
+	if to := msg.Header.Get("To"); to != "a@trackit.io, b@trackit.io" {
+		t.Errorf("Unexpected To header: %s", to)
+	}
+	if cc := msg.Header.Get("Cc"); cc != "c@trackit.io" {
+		t.Errorf("Unexpected Cc header: %s", cc)
+	}
+	if strings.Contains(string(raw), "d@trackit.io") {
+		t.Errorf("Bcc recipient disclosed in message")
+	}
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("Invalid Date header: %s", err.Error())
+	}
+	if id := msg.Header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@msolution.io>") {
+		t.Errorf("Unexpected Message-ID header: %s", id)
+	}
+}
+
+func TestBuildMultipart(t *testing.T) {
+	m := Message{
+		From:    "team@msolution.io",
+		To:      []string{"thibaut@trackit.io"},
+		Subject: "Rapport mensuel",
+		Text:    "text body",
+		Html:    "<p>html body</p>",
+		Attachments: []Attachment{
+			{Filename: "report.xlsx", ContentType: "application/octet-stream", Content: bytes.Repeat([]byte{0, 1, 2, 3}, 100)},
+		},
+	}
+	raw, err := m.Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %s", err.Error())
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Unexpected Content-Type: %s", msg.Header.Get("Content-Type"))
+	}
+	mixed := multipart.NewReader(msg.Body, params["boundary"])
+	alternative, err := mixed.NextPart()
+	if err != nil {
+		t.Fatalf("Missing body part: %s", err.Error())
+	}
+	mediaType, params, _ = mime.ParseMediaType(alternative.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Unexpected body Content-Type: %s", mediaType)
+	}
+	bodies := multipart.NewReader(alternative, params["boundary"])
+	for _, expected := range []struct {
+		mediaType string
+		content   string
+	}{
+		{"text/plain", m.Text},
+		{"text/html", m.Html},
+	} {
+		part, err := bodies.NextPart()
+		if err != nil {
+			t.Fatalf("Missing %s part: %s", expected.mediaType, err.Error())
+		}
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		content, _ := ioutil.ReadAll(part)
+		if mediaType != expected.mediaType || string(content) != expected.content {
+			t.Errorf("Unexpected part: %s (%s) instead of %s (%s)", mediaType, content, expected.mediaType, expected.content)
+		}
+	}
+	attachment, err := mixed.NextPart()
+	if err != nil {
+		t.Fatalf("Missing attachment: %s", err.Error())
+	}
+	if attachment.FileName() != "report.xlsx" {
+		t.Errorf("Unexpected attachment filename: %s", attachment.FileName())
+	}
+	encoded, _ := ioutil.ReadAll(attachment)
+	for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\r\n") {
+		if len(line) > base64LineLength {
+			t.Errorf("Attachment line longer than %d characters", base64LineLength)
+		}
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	msg, err := PasswordResetTemplate.Render(LinkData{Link: "https://re.trackit.io/reset/1/token"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if msg.Subject == "" {
+		t.Errorf("Empty subject")
+	}
+	if !strings.Contains(msg.Text, "https://re.trackit.io/reset/1/token") || !strings.Contains(msg.Html, "https://re.trackit.io/reset/1/token") {
+		t.Errorf("Link missing from rendered template")
+	}
+	msg, err = AnomaliesTemplate.Render(AnomaliesData{
+		AwsAccount:  "<prod>",
+		AwsIdentity: "123456789012",
+		Anomalies: []AnomalyData{
+			{Date: testDate, Dimension: "product", Value: "AmazonEC2", Cost: 120, MaxExpected: 80},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if !strings.Contains(msg.Text, "AmazonEC2") || !strings.Contains(msg.Html, "AmazonEC2") {
+		t.Errorf("Anomaly missing from rendered template")
+	}
+	if strings.Contains(msg.Html, "<prod>") {
+		t.Errorf("Account name not escaped in HTML template")
+	}
+}
+
+func TestSendTls(t *testing.T) {
+	certificate, pool, _ := newTestCertificate(t)
+	for _, c := range []struct {
+		name               string
+		startTls           bool
+		implicitTls        bool
+		tlsMode            TlsMode
+		rootCAs            *x509.CertPool
+		insecureSkipVerify bool
+		user               string
+		expectedTls        bool
+		expectedError      bool
+	}{
+		{"starttls", true, false, TlsStartTls, pool, false, "user", true, false},
+		{"starttls by default", true, false, "", pool, false, "", true, false},
+		{"starttls unsupported", false, false, TlsStartTls, pool, false, "", false, true},
+		{"starttls unverified certificate", true, false, TlsStartTls, nil, false, "", false, true},
+		{"starttls insecure", true, false, TlsStartTls, nil, true, "", true, false},
+		{"opportunistic with starttls", true, false, TlsOpportunistic, pool, false, "", true, false},
+		{"opportunistic without starttls", false, false, TlsOpportunistic, pool, false, "", false, false},
+		{"opportunistic unverified certificate", true, false, TlsOpportunistic, nil, false, "", false, true},
+		{"implicit", false, true, TlsImplicit, pool, false, "user", true, false},
+		{"implicit unverified certificate", false, true, TlsImplicit, nil, false, "", false, true},
+		{"implicit on plain server", true, false, TlsImplicit, pool, false, "", false, true},
+		{"unknown mode", true, false, TlsMode("ssl"), pool, false, "", false, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			server := newTestSmtpServer(t, certificate, c.startTls, c.implicitTls)
+			defer server.close()
+			m := Mail{
+				SmtpAddress:        "127.0.0.1",
+				SmtpPort:           server.port(),
+				SmtpUser:           c.user,
+				SmtpPassword:       "password",
+				TlsMode:            c.tlsMode,
+				RootCAs:            c.rootCAs,
+				InsecureSkipVerify: c.insecureSkipVerify,
+				Message: Message{
+					From:    "team@msolution.io",
+					To:      []string{"thibaut@trackit.io"},
+					Bcc:     []string{"bcc@trackit.io"},
+					Subject: "test subject!",
+					Text:    "test body!",
+				},
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := m.Send(ctx)
+			received := server.received()
+			if c.expectedError {
+				if err == nil {
+					t.Fatalf("Expected an error")
+				}
+				if len(received) != 0 {
+					t.Fatalf("Mail sent despite the error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err.Error())
+			}
+			if len(received) != 1 {
+				t.Fatalf("Expected 1 mail, got %d", len(received))
+			}
+			if received[0].tls != c.expectedTls {
+				t.Errorf("Expected TLS to be %t", c.expectedTls)
+			}
+			if received[0].auth != (c.user != "") {
+				t.Errorf("Expected authentication to be %t", c.user != "")
+			}
+			if len(received[0].recipients) != 2 {
+				t.Errorf("Expected 2 recipients, got %v", received[0].recipients)
+			}
+			if !strings.Contains(received[0].data, "test body!") {
+				t.Errorf("Unexpected data: %s", received[0].data)
+			}
+		})
+	}
+}
+
+func TestLoadCaBundle(t *testing.T) {
+	certificate, _, certificatePem := newTestCertificate(t)
+	dir, err := ioutil.TempDir("", "trackit-mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if pool, err := LoadCaBundle(""); pool != nil || err != nil {
+		t.Errorf("Expected no pool and no error for an empty path")
+	}
+	invalid := filepath.Join(dir, "invalid.pem")
+	ioutil.WriteFile(invalid, []byte("not a certificate"), 0600)
+	if _, err := LoadCaBundle(invalid); err != ErrInvalidCaBundle {
+		t.Errorf("Expected ErrInvalidCaBundle, got %v", err)
+	}
+	if _, err := LoadCaBundle(filepath.Join(dir, "missing.pem")); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+	valid := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(valid, certificatePem, 0600)
+	pool, err := LoadCaBundle(valid)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	server := newTestSmtpServer(t, certificate, false, true)
+	defer server.close()
+	conn, err := tls.Dial("tcp", "127.0.0.1:"+server.port(), &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("Certificate not trusted with the CA bundle: %s", err.Error())
+	}
+	conn.Close()
+}
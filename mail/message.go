@@ -0,0 +1,240 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// base64LineLength is the maximum length of the lines of the base64 encoded
+// attachments, as required by RFC 2045.
+const base64LineLength = 76
+
+var ErrNoRecipient = errors.New("mail has no recipient")
+
+// Attachment is a file attached to a mail.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// Message is the content of a mail. It is sent as plain text if it has no
+// Html and no Attachments, and as a MIME multipart message otherwise.
+// Date defaults to the current date and MessageId to a random identifier.
+type Message struct {
+	From        string
+	To          []string
+	Cc          []string
+	Bcc         []string
+	Subject     string
+	Text        string
+	Html        string
+	Attachments []Attachment
+	Date        time.Time
+	MessageId   string
+}
+
+// Recipients returns the addresses of all the recipients of the message,
+// including the blind carbon copies.
+func (m Message) Recipients() []string {
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	recipients = append(recipients, m.To...)
+	recipients = append(recipients, m.Cc...)
+	return append(recipients, m.Bcc...)
+}
+
+// generateMessageId returns a random Message-ID in the domain of the sender.
+func generateMessageId(from string) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	domain := "trackit.io"
+	if at := strings.LastIndex(from, "@"); at != -1 {
+		domain = strings.TrimRight(from[at+1:], ">")
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(id), domain), nil
+}
+
+// writeHeader writes the headers of the message. The Bcc recipients are not
+// written as they must not be disclosed.
+func (m Message) writeHeader(buffer *bytes.Buffer) error {
+	date := m.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	messageId := m.MessageId
+	if messageId == "" {
+		var err error
+		if messageId, err = generateMessageId(m.From); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(buffer, "From: %s\r\n", m.From)
+	if len(m.To) > 0 {
+		fmt.Fprintf(buffer, "To: %s\r\n", strings.Join(m.To, ", "))
+	}
+	if len(m.Cc) > 0 {
+		fmt.Fprintf(buffer, "Cc: %s\r\n", strings.Join(m.Cc, ", "))
+	}
+	fmt.Fprintf(buffer, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(buffer, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(buffer, "Message-ID: %s\r\n", messageId)
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+	return nil
+}
+
+// writeQuotedPrintable writes content encoded as quoted-printable.
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// writeBase64 writes content encoded as base64, in lines of base64LineLength.
+func writeBase64(w io.Writer, content []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > base64LineLength {
+		if _, err := io.WriteString(w, encoded[:base64LineLength]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[base64LineLength:]
+	}
+	_, err := io.WriteString(w, encoded+"\r\n")
+	return err
+}
+
+// textPartHeader returns the header of a text part of the message.
+func textPartHeader(subtype string) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Type":              {"text/" + subtype + "; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	}
+}
+
+// writeAlternative writes the text and the HTML versions of the body in a
+// multipart/alternative part created by createPart.
+func (m Message) writeAlternative(createPart func(textproto.MIMEHeader) (io.Writer, error)) error {
+	var body bytes.Buffer
+	alternative := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		subtype string
+		content string
+	}{
+		{"plain", m.Text},
+		{"html", m.Html},
+	} {
+		if w, err := alternative.CreatePart(textPartHeader(part.subtype)); err != nil {
+			return err
+		} else if err := writeQuotedPrintable(w, part.content); err != nil {
+			return err
+		}
+	}
+	if err := alternative.Close(); err != nil {
+		return err
+	}
+	w, err := createPart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + alternative.Boundary()},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(body.Bytes())
+	return err
+}
+
+// writeBody writes the body of the message, which is a text part, a
+// multipart/alternative part or, if there are attachments, a multipart/mixed
+// part.
+func (m Message) writeBody(buffer *bytes.Buffer) error {
+	writeHeaderPart := func(header textproto.MIMEHeader) (io.Writer, error) {
+		for _, key := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+			if value := header.Get(key); value != "" {
+				fmt.Fprintf(buffer, "%s: %s\r\n", key, value)
+			}
+		}
+		buffer.WriteString("\r\n")
+		return buffer, nil
+	}
+	if len(m.Attachments) == 0 {
+		if m.Html == "" {
+			w, _ := writeHeaderPart(textPartHeader("plain"))
+			return writeQuotedPrintable(w, m.Text)
+		}
+		return m.writeAlternative(writeHeaderPart)
+	}
+	var body bytes.Buffer
+	mixed := multipart.NewWriter(&body)
+	if m.Html == "" {
+		if w, err := mixed.CreatePart(textPartHeader("plain")); err != nil {
+			return err
+		} else if err := writeQuotedPrintable(w, m.Text); err != nil {
+			return err
+		}
+	} else if err := m.writeAlternative(mixed.CreatePart); err != nil {
+		return err
+	}
+	for _, attachment := range m.Attachments {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(contentType, map[string]string{"name": attachment.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		if err != nil {
+			return err
+		} else if err := writeBase64(w, attachment.Content); err != nil {
+			return err
+		}
+	}
+	if err := mixed.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(buffer, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mixed.Boundary())
+	_, err := buffer.Write(body.Bytes())
+	return err
+}
+
+// Build returns the message formatted as described in RFC 5322.
+func (m Message) Build() ([]byte, error) {
+	if len(m.Recipients()) == 0 {
+		return nil, ErrNoRecipient
+	}
+	var buffer bytes.Buffer
+	if err := m.writeHeader(&buffer); err != nil {
+		return nil, err
+	} else if err := m.writeBody(&buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
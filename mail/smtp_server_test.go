@@ -0,0 +1,208 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package mail
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSmtpServer is a minimal SMTP server used to test the TLS modes of Mail.
+// It supports STARTTLS if startTls is set and serves TLS from the start of
+// the connection if implicitTls is set.
+type testSmtpServer struct {
+	listener    net.Listener
+	tlsConfig   *tls.Config
+	startTls    bool
+	implicitTls bool
+
+	mutex    sync.Mutex
+	messages []testSmtpMessage
+}
+
+// testSmtpMessage is a message received by a testSmtpServer.
+type testSmtpMessage struct {
+	from       string
+	recipients []string
+	data       string
+	tls        bool
+	auth       bool
+}
+
+// newTestCertificate returns a self-signed certificate valid for 127.0.0.1
+// along with a pool holding it, to be used as a CA bundle.
+func newTestCertificate(t *testing.T) (tls.Certificate, *x509.CertPool, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "trackit test SMTP server"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// newTestSmtpServer starts a testSmtpServer on a random port of 127.0.0.1.
+func newTestSmtpServer(t *testing.T, certificate tls.Certificate, startTls, implicitTls bool) *testSmtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testSmtpServer{
+		listener:    listener,
+		tlsConfig:   &tls.Config{Certificates: []tls.Certificate{certificate}},
+		startTls:    startTls,
+		implicitTls: implicitTls,
+	}
+	go s.serve()
+	return s
+}
+
+func (s *testSmtpServer) port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+func (s *testSmtpServer) close() {
+	s.listener.Close()
+}
+
+func (s *testSmtpServer) received() []testSmtpMessage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]testSmtpMessage{}, s.messages...)
+}
+
+func (s *testSmtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle runs an SMTP session. It only implements the commands used by
+// net/smtp.
+func (s *testSmtpServer) handle(conn net.Conn) {
+	defer func() { conn.Close() }()
+	isTls := s.implicitTls
+	if isTls {
+		conn = tls.Server(conn, s.tlsConfig)
+	}
+	reader := bufio.NewReader(conn)
+	reply := func(lines ...string) {
+		for i, line := range lines {
+			separator := "-"
+			if i == len(lines)-1 {
+				separator = " "
+			}
+			conn.Write([]byte(line[:3] + separator + line[4:] + "\r\n"))
+		}
+	}
+	var message testSmtpMessage
+	reply("220 trackit test SMTP server")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO":
+			extensions := []string{"250 localhost"}
+			if s.startTls && !isTls {
+				extensions = append(extensions, "250 STARTTLS")
+			}
+			reply(append(extensions, "250 AUTH PLAIN")...)
+		case "STARTTLS":
+			if !s.startTls || isTls {
+				reply("502 not supported")
+				continue
+			}
+			reply("220 ready to start TLS")
+			conn = tls.Server(conn, s.tlsConfig)
+			reader = bufio.NewReader(conn)
+			isTls = true
+		case "AUTH":
+			message.auth = true
+			reply("235 authenticated")
+		case "MAIL":
+			message.from = line
+			reply("250 ok")
+		case "RCPT":
+			message.recipients = append(message.recipients, line)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			message.data = data.String()
+			message.tls = isTls
+			s.mutex.Lock()
+			s.messages = append(s.messages, message)
+			s.mutex.Unlock()
+			message = testSmtpMessage{}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
@@ -0,0 +1,213 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+)
+
+// trackitUrl is the URL of the Trackit web application linked in the mails.
+const trackitUrl = "https://re.trackit.io/"
+
+type (
+	// Template renders the subject, the plain text body and the HTML body of
+	// a kind of mail.
+	Template struct {
+		subject *texttemplate.Template
+		text    *texttemplate.Template
+		html    *htmltemplate.Template
+	}
+
+	// AnomalyData is an anomaly listed in AnomaliesData.
+	AnomalyData struct {
+		Date        time.Time
+		Dimension   string
+		Value       string
+		Cost        float64
+		MaxExpected float64
+	}
+
+	// AnomaliesData is the data of AnomaliesTemplate.
+	AnomaliesData struct {
+		AwsAccount  string
+		AwsIdentity string
+		Anomalies   []AnomalyData
+	}
+
+	// LinkData is the data of the templates whose mail contains a link to
+	// follow, such as PasswordResetTemplate.
+	LinkData struct {
+		Link string
+	}
+
+	// CostMoverData is a usage type whose cost changed between two periods,
+	// listed in DigestAccountData.
+	CostMoverData struct {
+		UsageType    string
+		Cost         float64
+		PreviousCost float64
+		Delta        float64
+	}
+
+	// DigestAccountData is the summary of an AWS account in DigestData.
+	// PreviousMonthToDate is the spend of the previous month up to the same
+	// day of the month as MonthToDate.
+	DigestAccountData struct {
+		AwsAccount          string
+		AwsIdentity         string
+		Anomalies           []AnomalyData
+		Movers              []CostMoverData
+		MonthToDate         float64
+		PreviousMonthToDate float64
+		PreviousMonth       float64
+	}
+
+	// DigestData is the data of DigestTemplate. Begin and End are the first
+	// and the last days summarized by the digest.
+	DigestData struct {
+		Frequency string
+		Begin     time.Time
+		End       time.Time
+		Accounts  []DigestAccountData
+	}
+
+	// ReportData is the data of MonthlyReportTemplate.
+	ReportData struct {
+		AwsAccount string
+		Month      string
+	}
+)
+
+// newTemplate parses the templates of a kind of mail. It panics if one of
+// them is invalid.
+func newTemplate(name, subject, text, html string) Template {
+	funcs := map[string]interface{}{"trackitUrl": func() string { return trackitUrl }}
+	return Template{
+		subject: texttemplate.Must(texttemplate.New(name + "Subject").Funcs(funcs).Parse(subject)),
+		text:    texttemplate.Must(texttemplate.New(name + "Text").Funcs(funcs).Parse(text)),
+		html:    htmltemplate.Must(htmltemplate.New(name + "Html").Funcs(funcs).Parse(htmlLayoutBegin + html + htmlLayoutEnd)),
+	}
+}
+
+// Render returns a message whose subject and bodies are rendered with data.
+func (t Template) Render(data interface{}) (Message, error) {
+	var subject, text, html bytes.Buffer
+	if err := t.subject.Execute(&subject, data); err != nil {
+		return Message{}, err
+	} else if err := t.text.Execute(&text, data); err != nil {
+		return Message{}, err
+	} else if err := t.html.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Subject: subject.String(),
+		Text:    text.String(),
+		Html:    html.String(),
+	}, nil
+}
+
+const htmlLayoutBegin = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body style="font-family: Helvetica, Arial, sans-serif; color: #333333;">
+`
+
+const htmlLayoutEnd = `
+<p style="color: #999999; font-size: 12px;">Trackit - <a href="{{trackitUrl}}">{{trackitUrl}}</a></p>
+</body>
+</html>
+`
+
+var (
+	// AnomaliesTemplate is the template of the anomaly alerts.
+	AnomaliesTemplate = newTemplate("anomalies",
+		`{{if eq (len .Anomalies) 1}}Cost anomaly detected{{else}}{{len .Anomalies}} cost anomalies detected{{end}} on {{.AwsAccount}}`,
+		`Hi, unusual costs were detected on your AWS account {{.AwsAccount}} ({{.AwsIdentity}}):
+{{range .Anomalies}}- {{.Date.Format "2006-01-02"}}, {{.Dimension}} {{.Value}}: ${{printf "%.2f" .Cost}} spent while at most ${{printf "%.2f" .MaxExpected}} was expected
+{{end}}You can connect to your account to see the details: {{trackitUrl}}`,
+		`<p>Hi, unusual costs were detected on your AWS account <b>{{.AwsAccount}}</b> ({{.AwsIdentity}}):</p>
+<table style="border-collapse: collapse;">
+<tr><th align="left">Date</th><th align="left">Dimension</th><th align="left">Value</th><th align="right">Cost</th><th align="right">Expected at most</th></tr>
+{{range .Anomalies}}<tr><td>{{.Date.Format "2006-01-02"}}</td><td>{{.Dimension}}</td><td>{{.Value}}</td><td align="right">${{printf "%.2f" .Cost}}</td><td align="right">${{printf "%.2f" .MaxExpected}}</td></tr>
+{{end}}</table>
+<p><a href="{{trackitUrl}}">Connect to your account</a> to see the details.</p>`)
+
+	// PasswordResetTemplate is the template of the password recovery mails.
+	PasswordResetTemplate = newTemplate("passwordReset",
+		`Reset your Trackit password`,
+		`Please follow this link to recover your password: {{.Link}}. This link is valid for an hour.`,
+		`<p>Please follow <a href="{{.Link}}">this link</a> to recover your password. This link is valid for an hour.</p>`)
+
+	// InviteTemplate is the template of the mails inviting a new user to
+	// access a shared AWS account.
+	InviteTemplate = newTemplate("invite",
+		`You are invited to join Trackit`,
+		`Hi, you have been invited to join trackit. Please follow this link to create your account: {{.Link}}.`,
+		`<p>Hi, you have been invited to join Trackit. Please follow <a href="{{.Link}}">this link</a> to create your account.</p>`)
+
+	// AccountSharedTemplate is the template of the mails telling an existing
+	// user an AWS account has been shared with them.
+	AccountSharedTemplate = newTemplate("accountShared",
+		`An AWS account has been added to your Trackit account`,
+		`Hi, a new AWS account has been added to your Trackit Account. You can connect to your account to manage it : {{trackitUrl}}`,
+		`<p>Hi, a new AWS account has been added to your Trackit account. <a href="{{trackitUrl}}">Connect to your account</a> to manage it.</p>`)
+
+	// DigestTemplate is the template of the daily and weekly digests.
+	DigestTemplate = newTemplate("digest",
+		`Your {{.Frequency}} Trackit digest`,
+		`Hi, here is your {{.Frequency}} Trackit digest from {{.Begin.Format "2006-01-02"}} to {{.End.Format "2006-01-02"}}.
+{{range .Accounts}}
+{{.AwsAccount}} ({{.AwsIdentity}})
+Month-to-date spend: ${{printf "%.2f" .MonthToDate}}, against ${{printf "%.2f" .PreviousMonthToDate}} at the same date last month and ${{printf "%.2f" .PreviousMonth}} over the whole previous month.
+{{if .Anomalies}}New anomalies:
+{{range .Anomalies}}- {{.Date.Format "2006-01-02"}}, {{.Dimension}} {{.Value}}: ${{printf "%.2f" .Cost}} spent while at most ${{printf "%.2f" .MaxExpected}} was expected
+{{end}}{{else}}No new anomaly.
+{{end}}{{if .Movers}}Biggest cost changes:
+{{range .Movers}}- {{.UsageType}}: ${{printf "%.2f" .Cost}} ({{printf "%+.2f" .Delta}})
+{{end}}{{end}}{{end}}
+You can connect to your account to see the details: {{trackitUrl}}`,
+		`<p>Hi, here is your {{.Frequency}} Trackit digest from {{.Begin.Format "2006-01-02"}} to {{.End.Format "2006-01-02"}}.</p>
+{{range .Accounts}}<h3>{{.AwsAccount}} ({{.AwsIdentity}})</h3>
+<p>Month-to-date spend: <b>${{printf "%.2f" .MonthToDate}}</b>, against ${{printf "%.2f" .PreviousMonthToDate}} at the same date last month and ${{printf "%.2f" .PreviousMonth}} over the whole previous month.</p>
+{{if .Anomalies}}<p>New anomalies:</p>
+<table style="border-collapse: collapse;">
+<tr><th align="left">Date</th><th align="left">Dimension</th><th align="left">Value</th><th align="right">Cost</th><th align="right">Expected at most</th></tr>
+{{range .Anomalies}}<tr><td>{{.Date.Format "2006-01-02"}}</td><td>{{.Dimension}}</td><td>{{.Value}}</td><td align="right">${{printf "%.2f" .Cost}}</td><td align="right">${{printf "%.2f" .MaxExpected}}</td></tr>
+{{end}}</table>
+{{else}}<p>No new anomaly.</p>
+{{end}}{{if .Movers}}<p>Biggest cost changes:</p>
+<table style="border-collapse: collapse;">
+<tr><th align="left">Usage type</th><th align="right">Previous cost</th><th align="right">Cost</th><th align="right">Change</th></tr>
+{{range .Movers}}<tr><td>{{.UsageType}}</td><td align="right">${{printf "%.2f" .PreviousCost}}</td><td align="right">${{printf "%.2f" .Cost}}</td><td align="right">{{printf "%+.2f" .Delta}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}<p><a href="{{trackitUrl}}">Connect to your account</a> to see the details.</p>`)
+
+	// MonthlyReportTemplate is the template of the mails the monthly
+	// spreadsheet report is attached to.
+	MonthlyReportTemplate = newTemplate("monthlyReport",
+		`Your Trackit report of {{.Month}} for {{.AwsAccount}}`,
+		`Hi, you will find attached the Trackit report of {{.Month}} for your AWS account {{.AwsAccount}}.`,
+		`<p>Hi, you will find attached the Trackit report of {{.Month}} for your AWS account <b>{{.AwsAccount}}</b>.</p>`)
+)
+
+// GetResetLink returns the link to the page where a user sets their password
+// with a forgotten password token.
+func GetResetLink(forgottenPasswordId int, token string) string {
+	return fmt.Sprintf("%sreset/%d/%s", trackitUrl, forgottenPasswordId, token)
+}
@@ -0,0 +1,52 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package models contains the types for schema 'trackit'.
+package models
+
+// DeleteAwsBillCheckpointsByAwsBillRepositoryIDEsIndex deletes the checkpoints
+// of the ingestion of a bill repository in an ES index.
+func DeleteAwsBillCheckpointsByAwsBillRepositoryIDEsIndex(db XODB, awsBillRepositoryID int, esIndex string) error {
+	var err error
+
+	// sql query
+	const sqlstr = `DELETE FROM trackit.aws_bill_checkpoint WHERE aws_bill_repository_id = ? AND es_index = ?`
+
+	// run query
+	XOLog(sqlstr, awsBillRepositoryID, esIndex)
+	_, err = db.Exec(sqlstr, awsBillRepositoryID, esIndex)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAwsBillCheckpointsByAwsBillRepositoryID deletes all the checkpoints of
+// the ingestions of a bill repository.
+func DeleteAwsBillCheckpointsByAwsBillRepositoryID(db XODB, awsBillRepositoryID int) error {
+	var err error
+
+	// sql query
+	const sqlstr = `DELETE FROM trackit.aws_bill_checkpoint WHERE aws_bill_repository_id = ?`
+
+	// run query
+	XOLog(sqlstr, awsBillRepositoryID)
+	_, err = db.Exec(sqlstr, awsBillRepositoryID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
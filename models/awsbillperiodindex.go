@@ -0,0 +1,75 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package models contains the types for schema 'trackit'.
+package models
+
+// AwsBillPeriodIndexesByUserID returns the ES indices of the billing periods of
+// all the bill repositories of a user.
+func AwsBillPeriodIndexesByUserID(db XODB, userID int) ([]*AwsBillPeriodIndex, error) {
+	var err error
+
+	// sql query
+	const sqlstr = `SELECT ` +
+		`abpi.id, abpi.aws_bill_repository_id, abpi.billing_period, abpi.es_index ` +
+		`FROM trackit.aws_bill_period_index AS abpi ` +
+		`INNER JOIN trackit.aws_bill_repository AS abr ON abr.id = abpi.aws_bill_repository_id ` +
+		`INNER JOIN trackit.aws_account AS aa ON aa.id = abr.aws_account_id ` +
+		`WHERE aa.user_id = ? ` +
+		`ORDER BY abpi.aws_bill_repository_id, abpi.billing_period`
+
+	// run query
+	XOLog(sqlstr, userID)
+	q, err := db.Query(sqlstr, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+
+	// load results
+	res := []*AwsBillPeriodIndex{}
+	for q.Next() {
+		abpi := AwsBillPeriodIndex{
+			_exists: true,
+		}
+
+		// scan
+		err = q.Scan(&abpi.ID, &abpi.AwsBillRepositoryID, &abpi.BillingPeriod, &abpi.EsIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &abpi)
+	}
+
+	return res, nil
+}
+
+// DeleteAwsBillPeriodIndexesByAwsBillRepositoryID deletes the billing period
+// indices of a bill repository.
+func DeleteAwsBillPeriodIndexesByAwsBillRepositoryID(db XODB, awsBillRepositoryID int) error {
+	var err error
+
+	// sql query
+	const sqlstr = `DELETE FROM trackit.aws_bill_period_index WHERE aws_bill_repository_id = ?`
+
+	// run query
+	XOLog(sqlstr, awsBillRepositoryID)
+	_, err = db.Exec(sqlstr, awsBillRepositoryID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
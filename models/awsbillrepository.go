@@ -20,7 +20,7 @@ package models
 func AwsBillRepositoriesWithDueUpdate(db XODB) ([]*AwsBillRepository, error) {
 	var err error
 	const sqlstr = `SELECT ` +
-		`id, aws_account_id, bucket, prefix, last_imported_manifest, next_update, error ` +
+		`id, aws_account_id, bucket, prefix, last_imported_manifest, next_update, error, backend, endpoint, access_key_id, secret_access_key ` +
 		`FROM trackit.aws_bill_repository ` +
 		`WHERE next_update <= NOW()`
 	XOLog(sqlstr)
@@ -33,7 +33,7 @@ func AwsBillRepositoriesWithDueUpdate(db XODB) ([]*AwsBillRepository, error) {
 		abr := AwsBillRepository{
 			_exists: true,
 		}
-		err = q.Scan(&abr.ID, &abr.AwsAccountID, &abr.Bucket, &abr.Prefix, &abr.LastImportedManifest, &abr.NextUpdate, &abr.Error)
+		err = q.Scan(&abr.ID, &abr.AwsAccountID, &abr.Bucket, &abr.Prefix, &abr.LastImportedManifest, &abr.NextUpdate, &abr.Error, &abr.Backend, &abr.Endpoint, &abr.AccessKeyID, &abr.SecretAccessKey)
 		if err != nil {
 			return nil, err
 		}
@@ -48,11 +48,11 @@ func (abr *AwsBillRepository) UpdateUnsafe(db XODB) error {
 
 	// sql query
 	const sqlstr = `UPDATE trackit.aws_bill_repository SET ` +
-		`aws_account_id = ?, bucket = ?, prefix = ?, last_imported_manifest = ?, next_update = ?, error = ?` +
+		`aws_account_id = ?, bucket = ?, prefix = ?, last_imported_manifest = ?, next_update = ?, error = ?, backend = ?, endpoint = ?, access_key_id = ?, secret_access_key = ?` +
 		` WHERE id = ?`
 
 	// run query
-	XOLog(sqlstr, abr.AwsAccountID, abr.Bucket, abr.Prefix, abr.LastImportedManifest, abr.NextUpdate, abr.Error, abr.ID)
-	_, err = db.Exec(sqlstr, abr.AwsAccountID, abr.Bucket, abr.Prefix, abr.LastImportedManifest, abr.NextUpdate, abr.Error, abr.ID)
+	XOLog(sqlstr, abr.AwsAccountID, abr.Bucket, abr.Prefix, abr.LastImportedManifest, abr.NextUpdate, abr.Error, abr.Backend, abr.Endpoint, abr.AccessKeyID, abr.SecretAccessKey, abr.ID)
+	_, err = db.Exec(sqlstr, abr.AwsAccountID, abr.Bucket, abr.Prefix, abr.LastImportedManifest, abr.NextUpdate, abr.Error, abr.Backend, abr.Endpoint, abr.AccessKeyID, abr.SecretAccessKey, abr.ID)
 	return err
 }
@@ -17,6 +17,10 @@ type AwsBillRepository struct {
 	LastImportedManifest time.Time `json:"last_imported_manifest"` // last_imported_manifest
 	NextUpdate           time.Time `json:"next_update"`            // next_update
 	Error                string    `json:"error"`                  // error
+	Backend              string    `json:"backend"`                // backend
+	Endpoint             string    `json:"endpoint"`               // endpoint
+	AccessKeyID          string    `json:"access_key_id"`          // access_key_id
+	SecretAccessKey      string    `json:"-"`                      // secret_access_key
 
 	// xo fields
 	_exists, _deleted bool
@@ -43,14 +47,14 @@ func (abr *AwsBillRepository) Insert(db XODB) error {
 
 	// sql insert query, primary key provided by autoincrement
 	const sqlstr = `INSERT INTO trackit.aws_bill_repository (` +
-		`aws_account_id, bucket, prefix, last_imported_manifest, next_update, error` +
+		`aws_account_id, bucket, prefix, last_imported_manifest, next_update, error, backend, endpoint, access_key_id, secret_access_key` +
 		`) VALUES (` +
-		`?, ?, ?, ?, ?, ?` +
+		`?, ?, ?, ?, ?, ?, ?, ?, ?, ?` +
 		`)`
 
 	// run query
-	XOLog(sqlstr, abr.AwsAccountID, abr.Bucket, abr.Prefix, abr.LastImportedManifest, abr.NextUpdate, abr.Error)
-	res, err := db.Exec(sqlstr, abr.AwsAccountID, abr.Bucket, abr.Prefix, abr.LastImportedManifest, abr.NextUpdate, abr.Error)
+	XOLog(sqlstr, abr.AwsAccountID, abr.Bucket, abr.Prefix, abr.LastImportedManifest, abr.NextUpdate, abr.Error, abr.Backend, abr.Endpoint, abr.AccessKeyID, abr.SecretAccessKey)
+	res, err := db.Exec(sqlstr, abr.AwsAccountID, abr.Bucket, abr.Prefix, abr.LastImportedManifest, abr.NextUpdate, abr.Error, abr.Backend, abr.Endpoint, abr.AccessKeyID, abr.SecretAccessKey)
 	if err != nil {
 		return err
 	}
@@ -84,12 +88,12 @@ func (abr *AwsBillRepository) Update(db XODB) error {
 
 	// sql query
 	const sqlstr = `UPDATE trackit.aws_bill_repository SET ` +
-		`aws_account_id = ?, bucket = ?, prefix = ?, last_imported_manifest = ?, next_update = ?, error = ?` +
+		`aws_account_id = ?, bucket = ?, prefix = ?, last_imported_manifest = ?, next_update = ?, error = ?, backend = ?, endpoint = ?, access_key_id = ?, secret_access_key = ?` +
 		` WHERE id = ?`
 
 	// run query
-	XOLog(sqlstr, abr.AwsAccountID, abr.Bucket, abr.Prefix, abr.LastImportedManifest, abr.NextUpdate, abr.Error, abr.ID)
-	_, err = db.Exec(sqlstr, abr.AwsAccountID, abr.Bucket, abr.Prefix, abr.LastImportedManifest, abr.NextUpdate, abr.Error, abr.ID)
+	XOLog(sqlstr, abr.AwsAccountID, abr.Bucket, abr.Prefix, abr.LastImportedManifest, abr.NextUpdate, abr.Error, abr.Backend, abr.Endpoint, abr.AccessKeyID, abr.SecretAccessKey, abr.ID)
+	_, err = db.Exec(sqlstr, abr.AwsAccountID, abr.Bucket, abr.Prefix, abr.LastImportedManifest, abr.NextUpdate, abr.Error, abr.Backend, abr.Endpoint, abr.AccessKeyID, abr.SecretAccessKey, abr.ID)
 	return err
 }
 
@@ -147,7 +151,7 @@ func AwsBillRepositoryByID(db XODB, id int) (*AwsBillRepository, error) {
 
 	// sql query
 	const sqlstr = `SELECT ` +
-		`id, aws_account_id, bucket, prefix, last_imported_manifest, next_update, error ` +
+		`id, aws_account_id, bucket, prefix, last_imported_manifest, next_update, error, backend, endpoint, access_key_id, secret_access_key ` +
 		`FROM trackit.aws_bill_repository ` +
 		`WHERE id = ?`
 
@@ -157,7 +161,7 @@ func AwsBillRepositoryByID(db XODB, id int) (*AwsBillRepository, error) {
 		_exists: true,
 	}
 
-	err = db.QueryRow(sqlstr, id).Scan(&abr.ID, &abr.AwsAccountID, &abr.Bucket, &abr.Prefix, &abr.LastImportedManifest, &abr.NextUpdate, &abr.Error)
+	err = db.QueryRow(sqlstr, id).Scan(&abr.ID, &abr.AwsAccountID, &abr.Bucket, &abr.Prefix, &abr.LastImportedManifest, &abr.NextUpdate, &abr.Error, &abr.Backend, &abr.Endpoint, &abr.AccessKeyID, &abr.SecretAccessKey)
 	if err != nil {
 		return nil, err
 	}
@@ -173,7 +177,7 @@ func AwsBillRepositoriesByAwsAccountID(db XODB, awsAccountID int) ([]*AwsBillRep
 
 	// sql query
 	const sqlstr = `SELECT ` +
-		`id, aws_account_id, bucket, prefix, last_imported_manifest, next_update, error ` +
+		`id, aws_account_id, bucket, prefix, last_imported_manifest, next_update, error, backend, endpoint, access_key_id, secret_access_key ` +
 		`FROM trackit.aws_bill_repository ` +
 		`WHERE aws_account_id = ?`
 
@@ -193,7 +197,7 @@ func AwsBillRepositoriesByAwsAccountID(db XODB, awsAccountID int) ([]*AwsBillRep
 		}
 
 		// scan
-		err = q.Scan(&abr.ID, &abr.AwsAccountID, &abr.Bucket, &abr.Prefix, &abr.LastImportedManifest, &abr.NextUpdate, &abr.Error)
+		err = q.Scan(&abr.ID, &abr.AwsAccountID, &abr.Bucket, &abr.Prefix, &abr.LastImportedManifest, &abr.NextUpdate, &abr.Error, &abr.Backend, &abr.Endpoint, &abr.AccessKeyID, &abr.SecretAccessKey)
 		if err != nil {
 			return nil, err
 		}
@@ -39,3 +39,19 @@ func LastAwsBillUpdateJobsByAwsBillRepositoryID(db XODB, awsBillRepositoryID int
 
 	return &abuj, nil
 }
+
+// UpdateProgress updates the progress counters of the AwsBillUpdateJob, and
+// only them, in the database.
+func (abuj *AwsBillUpdateJob) UpdateProgress(db XODB) error {
+	var err error
+
+	// sql query
+	const sqlstr = `UPDATE trackit.aws_bill_update_job SET ` +
+		`manifests_total = ?, manifests_done = ?, keys_total = ?, keys_done = ?, bytes_total = ?, bytes_done = ?, line_items = ?` +
+		` WHERE id = ?`
+
+	// run query
+	XOLog(sqlstr, abuj.ManifestsTotal, abuj.ManifestsDone, abuj.KeysTotal, abuj.KeysDone, abuj.BytesTotal, abuj.BytesDone, abuj.LineItems, abuj.ID)
+	_, err = db.Exec(sqlstr, abuj.ManifestsTotal, abuj.ManifestsDone, abuj.KeysTotal, abuj.KeysDone, abuj.BytesTotal, abuj.BytesDone, abuj.LineItems, abuj.ID)
+	return err
+}
@@ -16,6 +16,13 @@ type AwsBillUpdateJob struct {
 	Completed           time.Time `json:"completed"`              // completed
 	WorkerID            string    `json:"worker_id"`              // worker_id
 	Error               string    `json:"error"`                  // error
+	ManifestsTotal      int       `json:"manifests_total"`        // manifests_total
+	ManifestsDone       int       `json:"manifests_done"`         // manifests_done
+	KeysTotal           int       `json:"keys_total"`             // keys_total
+	KeysDone            int       `json:"keys_done"`              // keys_done
+	BytesTotal          int64     `json:"bytes_total"`            // bytes_total
+	BytesDone           int64     `json:"bytes_done"`             // bytes_done
+	LineItems           int64     `json:"line_items"`             // line_items
 
 	// xo fields
 	_exists, _deleted bool
@@ -42,14 +49,14 @@ func (abuj *AwsBillUpdateJob) Insert(db XODB) error {
 
 	// sql insert query, primary key provided by autoincrement
 	const sqlstr = `INSERT INTO trackit.aws_bill_update_job (` +
-		`aws_bill_repository_id, expired, completed, worker_id, error` +
+		`aws_bill_repository_id, expired, completed, worker_id, error, manifests_total, manifests_done, keys_total, keys_done, bytes_total, bytes_done, line_items` +
 		`) VALUES (` +
-		`?, ?, ?, ?, ?` +
+		`?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?` +
 		`)`
 
 	// run query
-	XOLog(sqlstr, abuj.AwsBillRepositoryID, abuj.Expired, abuj.Completed, abuj.WorkerID, abuj.Error)
-	res, err := db.Exec(sqlstr, abuj.AwsBillRepositoryID, abuj.Expired, abuj.Completed, abuj.WorkerID, abuj.Error)
+	XOLog(sqlstr, abuj.AwsBillRepositoryID, abuj.Expired, abuj.Completed, abuj.WorkerID, abuj.Error, abuj.ManifestsTotal, abuj.ManifestsDone, abuj.KeysTotal, abuj.KeysDone, abuj.BytesTotal, abuj.BytesDone, abuj.LineItems)
+	res, err := db.Exec(sqlstr, abuj.AwsBillRepositoryID, abuj.Expired, abuj.Completed, abuj.WorkerID, abuj.Error, abuj.ManifestsTotal, abuj.ManifestsDone, abuj.KeysTotal, abuj.KeysDone, abuj.BytesTotal, abuj.BytesDone, abuj.LineItems)
 	if err != nil {
 		return err
 	}
@@ -83,12 +90,12 @@ func (abuj *AwsBillUpdateJob) Update(db XODB) error {
 
 	// sql query
 	const sqlstr = `UPDATE trackit.aws_bill_update_job SET ` +
-		`aws_bill_repository_id = ?, expired = ?, completed = ?, worker_id = ?, error = ?` +
+		`aws_bill_repository_id = ?, expired = ?, completed = ?, worker_id = ?, error = ?, manifests_total = ?, manifests_done = ?, keys_total = ?, keys_done = ?, bytes_total = ?, bytes_done = ?, line_items = ?` +
 		` WHERE id = ?`
 
 	// run query
-	XOLog(sqlstr, abuj.AwsBillRepositoryID, abuj.Expired, abuj.Completed, abuj.WorkerID, abuj.Error, abuj.ID)
-	_, err = db.Exec(sqlstr, abuj.AwsBillRepositoryID, abuj.Expired, abuj.Completed, abuj.WorkerID, abuj.Error, abuj.ID)
+	XOLog(sqlstr, abuj.AwsBillRepositoryID, abuj.Expired, abuj.Completed, abuj.WorkerID, abuj.Error, abuj.ManifestsTotal, abuj.ManifestsDone, abuj.KeysTotal, abuj.KeysDone, abuj.BytesTotal, abuj.BytesDone, abuj.LineItems, abuj.ID)
+	_, err = db.Exec(sqlstr, abuj.AwsBillRepositoryID, abuj.Expired, abuj.Completed, abuj.WorkerID, abuj.Error, abuj.ManifestsTotal, abuj.ManifestsDone, abuj.KeysTotal, abuj.KeysDone, abuj.BytesTotal, abuj.BytesDone, abuj.LineItems, abuj.ID)
 	return err
 }
 
@@ -146,7 +153,7 @@ func AwsBillUpdateJobByID(db XODB, id int) (*AwsBillUpdateJob, error) {
 
 	// sql query
 	const sqlstr = `SELECT ` +
-		`id, aws_bill_repository_id, expired, completed, worker_id, error ` +
+		`id, aws_bill_repository_id, expired, completed, worker_id, error, manifests_total, manifests_done, keys_total, keys_done, bytes_total, bytes_done, line_items ` +
 		`FROM trackit.aws_bill_update_job ` +
 		`WHERE id = ?`
 
@@ -156,7 +163,7 @@ func AwsBillUpdateJobByID(db XODB, id int) (*AwsBillUpdateJob, error) {
 		_exists: true,
 	}
 
-	err = db.QueryRow(sqlstr, id).Scan(&abuj.ID, &abuj.AwsBillRepositoryID, &abuj.Expired, &abuj.Completed, &abuj.WorkerID, &abuj.Error)
+	err = db.QueryRow(sqlstr, id).Scan(&abuj.ID, &abuj.AwsBillRepositoryID, &abuj.Expired, &abuj.Completed, &abuj.WorkerID, &abuj.Error, &abuj.ManifestsTotal, &abuj.ManifestsDone, &abuj.KeysTotal, &abuj.KeysDone, &abuj.BytesTotal, &abuj.BytesDone, &abuj.LineItems)
 	if err != nil {
 		return nil, err
 	}
@@ -172,7 +179,7 @@ func AwsBillUpdateJobsByAwsBillRepositoryID(db XODB, awsBillRepositoryID int) ([
 
 	// sql query
 	const sqlstr = `SELECT ` +
-		`id, aws_bill_repository_id, expired, completed, worker_id, error ` +
+		`id, aws_bill_repository_id, expired, completed, worker_id, error, manifests_total, manifests_done, keys_total, keys_done, bytes_total, bytes_done, line_items ` +
 		`FROM trackit.aws_bill_update_job ` +
 		`WHERE aws_bill_repository_id = ?`
 
@@ -192,7 +199,7 @@ func AwsBillUpdateJobsByAwsBillRepositoryID(db XODB, awsBillRepositoryID int) ([
 		}
 
 		// scan
-		err = q.Scan(&abuj.ID, &abuj.AwsBillRepositoryID, &abuj.Expired, &abuj.Completed, &abuj.WorkerID, &abuj.Error)
+		err = q.Scan(&abuj.ID, &abuj.AwsBillRepositoryID, &abuj.Expired, &abuj.Completed, &abuj.WorkerID, &abuj.Error, &abuj.ManifestsTotal, &abuj.ManifestsDone, &abuj.KeysTotal, &abuj.KeysDone, &abuj.BytesTotal, &abuj.BytesDone, &abuj.LineItems)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,42 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package models contains the types for schema 'trackit'.
+package models
+
+// AllBudgets retrieves every budget in the database.
+func AllBudgets(db XODB) ([]*Budget, error) {
+	var err error
+	const sqlstr = `SELECT ` +
+		`id, user_id, aws_account_id, name, tag_key, tag_value, period, amount, thresholds ` +
+		`FROM trackit.budget`
+	XOLog(sqlstr)
+	q, err := db.Query(sqlstr)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+	var res []*Budget
+	for q.Next() {
+		b := Budget{
+			_exists: true,
+		}
+		err = q.Scan(&b.ID, &b.UserID, &b.AwsAccountID, &b.Name, &b.TagKey, &b.TagValue, &b.Period, &b.Amount, &b.Thresholds)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &b)
+	}
+	return res, nil
+}
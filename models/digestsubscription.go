@@ -0,0 +1,42 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package models contains the types for schema 'trackit'.
+package models
+
+// AllDigestSubscriptions retrieves every digest subscription in the database.
+func AllDigestSubscriptions(db XODB) ([]*DigestSubscription, error) {
+	var err error
+	const sqlstr = `SELECT ` +
+		`id, user_id, aws_account_id, frequency, hour, last_sent ` +
+		`FROM trackit.digest_subscription`
+	XOLog(sqlstr)
+	q, err := db.Query(sqlstr)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+	var res []*DigestSubscription
+	for q.Next() {
+		ds := DigestSubscription{
+			_exists: true,
+		}
+		err = q.Scan(&ds.ID, &ds.UserID, &ds.AwsAccountID, &ds.Frequency, &ds.Hour, &ds.LastSent)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &ds)
+	}
+	return res, nil
+}
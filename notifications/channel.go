@@ -0,0 +1,193 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	netmail "net/mail"
+	"net/url"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/models"
+	"github.com/trackit/trackit/users"
+	"github.com/trackit/trackit/util"
+)
+
+var (
+	ErrInvalidName    = errors.New("name must not be empty")
+	ErrInvalidAddress = errors.New("target must be an email address")
+	ErrInvalidUrl     = errors.New("target must be an http or https URL")
+	ErrPrivateUrl     = errors.New("target must not be a private address")
+)
+
+// Channel is a way to notify a user, either for a single AWS account or for
+// every account of the user.
+type Channel struct {
+	Id           int    `json:"id"`
+	Type         string `json:"type"`
+	Name         string `json:"name"`
+	AwsAccountId *int   `json:"awsAccountId,omitempty"`
+	Target       string `json:"target"`
+	Secret       string `json:"secret,omitempty"`
+	Disabled     bool   `json:"disabled"`
+}
+
+// validate checks the channel is well formed.
+func (c Channel) validate() error {
+	if c.Name == "" {
+		return ErrInvalidName
+	}
+	switch c.Type {
+	case ChannelEmail:
+		if _, err := netmail.ParseAddress(c.Target); err != nil {
+			return ErrInvalidAddress
+		}
+	case ChannelWebhook, ChannelSlack, ChannelTeams:
+		if u, err := url.Parse(c.Target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidUrl
+		} else if !util.IsPublicHost(u.Hostname()) {
+			return ErrPrivateUrl
+		}
+	default:
+		return ErrUnknownChannelType
+	}
+	return nil
+}
+
+// generateSecret returns a random secret used to sign the webhooks payloads.
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// channelFromDbChannel converts a models.NotificationChannel to a Channel.
+func channelFromDbChannel(dbChannel models.NotificationChannel) Channel {
+	c := Channel{
+		Id:       dbChannel.ID,
+		Type:     dbChannel.Type,
+		Name:     dbChannel.Name,
+		Target:   dbChannel.Target,
+		Secret:   dbChannel.Secret,
+		Disabled: dbChannel.Disabled,
+	}
+	if dbChannel.AwsAccountID.Valid {
+		awsAccountId := int(dbChannel.AwsAccountID.Int64)
+		c.AwsAccountId = &awsAccountId
+	}
+	return c
+}
+
+// redactTarget returns the target of a channel without what allows to post to
+// it. Only the scheme and the host of the webhook URLs are kept.
+func redactTarget(c Channel) string {
+	if c.Type == ChannelEmail {
+		return c.Target
+	} else if u, err := url.Parse(c.Target); err == nil {
+		return u.Scheme + "://" + u.Host
+	}
+	return ""
+}
+
+// updateDbChannel sets the fields of a models.NotificationChannel from a Channel.
+func updateDbChannel(dbChannel *models.NotificationChannel, c Channel) {
+	dbChannel.Type = c.Type
+	dbChannel.Name = c.Name
+	dbChannel.Target = c.Target
+	dbChannel.Secret = c.Secret
+	dbChannel.Disabled = c.Disabled
+	if c.AwsAccountId != nil {
+		dbChannel.AwsAccountID = sql.NullInt64{Int64: int64(*c.AwsAccountId), Valid: true}
+	} else {
+		dbChannel.AwsAccountID = sql.NullInt64{}
+	}
+}
+
+// GetNotifiers returns the notifiers of the enabled channels of a user which
+// apply to an AWS account, or to every account if awsAccountId is nil.
+// Users are notified by email when none of their channels applies.
+func GetNotifiers(tx *sql.Tx, user users.User, awsAccountId *int) ([]Notifier, error) {
+	dbChannels, err := models.NotificationChannelsByUserID(tx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	return getChannelsNotifiers(dbChannels, user, awsAccountId)
+}
+
+// getChannelsNotifiers returns the notifiers of the enabled channels which
+// apply to an AWS account, or an email notifier if none of them does.
+func getChannelsNotifiers(dbChannels []*models.NotificationChannel, user users.User, awsAccountId *int) ([]Notifier, error) {
+	notifiers := make([]Notifier, 0, len(dbChannels))
+	for _, dbChannel := range dbChannels {
+		if dbChannel.Disabled {
+			continue
+		} else if dbChannel.AwsAccountID.Valid && (awsAccountId == nil || int(dbChannel.AwsAccountID.Int64) != *awsAccountId) {
+			continue
+		}
+		notifier, err := NewNotifier(dbChannel.Type, dbChannel.Name, dbChannel.Target, dbChannel.Secret)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	if len(notifiers) == 0 {
+		return []Notifier{emailNotifier{user.Email}}, nil
+	}
+	return notifiers, nil
+}
+
+// Notify sends a notification to a user through the channels returned by
+// GetNotifiers. It returns the recipients which were notified. A channel
+// failing does not prevent the others from being notified, an error is
+// only returned if none of them succeeded.
+func Notify(ctx context.Context, tx *sql.Tx, user users.User, awsAccountId *int, notification Notification) ([]string, error) {
+	notifiers, err := GetNotifiers(tx, user, awsAccountId)
+	if err != nil {
+		return nil, err
+	}
+	return NotifyAll(ctx, user, notifiers, notification)
+}
+
+// NotifyAll sends a notification to a user through notifiers, as Notify
+// does. It lets callers look the notifiers up in a transaction and only
+// notify once it is committed.
+func NotifyAll(ctx context.Context, user users.User, notifiers []Notifier, notification Notification) ([]string, error) {
+	var err error
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	recipients := make([]string, 0, len(notifiers))
+	for _, notifier := range notifiers {
+		if err = notifier.Notify(ctx, notification); err != nil {
+			logger.Error("Failed to send notification.", map[string]interface{}{
+				"userId":    user.Id,
+				"event":     notification.Event,
+				"recipient": notifier.Recipient(),
+				"error":     err.Error(),
+			})
+		} else {
+			recipients = append(recipients, notifier.Recipient())
+		}
+	}
+	if len(recipients) == 0 && err != nil {
+		return nil, err
+	}
+	return recipients, nil
+}
@@ -0,0 +1,82 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package notifications
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/trackit/trackit/models"
+	"github.com/trackit/trackit/users"
+)
+
+var testChannelsUser = users.User{Id: 1, Email: "user@example.com"}
+
+// testDbChannels are a Slack channel for the AWS account 1 and a disabled
+// JSON webhook for every account.
+var testDbChannels = []*models.NotificationChannel{
+	{
+		ID:           1,
+		UserID:       1,
+		AwsAccountID: sql.NullInt64{Int64: 1, Valid: true},
+		Type:         ChannelSlack,
+		Name:         "On-call",
+		Target:       "https://hooks.slack.com/services/T0/B0/X",
+	},
+	{
+		ID:       2,
+		UserID:   1,
+		Type:     ChannelWebhook,
+		Name:     "Hook",
+		Target:   "https://example.com/hook",
+		Secret:   "secret",
+		Disabled: true,
+	},
+}
+
+func TestGetChannelsNotifiers(t *testing.T) {
+	awsAccountId := 1
+	notifiers, err := getChannelsNotifiers(testDbChannels, testChannelsUser, &awsAccountId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifiers) != 1 || notifiers[0].Recipient() != "slack:On-call" {
+		t.Errorf("Expected the Slack channel to be notified, got %v.", notifiers)
+	}
+}
+
+func TestGetChannelsNotifiersEmailFallback(t *testing.T) {
+	awsAccountId := 2
+	for _, id := range []*int{&awsAccountId, nil} {
+		notifiers, err := getChannelsNotifiers(testDbChannels, testChannelsUser, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notifiers) != 1 || notifiers[0].Recipient() != testChannelsUser.Email {
+			t.Errorf("Expected the user to be notified by email, got %v.", notifiers)
+		}
+	}
+}
+
+func TestRedactTarget(t *testing.T) {
+	c := Channel{Type: ChannelSlack, Target: "https://hooks.slack.com/services/T0/B0/X"}
+	if target := redactTarget(c); target != "https://hooks.slack.com" {
+		t.Errorf("Expected the Slack URL to be redacted, got %s.", target)
+	}
+	c = Channel{Type: ChannelEmail, Target: "user@example.com"}
+	if target := redactTarget(c); target != c.Target {
+		t.Errorf("Expected the email address to be kept, got %s.", target)
+	}
+}
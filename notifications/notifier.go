@@ -0,0 +1,110 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package notifications sends the anomalies and the alerts of a user through
+// the channels they configured: email, signed JSON webhook, Slack incoming
+// webhook or Microsoft Teams connector.
+package notifications
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/trackit/trackit/mail"
+	"github.com/trackit/trackit/util"
+)
+
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelSlack   = "slack"
+	ChannelTeams   = "teams"
+)
+
+const (
+	EventAnomalies = "anomalies"
+	EventBudget    = "budget"
+)
+
+// webhookTimeout is the maximum duration of a request to a webhook.
+const webhookTimeout = 10 * time.Second
+
+var ErrUnknownChannelType = errors.New("channel type must be one of email, webhook, slack or teams")
+
+// Notification is a message sent to a user. Data holds the structured
+// content of the notification and is only sent to the JSON webhooks. Html is
+// an optional HTML version of Body only sent by email.
+type Notification struct {
+	Event   string      `json:"event"`
+	Subject string      `json:"subject"`
+	Body    string      `json:"body"`
+	Html    string      `json:"-"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Notifier sends notifications through a channel.
+type Notifier interface {
+	// Notify sends the notification.
+	Notify(ctx context.Context, notification Notification) error
+	// Recipient describes who receives the notifications: an email address
+	// or the type and the name of the channel, e.g. "slack:On-call".
+	Recipient() string
+}
+
+// httpClient is the client used to call the webhooks. It only connects to
+// public addresses so that webhooks cannot be used to reach internal services.
+var httpClient = &http.Client{
+	Transport: util.PublicHTTPTransport(),
+	Timeout:   webhookTimeout,
+}
+
+// emailNotifier sends notifications by email.
+type emailNotifier struct {
+	address string
+}
+
+// Notify sends the notification by email using the SMTP configuration.
+func (n emailNotifier) Notify(ctx context.Context, notification Notification) error {
+	return mail.SendMessage(mail.Message{
+		To:      []string{n.address},
+		Subject: notification.Subject,
+		Text:    notification.Body,
+		Html:    notification.Html,
+	}, ctx)
+}
+
+// Recipient returns the email address.
+func (n emailNotifier) Recipient() string {
+	return n.address
+}
+
+// NewNotifier returns the notifier of a channel type. target is an email
+// address for the email channels and an URL for the others. secret is only
+// used by the JSON webhooks to sign their payload.
+func NewNotifier(channelType, name, target, secret string) (Notifier, error) {
+	switch channelType {
+	case ChannelEmail:
+		return emailNotifier{target}, nil
+	case ChannelWebhook:
+		return webhookNotifier{name, target, secret}, nil
+	case ChannelSlack:
+		return slackNotifier{name, target}, nil
+	case ChannelTeams:
+		return teamsNotifier{name, target}, nil
+	default:
+		return nil, ErrUnknownChannelType
+	}
+}
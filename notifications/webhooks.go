@@ -0,0 +1,162 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader is the header holding the signature of the payload sent to
+// the JSON webhooks. Its value is "t=<timestamp>,v1=<signature>" where
+// signature is the hex encoded HMAC-SHA256, keyed with the secret of the
+// channel, of the timestamp, a dot and the payload.
+const SignatureHeader = "X-Trackit-Signature"
+
+type (
+	// webhookNotifier posts notifications as signed JSON to an URL.
+	webhookNotifier struct {
+		name   string
+		url    string
+		secret string
+	}
+
+	// webhookPayload is the payload posted to the JSON webhooks.
+	webhookPayload struct {
+		Notification
+		Date time.Time `json:"date"`
+	}
+
+	// slackNotifier posts notifications to a Slack incoming webhook.
+	slackNotifier struct {
+		name string
+		url  string
+	}
+
+	// slackPayload is the payload of a Slack incoming webhook.
+	slackPayload struct {
+		Text string `json:"text"`
+	}
+
+	// teamsNotifier posts notifications to a Microsoft Teams connector.
+	teamsNotifier struct {
+		name string
+		url  string
+	}
+
+	// teamsPayload is the MessageCard payload of a Microsoft Teams connector.
+	teamsPayload struct {
+		Type    string `json:"@type"`
+		Context string `json:"@context"`
+		Summary string `json:"summary"`
+		Title   string `json:"title"`
+		Text    string `json:"text"`
+	}
+)
+
+// postJson posts body to url, with the additional headers.
+// It fails if the response status is not 2xx.
+func postJson(ctx context.Context, url string, body []byte, headers map[string]string) error {
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the value of the SignatureHeader of a payload sent at timestamp.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	t := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(payload)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notify posts the notification and its data with a signature.
+func (n webhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	now := time.Now().UTC()
+	body, err := json.Marshal(webhookPayload{notification, now})
+	if err != nil {
+		return err
+	}
+	return postJson(ctx, n.url, body, map[string]string{
+		SignatureHeader: Sign(n.secret, now.Unix(), body),
+	})
+}
+
+// Recipient returns the name of the channel.
+func (n webhookNotifier) Recipient() string {
+	return ChannelWebhook + ":" + n.name
+}
+
+// Notify posts the subject and the body of the notification.
+func (n slackNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(slackPayload{
+		Text: "*" + notification.Subject + "*\n" + notification.Body,
+	})
+	if err != nil {
+		return err
+	}
+	return postJson(ctx, n.url, body, nil)
+}
+
+// Recipient returns the name of the channel.
+func (n slackNotifier) Recipient() string {
+	return ChannelSlack + ":" + n.name
+}
+
+// Notify posts the subject and the body of the notification as a MessageCard.
+// The lines of the body are separated by blank lines as Teams joins them otherwise.
+func (n teamsNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(teamsPayload{
+		Type:    "MessageCard",
+		Context: "https://schema.org/extensions",
+		Summary: notification.Subject,
+		Title:   notification.Subject,
+		Text:    strings.Replace(notification.Body, "\n", "\n\n", -1),
+	})
+	if err != nil {
+		return err
+	}
+	return postJson(ctx, n.url, body, nil)
+}
+
+// Recipient returns the name of the channel.
+func (n teamsNotifier) Recipient() string {
+	return ChannelTeams + ":" + n.name
+}
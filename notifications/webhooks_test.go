@@ -0,0 +1,174 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// publicHTTPClient is the client used to call the webhooks outside of the
+// tests, which cannot reach the test webhooks listening on the loopback.
+var publicHTTPClient = httpClient
+
+func TestMain(m *testing.M) {
+	httpClient = &http.Client{Timeout: webhookTimeout}
+	os.Exit(m.Run())
+}
+
+// receivedRequest is a request received by a test webhook.
+type receivedRequest struct {
+	body   []byte
+	header http.Header
+}
+
+// newTestWebhook starts a webhook responding with status and sending the
+// requests it receives to the returned channel.
+func newTestWebhook(t *testing.T, status int) (*httptest.Server, chan receivedRequest) {
+	received := make(chan receivedRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		received <- receivedRequest{body, r.Header}
+		w.WriteHeader(status)
+	}))
+	return server, received
+}
+
+var testNotification = Notification{
+	Event:   EventAnomalies,
+	Subject: "Cost anomaly detected on Production",
+	Body:    "line 1\nline 2",
+	Data:    map[string]interface{}{"awsAccount": "123456789012"},
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	server, received := newTestWebhook(t, http.StatusOK)
+	defer server.Close()
+	notifier, err := NewNotifier(ChannelWebhook, "Hook", server.URL, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(context.Background(), testNotification); err != nil {
+		t.Fatal(err)
+	}
+	request := <-received
+	var payload map[string]interface{}
+	if err := json.Unmarshal(request.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload["event"] != EventAnomalies || payload["subject"] != testNotification.Subject || payload["date"] == nil {
+		t.Errorf("Unexpected payload %s", string(request.body))
+	}
+	if data, ok := payload["data"].(map[string]interface{}); !ok || data["awsAccount"] != "123456789012" {
+		t.Errorf("Unexpected data in payload %s", string(request.body))
+	}
+	signature := request.header.Get(SignatureHeader)
+	parts := strings.SplitN(strings.TrimPrefix(signature, "t="), ",", 2)
+	timestamp, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		t.Fatalf("Unexpected signature %s", signature)
+	}
+	if expected := Sign("secret", timestamp, request.body); signature != expected {
+		t.Errorf("Expected signature %s but got %s", expected, signature)
+	}
+	if other := Sign("other", timestamp, request.body); signature == other {
+		t.Errorf("Signature does not depend on the secret")
+	}
+	if notifier.Recipient() != "webhook:Hook" {
+		t.Errorf("Unexpected recipient %s", notifier.Recipient())
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	server, received := newTestWebhook(t, http.StatusOK)
+	defer server.Close()
+	notifier, _ := NewNotifier(ChannelSlack, "On-call", server.URL, "")
+	if err := notifier.Notify(context.Background(), testNotification); err != nil {
+		t.Fatal(err)
+	}
+	request := <-received
+	expected := `{"text":"*Cost anomaly detected on Production*\nline 1\nline 2"}`
+	if string(request.body) != expected {
+		t.Errorf("Expected payload %s but got %s", expected, string(request.body))
+	}
+}
+
+func TestTeamsNotifier(t *testing.T) {
+	server, received := newTestWebhook(t, http.StatusOK)
+	defer server.Close()
+	notifier, _ := NewNotifier(ChannelTeams, "On-call", server.URL, "")
+	if err := notifier.Notify(context.Background(), testNotification); err != nil {
+		t.Fatal(err)
+	}
+	request := <-received
+	var payload teamsPayload
+	if err := json.Unmarshal(request.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Type != "MessageCard" || payload.Title != testNotification.Subject || payload.Text != "line 1\n\nline 2" {
+		t.Errorf("Unexpected payload %s", string(request.body))
+	}
+}
+
+func TestWebhookNotifierFailure(t *testing.T) {
+	server, _ := newTestWebhook(t, http.StatusNotFound)
+	defer server.Close()
+	notifier, _ := NewNotifier(ChannelSlack, "On-call", server.URL, "")
+	if err := notifier.Notify(context.Background(), testNotification); err == nil {
+		t.Errorf("Expected an error when the webhook responds with a 404")
+	}
+}
+
+func TestWebhookNotifierPrivateAddress(t *testing.T) {
+	server, _ := newTestWebhook(t, http.StatusOK)
+	defer server.Close()
+	httpClient = publicHTTPClient
+	defer func() { httpClient = &http.Client{Timeout: webhookTimeout} }()
+	notifier, _ := NewNotifier(ChannelWebhook, "Hook", server.URL, "secret")
+	if err := notifier.Notify(context.Background(), testNotification); err == nil {
+		t.Errorf("Expected an error when the webhook is on the loopback")
+	}
+}
+
+func TestChannelValidate(t *testing.T) {
+	for _, test := range []struct {
+		channel Channel
+		err     error
+	}{
+		{Channel{Type: ChannelEmail, Name: "Me", Target: "me@example.com"}, nil},
+		{Channel{Type: ChannelEmail, Name: "Me", Target: "https://example.com"}, ErrInvalidAddress},
+		{Channel{Type: ChannelSlack, Name: "On-call", Target: "https://hooks.slack.com/services/T/B/X"}, nil},
+		{Channel{Type: ChannelTeams, Name: "On-call", Target: "me@example.com"}, ErrInvalidUrl},
+		{Channel{Type: ChannelWebhook, Name: "", Target: "https://example.com"}, ErrInvalidName},
+		{Channel{Type: ChannelWebhook, Name: "Hook", Target: "http://169.254.169.254/latest/meta-data"}, ErrPrivateUrl},
+		{Channel{Type: ChannelWebhook, Name: "Hook", Target: "http://[::1]:8080/hook"}, ErrPrivateUrl},
+		{Channel{Type: ChannelSlack, Name: "On-call", Target: "http://localhost:9200"}, ErrPrivateUrl},
+		{Channel{Type: "pager", Name: "On-call", Target: "https://example.com"}, ErrUnknownChannelType},
+	} {
+		if err := test.channel.validate(); err != test.err {
+			t.Errorf("Expected %v for %v but got %v", test.err, test.channel, err)
+		}
+	}
+}
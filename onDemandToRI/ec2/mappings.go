@@ -42,7 +42,7 @@ func init() {
 const TemplateOdToRiEc2Report = `
 {
 	"template": "*-od-to-ri-ec2-reports",
-	"version": 1,
+	"version": 2,
 	"mappings": {
 		"od-to-ri-ec2-report": {
 			"properties": {
@@ -92,6 +92,19 @@ const TemplateOdToRiEc2Report = `
 									"type": "double"
 								}
 							}
+						},
+						"bestOption": {
+							"properties": {
+								"upfront": {
+									"type": "double"
+								},
+								"monthly": {
+									"type": "double"
+								},
+								"monthlySaving": {
+									"type": "double"
+								}
+							}
 						}
 					}
 				},
@@ -107,6 +120,12 @@ const TemplateOdToRiEc2Report = `
 						"platform": {
 							"type": "keyword"
 						},
+						"tenancy": {
+							"type": "keyword"
+						},
+						"licenseModel": {
+							"type": "keyword"
+						},
 						"instanceCount": {
 							"type": "integer"
 						},
@@ -216,6 +235,139 @@ const TemplateOdToRiEc2Report = `
 											}
 										}
 									}
+								},
+								"options": {
+									"type": "nested",
+									"properties": {
+										"term": {
+											"type": "keyword"
+										},
+										"offeringClass": {
+											"type": "keyword"
+										},
+										"purchaseOption": {
+											"type": "keyword"
+										},
+										"upfront": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"monthly": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"global": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"saving": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"monthlySaving": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"breakEvenMonth": {
+											"type": "integer"
+										}
+									}
+								},
+								"bestOption": {
+									"properties": {
+										"term": {
+											"type": "keyword"
+										},
+										"offeringClass": {
+											"type": "keyword"
+										},
+										"purchaseOption": {
+											"type": "keyword"
+										},
+										"upfront": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"monthly": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"global": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"saving": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"monthlySaving": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"breakEvenMonth": {
+											"type": "integer"
+										}
+									}
 								}
 							}
 						}
@@ -0,0 +1,154 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToRiEc2
+
+import (
+	"strconv"
+	"time"
+)
+
+// OdToRiEc2Reports is the list of on demand to RI EC2 reports served by the
+// /ri/ec2/recommendations route
+type OdToRiEc2Reports []OdToRiEc2Report
+
+// odToRiEc2CSVHeader is the header of the CSV generated from OdToRiEc2Reports
+var odToRiEc2CSVHeader = []string{
+	"account",
+	"region",
+	"instanceType",
+	"platform",
+	"tenancy",
+	"instanceCount",
+	"reservationType",
+	"onDemandMonthly",
+	"oneYearReservationMonthly",
+	"oneYearSaving",
+	"threeYearsReservationMonthly",
+	"threeYearsSaving",
+	"bestOptionTerm",
+	"bestOptionOfferingClass",
+	"bestOptionPurchaseOption",
+	"bestOptionUpfront",
+	"bestOptionMonthly",
+	"bestOptionMonthlySaving",
+	"bestOptionBreakEvenMonth",
+}
+
+// ToCSVable generates the CSV content from OdToRiEc2Reports, with one line per
+// combination of region, instance type, platform and tenancy
+// The best option columns are empty when no purchase option saves money
+func (reports OdToRiEc2Reports) ToCSVable() [][]string {
+	formatFloat := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	csv := [][]string{odToRiEc2CSVHeader}
+	for _, report := range reports {
+		for _, instance := range report.Instances {
+			bestOption := make([]string, 7)
+			if best := instance.Reservation.BestOption; best != nil {
+				bestOption = []string{
+					best.Term,
+					best.OfferingClass,
+					best.PurchaseOption,
+					formatFloat(best.Upfront.Total),
+					formatFloat(best.Monthly.Total),
+					formatFloat(best.MonthlySaving.Total),
+					strconv.Itoa(best.BreakEvenMonth),
+				}
+			}
+			csv = append(csv, append([]string{
+				report.Account,
+				instance.Region,
+				instance.Type,
+				instance.Platform,
+				instance.Tenancy,
+				strconv.Itoa(instance.InstanceCount),
+				instance.Reservation.Type,
+				formatFloat(instance.OnDemand.Monthly.Total),
+				formatFloat(instance.Reservation.OneYear.Monthly.Total),
+				formatFloat(instance.Reservation.OneYear.Saving.Total),
+				formatFloat(instance.Reservation.ThreeYear.Monthly.Total),
+				formatFloat(instance.Reservation.ThreeYear.Saving.Total),
+			}, bestOption...))
+		}
+	}
+	return csv
+}
+
+// getDateForReport returns the begin and the end of the month of a date. If
+// the date is in the current month, the end is now.
+func getDateForReport(date time.Time) (begin, end time.Time) {
+	now := time.Now().UTC()
+	begin = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if date.Year() == now.Year() && date.Month() == now.Month() {
+		end = now
+	} else {
+		end = time.Date(date.Year(), date.Month()+1, 0, 23, 59, 59, 999999999, time.UTC)
+	}
+	return
+}
+
+// containsString returns true if value is in values, or if values is empty
+func containsString(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// filterOdToRiEc2Report keeps the instances of a report matching the platforms and
+// regions and computes the totals of the report again from these instances.
+// An empty list of platforms or regions matches every instance.
+func filterOdToRiEc2Report(report OdToRiEc2Report, platforms, regions []string) OdToRiEc2Report {
+	filtered := OdToRiEc2Report{
+		Account:    report.Account,
+		ReportDate: report.ReportDate,
+		Instances:  make([]InstancesSpecs, 0, len(report.Instances)),
+	}
+	for _, instance := range report.Instances {
+		if !containsString(platforms, instance.Platform) || !containsString(regions, instance.Region) {
+			continue
+		}
+		filtered.OnDemand.MonthlyTotal += instance.OnDemand.Monthly.Total
+		filtered.OnDemand.OneYearTotal += instance.OnDemand.OneYear.Total
+		filtered.OnDemand.ThreeYearsTotal += instance.OnDemand.ThreeYears.Total
+		filtered.Reservation.OneYear.MonthlyTotal += instance.Reservation.OneYear.Monthly.Total
+		filtered.Reservation.OneYear.GlobalTotal += instance.Reservation.OneYear.Global.Total
+		filtered.Reservation.OneYear.SavingTotal += instance.Reservation.OneYear.Saving.Total
+		filtered.Reservation.ThreeYear.MonthlyTotal += instance.Reservation.ThreeYear.Monthly.Total
+		filtered.Reservation.ThreeYear.GlobalTotal += instance.Reservation.ThreeYear.Global.Total
+		filtered.Reservation.ThreeYear.SavingTotal += instance.Reservation.ThreeYear.Saving.Total
+		filtered.Reservation.BestOption.Add(instance.Reservation.BestOption)
+		filtered.Instances = append(filtered.Instances, instance)
+	}
+	return filtered
+}
+
+// filterOdToRiEc2Reports filters each report of reports on platforms and regions
+func filterOdToRiEc2Reports(reports []OdToRiEc2Report, platforms, regions []string) OdToRiEc2Reports {
+	if len(platforms) == 0 && len(regions) == 0 {
+		return OdToRiEc2Reports(reports)
+	}
+	filtered := make(OdToRiEc2Reports, 0, len(reports))
+	for _, report := range reports {
+		filtered = append(filtered, filterOdToRiEc2Report(report, platforms, regions))
+	}
+	return filtered
+}
@@ -0,0 +1,87 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToRiEc2
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/trackit/trackit/cache"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/routes"
+	"github.com/trackit/trackit/users"
+)
+
+var (
+	// recommendationsQueryArgs allows to get required queryArgs params
+	recommendationsQueryArgs = []routes.QueryArg{
+		routes.AwsAccountsOptionalQueryArg,
+		routes.DateQueryArg,
+		routes.QueryArg{
+			Name:        "platforms",
+			Type:        routes.QueryArgStringSlice{},
+			Description: "Comma separated platforms of the instances, e.g. Linux/UNIX.",
+			Optional:    true,
+		},
+		routes.QueryArg{
+			Name:        "regions",
+			Type:        routes.QueryArgStringSlice{},
+			Description: "Comma separated regions of the instances.",
+			Optional:    true,
+		},
+	}
+)
+
+func init() {
+	routes.MethodMuxer{
+		http.MethodGet: routes.H(getRecommendations).With(
+			db.RequestTransaction{Db: db.Db},
+			users.RequireAuthenticatedUser{users.ViewerAsParent},
+			routes.QueryArgs(recommendationsQueryArgs),
+			cache.UsersCache{},
+			routes.Documentation{
+				Summary:     "get the EC2 reservations recommendations",
+				Description: "Responds with the on demand EC2 instances of the accounts and the savings that can be done by reserving them, based on the queryparams passed to it",
+			},
+		),
+	}.H().Register("/ri/ec2/recommendations")
+}
+
+// getRecommendations returns the latest on demand to RI EC2 report of each account of the month
+// of the date passed in the query params, in JSON or CSV format.
+func getRecommendations(request *http.Request, a routes.Arguments) (int, interface{}) {
+	user := a[users.AuthenticatedUser].(users.User)
+	tx := a[db.Transaction].(*sql.Tx)
+	parsedParams := RiEc2QueryParams{
+		AccountList: []string{},
+	}
+	parsedParams.DateBegin, parsedParams.DateEnd = getDateForReport(a[routes.DateQueryArg].(time.Time))
+	if a[recommendationsQueryArgs[0]] != nil {
+		parsedParams.AccountList = a[recommendationsQueryArgs[0]].([]string)
+	}
+	var platforms, regions []string
+	if a[recommendationsQueryArgs[2]] != nil {
+		platforms = a[recommendationsQueryArgs[2]].([]string)
+	}
+	if a[recommendationsQueryArgs[3]] != nil {
+		regions = a[recommendationsQueryArgs[3]].([]string)
+	}
+	returnCode, reports, err := GetRiEc2Report(request.Context(), parsedParams, user, tx)
+	if err != nil {
+		return returnCode, err
+	}
+	return returnCode, filterOdToRiEc2Reports(reports, platforms, regions)
+}
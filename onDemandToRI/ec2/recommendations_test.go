@@ -0,0 +1,79 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToRiEc2
+
+import (
+	"testing"
+
+	"github.com/trackit/trackit/onDemandToRI/utils"
+)
+
+func getTestInstance(region, platform string, onDemandMonthly, saving float64) InstancesSpecs {
+	instance := InstancesSpecs{
+		Region:        region,
+		Type:          "m5.large",
+		Platform:      platform,
+		InstanceCount: 1,
+	}
+	instance.OnDemand.Monthly = utils.Cost{onDemandMonthly, onDemandMonthly}
+	instance.Reservation.Type = "m5.large"
+	instance.Reservation.OneYear.Saving = utils.Cost{saving, saving}
+	return instance
+}
+
+func getTestReport() OdToRiEc2Report {
+	return OdToRiEc2Report{
+		Account: "123456789012",
+		Instances: []InstancesSpecs{
+			getTestInstance("us-east-1", "Linux/UNIX", 70, 200),
+			getTestInstance("eu-west-1", "Linux/UNIX", 80, 250),
+			getTestInstance("us-east-1", "Windows", 130, 300),
+		},
+	}
+}
+
+func TestFilterOdToRiEc2ReportsWithoutFilters(t *testing.T) {
+	reports := filterOdToRiEc2Reports([]OdToRiEc2Report{getTestReport()}, nil, nil)
+	if len(reports[0].Instances) != 3 {
+		t.Errorf("Expected 3 instances, got %d.", len(reports[0].Instances))
+	}
+}
+
+func TestFilterOdToRiEc2Reports(t *testing.T) {
+	reports := filterOdToRiEc2Reports([]OdToRiEc2Report{getTestReport()}, []string{"Linux/UNIX"}, []string{"us-east-1", "eu-west-1"})
+	report := reports[0]
+	if len(report.Instances) != 2 {
+		t.Fatalf("Expected 2 instances, got %d.", len(report.Instances))
+	}
+	if report.OnDemand.MonthlyTotal != 150 {
+		t.Errorf("Expected an on demand monthly total of 150, got %f.", report.OnDemand.MonthlyTotal)
+	}
+	if report.Reservation.OneYear.SavingTotal != 450 {
+		t.Errorf("Expected a one year saving total of 450, got %f.", report.Reservation.OneYear.SavingTotal)
+	}
+}
+
+func TestOdToRiEc2ReportsToCSVable(t *testing.T) {
+	csv := OdToRiEc2Reports{getTestReport()}.ToCSVable()
+	if len(csv) != 4 {
+		t.Fatalf("Expected a header and 3 lines, got %d lines.", len(csv))
+	}
+	if len(csv[0]) != len(odToRiEc2CSVHeader) || len(csv[1]) != len(odToRiEc2CSVHeader) {
+		t.Errorf("Expected lines of %d columns.", len(odToRiEc2CSVHeader))
+	}
+	if csv[3][3] != "Windows" || csv[3][7] != "130" || csv[3][9] != "300" {
+		t.Errorf("Unexpected CSV line: %v", csv[3])
+	}
+}
@@ -17,7 +17,6 @@ package onDemandToRiEc2
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"time"
@@ -30,34 +29,17 @@ import (
 	awsriEc2 "github.com/trackit/trackit/aws/usageReports/riEc2"
 	"github.com/trackit/trackit/db"
 	"github.com/trackit/trackit/es"
-	"github.com/trackit/trackit/models"
+	"github.com/trackit/trackit/onDemandToRI/utils"
 	"github.com/trackit/trackit/usageReports/ec2"
 	"github.com/trackit/trackit/usageReports/riEc2"
 	"github.com/trackit/trackit/users"
 )
 
 type (
-	Cost struct {
-		PerUnit float64 `json:"perUnit"`
-		Total   float64 `json:"total"`
-	}
-
-	OnDemandCost struct {
-		Monthly    Cost `json:"monthly"`
-		OneYear    Cost `json:"oneYear"`
-		ThreeYears Cost `json:"threeYears"`
-	}
-
 	ReservationCost struct {
-		Monthly Cost `json:"monthly"`
-		Global  Cost `json:"global"`
-		Saving  Cost `json:"saving"`
-	}
-
-	OnDemandTotalCost struct {
-		MonthlyTotal    float64 `json:"monthly"`
-		OneYearTotal    float64 `json:"oneYear"`
-		ThreeYearsTotal float64 `json:"threeYears"`
+		Monthly utils.Cost `json:"monthly"`
+		Global  utils.Cost `json:"global"`
+		Saving  utils.Cost `json:"saving"`
 	}
 
 	ReservationTotalCost struct {
@@ -66,29 +48,34 @@ type (
 		SavingTotal  float64 `json:"saving"`
 	}
 
-	// InstancesSpecs stores the costs calculated for a given region/instance/platform
+	// InstancesSpecs stores the costs calculated for a given region/instance/platform/tenancy
 	// combination
 	InstancesSpecs struct {
-		Region        string       `json:"region"`
-		Type          string       `json:"instanceType"`
-		Platform      string       `json:"platform"`
-		InstanceCount int          `json:"instanceCount"`
-		OnDemand      OnDemandCost `json:"onDemand"`
+		Region        string             `json:"region"`
+		Type          string             `json:"instanceType"`
+		Platform      string             `json:"platform"`
+		Tenancy       string             `json:"tenancy"`
+		LicenseModel  string             `json:"licenseModel"`
+		InstanceCount int                `json:"instanceCount"`
+		OnDemand      utils.OnDemandCost `json:"onDemand"`
 		Reservation   struct {
-			Type      string          `json:"type"`
-			OneYear   ReservationCost `json:"oneYear"`
-			ThreeYear ReservationCost `json:"threeYears"`
+			Type       string                 `json:"type"`
+			OneYear    ReservationCost        `json:"oneYear"`
+			ThreeYear  ReservationCost        `json:"threeYears"`
+			Options    []utils.PurchaseOption `json:"options"`
+			BestOption *utils.PurchaseOption  `json:"bestOption"`
 		} `json:"reservation"`
 	}
 
 	// OdToRiEc2Report stores all the on demand to RI EC2 report infos
 	OdToRiEc2Report struct {
-		Account     string            `json:"account"`
-		ReportDate  time.Time         `json:"reportDate"`
-		OnDemand    OnDemandTotalCost `json:"onDemand"`
+		Account     string                  `json:"account"`
+		ReportDate  time.Time               `json:"reportDate"`
+		OnDemand    utils.OnDemandTotalCost `json:"onDemand"`
 		Reservation struct {
-			OneYear   ReservationTotalCost `json:"oneYear"`
-			ThreeYear ReservationTotalCost `json:"threeYears"`
+			OneYear    ReservationTotalCost      `json:"oneYear"`
+			ThreeYear  ReservationTotalCost      `json:"threeYears"`
+			BestOption utils.BestOptionTotalCost `json:"bestOption"`
 		} `json:"reservation"`
 		Instances []InstancesSpecs `json:"instances"`
 	}
@@ -104,9 +91,10 @@ func addUnreservedInstance(unreservedInstances []InstancesSpecs, instanceReport
 		}
 	}
 	unreservedInstance := InstancesSpecs{
-		Region:        getRegionName(instanceReport.Instance.Region),
+		Region:        utils.GetRegionName(instanceReport.Instance.Region),
 		Type:          instanceReport.Instance.Type,
 		Platform:      instanceReport.Instance.Platform,
+		Tenancy:       getTenancy(instanceReport.Instance.Purchasing),
 		InstanceCount: 1,
 	}
 	return append(unreservedInstances, unreservedInstance)
@@ -172,38 +160,6 @@ func getUnreservedInstances(instancesReport []ec2.InstanceReport, reservationsRe
 	return unreservedInstances
 }
 
-// getEC2Pricings retrieves the EC2 pricings from the database
-// it returns a pricings.EC2Pricing and an error
-func getEC2Pricings(ctx context.Context) (pricings.EC2Pricing, error) {
-	ec2Pricings := pricings.EC2Pricing{}
-	tx, err := db.Db.BeginTx(ctx, nil)
-	if err != nil {
-		return ec2Pricings, err
-	}
-	ec2PricingDb, err := models.AwsPricingByProduct(tx, pricings.EC2ServiceCode)
-	if err != nil {
-		return ec2Pricings, err
-	}
-	err = json.Unmarshal(ec2PricingDb.Pricing, &ec2Pricings)
-	if err != nil {
-		return ec2Pricings, err
-	}
-	return ec2Pricings, nil
-}
-
-// getPricingForSpecs returns the pricings for a given region/platform/type combination
-func getPricingForSpecs(region, platform, instanceType string, ec2Pricings pricings.EC2Pricing) (pricings.EC2Specs, error) {
-	if platforms, ok := ec2Pricings.Region[region]; ok == false {
-		return pricings.EC2Specs{}, errors.New("Region not found in EC2 pricings")
-	} else if types, ok := platforms.Platform[platform]; ok == false {
-		return pricings.EC2Specs{}, errors.New("EC2Platform not found in EC2 pricings")
-	} else if costSpecs, ok := types.Type[instanceType]; ok == false {
-		return pricings.EC2Specs{}, errors.New("EC2Type not found in EC2 pricings")
-	} else {
-		return *costSpecs, nil
-	}
-}
-
 // getCurrentGenerationPricingEquivalent takes a previous generation InstancesSpecs and returns an equivalent pricing from
 // the current generation
 func getCurrentGenerationPricingEquivalent(unreservedSpec InstancesSpecs, ec2Pricings pricings.EC2Pricing) (string, pricings.EC2Specs, error) {
@@ -211,7 +167,7 @@ func getCurrentGenerationPricingEquivalent(unreservedSpec InstancesSpecs, ec2Pri
 	if ok == false {
 		return "", pricings.EC2Specs{}, errors.New("Equivalent instance type not found")
 	}
-	pricing, err := getPricingForSpecs(unreservedSpec.Region, unreservedSpec.Platform, unreservedSpec.Type, ec2Pricings)
+	pricing, err := ec2Pricings.GetSpecs(unreservedSpec.Region, unreservedSpec.Platform, unreservedSpec.Tenancy, equivalentType, "")
 	if err != nil {
 		return equivalentType, pricings.EC2Specs{}, errors.New("Pricing not found for equivalent type")
 	}
@@ -222,34 +178,38 @@ func getCurrentGenerationPricingEquivalent(unreservedSpec InstancesSpecs, ec2Pri
 func calculateCosts(ctx context.Context, unreservedIntances []InstancesSpecs, ec2Pricings pricings.EC2Pricing, report OdToRiEc2Report) OdToRiEc2Report {
 	logger := jsonlog.LoggerFromContextOrDefault(ctx)
 	for _, unreservedSpec := range unreservedIntances {
-		pricing, err := getPricingForSpecs(unreservedSpec.Region, unreservedSpec.Platform, unreservedSpec.Type, ec2Pricings)
+		pricing, err := ec2Pricings.GetSpecs(unreservedSpec.Region, unreservedSpec.Platform, unreservedSpec.Tenancy, unreservedSpec.Type, "")
 		if err != nil {
 			logger.Warning("Pricing not found", map[string]interface{}{
 				"error":    err.Error(),
 				"region":   unreservedSpec.Region,
 				"platform": unreservedSpec.Platform,
+				"tenancy":  unreservedSpec.Tenancy,
 				"type":     unreservedSpec.Type,
 			})
 			continue
 		}
+		unreservedSpec.LicenseModel = pricing.LicenseModel
 
-		odMonthlyPerUnit := getMonthlyCostPerUnit(pricing.OnDemandHourlyCost)
+		odMonthlyPerUnit := utils.GetMonthlyCostPerUnit(pricing.OnDemandHourlyCost)
 		odMonthlyTotal := odMonthlyPerUnit * float64(unreservedSpec.InstanceCount)
 
-		odMonthly := Cost{odMonthlyPerUnit, odMonthlyTotal}
+		odMonthly := utils.Cost{odMonthlyPerUnit, odMonthlyTotal}
 		report.OnDemand.MonthlyTotal += odMonthlyTotal
-		od1yr := Cost{odMonthlyPerUnit * 12.0, odMonthlyTotal * 12.0}
+		od1yr := utils.Cost{odMonthlyPerUnit * 12.0, odMonthlyTotal * 12.0}
 		report.OnDemand.OneYearTotal += odMonthlyTotal * 12.0
-		od3yr := Cost{odMonthlyPerUnit * 36.0, odMonthlyTotal * 36.0}
+		od3yr := utils.Cost{odMonthlyPerUnit * 36.0, odMonthlyTotal * 36.0}
 		report.OnDemand.ThreeYearsTotal += odMonthlyTotal * 36.0
 
-		unreservedSpec.OnDemand = OnDemandCost{odMonthly, od1yr, od3yr}
+		unreservedSpec.OnDemand = utils.OnDemandCost{odMonthly, od1yr, od3yr}
 
 		var ri1yrMonthlyCostPerUnit, ri3yrMonthlyCostPerUnit float64
+		var reservationOffers []pricings.ReservationOffer
 		if pricing.CurrentGeneration == true {
 			unreservedSpec.Reservation.Type = unreservedSpec.Type
-			ri1yrMonthlyCostPerUnit = getMonthlyCostPerUnit(pricing.OneYearStandardNoUpfrontHourlyCost)
-			ri3yrMonthlyCostPerUnit = getMonthlyCostPerUnit(pricing.ThreeYearsStandardNoUpfrontHourlyCost)
+			ri1yrMonthlyCostPerUnit = utils.GetMonthlyCostPerUnit(pricing.OneYearStandardNoUpfrontHourlyCost)
+			ri3yrMonthlyCostPerUnit = utils.GetMonthlyCostPerUnit(pricing.ThreeYearsStandardNoUpfrontHourlyCost)
+			reservationOffers = pricing.Reservations
 		} else {
 			currenGenType, pricing, err := getCurrentGenerationPricingEquivalent(unreservedSpec, ec2Pricings)
 			if err != nil {
@@ -257,38 +217,44 @@ func calculateCosts(ctx context.Context, unreservedIntances []InstancesSpecs, ec
 					"error":         err.Error(),
 					"region":        unreservedSpec.Region,
 					"platform":      unreservedSpec.Platform,
+					"tenancy":       unreservedSpec.Tenancy,
 					"previousType":  unreservedSpec.Type,
 					"currenGenType": currenGenType,
 				})
 				continue
 			}
 			unreservedSpec.Reservation.Type = currenGenType
-			ri1yrMonthlyCostPerUnit = getMonthlyCostPerUnit(pricing.OneYearStandardNoUpfrontHourlyCost)
-			ri3yrMonthlyCostPerUnit = getMonthlyCostPerUnit(pricing.ThreeYearsStandardNoUpfrontHourlyCost)
+			ri1yrMonthlyCostPerUnit = utils.GetMonthlyCostPerUnit(pricing.OneYearStandardNoUpfrontHourlyCost)
+			ri3yrMonthlyCostPerUnit = utils.GetMonthlyCostPerUnit(pricing.ThreeYearsStandardNoUpfrontHourlyCost)
+			reservationOffers = pricing.Reservations
 		}
 
 		ri1yrMonthlyCostTotal := ri1yrMonthlyCostPerUnit * float64(unreservedSpec.InstanceCount)
-		ri1yrMonthly := Cost{ri1yrMonthlyCostPerUnit, ri1yrMonthlyCostTotal}
+		ri1yrMonthly := utils.Cost{ri1yrMonthlyCostPerUnit, ri1yrMonthlyCostTotal}
 		report.Reservation.OneYear.MonthlyTotal += ri1yrMonthlyCostTotal
-		ri1yrGlobal := Cost{ri1yrMonthlyCostPerUnit * 12.0, ri1yrMonthlyCostTotal * 12.0}
+		ri1yrGlobal := utils.Cost{ri1yrMonthlyCostPerUnit * 12.0, ri1yrMonthlyCostTotal * 12.0}
 		report.Reservation.OneYear.GlobalTotal += ri1yrMonthlyCostTotal * 12.0
 		ri1yrSavingPerUnit := (odMonthlyPerUnit * 12.0) - (ri1yrMonthlyCostPerUnit * 12.0)
 		ri1yrSavingTotal := (odMonthlyTotal * 12.0) - (ri1yrMonthlyCostTotal * 12.0)
-		ri1yrSaving := Cost{ri1yrSavingPerUnit, ri1yrSavingTotal}
+		ri1yrSaving := utils.Cost{ri1yrSavingPerUnit, ri1yrSavingTotal}
 		report.Reservation.OneYear.SavingTotal += ri1yrSavingTotal
 		unreservedSpec.Reservation.OneYear = ReservationCost{ri1yrMonthly, ri1yrGlobal, ri1yrSaving}
 
 		ri3yrMonthlyCostTotal := ri3yrMonthlyCostPerUnit * float64(unreservedSpec.InstanceCount)
-		ri3yrMonthly := Cost{ri3yrMonthlyCostPerUnit, ri3yrMonthlyCostTotal}
+		ri3yrMonthly := utils.Cost{ri3yrMonthlyCostPerUnit, ri3yrMonthlyCostTotal}
 		report.Reservation.ThreeYear.MonthlyTotal += ri3yrMonthlyCostTotal
-		ri3yrGlobal := Cost{ri3yrMonthlyCostPerUnit * 36.0, ri3yrMonthlyCostPerUnit * 36.0}
+		ri3yrGlobal := utils.Cost{ri3yrMonthlyCostPerUnit * 36.0, ri3yrMonthlyCostTotal * 36.0}
 		report.Reservation.ThreeYear.GlobalTotal += ri3yrMonthlyCostTotal * 36.0
 		ri3yrSavingPerUnit := (odMonthlyPerUnit * 12.0) - (ri3yrMonthlyCostPerUnit * 12.0)
 		ri3yrSavingTotal := (odMonthlyTotal * 12.0) - (ri3yrMonthlyCostTotal * 12.0)
-		ri3yrSaving := Cost{ri3yrSavingPerUnit, ri3yrSavingTotal}
+		ri3yrSaving := utils.Cost{ri3yrSavingPerUnit, ri3yrSavingTotal}
 		report.Reservation.ThreeYear.SavingTotal += ri3yrSavingTotal
 		unreservedSpec.Reservation.ThreeYear = ReservationCost{ri3yrMonthly, ri3yrGlobal, ri3yrSaving}
 
+		unreservedSpec.Reservation.Options = utils.GetPurchaseOptions(reservationOffers, odMonthlyPerUnit, unreservedSpec.InstanceCount)
+		unreservedSpec.Reservation.BestOption = utils.GetBestPurchaseOption(unreservedSpec.Reservation.Options)
+		report.Reservation.BestOption.Add(unreservedSpec.Reservation.BestOption)
+
 		report.Instances = append(report.Instances, unreservedSpec)
 	}
 	return report
@@ -314,10 +280,17 @@ func RunOnDemandToRiEc2(ctx context.Context, aa aws.AwsAccount) error {
 		logger.Error("Unable to retrieve ec2 instances report", err.Error())
 		return err
 	}
-	ec2Pricings, err := getEC2Pricings(ctx)
+	ec2Pricings := pricings.EC2Pricing{}
+	err = utils.GetPricing(ctx, pricings.EC2ServiceCode, &ec2Pricings)
 	if err != nil {
 		logger.Error("Failed to retrieve ec2 pricings from database", err.Error())
 		return err
+	} else if err = ec2Pricings.CheckVersion(); err != nil {
+		logger.Error("Stored ec2 pricings are outdated, run the fetch-pricings task", map[string]interface{}{
+			"version":         ec2Pricings.Version,
+			"expectedVersion": pricings.EC2PricingVersion,
+		})
+		return err
 	}
 	unreservedIntances := getUnreservedInstances(instancesReport, reservationsReport)
 	report = calculateCosts(ctx, unreservedIntances, ec2Pricings, report)
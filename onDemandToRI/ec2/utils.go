@@ -19,34 +19,35 @@ import (
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/json"
-	"strconv"
 	"time"
 
 	"github.com/trackit/jsonlog"
 
 	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/aws/pricings"
 	"github.com/trackit/trackit/es"
+	"github.com/trackit/trackit/onDemandToRI/utils"
 	"github.com/trackit/trackit/usageReports/ec2"
 	"github.com/trackit/trackit/usageReports/riEc2"
 )
 
-var (
-	HoursPerMonth = 730.0
-)
-
-// getRegionName takes an availability zone or a region name and returns a region name
-func getRegionName(az string) string {
-	if _, err := strconv.Atoi(string(az[len(az)-1])); err == nil {
-		// The "az" finishes by a number, so it's a region name
-		return az
+// getTenancy takes the purchasing option of an instance and returns the tenancy
+// used in the EC2 pricings
+func getTenancy(purchasing string) string {
+	switch purchasing {
+	case "dedicated":
+		return pricings.EC2TenancyDedicated
+	case "host":
+		return pricings.EC2TenancyHost
+	default:
+		return pricings.EC2TenancyShared
 	}
-	return az[:len(az)-1]
 }
 
 // instanceMatchReservation takes an ec2.InstanceReport and an riEc2.ReservationReport
 // It returns true if the InstanceReport matches the ReservationReport
 func instanceMatchReservation(instanceReport ec2.InstanceReport, reservationReport riEc2.ReservationReport) bool {
-	if (getRegionName(instanceReport.Instance.Region) == reservationReport.Reservation.Region ||
+	if (utils.GetRegionName(instanceReport.Instance.Region) == reservationReport.Reservation.Region ||
 		instanceReport.Instance.Region == reservationReport.Reservation.AvailabilityZone) &&
 		instanceReport.Instance.Type == reservationReport.Reservation.Type &&
 		instanceReport.Instance.Platform == reservationReport.Reservation.ProductDescription {
@@ -58,22 +59,14 @@ func instanceMatchReservation(instanceReport ec2.InstanceReport, reservationRepo
 // instanceMatchSpecs takes an ec2.InstanceReport and an InstancesSpecs
 // it returns true if the InstanceReport matches the InstancesSpecs
 func instanceMatchSpecs(instanceReport ec2.InstanceReport, specs InstancesSpecs) bool {
-	if getRegionName(instanceReport.Instance.Region) == specs.Region &&
-		instanceReport.Instance.Type == specs.Type && instanceReport.Instance.Platform == specs.Platform {
+	if utils.GetRegionName(instanceReport.Instance.Region) == specs.Region &&
+		instanceReport.Instance.Type == specs.Type && instanceReport.Instance.Platform == specs.Platform &&
+		getTenancy(instanceReport.Instance.Purchasing) == specs.Tenancy {
 		return true
 	}
 	return false
 }
 
-// getMonthlyCostPerUnit returns the monthly cost based on the hourlyCost
-// it returns 0.0 if the hourlyCost is -1.0 (which means the pricing term does not exist)
-func getMonthlyCostPerUnit(hourlyCost float64) float64 {
-	if hourlyCost != -1.0 {
-		return hourlyCost * HoursPerMonth
-	}
-	return 0.0
-}
-
 // IngestOdToRiEc2Result saves a OdToRiEc2Report into elasticsearch
 func IngestOdToRiEc2Result(ctx context.Context, aa aws.AwsAccount, report OdToRiEc2Report) error {
 	logger := jsonlog.LoggerFromContextOrDefault(ctx)
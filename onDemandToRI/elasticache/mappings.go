@@ -0,0 +1,42 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToRiElastiCache
+
+import (
+	"context"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/es"
+	"github.com/trackit/trackit/onDemandToRI/utils"
+)
+
+const TypeOdToRiElastiCacheReport = "od-to-ri-elasticache-report"
+const IndexPrefixOdToRiElastiCacheReport = "od-to-ri-elasticache-reports"
+const TemplateNameOdToRiElastiCacheReport = "od-to-ri-elasticache-reports"
+
+// put the ElasticSearch index for *-od-to-ri-elasticache-reports indices at startup.
+func init() {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	template := utils.GetNodesReportTemplate(IndexPrefixOdToRiElastiCacheReport, TypeOdToRiElastiCacheReport)
+	res, err := es.Client.IndexPutTemplate(TemplateNameOdToRiElastiCacheReport).BodyString(template).Do(ctx)
+	if err != nil {
+		jsonlog.DefaultLogger.Error("Failed to put ES index OdToRiElastiCacheReport.", err)
+	} else {
+		jsonlog.DefaultLogger.Info("Put ES index OdToRiElastiCacheReport.", res)
+		ctxCancel()
+	}
+}
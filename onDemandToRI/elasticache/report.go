@@ -0,0 +1,135 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToRiElastiCache
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	elasticacheService "github.com/aws/aws-sdk-go/service/elasticache"
+	"github.com/trackit/jsonlog"
+
+	taws "github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/aws/pricings"
+	awsElastiCache "github.com/trackit/trackit/aws/usageReports/elasticache"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/es"
+	"github.com/trackit/trackit/onDemandToRI/utils"
+	"github.com/trackit/trackit/usageReports/elasticache"
+	"github.com/trackit/trackit/users"
+)
+
+const ReservedNodesStsSessionName = "fetch-elasticache-reserved-nodes"
+
+// getElastiCacheReport retrieves the latest ElastiCache daily report
+func getElastiCacheReport(ctx context.Context, aa taws.AwsAccount) ([]elasticache.InstanceReport, error) {
+	tx, err := db.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Commit()
+	user, err := users.GetUserWithId(tx, aa.UserId)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	currentMonthBeginning := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	_, res, err := elasticache.GetElastiCacheDailyInstances(ctx, elasticache.ElastiCacheQueryParams{
+		AccountList: []string{aa.AwsIdentity},
+		IndexList:   []string{es.IndexNameForUserId(aa.UserId, awsElastiCache.IndexPrefixElastiCacheReport)},
+		Date:        currentMonthBeginning,
+	}, user, tx)
+	return res, err
+}
+
+// fetchRegionReservedNodes retrieves the active reserved cache nodes of a region
+func fetchRegionReservedNodes(ctx context.Context, sess *session.Session) ([]utils.ReservedNodes, error) {
+	reservedNodes := make([]utils.ReservedNodes, 0)
+	region := aws.StringValue(sess.Config.Region)
+	svc := elasticacheService.New(sess)
+	err := svc.DescribeReservedCacheNodesPagesWithContext(ctx, &elasticacheService.DescribeReservedCacheNodesInput{},
+		func(page *elasticacheService.DescribeReservedCacheNodesOutput, lastPage bool) bool {
+			for _, reservation := range page.ReservedCacheNodes {
+				if aws.StringValue(reservation.State) != "active" {
+					continue
+				}
+				reservedNodes = append(reservedNodes, utils.ReservedNodes{
+					Region: region,
+					Type:   aws.StringValue(reservation.CacheNodeType),
+					Engine: pricings.GetElastiCachePricingEngine(aws.StringValue(reservation.ProductDescription)),
+					Count:  int(aws.Int64Value(reservation.CacheNodeCount)),
+				})
+			}
+			return true
+		})
+	return reservedNodes, err
+}
+
+// getUnreservedNodes takes a list of cluster reports and a list of reserved nodes
+// It returns the nodes without reservations grouped by region, node type and engine
+func getUnreservedNodes(instances []elasticache.InstanceReport, reservedNodes []utils.ReservedNodes) []utils.NodesSpecs {
+	nodes := make([]utils.NodesSpecs, 0)
+	for _, instance := range instances {
+		engine := pricings.GetElastiCachePricingEngine(instance.Instance.Engine)
+		if engine == "" || instance.Instance.Status != "available" {
+			continue
+		}
+		for _, node := range instance.Instance.Nodes {
+			region := node.Region
+			if region == "" {
+				region = instance.Instance.Region
+			}
+			nodes = utils.AddNodes(nodes, utils.GetRegionName(region), instance.Instance.NodeType, engine, 1)
+		}
+	}
+	return utils.RemoveReservedNodes(nodes, reservedNodes)
+}
+
+// RunOnDemandToRiElastiCache generates a report listing the unreserved ElastiCache nodes
+// and the savings that can be done by buying reservations
+// The result is saved into ES
+func RunOnDemandToRiElastiCache(ctx context.Context, aa taws.AwsAccount) error {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	report := utils.NodesReport{
+		Account:    aa.AwsIdentity,
+		ReportDate: time.Now().UTC(),
+	}
+	logger.Info("Generating on demand to reserved nodes ElastiCache report", map[string]interface{}{"awsAccountId": aa.Id})
+	instances, err := getElastiCacheReport(ctx, aa)
+	if err != nil {
+		logger.Error("Unable to retrieve ElastiCache daily report", err.Error())
+		return err
+	}
+	reservedNodes, err := utils.FetchReservedNodes(ctx, aa, ReservedNodesStsSessionName, fetchRegionReservedNodes)
+	if err != nil {
+		logger.Error("Unable to retrieve ElastiCache reserved nodes", err.Error())
+		return err
+	}
+	elastiCachePricings := pricings.NodePricing{}
+	if err = utils.GetPricing(ctx, pricings.ElastiCacheServiceCode, &elastiCachePricings); err != nil {
+		logger.Error("Failed to retrieve ElastiCache pricings from database", err.Error())
+		return err
+	}
+	report = utils.CalculateNodesCosts(ctx, getUnreservedNodes(instances, reservedNodes), elastiCachePricings, report)
+	return utils.IngestNodesReport(ctx, aa, report, IndexPrefixOdToRiElastiCacheReport, TypeOdToRiElastiCacheReport)
+}
+
+// GetRiElastiCacheReport gets on demand to RI ElastiCache reports based on query params
+func GetRiElastiCacheReport(ctx context.Context, parsedParams utils.NodesQueryParams, user users.User, tx *sql.Tx) (int, []utils.NodesReport, error) {
+	return utils.GetNodesReports(ctx, parsedParams, user, tx, IndexPrefixOdToRiElastiCacheReport)
+}
@@ -0,0 +1,126 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToRiEs
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/elasticsearchservice"
+	"github.com/trackit/jsonlog"
+
+	taws "github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/aws/pricings"
+	awsEs "github.com/trackit/trackit/aws/usageReports/es"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/es"
+	"github.com/trackit/trackit/onDemandToRI/utils"
+	usageReportsEs "github.com/trackit/trackit/usageReports/es"
+	"github.com/trackit/trackit/users"
+)
+
+const ReservedInstancesStsSessionName = "fetch-es-reserved-instances"
+
+// getEsReport retrieves the latest Elasticsearch Service daily report
+func getEsReport(ctx context.Context, aa taws.AwsAccount) ([]usageReportsEs.DomainReport, error) {
+	tx, err := db.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Commit()
+	user, err := users.GetUserWithId(tx, aa.UserId)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	currentMonthBeginning := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	_, res, err := usageReportsEs.GetEsDailyDomains(ctx, usageReportsEs.EsQueryParams{
+		AccountList: []string{aa.AwsIdentity},
+		IndexList:   []string{es.IndexNameForUserId(aa.UserId, awsEs.IndexPrefixESReport)},
+		Date:        currentMonthBeginning,
+	}, user, tx)
+	return res, err
+}
+
+// fetchRegionReservedInstances retrieves the active reserved Elasticsearch instances of a region
+func fetchRegionReservedInstances(ctx context.Context, sess *session.Session) ([]utils.ReservedNodes, error) {
+	reservedNodes := make([]utils.ReservedNodes, 0)
+	region := aws.StringValue(sess.Config.Region)
+	svc := elasticsearchservice.New(sess)
+	err := svc.DescribeReservedElasticsearchInstancesPagesWithContext(ctx, &elasticsearchservice.DescribeReservedElasticsearchInstancesInput{},
+		func(page *elasticsearchservice.DescribeReservedElasticsearchInstancesOutput, lastPage bool) bool {
+			for _, reservation := range page.ReservedElasticsearchInstances {
+				if aws.StringValue(reservation.State) != "active" {
+					continue
+				}
+				reservedNodes = append(reservedNodes, utils.ReservedNodes{
+					Region: region,
+					Type:   aws.StringValue(reservation.ElasticsearchInstanceType),
+					Engine: pricings.ESEngine,
+					Count:  int(aws.Int64Value(reservation.ElasticsearchInstanceCount)),
+				})
+			}
+			return true
+		})
+	return reservedNodes, err
+}
+
+// getUnreservedNodes takes a list of domain reports and a list of reserved instances
+// It returns the instances without reservations grouped by region and instance type
+func getUnreservedNodes(domains []usageReportsEs.DomainReport, reservedNodes []utils.ReservedNodes) []utils.NodesSpecs {
+	nodes := make([]utils.NodesSpecs, 0)
+	for _, domain := range domains {
+		region := utils.GetRegionName(domain.Domain.Region)
+		nodes = utils.AddNodes(nodes, region, domain.Domain.InstanceType, pricings.ESEngine, int(domain.Domain.InstanceCount))
+	}
+	return utils.RemoveReservedNodes(nodes, reservedNodes)
+}
+
+// RunOnDemandToRiEs generates a report listing the unreserved Elasticsearch Service
+// instances and the savings that can be done by buying reservations
+// The result is saved into ES
+func RunOnDemandToRiEs(ctx context.Context, aa taws.AwsAccount) error {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	report := utils.NodesReport{
+		Account:    aa.AwsIdentity,
+		ReportDate: time.Now().UTC(),
+	}
+	logger.Info("Generating on demand to reserved instances Elasticsearch Service report", map[string]interface{}{"awsAccountId": aa.Id})
+	domains, err := getEsReport(ctx, aa)
+	if err != nil {
+		logger.Error("Unable to retrieve Elasticsearch Service daily report", err.Error())
+		return err
+	}
+	reservedNodes, err := utils.FetchReservedNodes(ctx, aa, ReservedInstancesStsSessionName, fetchRegionReservedInstances)
+	if err != nil {
+		logger.Error("Unable to retrieve Elasticsearch Service reserved instances", err.Error())
+		return err
+	}
+	esPricings := pricings.NodePricing{}
+	if err = utils.GetPricing(ctx, pricings.ESServiceCode, &esPricings); err != nil {
+		logger.Error("Failed to retrieve Elasticsearch Service pricings from database", err.Error())
+		return err
+	}
+	report = utils.CalculateNodesCosts(ctx, getUnreservedNodes(domains, reservedNodes), esPricings, report)
+	return utils.IngestNodesReport(ctx, aa, report, IndexPrefixOdToRiEsReport, TypeOdToRiEsReport)
+}
+
+// GetRiEsReport gets on demand to RI Elasticsearch Service reports based on query params
+func GetRiEsReport(ctx context.Context, parsedParams utils.NodesQueryParams, user users.User, tx *sql.Tx) (int, []utils.NodesReport, error) {
+	return utils.GetNodesReports(ctx, parsedParams, user, tx, IndexPrefixOdToRiEsReport)
+}
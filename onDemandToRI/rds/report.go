@@ -0,0 +1,119 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToRiRds
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/aws/pricings"
+	awsRds "github.com/trackit/trackit/aws/usageReports/rds"
+	awsRiRds "github.com/trackit/trackit/aws/usageReports/riRdS"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/es"
+	"github.com/trackit/trackit/onDemandToRI/utils"
+	"github.com/trackit/trackit/usageReports/rds"
+	"github.com/trackit/trackit/usageReports/riRds"
+	"github.com/trackit/trackit/users"
+)
+
+// getRdsReports retrieves the latest RDS instances and RDS reservations daily reports
+func getRdsReports(ctx context.Context, aa aws.AwsAccount) ([]rds.InstanceReport, []riRds.ReservationReport, error) {
+	tx, err := db.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Commit()
+	user, err := users.GetUserWithId(tx, aa.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now().UTC()
+	currentMonthBeginning := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	_, instances, err := rds.GetRdsDailyInstances(ctx, rds.RdsQueryParams{
+		AccountList: []string{aa.AwsIdentity},
+		IndexList:   []string{es.IndexNameForUserId(aa.UserId, awsRds.IndexPrefixRDSReport)},
+		Date:        currentMonthBeginning,
+	}, user, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, reservations, err := riRds.GetReservedInstancesDaily(ctx, riRds.ReservedInstancesQueryParams{
+		AccountList: []string{aa.AwsIdentity},
+		IndexList:   []string{es.IndexNameForUserId(aa.UserId, awsRiRds.IndexPrefixReservedRDSReport)},
+		Date:        currentMonthBeginning,
+	}, user, tx)
+	return instances, reservations, err
+}
+
+// getUnreservedNodes takes a list of instance reports and a list of reservation reports
+// It returns the instances without reservations grouped by region, class and engine
+func getUnreservedNodes(instances []rds.InstanceReport, reservations []riRds.ReservationReport) []utils.NodesSpecs {
+	nodes := make([]utils.NodesSpecs, 0)
+	for _, instance := range instances {
+		engine := pricings.GetRdsPricingEngine(instance.Instance.Engine, instance.Instance.MultiAZ)
+		if engine == "" {
+			continue
+		}
+		region := utils.GetRegionName(instance.Instance.AvailabilityZone)
+		nodes = utils.AddNodes(nodes, region, instance.Instance.DBInstanceClass, engine, 1)
+	}
+	reservedNodes := make([]utils.ReservedNodes, 0, len(reservations))
+	for _, reservation := range reservations {
+		if reservation.Reservation.State != "active" {
+			continue
+		}
+		reservedNodes = append(reservedNodes, utils.ReservedNodes{
+			Region: utils.GetRegionName(reservation.Reservation.AvailabilityZone),
+			Type:   reservation.Reservation.DBInstanceClass,
+			Engine: pricings.GetRdsPricingEngine(reservation.Reservation.ProductDescription, reservation.Reservation.MultiAZ),
+			Count:  int(reservation.Reservation.DBInstanceCount),
+		})
+	}
+	return utils.RemoveReservedNodes(nodes, reservedNodes)
+}
+
+// RunOnDemandToRiRds generates a report listing the unreserved RDS instances and the
+// savings that can be done by buying reservations
+// The result is saved into ES
+func RunOnDemandToRiRds(ctx context.Context, aa aws.AwsAccount) error {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	report := utils.NodesReport{
+		Account:    aa.AwsIdentity,
+		ReportDate: time.Now().UTC(),
+	}
+	logger.Info("Generating on demand to reserved instances RDS report", map[string]interface{}{"awsAccountId": aa.Id})
+	instances, reservations, err := getRdsReports(ctx, aa)
+	if err != nil {
+		logger.Error("Unable to retrieve RDS daily reports", err.Error())
+		return err
+	}
+	rdsPricings := pricings.NodePricing{}
+	if err = utils.GetPricing(ctx, pricings.RDSServiceCode, &rdsPricings); err != nil {
+		logger.Error("Failed to retrieve RDS pricings from database", err.Error())
+		return err
+	}
+	report = utils.CalculateNodesCosts(ctx, getUnreservedNodes(instances, reservations), rdsPricings, report)
+	return utils.IngestNodesReport(ctx, aa, report, IndexPrefixOdToRiRdsReport, TypeOdToRiRdsReport)
+}
+
+// GetRiRdsReport gets on demand to RI RDS reports based on query params
+func GetRiRdsReport(ctx context.Context, parsedParams utils.NodesQueryParams, user users.User, tx *sql.Tx) (int, []utils.NodesReport, error) {
+	return utils.GetNodesReports(ctx, parsedParams, user, tx, IndexPrefixOdToRiRdsReport)
+}
@@ -0,0 +1,431 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/aws"
+	terrors "github.com/trackit/trackit/errors"
+	"github.com/trackit/trackit/es"
+	"github.com/trackit/trackit/users"
+)
+
+type (
+	// NodesQueryParams will store the parsed query params
+	NodesQueryParams struct {
+		AccountList []string
+		IndexList   []string
+		DateBegin   time.Time
+		DateEnd     time.Time
+	}
+
+	// Structure that allow to parse ES response for on demand to reserved nodes reports
+	ResponseNodesReports struct {
+		Accounts struct {
+			Buckets []struct {
+				Reports struct {
+					Hits struct {
+						Hits []struct {
+							Report NodesReport `json:"_source"`
+						} `json:"hits"`
+					} `json:"hits"`
+				} `json:"reports"`
+			} `json:"buckets"`
+		} `json:"accounts"`
+	}
+)
+
+// GetNodesReportTemplate returns the ElasticSearch template of the on demand to
+// reserved nodes reports stored in the indices with the given prefix and document type
+func GetNodesReportTemplate(indexPrefix, docType string) string {
+	return fmt.Sprintf(templateNodesReport, indexPrefix, docType)
+}
+
+// makeElasticSearchRequest prepares and run an ES request based on the NodesQueryParams
+// It will return the data, an http status code (as int) and an error.
+// If the index does not exist the error is returned with a 200 status code
+func makeElasticSearchRequest(ctx context.Context, parsedParams NodesQueryParams) (*elastic.SearchResult, int, error) {
+	l := jsonlog.LoggerFromContextOrDefault(ctx)
+	index := strings.Join(parsedParams.IndexList, ",")
+	res, err := getElasticSearchNodesParams(parsedParams, es.Client, index).Do(ctx)
+	if err != nil {
+		if elastic.IsNotFound(err) {
+			l.Warning("Query execution failed, ES index does not exists", map[string]interface{}{
+				"index": index,
+				"error": err.Error(),
+			})
+			return nil, http.StatusOK, terrors.GetErrorMessage(ctx, err)
+		} else if cast, ok := err.(*elastic.Error); ok && cast.Details != nil && cast.Details.Type == "search_phase_execution_exception" {
+			l.Error("Error while getting data from ES", map[string]interface{}{
+				"type":  fmt.Sprintf("%T", err),
+				"error": err,
+			})
+		} else {
+			l.Error("Query execution failed", map[string]interface{}{"error": err.Error()})
+		}
+		return nil, http.StatusInternalServerError, terrors.GetErrorMessage(ctx, err)
+	}
+	return res, http.StatusOK, nil
+}
+
+// getElasticSearchNodesParams is used to construct an ElasticSearch *elastic.SearchService
+// retrieving the latest report of each account between DateBegin and DateEnd
+func getElasticSearchNodesParams(params NodesQueryParams, client *elastic.Client, index string) *elastic.SearchService {
+	query := elastic.NewBoolQuery()
+	if len(params.AccountList) > 0 {
+		accountList := make([]interface{}, len(params.AccountList))
+		for i, v := range params.AccountList {
+			accountList[i] = v
+		}
+		query = query.Filter(elastic.NewTermsQuery("account", accountList...))
+	}
+	query = query.Filter(elastic.NewRangeQuery("reportDate").From(params.DateBegin).To(params.DateEnd))
+	search := client.Search().Index(index).Size(0).Query(query)
+	search.Aggregation("accounts", elastic.NewTermsAggregation().Field("account").
+		SubAggregation("reports", elastic.NewTopHitsAggregation().Sort("reportDate", false).Size(1)))
+	return search
+}
+
+// prepareResponseNodes parses the results from elasticsearch and returns an array of NodesReport
+func prepareResponseNodes(ctx context.Context, res *elastic.SearchResult) ([]NodesReport, error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	var response ResponseNodesReports
+	reports := make([]NodesReport, 0)
+	err := json.Unmarshal(*res.Aggregations["accounts"], &response.Accounts)
+	if err != nil {
+		logger.Error("Error while unmarshaling ES nodes response", err)
+		return nil, terrors.GetErrorMessage(ctx, err)
+	}
+	for _, account := range response.Accounts.Buckets {
+		for _, report := range account.Reports.Hits.Hits {
+			reports = append(reports, report.Report)
+		}
+	}
+	return reports, nil
+}
+
+// GetNodesReports gets the on demand to reserved nodes reports stored in the indices
+// with the given prefix based on query params
+func GetNodesReports(ctx context.Context, parsedParams NodesQueryParams, user users.User, tx *sql.Tx, indexPrefix string) (int, []NodesReport, error) {
+	accountsAndIndexes, returnCode, err := es.GetAccountsAndIndexes(parsedParams.AccountList, user, tx, indexPrefix)
+	if err != nil {
+		return returnCode, nil, err
+	}
+	parsedParams.AccountList = accountsAndIndexes.Accounts
+	parsedParams.IndexList = accountsAndIndexes.Indexes
+	res, returnCode, err := makeElasticSearchRequest(ctx, parsedParams)
+	if err != nil {
+		return returnCode, nil, err
+	} else if res == nil {
+		return http.StatusInternalServerError, nil, errors.New("Error while getting data. Please check again in few hours.")
+	}
+	reports, err := prepareResponseNodes(ctx, res)
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+	return http.StatusOK, reports, nil
+}
+
+// IngestNodesReport saves a NodesReport into elasticsearch, in the index of the
+// user with the given prefix
+func IngestNodesReport(ctx context.Context, aa aws.AwsAccount, report NodesReport, indexPrefix, docType string) error {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	logger.Info("Saving on demand to reserved nodes result for AWS account.", map[string]interface{}{
+		"awsAccount": aa,
+		"type":       docType,
+	})
+	ji, err := json.Marshal(struct {
+		Account    string    `json:"account"`
+		ReportDate time.Time `json:"reportDate"`
+	}{
+		report.Account,
+		report.ReportDate,
+	})
+	if err != nil {
+		logger.Error("Error when marshaling report var", err.Error())
+		return err
+	}
+	hash := md5.Sum(ji)
+	hash64 := base64.URLEncoding.EncodeToString(hash[:])
+	index := es.IndexNameForUserId(aa.UserId, indexPrefix)
+	if res, err := es.Client.
+		Index().
+		Index(index).
+		Type(docType).
+		BodyJson(report).
+		Id(hash64).
+		Do(context.Background()); err != nil {
+		logger.Error("Error when putting on demand to reserved nodes result in ES", err.Error())
+		return err
+	} else {
+		logger.Info("On demand to reserved nodes result put in ES", *res)
+	}
+	return nil
+}
+
+const templateNodesReport = `
+{
+	"template": "*-%[1]s",
+	"version": 1,
+	"mappings": {
+		"%[2]s": {
+			"properties": {
+				"account": {
+					"type": "keyword"
+				},
+				"reportDate": {
+					"type": "date"
+				},
+				"onDemand": {
+					"properties": {
+						"monthly": {
+							"type": "double"
+						},
+						"oneYear": {
+							"type": "double"
+						},
+						"threeYears": {
+							"type": "double"
+						}
+					}
+				},
+				"reservation": {
+					"properties": {
+						"bestOption": {
+							"properties": {
+								"upfront": {
+									"type": "double"
+								},
+								"monthly": {
+									"type": "double"
+								},
+								"monthlySaving": {
+									"type": "double"
+								}
+							}
+						}
+					}
+				},
+				"nodes": {
+					"type": "nested",
+					"properties": {
+						"region": {
+							"type": "keyword"
+						},
+						"type": {
+							"type": "keyword"
+						},
+						"engine": {
+							"type": "keyword"
+						},
+						"nodeCount": {
+							"type": "integer"
+						},
+						"onDemand": {
+							"properties": {
+								"monthly": {
+									"properties": {
+										"perUnit": {
+											"type": "double"
+										},
+										"total": {
+											"type": "double"
+										}
+									}
+								},
+								"oneYear": {
+									"properties": {
+										"perUnit": {
+											"type": "double"
+										},
+										"total": {
+											"type": "double"
+										}
+									}
+								},
+								"threeYears": {
+									"properties": {
+										"perUnit": {
+											"type": "double"
+										},
+										"total": {
+											"type": "double"
+										}
+									}
+								}
+							}
+						},
+						"reservation": {
+							"properties": {
+								"options": {
+									"type": "nested",
+									"properties": {
+										"term": {
+											"type": "keyword"
+										},
+										"offeringClass": {
+											"type": "keyword"
+										},
+										"purchaseOption": {
+											"type": "keyword"
+										},
+										"upfront": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"monthly": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"global": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"saving": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"monthlySaving": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"breakEvenMonth": {
+											"type": "integer"
+										}
+									}
+								},
+								"bestOption": {
+									"properties": {
+										"term": {
+											"type": "keyword"
+										},
+										"offeringClass": {
+											"type": "keyword"
+										},
+										"purchaseOption": {
+											"type": "keyword"
+										},
+										"upfront": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"monthly": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"global": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"saving": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"monthlySaving": {
+											"properties": {
+												"perUnit": {
+													"type": "double"
+												},
+												"total": {
+													"type": "double"
+												}
+											}
+										},
+										"breakEvenMonth": {
+											"type": "integer"
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+			},
+			"_all": {
+				"enabled": false
+			},
+			"numeric_detection": false,
+			"date_detection": false
+		}
+	}
+}
+`
@@ -0,0 +1,122 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/aws/pricings"
+)
+
+type (
+	// NodesSpecs stores the costs calculated for a given region/node type/engine
+	// combination of a reservable service (RDS, ElastiCache or Elasticsearch Service)
+	NodesSpecs struct {
+		Region      string       `json:"region"`
+		Type        string       `json:"type"`
+		Engine      string       `json:"engine"`
+		NodeCount   int          `json:"nodeCount"`
+		OnDemand    OnDemandCost `json:"onDemand"`
+		Reservation struct {
+			Options    []PurchaseOption `json:"options"`
+			BestOption *PurchaseOption  `json:"bestOption"`
+		} `json:"reservation"`
+	}
+
+	// ReservedNodes stores the number of active reserved nodes for a given
+	// region/node type/engine combination
+	ReservedNodes struct {
+		Region string
+		Type   string
+		Engine string
+		Count  int
+	}
+
+	// NodesReport stores all the on demand to reserved nodes report infos of a service
+	NodesReport struct {
+		Account     string            `json:"account"`
+		ReportDate  time.Time         `json:"reportDate"`
+		OnDemand    OnDemandTotalCost `json:"onDemand"`
+		Reservation struct {
+			BestOption BestOptionTotalCost `json:"bestOption"`
+		} `json:"reservation"`
+		Nodes []NodesSpecs `json:"nodes"`
+	}
+)
+
+// AddNodes adds count nodes to the NodesSpecs matching the region, the node type
+// and the engine, and creates it if it does not exist
+func AddNodes(nodes []NodesSpecs, region, nodeType, engine string, count int) []NodesSpecs {
+	for i, node := range nodes {
+		if node.Region == region && node.Type == nodeType && node.Engine == engine {
+			nodes[i].NodeCount += count
+			return nodes
+		}
+	}
+	return append(nodes, NodesSpecs{
+		Region:    region,
+		Type:      nodeType,
+		Engine:    engine,
+		NodeCount: count,
+	})
+}
+
+// RemoveReservedNodes removes the reserved nodes from the NodesSpecs matching their region,
+// node type and engine. The NodesSpecs without nodes left are removed.
+func RemoveReservedNodes(nodes []NodesSpecs, reservedNodes []ReservedNodes) []NodesSpecs {
+	for _, reserved := range reservedNodes {
+		for i, node := range nodes {
+			if node.Region == reserved.Region && node.Type == reserved.Type && node.Engine == reserved.Engine {
+				if node.NodeCount > reserved.Count {
+					nodes[i].NodeCount -= reserved.Count
+				} else {
+					nodes = append(nodes[:i], nodes[i+1:]...)
+				}
+				break
+			}
+		}
+	}
+	return nodes
+}
+
+// CalculateNodesCosts calculates the on demand cost of the unreserved nodes and the
+// savings of every reservation purchase option
+func CalculateNodesCosts(ctx context.Context, unreservedNodes []NodesSpecs, nodePricing pricings.NodePricing, report NodesReport) NodesReport {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	report.Nodes = make([]NodesSpecs, 0, len(unreservedNodes))
+	for _, unreservedNode := range unreservedNodes {
+		pricing, err := nodePricing.GetSpecs(unreservedNode.Region, unreservedNode.Engine, unreservedNode.Type)
+		if err != nil {
+			logger.Warning("Pricing not found", map[string]interface{}{
+				"error":  err.Error(),
+				"region": unreservedNode.Region,
+				"engine": unreservedNode.Engine,
+				"type":   unreservedNode.Type,
+			})
+			continue
+		}
+		odMonthlyPerUnit := GetMonthlyCostPerUnit(pricing.OnDemandHourlyCost)
+		unreservedNode.OnDemand = GetOnDemandCost(odMonthlyPerUnit, unreservedNode.NodeCount)
+		report.OnDemand.Add(unreservedNode.OnDemand)
+		unreservedNode.Reservation.Options = GetPurchaseOptions(pricing.Reservations, odMonthlyPerUnit, unreservedNode.NodeCount)
+		unreservedNode.Reservation.BestOption = GetBestPurchaseOption(unreservedNode.Reservation.Options)
+		report.Reservation.BestOption.Add(unreservedNode.Reservation.BestOption)
+		report.Nodes = append(report.Nodes, unreservedNode)
+	}
+	return report
+}
@@ -0,0 +1,65 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package utils
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/trackit/jsonlog"
+
+	taws "github.com/trackit/trackit/aws"
+	usageReports "github.com/trackit/trackit/aws/usageReports"
+	"github.com/trackit/trackit/config"
+)
+
+// FetchReservedNodes retrieves the active reserved nodes of an AWS account in
+// every region. fetchRegion is called with a session on each region.
+func FetchReservedNodes(ctx context.Context, aa taws.AwsAccount, sessionName string,
+	fetchRegion func(context.Context, *session.Session) ([]ReservedNodes, error)) ([]ReservedNodes, error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	creds, err := taws.GetTemporaryCredentials(aa, sessionName)
+	if err != nil {
+		logger.Error("Error when getting temporary credentials", err.Error())
+		return nil, err
+	}
+	defaultSession := session.Must(session.NewSession(&aws.Config{
+		Credentials: creds,
+		Region:      aws.String(config.AwsRegion),
+	}))
+	regions, err := usageReports.FetchRegionsList(ctx, defaultSession)
+	if err != nil {
+		logger.Error("Error when fetching regions list", err.Error())
+		return nil, err
+	}
+	reservedNodes := make([]ReservedNodes, 0)
+	for _, region := range regions {
+		sess := session.Must(session.NewSession(&aws.Config{
+			Credentials: creds,
+			Region:      aws.String(region),
+		}))
+		res, err := fetchRegion(ctx, sess)
+		if err != nil {
+			logger.Error("Error when fetching reserved nodes", map[string]interface{}{
+				"region": region,
+				"error":  err.Error(),
+			})
+			return nil, err
+		}
+		reservedNodes = append(reservedNodes, res...)
+	}
+	return reservedNodes, nil
+}
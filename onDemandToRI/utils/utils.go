@@ -0,0 +1,205 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/trackit/trackit/aws/pricings"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/models"
+)
+
+var (
+	HoursPerMonth = 730.0
+
+	// TermsMonths maps the lease contract lengths of the pricings to their number of months
+	TermsMonths = map[string]int{
+		"1yr": 12,
+		"3yr": 36,
+	}
+)
+
+type (
+	Cost struct {
+		PerUnit float64 `json:"perUnit"`
+		Total   float64 `json:"total"`
+	}
+
+	OnDemandCost struct {
+		Monthly    Cost `json:"monthly"`
+		OneYear    Cost `json:"oneYear"`
+		ThreeYears Cost `json:"threeYears"`
+	}
+
+	OnDemandTotalCost struct {
+		MonthlyTotal    float64 `json:"monthly"`
+		OneYearTotal    float64 `json:"oneYear"`
+		ThreeYearsTotal float64 `json:"threeYears"`
+	}
+
+	// PurchaseOption stores the costs of a reservation purchase option for a
+	// group of identical instances or nodes
+	// BreakEvenMonth is the month from which the reservation costs less than
+	// on demand, it is -1 if the reservation never pays off during its term
+	PurchaseOption struct {
+		Term           string `json:"term"`
+		OfferingClass  string `json:"offeringClass"`
+		PurchaseOption string `json:"purchaseOption"`
+		Upfront        Cost   `json:"upfront"`
+		Monthly        Cost   `json:"monthly"`
+		Global         Cost   `json:"global"`
+		Saving         Cost   `json:"saving"`
+		MonthlySaving  Cost   `json:"monthlySaving"`
+		BreakEvenMonth int    `json:"breakEvenMonth"`
+	}
+
+	BestOptionTotalCost struct {
+		UpfrontTotal       float64 `json:"upfront"`
+		MonthlyTotal       float64 `json:"monthly"`
+		MonthlySavingTotal float64 `json:"monthlySaving"`
+	}
+)
+
+// Add adds the costs of a best purchase option to the totals
+func (totals *BestOptionTotalCost) Add(option *PurchaseOption) {
+	if option != nil {
+		totals.UpfrontTotal += option.Upfront.Total
+		totals.MonthlyTotal += option.Monthly.Total
+		totals.MonthlySavingTotal += option.MonthlySaving.Total
+	}
+}
+
+// GetRegionName takes an availability zone or a region name and returns a region name
+func GetRegionName(az string) string {
+	if len(az) == 0 {
+		return az
+	} else if _, err := strconv.Atoi(string(az[len(az)-1])); err == nil {
+		// The "az" finishes by a number, so it's a region name
+		return az
+	}
+	return az[:len(az)-1]
+}
+
+// GetMonthlyCostPerUnit returns the monthly cost based on the hourlyCost
+// it returns 0.0 if the hourlyCost is -1.0 (which means the pricing term does not exist)
+func GetMonthlyCostPerUnit(hourlyCost float64) float64 {
+	if hourlyCost != -1.0 {
+		return hourlyCost * HoursPerMonth
+	}
+	return 0.0
+}
+
+// GetOnDemandCost returns the monthly, one year and three years on demand costs
+// for the monthly cost per unit and the number of instances
+func GetOnDemandCost(odMonthlyPerUnit float64, count int) OnDemandCost {
+	odMonthlyTotal := odMonthlyPerUnit * float64(count)
+	return OnDemandCost{
+		Cost{odMonthlyPerUnit, odMonthlyTotal},
+		Cost{odMonthlyPerUnit * 12.0, odMonthlyTotal * 12.0},
+		Cost{odMonthlyPerUnit * 36.0, odMonthlyTotal * 36.0},
+	}
+}
+
+// Add adds on demand costs to the totals
+func (totals *OnDemandTotalCost) Add(cost OnDemandCost) {
+	totals.MonthlyTotal += cost.Monthly.Total
+	totals.OneYearTotal += cost.OneYear.Total
+	totals.ThreeYearsTotal += cost.ThreeYears.Total
+}
+
+// GetBreakEvenMonth returns the month of the term from which the cumulated cost of a
+// reservation is lower than the cumulated on demand cost
+// It returns -1 if the reservation never pays off during its term
+func GetBreakEvenMonth(odMonthly, upfront, riMonthly float64, termMonths int) int {
+	monthlySaving := odMonthly - riMonthly
+	if monthlySaving <= 0.0 {
+		return -1
+	}
+	breakEvenMonth := int(math.Ceil(upfront / monthlySaving))
+	if breakEvenMonth < 1 {
+		breakEvenMonth = 1
+	}
+	if breakEvenMonth > termMonths {
+		return -1
+	}
+	return breakEvenMonth
+}
+
+// GetPurchaseOptions computes the costs of every reservation purchase option available
+// in the pricing for the on demand monthly cost per unit and the number of instances
+func GetPurchaseOptions(offers []pricings.ReservationOffer, odMonthlyPerUnit float64, count int) []PurchaseOption {
+	options := make([]PurchaseOption, 0, len(offers))
+	total := float64(count)
+	for _, offer := range offers {
+		termMonths, ok := TermsMonths[offer.LeaseContractLength]
+		if ok == false || (offer.UpfrontCost == 0.0 && offer.HourlyCost == 0.0) {
+			continue
+		}
+		months := float64(termMonths)
+		riMonthlyPerUnit := GetMonthlyCostPerUnit(offer.HourlyCost)
+		globalPerUnit := offer.UpfrontCost + riMonthlyPerUnit*months
+		savingPerUnit := odMonthlyPerUnit*months - globalPerUnit
+		options = append(options, PurchaseOption{
+			Term:           offer.LeaseContractLength,
+			OfferingClass:  offer.OfferingClass,
+			PurchaseOption: offer.PurchaseOption,
+			Upfront:        Cost{offer.UpfrontCost, offer.UpfrontCost * total},
+			Monthly:        Cost{riMonthlyPerUnit, riMonthlyPerUnit * total},
+			Global:         Cost{globalPerUnit, globalPerUnit * total},
+			Saving:         Cost{savingPerUnit, savingPerUnit * total},
+			MonthlySaving:  Cost{savingPerUnit / months, savingPerUnit * total / months},
+			BreakEvenMonth: GetBreakEvenMonth(odMonthlyPerUnit, offer.UpfrontCost, riMonthlyPerUnit, termMonths),
+		})
+	}
+	return options
+}
+
+// GetBestPurchaseOption returns the purchase option with the highest saving per month
+// It returns nil if no purchase option saves money
+func GetBestPurchaseOption(options []PurchaseOption) *PurchaseOption {
+	var best *PurchaseOption
+	for i, option := range options {
+		if option.MonthlySaving.PerUnit <= 0.0 {
+			continue
+		}
+		if best == nil || option.MonthlySaving.PerUnit > best.MonthlySaving.PerUnit {
+			best = &options[i]
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	bestCopy := *best
+	return &bestCopy
+}
+
+// GetPricing retrieves the pricings of a product from the database and
+// unmarshals them in pricing
+func GetPricing(ctx context.Context, product string, pricing interface{}) error {
+	tx, err := db.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Commit()
+	pricingDb, err := models.AwsPricingByProduct(tx, product)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(pricingDb.Pricing, pricing)
+}
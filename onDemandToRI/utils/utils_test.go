@@ -0,0 +1,76 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/trackit/trackit/aws/pricings"
+)
+
+func TestGetBreakEvenMonth(t *testing.T) {
+	for _, c := range []struct {
+		odMonthly, upfront, riMonthly float64
+		termMonths                    int
+		expected                      int
+	}{
+		{100, 0, 60, 12, 1},
+		{100, 400, 60, 12, 10},
+		{100, 410, 60, 12, 11},
+		{100, 1000, 0, 12, 10},
+		{100, 1300, 0, 12, -1},
+		{100, 0, 120, 36, -1},
+	} {
+		if res := GetBreakEvenMonth(c.odMonthly, c.upfront, c.riMonthly, c.termMonths); res != c.expected {
+			t.Errorf("Expected break even month %d for %v, got %d.", c.expected, c, res)
+		}
+	}
+}
+
+func TestGetPurchaseOptions(t *testing.T) {
+	offers := []pricings.ReservationOffer{
+		{"1yr", "standard", "No Upfront", 0, 0.06},
+		{"1yr", "standard", "All Upfront", 500, 0},
+		{"3yr", "convertible", "Partial Upfront", 600, 0.02},
+		{"3yr", "standard", "No Upfront", 0, 0},
+		{"5yr", "standard", "No Upfront", 0, 0.01},
+	}
+	odMonthlyPerUnit := 0.1 * HoursPerMonth
+	options := GetPurchaseOptions(offers, odMonthlyPerUnit, 2)
+	if len(options) != 3 {
+		t.Fatalf("Expected 3 purchase options, got %d.", len(options))
+	}
+	allUpfront := options[1]
+	if allUpfront.Upfront.Total != 1000 || allUpfront.Global.PerUnit != 500 || allUpfront.Monthly.PerUnit != 0 {
+		t.Errorf("Unexpected all upfront costs: %v", allUpfront)
+	}
+	if allUpfront.Saving.PerUnit != odMonthlyPerUnit*12-500 || allUpfront.BreakEvenMonth != 7 {
+		t.Errorf("Unexpected all upfront saving: %v", allUpfront)
+	}
+	best := GetBestPurchaseOption(options)
+	if best == nil || best.Term != "3yr" || best.PurchaseOption != "Partial Upfront" {
+		t.Errorf("Expected the 3 years partial upfront option to be the best, got %v.", best)
+	}
+}
+
+func TestGetBestPurchaseOptionWithoutSaving(t *testing.T) {
+	offers := []pricings.ReservationOffer{
+		{"1yr", "standard", "No Upfront", 0, 0.2},
+	}
+	options := GetPurchaseOptions(offers, 0.1*HoursPerMonth, 1)
+	if best := GetBestPurchaseOption(options); best != nil {
+		t.Errorf("Expected no best option, got %v.", best)
+	}
+}
@@ -0,0 +1,241 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToSP
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/aws/usageReports/savingsPlans"
+	terrors "github.com/trackit/trackit/errors"
+	"github.com/trackit/trackit/es"
+)
+
+const maxAggregationSize = 0x7FFFFFFF
+
+type (
+	// OdToSpQueryParams will store the parsed query params
+	OdToSpQueryParams struct {
+		AccountList []string
+		IndexList   []string
+		DateBegin   time.Time
+		DateEnd     time.Time
+	}
+
+	// Structure that allow to parse ES response for on demand to Savings Plans reports
+	ResponseOdToSpReports struct {
+		Accounts struct {
+			Buckets []struct {
+				Reports struct {
+					Hits struct {
+						Hits []struct {
+							Report OdToSpReport `json:"_source"`
+						} `json:"hits"`
+					} `json:"hits"`
+				} `json:"reports"`
+			} `json:"buckets"`
+		} `json:"accounts"`
+	}
+
+	// Structure that allow to parse ES response for the on demand usage
+	ResponseOnDemandUsage struct {
+		Hours struct {
+			Buckets []struct {
+				Cost esValue `json:"cost"`
+			} `json:"buckets"`
+		} `json:"hours"`
+		UsageTypes struct {
+			Buckets []struct {
+				UsageType  string `json:"key"`
+				Operations struct {
+					Buckets []struct {
+						Operation string  `json:"key"`
+						Amount    esValue `json:"amount"`
+						Cost      esValue `json:"cost"`
+					} `json:"buckets"`
+				} `json:"operations"`
+			} `json:"buckets"`
+		} `json:"usageTypes"`
+	}
+
+	esValue struct {
+		Value float64 `json:"value"`
+	}
+)
+
+// makeElasticSearchRequest prepares and run an ES request
+// based on the OdToSpQueryParams and search params
+// It will return the data, an http status code (as int) and an error.
+// Because an error can be generated, but is not critical and is not needed to be known by
+// the user (e.g if the index does not exists because it was not yet indexed ) the error will
+// be returned, but instead of having a 500 status code, it will return the provided status code
+// with empty data
+func makeElasticSearchRequest(ctx context.Context, parsedParams OdToSpQueryParams,
+	esSearchParams func(OdToSpQueryParams, *elastic.Client, string) *elastic.SearchService) (*elastic.SearchResult, int, error) {
+	l := jsonlog.LoggerFromContextOrDefault(ctx)
+	index := strings.Join(parsedParams.IndexList, ",")
+	searchService := esSearchParams(
+		parsedParams,
+		es.Client,
+		index,
+	)
+	res, err := searchService.Do(ctx)
+	if err != nil {
+		if elastic.IsNotFound(err) {
+			l.Warning("Query execution failed, ES index does not exists", map[string]interface{}{
+				"index": index,
+				"error": err.Error(),
+			})
+			return nil, http.StatusOK, terrors.GetErrorMessage(ctx, err)
+		} else if cast, ok := err.(*elastic.Error); ok && cast.Details != nil && cast.Details.Type == "search_phase_execution_exception" {
+			l.Error("Error while getting data from ES", map[string]interface{}{
+				"type":  fmt.Sprintf("%T", err),
+				"error": err,
+			})
+		} else {
+			l.Error("Query execution failed", map[string]interface{}{"error": err.Error()})
+		}
+		return nil, http.StatusInternalServerError, terrors.GetErrorMessage(ctx, err)
+	}
+	return res, http.StatusOK, nil
+}
+
+// createQueryAccountFilterOdToSp creates and returns a new *elastic.TermsQuery on the accountList array
+func createQueryAccountFilterOdToSp(accountList []string) *elastic.TermsQuery {
+	accountListFormatted := make([]interface{}, len(accountList))
+	for i, v := range accountList {
+		accountListFormatted[i] = v
+	}
+	return elastic.NewTermsQuery("account", accountListFormatted...)
+}
+
+// createQueryTimeRange creates and returns a new *elastic.RangeQuery based on the duration
+// defined by durationBegin and durationEnd
+func createQueryTimeRange(durationBegin time.Time, durationEnd time.Time) *elastic.RangeQuery {
+	return elastic.NewRangeQuery("reportDate").
+		From(durationBegin).To(durationEnd)
+}
+
+// getElasticSearchOdToSpParams is used to construct an ElasticSearch *elastic.SearchService used to perform a request on ES
+// It takes as parameters :
+//   - params OdToSpQueryParams : contains the list of accounts and the date
+//   - client *elastic.Client : an instance of *elastic.Client that represent an Elastic Search client.
+//     It needs to be fully configured and ready to execute a client.Search()
+//   - index string : The Elastic Search index on which to execute the query. In this context the default value
+//     should be "od-to-sp-reports"
+//
+// This function excepts arguments passed to it to be sanitize. If they are not, the following cases will make
+// it crash :
+//   - If the client is nil or malconfigured, it will crash
+//   - If the index is not an index present in the ES, it will crash
+func getElasticSearchOdToSpParams(params OdToSpQueryParams, client *elastic.Client, index string) *elastic.SearchService {
+	query := elastic.NewBoolQuery()
+	if len(params.AccountList) > 0 {
+		query = query.Filter(createQueryAccountFilterOdToSp(params.AccountList))
+	}
+	query = query.Filter(createQueryTimeRange(params.DateBegin, params.DateEnd))
+	search := client.Search().Index(index).Size(0).Query(query)
+	search.Aggregation("accounts", elastic.NewTermsAggregation().Field("account").
+		SubAggregation("reports", elastic.NewTopHitsAggregation().Sort("reportDate", false).Size(1)))
+	return search
+}
+
+// prepareResponseOdToSp parses the results from elasticsearch and returns an array of on demand to Savings Plans reports
+func prepareResponseOdToSp(ctx context.Context, res *elastic.SearchResult) ([]OdToSpReport, error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	var response ResponseOdToSpReports
+	reports := make([]OdToSpReport, 0)
+	err := json.Unmarshal(*res.Aggregations["accounts"], &response.Accounts)
+	if err != nil {
+		logger.Error("Error while unmarshaling ES on demand to Savings Plans response", err)
+		return nil, terrors.GetErrorMessage(ctx, err)
+	}
+	for _, account := range response.Accounts.Buckets {
+		for _, report := range account.Reports.Hits.Hits {
+			reports = append(reports, report.Report)
+		}
+	}
+	return reports, nil
+}
+
+// getElasticSearchOnDemandUsageParams is used to construct an ElasticSearch *elastic.SearchService
+// aggregating the on demand usage of an account which could be covered by a Savings Plan, per hour
+// and per usage type and operation. Hours without any spend are part of the result.
+func getElasticSearchOnDemandUsageParams(account string, durationBegin, durationEnd time.Time, client *elastic.Client, index string) *elastic.SearchService {
+	query := elastic.NewBoolQuery()
+	query = query.Filter(elastic.NewTermQuery("usageAccountId", account))
+	query = query.Filter(elastic.NewTermQuery("lineItemType", "Usage"))
+	query = query.Filter(savingsPlans.CreateQueryEligibleUsage())
+	query = query.Filter(elastic.NewRangeQuery("usageStartDate").
+		From(durationBegin).To(durationEnd).IncludeUpper(false))
+	search := client.Search().Index(index).Size(0).Query(query)
+	search.Aggregation("hours", elastic.NewDateHistogramAggregation().Field("usageStartDate").Interval("hour").
+		MinDocCount(0).ExtendedBounds(durationBegin.Unix()*1000, durationEnd.Add(-time.Hour).Unix()*1000).
+		SubAggregation("cost", elastic.NewSumAggregation().Field("unblendedCost")))
+	search.Aggregation("usageTypes", elastic.NewTermsAggregation().Field("usageType").Size(maxAggregationSize).
+		SubAggregation("operations", elastic.NewTermsAggregation().Field("operation").Size(maxAggregationSize).
+			SubAggregation("amount", elastic.NewSumAggregation().Field("usageAmount")).
+			SubAggregation("cost", elastic.NewSumAggregation().Field("unblendedCost"))))
+	return search
+}
+
+// getOnDemandUsage returns the hourly on demand spend of an account which could be covered
+// by a Savings Plan between durationBegin and durationEnd, and this usage per usage type and
+// operation.
+func getOnDemandUsage(ctx context.Context, aa aws.AwsAccount, durationBegin, durationEnd time.Time) ([]float64, []usageCost, error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	index := es.IndexNameForUserId(aa.UserId, es.IndexPrefixLineItems)
+	res, err := getElasticSearchOnDemandUsageParams(aa.AwsIdentity, durationBegin, durationEnd, es.Client, index).Do(ctx)
+	if err != nil {
+		if elastic.IsNotFound(err) {
+			logger.Warning("Query execution failed, ES index does not exists", map[string]interface{}{
+				"index": index,
+				"error": err.Error(),
+			})
+			return []float64{}, []usageCost{}, nil
+		}
+		return nil, nil, err
+	}
+	var response ResponseOnDemandUsage
+	if err = json.Unmarshal(*res.Aggregations["hours"], &response.Hours); err != nil {
+		return nil, nil, err
+	} else if err = json.Unmarshal(*res.Aggregations["usageTypes"], &response.UsageTypes); err != nil {
+		return nil, nil, err
+	}
+	hourlySpend := make([]float64, 0, len(response.Hours.Buckets))
+	for _, hour := range response.Hours.Buckets {
+		hourlySpend = append(hourlySpend, hour.Cost.Value)
+	}
+	usage := make([]usageCost, 0, len(response.UsageTypes.Buckets))
+	for _, usageType := range response.UsageTypes.Buckets {
+		for _, operation := range usageType.Operations.Buckets {
+			usage = append(usage, usageCost{
+				UsageType: usageType.UsageType,
+				Operation: operation.Operation,
+				Amount:    operation.Amount.Value,
+				Cost:      operation.Cost.Value,
+			})
+		}
+	}
+	return hourlySpend, usage, nil
+}
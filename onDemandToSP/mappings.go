@@ -0,0 +1,130 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToSP
+
+import (
+	"context"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/es"
+)
+
+const TypeOdToSpReport = "od-to-sp-report"
+const IndexPrefixOdToSpReport = "od-to-sp-reports"
+const TemplateNameOdToSpReport = "od-to-sp-reports"
+
+// put the ElasticSearch index for *-od-to-sp-reports indices at startup.
+func init() {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	res, err := es.Client.IndexPutTemplate(TemplateNameOdToSpReport).BodyString(TemplateOdToSpReport).Do(ctx)
+	if err != nil {
+		jsonlog.DefaultLogger.Error("Failed to put ES index OdToSpReport.", err)
+	} else {
+		jsonlog.DefaultLogger.Info("Put ES index OdToSpReport.", res)
+		ctxCancel()
+	}
+}
+
+const TemplateOdToSpReport = `
+{
+	"template": "*-od-to-sp-reports",
+	"version": 2,
+	"mappings": {
+		"od-to-sp-report": {
+			"properties": {
+				"account": {
+					"type": "keyword"
+				},
+				"reportDate": {
+					"type": "date"
+				},
+				"begin": {
+					"type": "date"
+				},
+				"end": {
+					"type": "date"
+				},
+				"onDemand": {
+					"properties": {
+						"hourlyAverage": {
+							"type": "double"
+						},
+						"hourlyPeak": {
+							"type": "double"
+						},
+						"monthly": {
+							"type": "double"
+						}
+					}
+				},
+				"recommendations": {
+					"type": "nested",
+					"properties": {
+						"term": {
+							"type": "keyword"
+						},
+						"paymentOption": {
+							"type": "keyword"
+						},
+						"rate": {
+							"type": "double"
+						},
+						"hourlyCommitment": {
+							"type": "double"
+						},
+						"upfront": {
+							"type": "double"
+						},
+						"recurringHourlyCost": {
+							"type": "double"
+						},
+						"coveredHourlySpend": {
+							"type": "double"
+						},
+						"coverage": {
+							"type": "double"
+						},
+						"monthlySavings": {
+							"type": "double"
+						},
+						"termSavings": {
+							"type": "double"
+						},
+						"savingsPercentage": {
+							"type": "double"
+						},
+						"estimatedUtilization": {
+							"type": "double"
+						},
+						"underutilizedHours": {
+							"type": "double"
+						},
+						"risk": {
+							"type": "keyword"
+						}
+					}
+				}
+			},
+			"_all": {
+				"enabled": false
+			},
+			"numeric_detection": false,
+			"date_detection": false
+		}
+	}
+}
+`
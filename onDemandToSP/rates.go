@@ -0,0 +1,170 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToSP
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/trackit/trackit/aws/savingsplans"
+)
+
+const (
+	// savingsPlanType is the type of the recommended Savings Plans, which
+	// apply to EC2, Fargate and Lambda in every region.
+	savingsPlanType = "Compute"
+	// usageTypesPerRequest is the number of usage types whose rates are
+	// requested at once.
+	usageTypesPerRequest = 100
+	// secondsPerYear is the duration of a year in the terms of the Savings Plans.
+	secondsPerYear = 365 * 24 * 60 * 60
+)
+
+// paymentOptions are the payment options of the Savings Plans, with the part
+// of the commitment paid upfront.
+var paymentOptions = []struct {
+	name         string
+	upfrontRatio float64
+}{
+	{"No Upfront", 0.0},
+	{"Partial Upfront", 0.5},
+	{"All Upfront", 1.0},
+}
+
+// usageCost is the on demand usage of a usage type and operation over the
+// lookback period.
+type usageCost struct {
+	UsageType string
+	Operation string
+	Amount    float64
+	Cost      float64
+}
+
+// offerKey identifies a Savings Plan offer by its term and payment option.
+type offerKey struct {
+	durationSeconds int64
+	paymentOption   string
+}
+
+// usageKey identifies the usage a Savings Plan rate applies to.
+type usageKey struct {
+	usageType string
+	operation string
+}
+
+// getRatesClient returns a Savings Plans client using the instance role. The
+// rates are public, so the role of the AWS accounts is not needed.
+func getRatesClient() *savingsplans.Client {
+	sess := session.Must(session.NewSession(&aws.Config{
+		CredentialsChainVerboseErrors: aws.Bool(true),
+	}))
+	return savingsplans.New(sess.Config.Credentials)
+}
+
+// fetchSavingsPlansRates fetches the rates of the Compute Savings Plans for
+// the usage types of usage.
+func fetchSavingsPlansRates(ctx context.Context, usage []usageCost) ([]savingsplans.SavingsPlanOfferingRate, error) {
+	usageTypesSet := make(map[string]bool)
+	for _, cost := range usage {
+		usageTypesSet[cost.UsageType] = true
+	}
+	usageTypes := make([]string, 0, len(usageTypesSet))
+	for usageType := range usageTypesSet {
+		usageTypes = append(usageTypes, usageType)
+	}
+	sort.Strings(usageTypes)
+	client := getRatesClient()
+	var rates []savingsplans.SavingsPlanOfferingRate
+	for begin := 0; begin < len(usageTypes); begin += usageTypesPerRequest {
+		end := begin + usageTypesPerRequest
+		if end > len(usageTypes) {
+			end = len(usageTypes)
+		}
+		requestRates, err := client.DescribeAllSavingsPlansOfferingRates(ctx, savingsplans.DescribeSavingsPlansOfferingRatesInput{
+			SavingsPlanTypes: []string{savingsPlanType},
+			UsageTypes:       usageTypes[begin:end],
+		})
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, requestRates...)
+	}
+	return rates, nil
+}
+
+// getSavingsPlanOffers returns the Savings Plan offers the recommender
+// considers, with their rate for the on demand usage of the account. The rate
+// of an offer is the price paid with the Savings Plan for one dollar of this
+// usage: the usage is priced with the rates of the offer, and the usage no
+// rate applies to is priced on demand. Offers are sorted by term and payment
+// option.
+func getSavingsPlanOffers(rates []savingsplans.SavingsPlanOfferingRate, usage []usageCost) []SavingsPlanOffer {
+	offersRates := make(map[offerKey]map[usageKey]float64)
+	for _, rate := range rates {
+		value, err := strconv.ParseFloat(rate.Rate, 64)
+		if err != nil || rate.SavingsPlanOffering.PlanType != savingsPlanType {
+			continue
+		}
+		key := offerKey{rate.SavingsPlanOffering.DurationSeconds, rate.SavingsPlanOffering.PaymentOption}
+		if offersRates[key] == nil {
+			offersRates[key] = make(map[usageKey]float64)
+		}
+		offersRates[key][usageKey{rate.UsageType, rate.Operation}] = value
+	}
+	var onDemandCost float64
+	for _, cost := range usage {
+		onDemandCost += cost.Cost
+	}
+	offers := make([]SavingsPlanOffer, 0, len(offersRates))
+	if onDemandCost == 0 {
+		return offers
+	}
+	for _, paymentOption := range paymentOptions {
+		for key, offerRates := range offersRates {
+			if key.paymentOption != paymentOption.name {
+				continue
+			}
+			var savingsPlanCost float64
+			for _, cost := range usage {
+				if rate, ok := offerRates[usageKey{cost.UsageType, cost.Operation}]; ok {
+					savingsPlanCost += rate * cost.Amount
+				} else {
+					savingsPlanCost += cost.Cost
+				}
+			}
+			years := int(math.Floor(float64(key.durationSeconds)/secondsPerYear + 0.5))
+			if years == 0 {
+				continue
+			}
+			offers = append(offers, SavingsPlanOffer{
+				Term:          fmt.Sprintf("%dyr", years),
+				PaymentOption: paymentOption.name,
+				Years:         years,
+				Rate:          savingsPlanCost / onDemandCost,
+				UpfrontRatio:  paymentOption.upfrontRatio,
+			})
+		}
+	}
+	sort.SliceStable(offers, func(i, j int) bool {
+		return offers[i].Years < offers[j].Years
+	})
+	return offers
+}
@@ -0,0 +1,144 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToSP
+
+import (
+	"sort"
+)
+
+var (
+	HoursPerMonth = 730.0
+)
+
+// SavingsPlanOffer describes a Savings Plan term and payment option. Rate is
+// the price paid with the Savings Plan for one dollar of on demand usage of
+// the account, computed from the rates of the offer by getSavingsPlanOffers.
+type SavingsPlanOffer struct {
+	Term          string
+	PaymentOption string
+	Years         int
+	Rate          float64
+	UpfrontRatio  float64
+}
+
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+// utilizationRisks maps the minimum estimated utilization of a commitment
+// to its utilization risk.
+var utilizationRisks = []struct {
+	utilization float64
+	risk        string
+}{
+	{95.0, RiskLow},
+	{85.0, RiskMedium},
+}
+
+// getOptimalCoveredSpend returns the hourly on demand spend a Savings Plan
+// with the given rate should cover to maximize the savings over the hourly
+// on demand spend. Covering a spend level costs rate times this level every
+// hour, and saves the on demand spend below this level. The savings are
+// maximal on one of the observed hourly spends, so they are all evaluated.
+func getOptimalCoveredSpend(hourlySpend []float64, rate float64) float64 {
+	sorted := make([]float64, len(hourlySpend))
+	copy(sorted, hourlySpend)
+	sort.Float64s(sorted)
+	hours := float64(len(sorted))
+	bestSpend, bestSavings := 0.0, 0.0
+	belowSpend := 0.0
+	for i, spend := range sorted {
+		savings := belowSpend + spend*(hours-float64(i)) - rate*spend*hours
+		if savings > bestSavings {
+			bestSpend, bestSavings = spend, savings
+		}
+		belowSpend += spend
+	}
+	return bestSpend
+}
+
+// getUtilizationRisk returns the utilization risk of a commitment based on
+// its estimated utilization.
+func getUtilizationRisk(utilization float64) string {
+	for _, risk := range utilizationRisks {
+		if utilization >= risk.utilization {
+			return risk.risk
+		}
+	}
+	return RiskHigh
+}
+
+// recommendSavingsPlan computes the optimal commitment for a Savings Plan offer
+// based on the hourly on demand spend, and the savings it would have made.
+func recommendSavingsPlan(hourlySpend []float64, offer SavingsPlanOffer) SpRecommendation {
+	recommendation := SpRecommendation{
+		Term:          offer.Term,
+		PaymentOption: offer.PaymentOption,
+		Rate:          offer.Rate,
+		Risk:          RiskLow,
+	}
+	if len(hourlySpend) == 0 {
+		return recommendation
+	}
+	coveredSpend := getOptimalCoveredSpend(hourlySpend, offer.Rate)
+	if coveredSpend == 0 {
+		return recommendation
+	}
+	hours := float64(len(hourlySpend))
+	var onDemandSpend, usedSpend, underutilizedHours float64
+	for _, spend := range hourlySpend {
+		onDemandSpend += spend
+		if spend < coveredSpend {
+			usedSpend += spend
+			underutilizedHours++
+		} else {
+			usedSpend += coveredSpend
+		}
+	}
+	savings := usedSpend - offer.Rate*coveredSpend*hours
+	termHours := HoursPerMonth * 12.0 * float64(offer.Years)
+	recommendation.CoveredHourlySpend = coveredSpend
+	recommendation.Coverage = usedSpend / onDemandSpend * 100.0
+	recommendation.HourlyCommitment = offer.Rate * coveredSpend
+	recommendation.Upfront = recommendation.HourlyCommitment * termHours * offer.UpfrontRatio
+	recommendation.RecurringHourlyCost = recommendation.HourlyCommitment * (1.0 - offer.UpfrontRatio)
+	recommendation.MonthlySavings = savings / hours * HoursPerMonth
+	recommendation.TermSavings = recommendation.MonthlySavings * 12.0 * float64(offer.Years)
+	recommendation.SavingsPercentage = savings / onDemandSpend * 100.0
+	recommendation.EstimatedUtilization = usedSpend / (coveredSpend * hours) * 100.0
+	recommendation.UnderutilizedHours = underutilizedHours / hours * 100.0
+	recommendation.Risk = getUtilizationRisk(recommendation.EstimatedUtilization)
+	return recommendation
+}
+
+// getOnDemandSpend summarizes the hourly on demand spend.
+func getOnDemandSpend(hourlySpend []float64) OnDemandSpend {
+	var spend OnDemandSpend
+	if len(hourlySpend) == 0 {
+		return spend
+	}
+	var total float64
+	for _, hourSpend := range hourlySpend {
+		total += hourSpend
+		if hourSpend > spend.HourlyPeak {
+			spend.HourlyPeak = hourSpend
+		}
+	}
+	spend.HourlyAverage = total / float64(len(hourlySpend))
+	spend.Monthly = spend.HourlyAverage * HoursPerMonth
+	return spend
+}
@@ -0,0 +1,163 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToSP
+
+import (
+	"math"
+	"testing"
+
+	"github.com/trackit/trackit/aws/savingsplans"
+)
+
+func floatEquals(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// testUsage is 100 hours of m5.large on Linux and 100 hours of m5.large on
+// Windows, which are not covered by the test rates.
+var testUsage = []usageCost{
+	{"BoxUsage:m5.large", "RunInstances", 100, 9.6},
+	{"BoxUsage:m5.large", "RunInstances:0002", 100, 18.8},
+}
+
+// testRate returns the rate of a Compute Savings Plan for the Linux m5.large.
+func testRate(durationSeconds int64, paymentOption, rate string) savingsplans.SavingsPlanOfferingRate {
+	return savingsplans.SavingsPlanOfferingRate{
+		SavingsPlanOffering: savingsplans.SavingsPlanOffering{
+			PaymentOption:   paymentOption,
+			PlanType:        "Compute",
+			DurationSeconds: durationSeconds,
+		},
+		Rate:      rate,
+		UsageType: "BoxUsage:m5.large",
+		Operation: "RunInstances",
+	}
+}
+
+func TestGetSavingsPlanOffers(t *testing.T) {
+	rates := []savingsplans.SavingsPlanOfferingRate{
+		testRate(94608000, "All Upfront", "0.0288"),
+		testRate(31536000, "Partial Upfront", "0.0624"),
+		testRate(31536000, "No Upfront", "0.0672"),
+		testRate(31536000, "All Upfront", "invalid"),
+	}
+	offers := getSavingsPlanOffers(rates, testUsage)
+	if len(offers) != 3 {
+		t.Fatalf("Expected 3 offers, got %+v.", offers)
+	}
+	if offers[0].Term != "1yr" || offers[0].PaymentOption != "No Upfront" || offers[0].Years != 1 || offers[0].UpfrontRatio != 0 {
+		t.Errorf("Expected the 1 year No Upfront offer first, got %+v.", offers[0])
+	}
+	if !floatEquals(offers[0].Rate, (6.72+18.8)/28.4) {
+		t.Errorf("Expected the usage without rate to be priced on demand, got a rate of %f.", offers[0].Rate)
+	}
+	if offers[1].PaymentOption != "Partial Upfront" || offers[1].UpfrontRatio != 0.5 {
+		t.Errorf("Expected the 1 year Partial Upfront offer second, got %+v.", offers[1])
+	}
+	if offers[2].Term != "3yr" || offers[2].PaymentOption != "All Upfront" || !floatEquals(offers[2].Rate, (2.88+18.8)/28.4) {
+		t.Errorf("Expected the 3 years All Upfront offer last, got %+v.", offers[2])
+	}
+}
+
+func TestGetSavingsPlanOffersWithoutUsage(t *testing.T) {
+	rates := []savingsplans.SavingsPlanOfferingRate{testRate(31536000, "No Upfront", "0.0672")}
+	if offers := getSavingsPlanOffers(rates, nil); len(offers) != 0 {
+		t.Errorf("Expected no offer without usage, got %+v.", offers)
+	}
+}
+
+func TestOptimalCoveredSpendFlat(t *testing.T) {
+	hourlySpend := []float64{10, 10, 10, 10}
+	if spend := getOptimalCoveredSpend(hourlySpend, 0.7); !floatEquals(spend, 10) {
+		t.Errorf("Expected a flat spend to be fully covered, got %f.", spend)
+	}
+}
+
+func TestOptimalCoveredSpendSpiky(t *testing.T) {
+	// The spend is 2 during 9 hours and 20 during one hour. Covering 20 at
+	// a rate of 0.7 would cost 140 to save 38, so only the baseline is covered.
+	hourlySpend := []float64{2, 2, 2, 2, 2, 20, 2, 2, 2, 2}
+	if spend := getOptimalCoveredSpend(hourlySpend, 0.7); !floatEquals(spend, 2) {
+		t.Errorf("Expected the baseline spend to be covered, got %f.", spend)
+	}
+}
+
+func TestOptimalCoveredSpendWithoutDiscount(t *testing.T) {
+	if spend := getOptimalCoveredSpend([]float64{10, 10}, 1); spend != 0 {
+		t.Errorf("Expected no commitment without discount, got %f.", spend)
+	}
+}
+
+func TestRecommendSavingsPlan(t *testing.T) {
+	hourlySpend := []float64{10, 10, 10, 10}
+	offer := SavingsPlanOffer{"1yr", "Partial Upfront", 1, 0.7, 0.5}
+	recommendation := recommendSavingsPlan(hourlySpend, offer)
+	if recommendation.Term != "1yr" || recommendation.PaymentOption != "Partial Upfront" || !floatEquals(recommendation.Rate, 0.7) {
+		t.Errorf("Expected the recommendation to describe its offer, got %+v.", recommendation)
+	}
+	if !floatEquals(recommendation.HourlyCommitment, 7) || !floatEquals(recommendation.CoveredHourlySpend, 10) {
+		t.Errorf("Expected an hourly commitment of 7 covering 10, got %f covering %f.", recommendation.HourlyCommitment, recommendation.CoveredHourlySpend)
+	}
+	if !floatEquals(recommendation.Upfront, 7*8760*0.5) || !floatEquals(recommendation.RecurringHourlyCost, 3.5) {
+		t.Errorf("Expected half of the commitment upfront, got %f upfront and %f hourly.", recommendation.Upfront, recommendation.RecurringHourlyCost)
+	}
+	if !floatEquals(recommendation.MonthlySavings, 3*HoursPerMonth) || !floatEquals(recommendation.TermSavings, 3*8760) {
+		t.Errorf("Expected savings of 3 per hour, got %f per month and %f per term.", recommendation.MonthlySavings, recommendation.TermSavings)
+	}
+	if !floatEquals(recommendation.SavingsPercentage, 30) || !floatEquals(recommendation.Coverage, 100) {
+		t.Errorf("Expected 30%% of savings and a full coverage, got %f and %f.", recommendation.SavingsPercentage, recommendation.Coverage)
+	}
+	if !floatEquals(recommendation.EstimatedUtilization, 100) || recommendation.Risk != RiskLow {
+		t.Errorf("Expected a full utilization with a low risk, got %f and %s.", recommendation.EstimatedUtilization, recommendation.Risk)
+	}
+}
+
+func TestRecommendSavingsPlanRisk(t *testing.T) {
+	// Covering 10 at a rate of 0.5 saves 16 while covering 6 saves 12, so
+	// the commitment is used at 90% and is underutilized one hour out of 4.
+	hourlySpend := []float64{10, 10, 10, 6}
+	recommendation := recommendSavingsPlan(hourlySpend, SavingsPlanOffer{"3yr", "No Upfront", 3, 0.5, 0})
+	if !floatEquals(recommendation.CoveredHourlySpend, 10) || !floatEquals(recommendation.MonthlySavings, 4*HoursPerMonth) {
+		t.Errorf("Expected to cover 10 saving 4 per hour, got %f saving %f per month.", recommendation.CoveredHourlySpend, recommendation.MonthlySavings)
+	}
+	if !floatEquals(recommendation.EstimatedUtilization, 90) || !floatEquals(recommendation.UnderutilizedHours, 25) {
+		t.Errorf("Expected a utilization of 90%% with 25%% of underutilized hours, got %f and %f.", recommendation.EstimatedUtilization, recommendation.UnderutilizedHours)
+	}
+	if recommendation.Risk != RiskMedium || recommendation.Upfront != 0 {
+		t.Errorf("Expected a medium risk without upfront, got %s and %f.", recommendation.Risk, recommendation.Upfront)
+	}
+}
+
+func TestUtilizationRisk(t *testing.T) {
+	for utilization, risk := range map[float64]string{100: RiskLow, 95: RiskLow, 90: RiskMedium, 50: RiskHigh} {
+		if getUtilizationRisk(utilization) != risk {
+			t.Errorf("Expected a %s risk for a utilization of %f, got %s.", risk, utilization, getUtilizationRisk(utilization))
+		}
+	}
+}
+
+func TestComputeOdToSpReport(t *testing.T) {
+	offers := []SavingsPlanOffer{
+		{"1yr", "No Upfront", 1, 0.7, 0},
+		{"3yr", "All Upfront", 3, 0.5, 1},
+	}
+	report := computeOdToSpReport([]float64{10, 10}, offers, OdToSpReport{})
+	if len(report.Recommendations) != 2 || report.Recommendations[1].Term != "3yr" || !floatEquals(report.Recommendations[1].HourlyCommitment, 5) {
+		t.Errorf("Expected one recommendation per offer, got %+v.", report.Recommendations)
+	}
+	if !floatEquals(report.OnDemand.HourlyAverage, 10) {
+		t.Errorf("Expected an hourly average of 10, got %f.", report.OnDemand.HourlyAverage)
+	}
+}
@@ -0,0 +1,81 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToSP
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/trackit/trackit/cache"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/routes"
+	"github.com/trackit/trackit/users"
+)
+
+var (
+	// recommendationsQueryArgs allows to get required queryArgs params
+	recommendationsQueryArgs = []routes.QueryArg{
+		routes.AwsAccountsOptionalQueryArg,
+		routes.DateQueryArg,
+	}
+)
+
+func init() {
+	routes.MethodMuxer{
+		http.MethodGet: routes.H(getRecommendations).With(
+			db.RequestTransaction{Db: db.Db},
+			users.RequireAuthenticatedUser{users.ViewerAsParent},
+			routes.QueryArgs(recommendationsQueryArgs),
+			cache.UsersCache{},
+			routes.Documentation{
+				Summary:     "get the Savings Plans recommendations",
+				Description: "Responds with the on demand compute spend of the accounts and the Savings Plan commitment to buy for each term and payment option with its projected savings, based on the queryparams passed to it",
+			},
+		),
+	}.H().Register("/sp/recommendations")
+}
+
+// getRecommendations returns the latest on demand to Savings Plans report of each
+// account of the month of the date passed in the query params.
+func getRecommendations(request *http.Request, a routes.Arguments) (int, interface{}) {
+	user := a[users.AuthenticatedUser].(users.User)
+	tx := a[db.Transaction].(*sql.Tx)
+	parsedParams := OdToSpQueryParams{
+		AccountList: []string{},
+	}
+	parsedParams.DateBegin, parsedParams.DateEnd = getDateForReport(a[routes.DateQueryArg].(time.Time))
+	if a[recommendationsQueryArgs[0]] != nil {
+		parsedParams.AccountList = a[recommendationsQueryArgs[0]].([]string)
+	}
+	returnCode, reports, err := GetOdToSpReport(request.Context(), parsedParams, user, tx)
+	if err != nil {
+		return returnCode, err
+	}
+	return returnCode, reports
+}
+
+// getDateForReport returns the beginning and the end of the month of date, the
+// end being now for the current month.
+func getDateForReport(date time.Time) (begin, end time.Time) {
+	now := time.Now().UTC()
+	begin = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if date.Year() == now.Year() && date.Month() == now.Month() {
+		end = now
+	} else {
+		end = time.Date(date.Year(), date.Month()+1, 0, 23, 59, 59, 999999999, time.UTC)
+	}
+	return
+}
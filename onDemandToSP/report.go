@@ -0,0 +1,137 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToSP
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/es"
+	"github.com/trackit/trackit/users"
+)
+
+// lookbackDays is the number of days of on demand spend the recommendations are based on
+const lookbackDays = 30
+
+type (
+	// OnDemandSpend summarizes the on demand spend which could be covered by a Savings Plan
+	OnDemandSpend struct {
+		HourlyAverage float64 `json:"hourlyAverage"`
+		HourlyPeak    float64 `json:"hourlyPeak"`
+		Monthly       float64 `json:"monthly"`
+	}
+
+	// SpRecommendation stores the recommended commitment for a Savings Plan term and
+	// payment option, and the savings it would have made over the lookback period
+	SpRecommendation struct {
+		Term                 string  `json:"term"`
+		PaymentOption        string  `json:"paymentOption"`
+		Rate                 float64 `json:"rate"`
+		HourlyCommitment     float64 `json:"hourlyCommitment"`
+		Upfront              float64 `json:"upfront"`
+		RecurringHourlyCost  float64 `json:"recurringHourlyCost"`
+		CoveredHourlySpend   float64 `json:"coveredHourlySpend"`
+		Coverage             float64 `json:"coverage"`
+		MonthlySavings       float64 `json:"monthlySavings"`
+		TermSavings          float64 `json:"termSavings"`
+		SavingsPercentage    float64 `json:"savingsPercentage"`
+		EstimatedUtilization float64 `json:"estimatedUtilization"`
+		UnderutilizedHours   float64 `json:"underutilizedHours"`
+		Risk                 string  `json:"risk"`
+	}
+
+	// OdToSpReport stores all the on demand to Savings Plans report infos
+	OdToSpReport struct {
+		Account         string             `json:"account"`
+		ReportDate      time.Time          `json:"reportDate"`
+		Begin           time.Time          `json:"begin"`
+		End             time.Time          `json:"end"`
+		OnDemand        OnDemandSpend      `json:"onDemand"`
+		Recommendations []SpRecommendation `json:"recommendations"`
+	}
+)
+
+// getLookbackPeriod returns the period the recommendations are based on. The
+// last day is left out since its billing data are usually incomplete.
+func getLookbackPeriod(now time.Time) (begin, end time.Time) {
+	end = time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.UTC)
+	begin = end.AddDate(0, 0, -lookbackDays)
+	return
+}
+
+// computeOdToSpReport fills a report with the recommendations for all the Savings Plan offers
+func computeOdToSpReport(hourlySpend []float64, offers []SavingsPlanOffer, report OdToSpReport) OdToSpReport {
+	report.OnDemand = getOnDemandSpend(hourlySpend)
+	report.Recommendations = make([]SpRecommendation, 0, len(offers))
+	for _, offer := range offers {
+		report.Recommendations = append(report.Recommendations, recommendSavingsPlan(hourlySpend, offer))
+	}
+	return report
+}
+
+// RunOnDemandToSp generates a report recommending the Savings Plans commitment
+// to buy, based on the hourly on demand compute spend of the account
+// The result is saved into ES
+func RunOnDemandToSp(ctx context.Context, aa aws.AwsAccount) error {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	now := time.Now().UTC()
+	begin, end := getLookbackPeriod(now)
+	report := OdToSpReport{
+		Account:    aa.AwsIdentity,
+		ReportDate: now,
+		Begin:      begin,
+		End:        end,
+	}
+	logger.Info("Generating on demand to Savings Plans report", map[string]interface{}{"awsAccountId": aa.Id})
+	hourlySpend, usage, err := getOnDemandUsage(ctx, aa, begin, end)
+	if err != nil {
+		logger.Error("Unable to retrieve on demand usage", err.Error())
+		return err
+	}
+	rates, err := fetchSavingsPlansRates(ctx, usage)
+	if err != nil {
+		logger.Error("Unable to retrieve Savings Plans rates", err.Error())
+		return err
+	}
+	report = computeOdToSpReport(hourlySpend, getSavingsPlanOffers(rates, usage), report)
+	return IngestOdToSpResult(ctx, aa, report)
+}
+
+// GetOdToSpReport gets on demand to Savings Plans reports based on query params
+func GetOdToSpReport(ctx context.Context, parsedParams OdToSpQueryParams, user users.User, tx *sql.Tx) (int, []OdToSpReport, error) {
+	accountsAndIndexes, returnCode, err := es.GetAccountsAndIndexes(parsedParams.AccountList, user, tx, IndexPrefixOdToSpReport)
+	if err != nil {
+		return returnCode, nil, err
+	}
+	parsedParams.AccountList = accountsAndIndexes.Accounts
+	parsedParams.IndexList = accountsAndIndexes.Indexes
+	res, returnCode, err := makeElasticSearchRequest(ctx, parsedParams, getElasticSearchOdToSpParams)
+	if err != nil {
+		return returnCode, nil, err
+	} else if res == nil {
+		return http.StatusInternalServerError, nil, errors.New("Error while getting data. Please check again in few hours.")
+	}
+	reports, err := prepareResponseOdToSp(ctx, res)
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+	return http.StatusOK, reports, nil
+}
@@ -0,0 +1,64 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package onDemandToSP
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/es"
+)
+
+// IngestOdToSpResult saves a OdToSpReport into elasticsearch
+func IngestOdToSpResult(ctx context.Context, aa aws.AwsAccount, report OdToSpReport) error {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	logger.Info("Saving od to sp result for AWS account.", map[string]interface{}{
+		"awsAccount": aa,
+	})
+	client := es.Client
+	ji, err := json.Marshal(struct {
+		Account    string    `json:"account"`
+		ReportDate time.Time `json:"reportDate"`
+	}{
+		report.Account,
+		report.ReportDate,
+	})
+	if err != nil {
+		logger.Error("Error when marshaling report var", err.Error())
+		return err
+	}
+	hash := md5.Sum(ji)
+	hash64 := base64.URLEncoding.EncodeToString(hash[:])
+	index := es.IndexNameForUserId(aa.UserId, IndexPrefixOdToSpReport)
+	if res, err := client.
+		Index().
+		Index(index).
+		Type(TypeOdToSpReport).
+		BodyJson(report).
+		Id(hash64).
+		Do(context.Background()); err != nil {
+		logger.Error("Error when putting od to sp result in ES", err.Error())
+		return err
+	} else {
+		logger.Info("od to sp result put in ES", *res)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/360EntSecGroup-Skylar/excelize"
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/aws/usageReports/history"
+	onDemandToRiElastiCache "github.com/trackit/trackit/onDemandToRI/elasticache"
+	onDemandToRiEs "github.com/trackit/trackit/onDemandToRI/es"
+	onDemandToRiRds "github.com/trackit/trackit/onDemandToRI/rds"
+	"github.com/trackit/trackit/onDemandToRI/utils"
+	"github.com/trackit/trackit/users"
+)
+
+// getNodesReports retrieves on demand to reserved nodes reports
+type getNodesReports func(context.Context, utils.NodesQueryParams, users.User, *sql.Tx) (int, []utils.NodesReport, error)
+
+const odToRiRdsReportSheetName = "On Demand to RI RDS Report"
+const odToRiElastiCacheReportSheetName = "On Demand to RI ElastiCache"
+const odToRiEsReportSheetName = "On Demand to RI ES Report"
+
+var odToRiRdsReportModule = module{
+	Name:          "On Demand to Reserved Instances RDS Report",
+	SheetName:     odToRiRdsReportSheetName,
+	ErrorName:     "odToRiRdsReportError",
+	GenerateSheet: generateOdToRiNodesReportSheet(odToRiRdsReportSheetName, onDemandToRiRds.GetRiRdsReport),
+}
+
+var odToRiElastiCacheReportModule = module{
+	Name:          "On Demand to Reserved Nodes ElastiCache Report",
+	SheetName:     odToRiElastiCacheReportSheetName,
+	ErrorName:     "odToRiElastiCacheReportError",
+	GenerateSheet: generateOdToRiNodesReportSheet(odToRiElastiCacheReportSheetName, onDemandToRiElastiCache.GetRiElastiCacheReport),
+}
+
+var odToRiEsReportModule = module{
+	Name:          "On Demand to Reserved Instances ES Report",
+	SheetName:     odToRiEsReportSheetName,
+	ErrorName:     "odToRiEsReportError",
+	GenerateSheet: generateOdToRiNodesReportSheet(odToRiEsReportSheetName, onDemandToRiEs.GetRiEsReport),
+}
+
+// generateOdToRiNodesReportSheet returns a function generating a sheet with the unreserved
+// nodes of a service and the best reservation purchase option for each of them
+// It will get data for given AWS account and for the month of a given date
+func generateOdToRiNodesReportSheet(sheetName string, getReports getNodesReports) func(context.Context, []aws.AwsAccount, time.Time, *sql.Tx, *excelize.File) error {
+	return func(ctx context.Context, aas []aws.AwsAccount, date time.Time, tx *sql.Tx, file *excelize.File) (err error) {
+		if date.IsZero() {
+			date, _ = history.GetHistoryDate()
+		}
+		data, err := odToRiNodesReportGetData(ctx, aas, date, tx, sheetName, getReports)
+		if err == nil {
+			return odToRiNodesReportInsertDataInSheet(aas, file, sheetName, data)
+		}
+		return
+	}
+}
+
+func odToRiNodesReportGetData(ctx context.Context, aas []aws.AwsAccount, date time.Time, tx *sql.Tx, sheetName string, getReports getNodesReports) (reports []utils.NodesReport, err error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	identities := getAwsIdentities(aas)
+	user, err := users.GetUserWithId(tx, aas[0].UserId)
+	if err != nil {
+		return
+	}
+	parameters := utils.NodesQueryParams{
+		AccountList: identities,
+		DateBegin:   time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC),
+		DateEnd:     time.Date(date.Year(), date.Month()+1, 0, 23, 59, 59, 999999999, time.UTC),
+	}
+	logger.Debug("Getting "+sheetName+" for accounts", map[string]interface{}{
+		"accounts": aas,
+		"date":     date,
+	})
+	_, reports, err = getReports(ctx, parameters, user, tx)
+	if err != nil {
+		logger.Error("An error occurred while generating a "+sheetName, map[string]interface{}{
+			"error":    err,
+			"accounts": aas,
+			"date":     date,
+		})
+	}
+	return
+}
+
+func odToRiNodesReportInsertDataInSheet(aas []aws.AwsAccount, file *excelize.File, sheetName string, data []utils.NodesReport) (err error) {
+	file.NewSheet(sheetName)
+	odToRiNodesReportGenerateHeader(file, sheetName)
+	line := 4
+	for _, report := range data {
+		account := getAwsAccount(report.Account, aas)
+		formattedAccount := report.Account
+		if account != nil {
+			formattedAccount = formatAwsAccount(*account)
+		}
+		for _, node := range report.Nodes {
+			cells := cells{
+				newCell(formattedAccount, "A"+strconv.Itoa(line)),
+				newCell(node.Region, "B"+strconv.Itoa(line)),
+				newCell(node.Type, "C"+strconv.Itoa(line)),
+				newCell(node.Engine, "D"+strconv.Itoa(line)),
+				newCell(node.NodeCount, "E"+strconv.Itoa(line)),
+				newCell(node.OnDemand.Monthly.Total, "F"+strconv.Itoa(line)).addStyles("price"),
+				newCell(node.OnDemand.OneYear.Total, "G"+strconv.Itoa(line)).addStyles("price"),
+			}
+			if best := node.Reservation.BestOption; best != nil {
+				cells = append(cells,
+					newCell(best.Term, "H"+strconv.Itoa(line)),
+					newCell(best.OfferingClass, "I"+strconv.Itoa(line)),
+					newCell(best.PurchaseOption, "J"+strconv.Itoa(line)),
+					newCell(best.Upfront.Total, "K"+strconv.Itoa(line)).addStyles("price"),
+					newCell(best.Monthly.Total, "L"+strconv.Itoa(line)).addStyles("price"),
+					newCell(best.MonthlySaving.Total, "M"+strconv.Itoa(line)).addStyles("price"),
+					newCell(best.BreakEvenMonth, "N"+strconv.Itoa(line)),
+				)
+			} else {
+				cells = append(cells, newCell("No saving", "H"+strconv.Itoa(line)).mergeTo("N"+strconv.Itoa(line)))
+			}
+			cells.addStyles("borders", "centerText").setValues(file, sheetName)
+			line++
+		}
+	}
+	return
+}
+
+func odToRiNodesReportGenerateHeader(file *excelize.File, sheetName string) {
+	header := cells{
+		newCell("Account", "A1").mergeTo("A3"),
+		newCell("Nodes", "B1").mergeTo("E1"),
+		newCell("Region", "B2").mergeTo("B3"),
+		newCell("Type", "C2").mergeTo("C3"),
+		newCell("Engine", "D2").mergeTo("D3"),
+		newCell("Count", "E2").mergeTo("E3"),
+		newCell("On Demand", "F1").mergeTo("G2"),
+		newCell("Monthly", "F3"),
+		newCell("One Year", "G3"),
+		newCell("Best Reservation Option", "H1").mergeTo("N1"),
+		newCell("Term", "H2").mergeTo("H3"),
+		newCell("Offering Class", "I2").mergeTo("I3"),
+		newCell("Purchase Option", "J2").mergeTo("J3"),
+		newCell("Cost", "K2").mergeTo("L2"),
+		newCell("Upfront", "K3"),
+		newCell("Monthly", "L3"),
+		newCell("Monthly Saving", "M2").mergeTo("M3"),
+		newCell("Break-even Month", "N2").mergeTo("N3"),
+	}
+	header.addStyles("borders", "bold", "centerText").setValues(file, sheetName)
+	columns := columnsWidth{
+		newColumnWidth("A", 30),
+		newColumnWidth("B", 15),
+		newColumnWidth("C", 25),
+		newColumnWidth("D", 30),
+		newColumnWidth("E", 7.5),
+		newColumnWidth("F", 12.5).toColumn("G"),
+		newColumnWidth("H", 7.5),
+		newColumnWidth("I", 15),
+		newColumnWidth("J", 17.5),
+		newColumnWidth("K", 12.5).toColumn("M"),
+		newColumnWidth("N", 17.5),
+	}
+	columns.setValues(file, sheetName)
+	return
+}
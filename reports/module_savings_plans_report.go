@@ -0,0 +1,167 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/360EntSecGroup-Skylar/excelize"
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/aws/usageReports/history"
+	awsSavingsPlans "github.com/trackit/trackit/aws/usageReports/savingsPlans"
+	"github.com/trackit/trackit/usageReports/savingsPlans"
+	"github.com/trackit/trackit/users"
+)
+
+const savingsPlansReportSheetName = "Savings Plans Report"
+
+var savingsPlansReportModule = module{
+	Name:          "Savings Plans Report",
+	SheetName:     savingsPlansReportSheetName,
+	ErrorName:     "savingsPlansReportError",
+	GenerateSheet: generateSavingsPlansReportSheet,
+}
+
+// generateSavingsPlansReportSheet will generate a sheet with the Savings Plans utilization and coverage
+// It will get data for given AWS account and for a given date
+func generateSavingsPlansReportSheet(ctx context.Context, aas []aws.AwsAccount, date time.Time, tx *sql.Tx, file *excelize.File) (err error) {
+	if date.IsZero() {
+		date, _ = history.GetHistoryDate()
+	}
+	return savingsPlansReportGenerateSheet(ctx, aas, date, tx, file)
+}
+
+func savingsPlansReportGenerateSheet(ctx context.Context, aas []aws.AwsAccount, date time.Time, tx *sql.Tx, file *excelize.File) (err error) {
+	data, err := savingsPlansReportGetData(ctx, aas, date, tx)
+	if err == nil {
+		return savingsPlansReportInsertDataInSheet(aas, file, data)
+	}
+	return
+}
+
+func savingsPlansReportGetData(ctx context.Context, aas []aws.AwsAccount, date time.Time, tx *sql.Tx) (reports []awsSavingsPlans.SavingsPlansReport, err error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	identities := getAwsIdentities(aas)
+	user, err := users.GetUserWithId(tx, aas[0].UserId)
+	if err != nil {
+		return
+	}
+	parameters := savingsPlans.SavingsPlansQueryParams{
+		AccountList: identities,
+		Date:        date,
+	}
+	logger.Debug("Getting Savings Plans Report for accounts", map[string]interface{}{
+		"accounts": aas,
+		"date":     date,
+	})
+	_, reports, err = savingsPlans.GetSavingsPlansData(ctx, parameters, user, tx)
+	if err != nil {
+		logger.Error("An error occurred while generating a Savings Plans Report", map[string]interface{}{
+			"error":    err,
+			"accounts": aas,
+			"date":     date,
+		})
+	}
+	return
+}
+
+func savingsPlansReportInsertDataInSheet(aas []aws.AwsAccount, file *excelize.File, data []awsSavingsPlans.SavingsPlansReport) (err error) {
+	file.NewSheet(savingsPlansReportSheetName)
+	savingsPlansReportGenerateHeader(file)
+	line := 4
+	for _, report := range data {
+		account := getAwsAccount(report.Account, aas)
+		formattedAccount := report.Account
+		if account != nil {
+			formattedAccount = formatAwsAccount(*account)
+		}
+		toLine := line
+		for _, savingsPlan := range report.SavingsPlans {
+			planCells := cells{
+				newCell(savingsPlan.Arn, "B"+strconv.Itoa(toLine)),
+				newCell(savingsPlan.OfferingType, "C"+strconv.Itoa(toLine)),
+				newCell(savingsPlan.PurchaseTerm, "D"+strconv.Itoa(toLine)),
+				newCell(savingsPlan.PaymentOption, "E"+strconv.Itoa(toLine)),
+				newCell(savingsPlan.Region, "F"+strconv.Itoa(toLine)),
+				newCell(savingsPlan.Start.Format("2006-01-02T15:04:05"), "G"+strconv.Itoa(toLine)),
+				newCell(savingsPlan.End.Format("2006-01-02T15:04:05"), "H"+strconv.Itoa(toLine)),
+				newCell(savingsPlan.HourlyCommitment, "I"+strconv.Itoa(toLine)).addStyles("price"),
+				newCell(savingsPlan.TotalCommitment, "J"+strconv.Itoa(toLine)).addStyles("price"),
+				newCell(savingsPlan.UsedCommitment, "K"+strconv.Itoa(toLine)).addStyles("price"),
+				newCell(savingsPlan.UnusedCommitment, "L"+strconv.Itoa(toLine)).addStyles("price"),
+				newCell(formatMetricPercentage(savingsPlan.Utilization), "M"+strconv.Itoa(toLine)).addStyles("percentage"),
+			}
+			planCells.addStyles("borders", "centerText").setValues(file, savingsPlansReportSheetName)
+			toLine++
+		}
+		if toLine > line {
+			toLine--
+		}
+		coverage := report.Coverage
+		cells := cells{
+			newCell(formattedAccount, "A"+strconv.Itoa(line)).mergeTo("A" + strconv.Itoa(toLine)),
+			newCell(coverage.CoveredCost, "N"+strconv.Itoa(line)).mergeTo("N" + strconv.Itoa(toLine)).addStyles("price"),
+			newCell(coverage.OnDemandCost, "O"+strconv.Itoa(line)).mergeTo("O" + strconv.Itoa(toLine)).addStyles("price"),
+			newCell(formatMetricPercentage(coverage.Coverage), "P"+strconv.Itoa(line)).mergeTo("P" + strconv.Itoa(toLine)).addStyles("percentage"),
+		}
+		cells.addStyles("borders", "centerText").setValues(file, savingsPlansReportSheetName)
+		line = toLine + 1
+	}
+	return
+}
+
+func savingsPlansReportGenerateHeader(file *excelize.File) {
+	header := cells{
+		newCell("Account", "A1").mergeTo("A3"),
+		newCell("Savings Plans", "B1").mergeTo("M1"),
+		newCell("ARN", "B2").mergeTo("B3"),
+		newCell("Offering Type", "C2").mergeTo("C3"),
+		newCell("Term", "D2").mergeTo("D3"),
+		newCell("Payment Option", "E2").mergeTo("E3"),
+		newCell("Region", "F2").mergeTo("F3"),
+		newCell("Dates", "G2").mergeTo("H2"),
+		newCell("Start", "G3"),
+		newCell("End", "H3"),
+		newCell("Commitment", "I2").mergeTo("M2"),
+		newCell("Hourly", "I3"),
+		newCell("Total", "J3"),
+		newCell("Used", "K3"),
+		newCell("Unused", "L3"),
+		newCell("Utilization", "M3"),
+		newCell("Coverage", "N1").mergeTo("P2"),
+		newCell("Covered Cost", "N3"),
+		newCell("On Demand Cost", "O3"),
+		newCell("Coverage", "P3"),
+	}
+	header.addStyles("borders", "bold", "centerText").setValues(file, savingsPlansReportSheetName)
+	columns := columnsWidth{
+		newColumnWidth("A", 30),
+		newColumnWidth("B", 70),
+		newColumnWidth("C", 25),
+		newColumnWidth("D", 7.5),
+		newColumnWidth("E", 17.5),
+		newColumnWidth("F", 15),
+		newColumnWidth("G", 25).toColumn("H"),
+		newColumnWidth("I", 12.5).toColumn("M"),
+		newColumnWidth("N", 17.5).toColumn("P"),
+	}
+	columns.setValues(file, savingsPlansReportSheetName)
+	return
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/trackit/jsonlog"
 
 	"github.com/trackit/trackit/aws"
+	"github.com/trackit/trackit/config"
 	"github.com/trackit/trackit/db"
 )
 
@@ -31,6 +32,7 @@ import (
 // Note: First sheet is removed since it is unused (Created by excelize)
 // Report is then uploaded to an S3 bucket
 // Note: File can be saved locally by using `saveSpreadsheetLocally` instead of `saveSpreadsheet`
+// Report is also mailed to the owner of the AWS account if config.ReportsEmail is set
 func GenerateReport(ctx context.Context, aa aws.AwsAccount, aas []aws.AwsAccount, date time.Time) (errs map[string]error) {
 	logger := jsonlog.LoggerFromContextOrDefault(ctx)
 	now := time.Now()
@@ -71,6 +73,9 @@ func GenerateReport(ctx context.Context, aa aws.AwsAccount, aas []aws.AwsAccount
 		}
 		file.File.DeleteSheet(file.File.GetSheetName(1))
 		errs["speadsheetError"] = saveSpreadsheet(ctx, file, reportType)
+		if config.ReportsEmail {
+			errs["mailError"] = mailSpreadsheet(ctx, file, reportType)
+		}
 	} else {
 		errs["speadsheetError"] = err
 	}
@@ -29,8 +29,13 @@ import (
 	taws "github.com/trackit/trackit/aws"
 	"github.com/trackit/trackit/awsSession"
 	"github.com/trackit/trackit/config"
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/mail"
+	"github.com/trackit/trackit/models"
 )
 
+const spreadsheetContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
 type spreadsheet struct {
 	account taws.AwsAccount
 	date    string
@@ -116,3 +121,49 @@ func saveSpreadsheet(ctx context.Context, file spreadsheet, reportType spreadshe
 	}
 	return
 }
+
+// mailSpreadsheet sends the spreadsheet by mail to the owner of its AWS account
+func mailSpreadsheet(ctx context.Context, file spreadsheet, reportType spreadsheetType) (err error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+
+	filename := getFilename(file.account, file.date, reportType)
+	user, err := models.UserByID(db.Db, file.account.UserId)
+	if err != nil {
+		logger.Error("Failed to get report owner", map[string]interface{}{
+			"report": filename,
+			"error":  err.Error(),
+		})
+		return
+	}
+	content, err := file.File.WriteToBuffer()
+	if err != nil {
+		logger.Error("Error while writing report", map[string]interface{}{
+			"report": filename,
+			"error":  err.Error(),
+		})
+		return
+	}
+	message, err := mail.MonthlyReportTemplate.Render(mail.ReportData{
+		AwsAccount: file.account.Pretty,
+		Month:      file.date,
+	})
+	if err != nil {
+		return
+	}
+	message.To = []string{user.Email}
+	message.Attachments = []mail.Attachment{{
+		Filename:    filename,
+		ContentType: spreadsheetContentType,
+		Content:     content.Bytes(),
+	}}
+	err = mail.SendMessage(message, ctx)
+	if err != nil {
+		logger.Error("Failed to mail report", map[string]interface{}{
+			"report": filename,
+			"error":  err.Error(),
+		})
+	} else {
+		logger.Info("Spreadsheet successfully mailed", filename)
+	}
+	return
+}
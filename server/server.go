@@ -32,7 +32,9 @@ import (
 	_ "github.com/trackit/trackit/costs"
 	_ "github.com/trackit/trackit/costs/anomalies"
 	_ "github.com/trackit/trackit/costs/diff"
+	_ "github.com/trackit/trackit/costs/forecast"
 	_ "github.com/trackit/trackit/costs/tags"
+	_ "github.com/trackit/trackit/notifications"
 	"github.com/trackit/trackit/periodic"
 	_ "github.com/trackit/trackit/plugins"
 	_ "github.com/trackit/trackit/reports"
@@ -46,6 +48,7 @@ import (
 	_ "github.com/trackit/trackit/usageReports/rds"
 	_ "github.com/trackit/trackit/usageReports/riEc2"
 	_ "github.com/trackit/trackit/usageReports/riRds"
+	_ "github.com/trackit/trackit/usageReports/savingsPlans"
 	_ "github.com/trackit/trackit/users"
 	_ "github.com/trackit/trackit/users/shared_account"
 )
@@ -72,6 +75,8 @@ var tasks = map[string]func(context.Context) error{
 	"check-cost":                  taskCheckCost,
 	"fetch-pricings":              taskFetchPricings,
 	"ingest-limit":                taskIngestLimit,
+	"check-budgets":               taskCheckBudgets,
+	"send-digests":                taskSendDigests,
 }
 
 // dockerHostnameRe matches the value of the HOSTNAME environment variable when
@@ -102,6 +107,8 @@ var sched periodic.Scheduler
 
 func schedulePeriodicTasks() {
 	sched.Register(taskIngestDue, 10*time.Minute, "ingest-due-updates")
+	sched.Register(taskCheckBudgets, time.Hour, "check-budgets")
+	sched.Register(taskSendDigests, time.Hour, "send-digests")
 	sched.Start()
 }
 
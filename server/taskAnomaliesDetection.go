@@ -67,8 +67,9 @@ func processAnomaliesForAccount(ctx context.Context, aaId int) (err error) {
 	if tx, err = db.Db.BeginTx(ctx, nil); err != nil {
 	} else if dbaa, err = models.AwsAccountByID(tx, aaId); err != nil {
 	} else if aa = aws.AwsAccountFromDbAwsAccount(*dbaa); err != nil {
-	} else if lastUpdate, err = anomalies.RunAnomaliesDetection(aa, dbaa.LastAnomaliesUpdate, ctx); err == nil {
-		err = registerAnomaliesUpdate(tx, lastUpdate, aa.Id)
+	} else if lastUpdate, err = anomalies.RunAnomaliesDetection(tx, aa, dbaa.LastAnomaliesUpdate, ctx); err != nil {
+	} else if err = registerAnomaliesUpdate(tx, lastUpdate, aa.Id); err == nil {
+		notifyAnomalies(ctx, tx, aa)
 	}
 	if err != nil && !elastic.IsNotFound(err) {
 		logger.Error("Failed to detect anomalies.", map[string]interface{}{
@@ -86,6 +87,17 @@ func processAnomaliesForAccount(ctx context.Context, aaId int) (err error) {
 	return
 }
 
+// notifyAnomalies notifies the owner of an AWS account about its new
+// anomalies. A failure is logged but does not cancel the detection.
+func notifyAnomalies(ctx context.Context, tx *sql.Tx, aa aws.AwsAccount) {
+	if err := anomalies.NotifyAnomalies(ctx, tx, aa); err != nil {
+		jsonlog.LoggerFromContextOrDefault(ctx).Error("Failed to notify anomalies.", map[string]interface{}{
+			"awsAccountId": aa.Id,
+			"error":        err.Error(),
+		})
+	}
+}
+
 func registerAnomaliesUpdate(tx *sql.Tx, lastUpdate time.Time, aaId int) error {
 	dbaa, err := models.AwsAccountByID(tx, aaId)
 	if err != nil {
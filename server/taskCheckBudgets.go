@@ -0,0 +1,35 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package main
+
+import (
+	"context"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/budgets"
+	"github.com/trackit/trackit/db"
+)
+
+// taskCheckBudgets checks the spending of all the budgets and sends alerts
+// for the thresholds which have been crossed.
+func taskCheckBudgets(ctx context.Context) (err error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	logger.Debug("Running task 'check-budgets'.", nil)
+	if err = budgets.CheckBudgets(ctx, db.Db); err != nil {
+		logger.Error("Failed to check budgets.", err.Error())
+	}
+	return
+}
@@ -18,6 +18,8 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"flag"
 
 	"github.com/trackit/jsonlog"
 
@@ -26,17 +28,94 @@ import (
 	"github.com/trackit/trackit/models"
 )
 
-// taskFetchPricings fetches the EC2 pricings and saves them in the database
+// pricingFetcher fetches the pricings of an AWS product, either from the
+// Pricing API or from a bulk offer file
+type pricingFetcher struct {
+	Product string
+	Fetch   func(context.Context) (interface{}, error)
+	Import  func(context.Context, string) (interface{}, error)
+}
+
+var pricingFetchers = []pricingFetcher{
+	{
+		pricings.EC2ServiceCode,
+		func(ctx context.Context) (interface{}, error) { return pricings.FetchEc2Pricings(ctx) },
+		func(ctx context.Context, location string) (interface{}, error) {
+			return pricings.ImportEc2Pricings(ctx, location)
+		},
+	},
+	{
+		pricings.RDSServiceCode,
+		func(ctx context.Context) (interface{}, error) { return pricings.FetchRdsPricings(ctx) },
+		func(ctx context.Context, location string) (interface{}, error) {
+			return pricings.ImportRdsPricings(ctx, location)
+		},
+	},
+	{
+		pricings.ElastiCacheServiceCode,
+		func(ctx context.Context) (interface{}, error) { return pricings.FetchElastiCachePricings(ctx) },
+		func(ctx context.Context, location string) (interface{}, error) {
+			return pricings.ImportElastiCachePricings(ctx, location)
+		},
+	},
+	{
+		pricings.ESServiceCode,
+		func(ctx context.Context) (interface{}, error) { return pricings.FetchEsPricings(ctx) },
+		func(ctx context.Context, location string) (interface{}, error) {
+			return pricings.ImportEsPricings(ctx, location)
+		},
+	},
+}
+
+// taskFetchPricings fetches the EC2, RDS, ElastiCache and Elasticsearch Service
+// pricings and saves them in the database
+// By default the pricings are fetched from the AWS Pricing API. If a location is
+// given as argument, they are imported from the AWS bulk offer files it contains
+// instead. The location is either a local directory or an S3 location formatted
+// as s3://bucket/prefix, containing files named after the service codes with a
+// ".json" or ".csv" extension (e.g. "AmazonEC2.json").
+// A failure on a product does not prevent the other products from being updated
 func taskFetchPricings(ctx context.Context) (err error) {
+	args := flag.Args()
 	logger := jsonlog.LoggerFromContextOrDefault(ctx)
-	res, err := pricings.FetchEc2Pricings(ctx)
+	logger.Debug("Running task 'fetch-pricings'.", map[string]interface{}{
+		"args": args,
+	})
+	location := ""
+	if len(args) > 1 {
+		return errors.New("taskFetchPricings accepts at most one offer files location argument")
+	} else if len(args) == 1 {
+		location = args[0]
+	}
+	for _, fetcher := range pricingFetchers {
+		if fetchErr := fetchPricing(ctx, fetcher, location); fetchErr != nil {
+			logger.Error("Failed to update pricings", map[string]interface{}{
+				"product": fetcher.Product,
+				"error":   fetchErr.Error(),
+			})
+			err = fetchErr
+		}
+	}
+	return
+}
+
+// fetchPricing fetches the pricings of a product and saves them in the database
+// The pricings are imported from the bulk offer files of location if it is not empty
+func fetchPricing(ctx context.Context, fetcher pricingFetcher, location string) (err error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	var res interface{}
+	if location != "" {
+		res, err = fetcher.Import(ctx, location)
+	} else {
+		res, err = fetcher.Fetch(ctx)
+	}
 	if err != nil {
-		logger.Error("Failed to retrieve ec2 pricings", err.Error())
+		logger.Error("Failed to retrieve pricings", map[string]interface{}{"product": fetcher.Product, "error": err.Error()})
 		return
 	}
 	serializedPricing, err := json.Marshal(res)
 	if err != nil {
-		logger.Error("Failed to serialize ec2 pricings", err.Error())
+		logger.Error("Failed to serialize pricings", map[string]interface{}{"product": fetcher.Product, "error": err.Error()})
 		return
 	}
 	var tx *sql.Tx
@@ -53,16 +132,16 @@ func taskFetchPricings(ctx context.Context) (err error) {
 		logger.Error("Failed to initiate sql transaction", err.Error())
 		return
 	} else {
-		ec2PricingDb, _ := models.AwsPricingByProduct(tx, pricings.EC2ServiceCode)
-		if ec2PricingDb == nil {
-			ec2PricingDb = &models.AwsPricing{
-				Product: pricings.EC2ServiceCode,
+		pricingDb, _ := models.AwsPricingByProduct(tx, fetcher.Product)
+		if pricingDb == nil {
+			pricingDb = &models.AwsPricing{
+				Product: fetcher.Product,
 			}
 		}
-		ec2PricingDb.Pricing = serializedPricing
-		err = ec2PricingDb.Save(tx)
+		pricingDb.Pricing = serializedPricing
+		err = pricingDb.Save(tx)
 		if err != nil {
-			logger.Error("Failed to save ec2 pricings", err.Error())
+			logger.Error("Failed to save pricings", map[string]interface{}{"product": fetcher.Product, "error": err.Error()})
 			return
 		}
 	}
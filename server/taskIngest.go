@@ -72,7 +72,7 @@ func ingestBillingDataForBillRepository(ctx context.Context, aaId, brId int) (er
 	} else if aa, err = aws.GetAwsAccountWithId(aaId, tx); err != nil {
 	} else if br, err = s3.GetBillRepositoryForAwsAccountById(aa, brId, tx); err != nil {
 	} else if updateId, err = registerUpdate(db.Db, br); err != nil {
-	} else if latestManifest, err = s3.UpdateReport(ctx, aa, br); err != nil {
+	} else if latestManifest, err = s3.UpdateReport(s3.ContextWithUpdateJob(ctx, updateId), aa, br); err != nil {
 		if billError, castok := err.(awserr.Error); castok {
 			br.Error = billError.Message()
 			s3.UpdateBillRepositoryWithoutContext(br, db.Db)
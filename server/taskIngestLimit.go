@@ -83,7 +83,7 @@ func ingestBillingDataForBillRepositoryLimit(ctx context.Context, aaId, brId int
 	} else if aa, err = aws.GetAwsAccountWithId(aaId, tx); err != nil {
 	} else if br, err = s3.GetBillRepositoryForAwsAccountById(aa, brId, tx); err != nil {
 	} else if updateId, err = registerUpdate(db.Db, br); err != nil {
-	} else if latestManifest, err = s3.UpdateReportLimit(ctx, aa, br, dateUpperLimit); err != nil {
+	} else if latestManifest, err = s3.UpdateReportLimit(s3.ContextWithUpdateJob(ctx, updateId), aa, br, dateUpperLimit); err != nil {
 		if billError, castok := err.(awserr.Error); castok {
 			br.Error = billError.Message()
 			s3.UpdateBillRepositoryWithoutContext(br, db.Db)
@@ -34,9 +34,14 @@ import (
 	"github.com/trackit/trackit/aws/usageReports/rds"
 	"github.com/trackit/trackit/aws/usageReports/riEc2"
 	"github.com/trackit/trackit/aws/usageReports/riRdS"
+	"github.com/trackit/trackit/aws/usageReports/savingsPlans"
 	"github.com/trackit/trackit/cache"
 	"github.com/trackit/trackit/db"
 	onDemandToRiEc2 "github.com/trackit/trackit/onDemandToRI/ec2"
+	onDemandToRiElastiCache "github.com/trackit/trackit/onDemandToRI/elasticache"
+	onDemandToRiEs "github.com/trackit/trackit/onDemandToRI/es"
+	onDemandToRiRds "github.com/trackit/trackit/onDemandToRI/rds"
+	"github.com/trackit/trackit/onDemandToSP"
 )
 
 const invalidAccId = -1
@@ -100,7 +105,7 @@ func ingestDataForAccount(ctx context.Context, aaId int, date time.Time) (err er
 	} else if aa, err = aws.GetAwsAccountWithId(aaId, tx); err != nil {
 	} else if updateId, err = registerAccountProcessing(db.Db, aa); err != nil {
 	} else {
-		var ec2Err, rdsErr, esErr, elastiCacheErr, lambdaErr, riEc2Err, riRdsErr, odToRiEc2Err, ebsErr error
+		var ec2Err, rdsErr, esErr, elastiCacheErr, lambdaErr, riEc2Err, riRdsErr, odToRiEc2Err, odToSpErr, odToRiRdsErr, odToRiElastiCacheErr, odToRiEsErr, ebsErr, savingsPlansErr error
 		if date.IsZero() {
 			ec2Err = processAccountEC2(ctx, aa)
 			rdsErr = processAccountRDS(ctx, aa)
@@ -110,13 +115,18 @@ func ingestDataForAccount(ctx context.Context, aaId int, date time.Time) (err er
 			riEc2Err = riEc2.FetchDailyReservationsStats(ctx, aa)
 			riRdsErr = riRdS.FetchDailyInstancesStats(ctx, aa)
 			odToRiEc2Err = onDemandToRiEc2.RunOnDemandToRiEc2(ctx, aa)
+			odToSpErr = onDemandToSP.RunOnDemandToSp(ctx, aa)
+			odToRiRdsErr = onDemandToRiRds.RunOnDemandToRiRds(ctx, aa)
+			odToRiElastiCacheErr = onDemandToRiElastiCache.RunOnDemandToRiElastiCache(ctx, aa)
+			odToRiEsErr = onDemandToRiEs.RunOnDemandToRiEs(ctx, aa)
 			ebsErr = processAccountEbsSnapshot(ctx, aa)
+			savingsPlansErr = processAccountSavingsPlans(ctx, aa)
 		}
 		historyCreated, historyErr := processAccountHistory(ctx, aa, date)
-		updateAccountProcessingCompletion(ctx, aaId, db.Db, updateId, nil, rdsErr, ec2Err, esErr, elastiCacheErr, lambdaErr, riEc2Err, riRdsErr, odToRiEc2Err, historyErr, ebsErr, historyCreated)
+		updateAccountProcessingCompletion(ctx, aaId, db.Db, updateId, nil, rdsErr, ec2Err, esErr, elastiCacheErr, lambdaErr, riEc2Err, riRdsErr, odToRiEc2Err, odToSpErr, odToRiRdsErr, odToRiElastiCacheErr, odToRiEsErr, historyErr, ebsErr, savingsPlansErr, historyCreated)
 	}
 	if err != nil {
-		updateAccountProcessingCompletion(ctx, aaId, db.Db, updateId, err, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false)
+		updateAccountProcessingCompletion(ctx, aaId, db.Db, updateId, err, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false)
 		logger.Error("Failed to process account data.", map[string]interface{}{
 			"awsAccountId": aaId,
 			"error":        err.Error(),
@@ -134,7 +144,10 @@ func ingestDataForAccount(ctx context.Context, aaId int, date time.Time) (err er
 		"/rds",
 		"/rds/unused",
 		"/ri/ec2",
+		"/ri/ec2/recommendations",
 		"/ri/rds",
+		"/savingsplans",
+		"/sp/recommendations",
 	}
 	_ = cache.RemoveMatchingCache(affectedRoutes, []string{aa.AwsIdentity}, logger)
 	return
@@ -152,9 +165,9 @@ func registerAccountProcessing(db *sql.DB, aa aws.AwsAccount) (int64, error) {
 	return res.LastInsertId()
 }
 
-func updateAccountProcessingCompletion(ctx context.Context, aaId int, db *sql.DB, updateId int64, jobErr, rdsErr, ec2Err, esErr, elastiCacheErr, lambdaErr, riEc2Err, riRdsErr, odToRiEc2Err, historyErr, ebsErr error, historyCreated bool) {
+func updateAccountProcessingCompletion(ctx context.Context, aaId int, db *sql.DB, updateId int64, jobErr, rdsErr, ec2Err, esErr, elastiCacheErr, lambdaErr, riEc2Err, riRdsErr, odToRiEc2Err, odToSpErr, odToRiRdsErr, odToRiElastiCacheErr, odToRiEsErr, historyErr, ebsErr, savingsPlansErr error, historyCreated bool) {
 	updateNextUpdateAccount(db, aaId)
-	rErr := registerAccountProcessingCompletion(db, updateId, jobErr, rdsErr, ec2Err, esErr, elastiCacheErr, lambdaErr, riEc2Err, riRdsErr, odToRiEc2Err, historyErr, ebsErr, historyCreated)
+	rErr := registerAccountProcessingCompletion(db, updateId, jobErr, rdsErr, ec2Err, esErr, elastiCacheErr, lambdaErr, riEc2Err, riRdsErr, odToRiEc2Err, odToSpErr, odToRiRdsErr, odToRiElastiCacheErr, odToRiEsErr, historyErr, ebsErr, savingsPlansErr, historyCreated)
 	if rErr != nil {
 		logger := jsonlog.LoggerFromContextOrDefault(ctx)
 		logger.Error("Failed to register account processing completion.", map[string]interface{}{
@@ -180,7 +193,7 @@ func updateNextUpdateAccount(db *sql.DB, aaId int) error {
 	return err
 }
 
-func registerAccountProcessingCompletion(db *sql.DB, updateId int64, jobErr, rdsErr, ec2Err, esErr, elastiCacheErr, lambdaErr, riEc2Err, riRdsErr, odToRiEc2Err, historyErr, ebsErr error, historyCreated bool) error {
+func registerAccountProcessingCompletion(db *sql.DB, updateId int64, jobErr, rdsErr, ec2Err, esErr, elastiCacheErr, lambdaErr, riEc2Err, riRdsErr, odToRiEc2Err, odToSpErr, odToRiRdsErr, odToRiElastiCacheErr, odToRiEsErr, historyErr, ebsErr, savingsPlansErr error, historyCreated bool) error {
 	const sqlstr = `UPDATE aws_account_update_job SET
 		completed=?,
 		jobError=?,
@@ -190,13 +203,18 @@ func registerAccountProcessingCompletion(db *sql.DB, updateId int64, jobErr, rds
 		elastiCacheError=?,
 		lambdaError=?,
 		ebsError=?,
+		savingsPlansError=?,
 		riEc2Error=?,
 		riRdsError=?,
 		odToRiEc2Error=?,
+		odToSpError=?,
+		odToRiRdsError=?,
+		odToRiElastiCacheError=?,
+		odToRiEsError=?,
 		historyError=?,
 		monthly_reports_generated=?
 	WHERE id=?`
-	_, err := db.Exec(sqlstr, time.Now(), errToStr(jobErr), errToStr(rdsErr), errToStr(ec2Err), errToStr(esErr), errToStr(elastiCacheErr), errToStr(lambdaErr), errToStr(ebsErr), errToStr(riEc2Err), errToStr(riRdsErr), errToStr(odToRiEc2Err), errToStr(historyErr), historyCreated, updateId)
+	_, err := db.Exec(sqlstr, time.Now(), errToStr(jobErr), errToStr(rdsErr), errToStr(ec2Err), errToStr(esErr), errToStr(elastiCacheErr), errToStr(lambdaErr), errToStr(ebsErr), errToStr(savingsPlansErr), errToStr(riEc2Err), errToStr(riRdsErr), errToStr(odToRiEc2Err), errToStr(odToSpErr), errToStr(odToRiRdsErr), errToStr(odToRiElastiCacheErr), errToStr(odToRiEsErr), errToStr(historyErr), historyCreated, updateId)
 	return err
 }
 
@@ -265,6 +283,19 @@ func processAccountEbsSnapshot(ctx context.Context, aa aws.AwsAccount) error {
 	return err
 }
 
+// processAccountSavingsPlans processes all the Savings Plans data for an AwsAccount
+func processAccountSavingsPlans(ctx context.Context, aa aws.AwsAccount) error {
+	err := savingsPlans.FetchDailySavingsPlansStats(ctx, aa)
+	if err != nil {
+		logger := jsonlog.LoggerFromContextOrDefault(ctx)
+		logger.Error("Failed to ingest Savings Plans data", map[string]interface{}{
+			"awsAccountId": aa.Id,
+			"error":        err.Error(),
+		})
+	}
+	return err
+}
+
 // processAccountLambda processes all the Lambda data for an AwsAccount
 func processAccountLambda(ctx context.Context, aa aws.AwsAccount) error {
 	err := lambda.FetchDailyFunctionsStats(ctx, aa)
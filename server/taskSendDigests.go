@@ -0,0 +1,35 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package main
+
+import (
+	"context"
+
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/db"
+	"github.com/trackit/trackit/digests"
+)
+
+// taskSendDigests sends the daily and weekly digests which are due to their
+// subscribers.
+func taskSendDigests(ctx context.Context) (err error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	logger.Debug("Running task 'send-digests'.", nil)
+	if err = digests.SendDigests(ctx, db.Db); err != nil {
+		logger.Error("Failed to send digests.", err.Error())
+	}
+	return
+}
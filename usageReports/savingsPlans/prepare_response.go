@@ -0,0 +1,61 @@
+//   Copyright 2019 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package savingsPlans
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/olivere/elastic"
+	"github.com/trackit/jsonlog"
+
+	"github.com/trackit/trackit/aws/usageReports/savingsPlans"
+)
+
+type (
+	// Structure that allow to parse ES response for Savings Plans daily reports
+	ResponseSavingsPlansDaily struct {
+		Accounts struct {
+			Buckets []struct {
+				Reports struct {
+					Hits struct {
+						Hits []struct {
+							Report savingsPlans.SavingsPlansReport `json:"_source"`
+						} `json:"hits"`
+					} `json:"hits"`
+				} `json:"reports"`
+			} `json:"buckets"`
+		} `json:"accounts"`
+	}
+)
+
+// prepareResponseSavingsPlansDaily parses the results from elasticsearch and returns
+// the latest Savings Plans report of each account
+func prepareResponseSavingsPlansDaily(ctx context.Context, resSavingsPlans *elastic.SearchResult) ([]savingsPlans.SavingsPlansReport, error) {
+	logger := jsonlog.LoggerFromContextOrDefault(ctx)
+	var parsedSavingsPlans ResponseSavingsPlansDaily
+	reports := make([]savingsPlans.SavingsPlansReport, 0)
+	err := json.Unmarshal(*resSavingsPlans.Aggregations["accounts"], &parsedSavingsPlans.Accounts)
+	if err != nil {
+		logger.Error("Error while unmarshaling ES Savings Plans response", err)
+		return nil, err
+	}
+	for _, account := range parsedSavingsPlans.Accounts.Buckets {
+		for _, report := range account.Reports.Hits.Hits {
+			reports = append(reports, report.Report)
+		}
+	}
+	return reports, nil
+}
@@ -18,7 +18,6 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"net/http"
 	"time"
 
@@ -129,9 +128,9 @@ func createForgottenPasswordEntry(request *http.Request, body forgottenPasswordR
 		logger.Error("Failed to insert forgotten password token in database.", err.Error())
 		return 500, errors.New("Failed to create forgotten password token")
 	}
-	mailSubject := "Reset your Trackit password"
-	mailBody := fmt.Sprintf("Please follow this link to recover your password: https://re.trackit.io/reset/%d/%s. This link is valid for an hour.", dbForgottenPassword.ID, token)
-	err = mail.SendMail(user.Email, mailSubject, mailBody, request.Context())
+	err = mail.SendTemplate(user.Email, mail.PasswordResetTemplate, mail.LinkData{
+		Link: mail.GetResetLink(dbForgottenPassword.ID, token),
+	}, request.Context())
 	if err != nil {
 		logger.Error("Failed to send password recovery email.", err.Error())
 		return 500, errors.New("Failed to send password recovery email")
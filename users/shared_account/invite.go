@@ -16,7 +16,6 @@ package shared_account
 
 import (
 	"time"
-	"fmt"
 	"errors"
 	"database/sql"
 	"context"
@@ -154,20 +153,19 @@ func resetPasswordGenerator(ctx context.Context, tx *sql.Tx, newUserId int) (mod
 func sendMailNotification(ctx context.Context, tx *sql.Tx, userMail string, userNew bool, newUserId int) (error) {
 	logger := jsonlog.LoggerFromContextOrDefault(ctx)
 	if userNew {
-		mailSubject := "An AWS account has been added to your Trackit account"
-		mailBody := fmt.Sprintf("%s", "Hi, a new AWS account has been added to your Trackit Account. " +
-			"You can connect to your account to manage it : https://re.trackit.io/")
-		err := mail.SendMail(userMail, mailSubject, mailBody, ctx)
+		err := mail.SendTemplate(userMail, mail.AccountSharedTemplate, nil, ctx)
 		if err != nil {
 			logger.Error("Failed to send email.", err.Error())
 			return err
 		}
 	} else {
 		dbForgottenPassword, token, err := resetPasswordGenerator(ctx, tx, newUserId)
-		mailSubject := "You are invited to join Trackit"
-		mailBody := fmt.Sprintf("Hi, you have been invited to join trackit. Please follow this link to create" +
-			" your account: https://re.trackit.io/reset/%d/%s.", dbForgottenPassword.ID, token)
-		err = mail.SendMail(userMail, mailSubject, mailBody, ctx)
+		if err != nil {
+			return err
+		}
+		err = mail.SendTemplate(userMail, mail.InviteTemplate, mail.LinkData{
+			Link: mail.GetResetLink(dbForgottenPassword.ID, token),
+		}, ctx)
 		if err != nil {
 			logger.Error("Failed to send viewer password email.", err.Error())
 			return err
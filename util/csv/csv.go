@@ -63,6 +63,10 @@ func (d *Decoder) SetHeader(h []string) {
 	d.header = h
 }
 
+func (d *Decoder) Header() []string {
+	return d.header
+}
+
 func (d *Decoder) ReadHeader() error {
 	if record, err := d.reader.Read(); err == nil {
 		d.SetHeader(record)
@@ -82,6 +86,17 @@ func (d *Decoder) ReadRecord(v interface{}) error {
 	}
 }
 
+// DecodeRecord stores a record which was not read by the decoder into v,
+// using the decoder's header. It allows decoding records from other formats
+// with the same struct tags.
+func (d *Decoder) DecodeRecord(record []string, v interface{}) error {
+	if rt, err := getRecordType(v); err != nil {
+		return err
+	} else {
+		return d.storeRecord(rt, v, record)
+	}
+}
+
 func (d *Decoder) storeRecord(rt recordType, vi interface{}, record []string) error {
 	v := reflect.ValueOf(vi)
 	if v.Type().Kind() == reflect.Ptr {
@@ -157,3 +157,16 @@ newline
 		}
 	}
 }
+
+func TestDecodeRecord(t *testing.T) {
+	var dn TaggedNames
+	var d Decoder
+	d.SetHeader([]string{"Baz", "Foo", "Bar"})
+	err := d.DecodeRecord([]string{"baz val", "foo val", "bar val"}, &dn)
+	if err != nil {
+		t.Errorf("DecodeRecord should succeed. Failed with %s.", err.Error())
+	}
+	if e := (TaggedNames{"foo val", "bar val", "baz val"}); e != dn {
+		t.Errorf("Record structure should be %#v, is %#v instead.", e, dn)
+	}
+}
@@ -0,0 +1,288 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+// Package parquet reads Apache Parquet files. It only supports what is needed
+// to read the Cost and Usage Reports AWS writes as Parquet: flat columns and
+// map columns, with plain or dictionary encoded values, compressed with
+// Snappy or GZIP.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+type parquetError string
+
+func (err parquetError) Error() string {
+	return string(err)
+}
+
+const (
+	ErrInvalidFile         = parquetError("Not a valid Parquet file.")
+	ErrUnsupportedEncoding = parquetError("Parquet encoding not supported.")
+	ErrUnsupportedCodec    = parquetError("Parquet compression codec not supported.")
+)
+
+const (
+	// magic is found at the start and at the end of Parquet files.
+	magic = "PAR1"
+
+	// footerSize is the size of the footer of Parquet files: the length of
+	// the metadata followed by the magic.
+	footerSize = 8
+
+	// maxMetadataSize bounds the size of the metadata read from a file.
+	maxMetadataSize = 64 << 20
+)
+
+// Type is the physical type of the values of a column.
+type Type int
+
+const (
+	Boolean           = Type(0)
+	Int32             = Type(1)
+	Int64             = Type(2)
+	Int96             = Type(3)
+	Float             = Type(4)
+	Double            = Type(5)
+	ByteArray         = Type(6)
+	FixedLenByteArray = Type(7)
+)
+
+// Repetition types of the fields of a schema.
+const (
+	repetitionRequired = 0
+	repetitionOptional = 1
+	repetitionRepeated = 2
+)
+
+// Converted types of the fields of a schema this package interprets.
+const (
+	convertedTimestampMillis = 9
+	convertedTimestampMicros = 10
+)
+
+// Column describes a leaf column of a Parquet file.
+type Column struct {
+	// Path is the path of the column in the schema, such as
+	// resource_tags.key_value.key for the keys of a map column.
+	Path []string
+	// Type is the physical type of the values of the column.
+	Type Type
+	// TimeUnit is the unit of the values of timestamp columns. It is zero
+	// for other columns.
+	TimeUnit time.Duration
+	// typeLength is the size of the values of fixed length columns.
+	typeLength int
+	// maxDefinitionLevel is the definition level of non-null values.
+	maxDefinitionLevel int
+	// maxRepetitionLevel is non-zero for repeated columns.
+	maxRepetitionLevel int
+}
+
+// File is an opened Parquet file.
+type File struct {
+	r         io.ReaderAt
+	size      int64
+	columns   []Column
+	rowGroups []rowGroup
+}
+
+// rowGroup is a row group of a Parquet file.
+type rowGroup struct {
+	numRows int64
+	chunks  []columnChunk
+}
+
+// columnChunk locates the pages of a column in a row group.
+type columnChunk struct {
+	codec     int64
+	numValues int64
+	offset    int64
+	size      int64
+}
+
+// Open reads the metadata of the Parquet file of the given size read from r.
+func Open(r io.ReaderAt, size int64) (*File, error) {
+	if size < int64(len(magic)+footerSize) {
+		return nil, ErrInvalidFile
+	}
+	var footer [footerSize]byte
+	if _, err := r.ReadAt(footer[:], size-footerSize); err != nil {
+		return nil, err
+	} else if string(footer[4:]) != magic {
+		return nil, ErrInvalidFile
+	}
+	metadataSize := int64(binary.LittleEndian.Uint32(footer[:4]))
+	if metadataSize > maxMetadataSize || metadataSize > size-footerSize-int64(len(magic)) {
+		return nil, ErrInvalidFile
+	}
+	metadata := make([]byte, metadataSize)
+	if _, err := r.ReadAt(metadata, size-footerSize-metadataSize); err != nil {
+		return nil, err
+	}
+	fileMetadata, err := readThriftStructure(bytes.NewReader(metadata))
+	if err != nil {
+		return nil, err
+	}
+	f := &File{r: r, size: size}
+	if f.columns, err = getColumns(fileMetadata.list(2)); err != nil {
+		return nil, err
+	}
+	if f.rowGroups, err = getRowGroups(fileMetadata.list(4), len(f.columns), size); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Columns returns the leaf columns of the file, in the order of the column
+// chunks of its row groups.
+func (f *File) Columns() []Column {
+	return f.columns
+}
+
+// NumRowGroups returns the number of row groups in the file.
+func (f *File) NumRowGroups() int {
+	return len(f.rowGroups)
+}
+
+// NumRows returns the number of rows in a row group.
+func (f *File) NumRows(rowGroup int) int64 {
+	return f.rowGroups[rowGroup].numRows
+}
+
+// ColumnReader returns a reader of the values of a column in a row group.
+func (f *File) ColumnReader(rowGroup, column int) *ColumnReader {
+	chunk := f.rowGroups[rowGroup].chunks[column]
+	return newColumnReader(io.NewSectionReader(f.r, chunk.offset, chunk.size), &f.columns[column], chunk)
+}
+
+// getColumns returns the leaf columns of a schema, flattened depth first in
+// its SchemaElements.
+func getColumns(elements []interface{}) ([]Column, error) {
+	if len(elements) == 0 {
+		return nil, ErrInvalidFile
+	}
+	root, _ := elements[0].(thriftStructure)
+	var columns []Column
+	next, err := addColumns(&columns, elements, 1, int(root.int(5, 0)), nil, 0, 0)
+	if err != nil {
+		return nil, err
+	} else if next != len(elements) {
+		return nil, ErrInvalidFile
+	}
+	return columns, nil
+}
+
+// addColumns appends to columns the leaf columns of the children of a group
+// of the schema, whose elements start at index i. It returns the index of
+// the element following the group.
+func addColumns(columns *[]Column, elements []interface{}, i, children int, path []string, definition, repetition int) (int, error) {
+	for ; children > 0; children-- {
+		if i >= len(elements) {
+			return 0, ErrInvalidFile
+		}
+		element, ok := elements[i].(thriftStructure)
+		if !ok {
+			return 0, ErrInvalidFile
+		}
+		i++
+		elementPath := append(path[:len(path):len(path)], element.string(4))
+		elementDefinition, elementRepetition := definition, repetition
+		switch element.int(3, repetitionRequired) {
+		case repetitionOptional:
+			elementDefinition++
+		case repetitionRepeated:
+			elementDefinition++
+			elementRepetition++
+		}
+		if grandChildren := int(element.int(5, 0)); grandChildren > 0 {
+			var err error
+			if i, err = addColumns(columns, elements, i, grandChildren, elementPath, elementDefinition, elementRepetition); err != nil {
+				return 0, err
+			}
+		} else {
+			*columns = append(*columns, Column{
+				Path:               elementPath,
+				Type:               Type(element.int(1, int64(ByteArray))),
+				TimeUnit:           getTimeUnit(element),
+				typeLength:         int(element.int(2, 0)),
+				maxDefinitionLevel: elementDefinition,
+				maxRepetitionLevel: elementRepetition,
+			})
+		}
+	}
+	return i, nil
+}
+
+// getTimeUnit returns the unit of the values of a timestamp column, from its
+// logical type or its converted type.
+func getTimeUnit(element thriftStructure) time.Duration {
+	if timestamp := element.structure(10).structure(8); timestamp != nil {
+		unit := timestamp.structure(2)
+		if _, ok := unit[1]; ok {
+			return time.Millisecond
+		} else if _, ok := unit[2]; ok {
+			return time.Microsecond
+		} else if _, ok := unit[3]; ok {
+			return time.Nanosecond
+		}
+	}
+	switch element.int(6, -1) {
+	case convertedTimestampMillis:
+		return time.Millisecond
+	case convertedTimestampMicros:
+		return time.Microsecond
+	}
+	return 0
+}
+
+// getRowGroups locates the column chunks of each row group in a file of the
+// given size.
+func getRowGroups(groups []interface{}, columns int, size int64) ([]rowGroup, error) {
+	rowGroups := make([]rowGroup, len(groups))
+	for i, g := range groups {
+		group, _ := g.(thriftStructure)
+		chunks := group.list(1)
+		if len(chunks) != columns {
+			return nil, ErrInvalidFile
+		}
+		rowGroups[i] = rowGroup{numRows: group.int(3, 0), chunks: make([]columnChunk, columns)}
+		for j, c := range chunks {
+			chunk, _ := c.(thriftStructure)
+			metadata := chunk.structure(3)
+			if metadata == nil {
+				return nil, ErrInvalidFile
+			}
+			offset := metadata.int(9, 0)
+			if dictionaryOffset := metadata.int(11, 0); dictionaryOffset > 0 && dictionaryOffset < offset {
+				offset = dictionaryOffset
+			}
+			chunkSize := metadata.int(7, 0)
+			if offset < 0 || chunkSize < 0 || offset > size || chunkSize > size-offset {
+				return nil, ErrInvalidFile
+			}
+			rowGroups[i].chunks[j] = columnChunk{
+				codec:     metadata.int(4, 0),
+				numValues: metadata.int(5, 0),
+				offset:    offset,
+				size:      chunkSize,
+			}
+		}
+	}
+	return rowGroups, nil
+}
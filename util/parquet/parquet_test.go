@@ -0,0 +1,154 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package parquet
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+// types.parquet was written by another Parquet implementation, with Snappy
+// compression, dictionary encoding for the int64 column, delta encoding for
+// the string column and small pages so that rows span several pages. Its
+// 300 rows hold values derived from their index, as checked by TestReadRows.
+func openTypesFile(t *testing.T) *File {
+	data, err := ioutil.ReadFile("testdata/types.parquet")
+	if err != nil {
+		t.Fatalf("Failed to read test file: %s.", err.Error())
+	}
+	f, err := Open(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Failed to open test file: %s.", err.Error())
+	}
+	return f
+}
+
+func TestColumns(t *testing.T) {
+	f := openTypesFile(t)
+	expected := []struct {
+		path     string
+		t        Type
+		timeUnit time.Duration
+	}{
+		{"boolean", Boolean, 0},
+		{"int32", Int32, 0},
+		{"int64", Int64, 0},
+		{"int96", Int96, 0},
+		{"float", Float, 0},
+		{"double", Double, 0},
+		{"string", ByteArray, 0},
+		{"timestamp", Int64, time.Microsecond},
+		{"list", Int32, 0},
+	}
+	columns := f.Columns()
+	if len(columns) != len(expected) {
+		t.Fatalf("File should have %d columns, has %d instead.", len(expected), len(columns))
+	}
+	for i, e := range expected {
+		c := columns[i]
+		if path := strings.Join(c.Path, "."); path != e.path || c.Type != e.t || c.TimeUnit != e.timeUnit {
+			t.Errorf("Column %d should be %s of type %d in %s, is %s of type %d in %s instead.", i, e.path, e.t, e.timeUnit, path, c.Type, c.TimeUnit)
+		}
+	}
+}
+
+func TestReadRows(t *testing.T) {
+	f := openTypesFile(t)
+	if f.NumRowGroups() != 1 || f.NumRows(0) != 300 {
+		t.Fatalf("File should have a row group of 300 rows, has %d row groups.", f.NumRowGroups())
+	}
+	readers := make([]*ColumnReader, len(f.Columns()))
+	for i := range readers {
+		readers[i] = f.ColumnReader(0, i)
+	}
+	row := make([][]Value, len(readers))
+	base := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 300; i++ {
+		for j, r := range readers {
+			var err error
+			if row[j], err = r.ReadRow(row[j][:0]); err != nil {
+				t.Fatalf("Failed to read column %d of row %d: %s.", j, i, err.Error())
+			}
+		}
+		if row[0][0].Boolean() != (i%3 == 0) {
+			t.Errorf("Boolean of row %d should be %t.", i, i%3 == 0)
+		}
+		if row[1][0].Int32() != int32(-i) {
+			t.Errorf("Int32 of row %d should be %d, is %d instead.", i, -i, row[1][0].Int32())
+		}
+		if row[2][0].Int64() != int64(i%7)<<40 {
+			t.Errorf("Int64 of row %d should be %d, is %d instead.", i, int64(i%7)<<40, row[2][0].Int64())
+		}
+		if row[3][0].Int96() != [3]uint32{uint32(i), 1, 2440588} {
+			t.Errorf("Int96 of row %d should be read, is %v instead.", i, row[3][0].Int96())
+		}
+		if row[4][0].Float() != float32(i)/4 || row[5][0].Double() != float64(i)/8 {
+			t.Errorf("Floats of row %d should be read, are %f and %f instead.", i, row[4][0].Float(), row[5][0].Double())
+		}
+		if i%2 == 0 && string(row[6][0].ByteArray()) != string(rune('a'+i%26)) {
+			t.Errorf("String of row %d should be %c, is %q instead.", i, rune('a'+i%26), row[6][0].ByteArray())
+		} else if i%2 != 0 && !row[6][0].IsNull() {
+			t.Errorf("String of row %d should be null.", i)
+		}
+		if timestamp := time.Unix(0, row[7][0].Int64()*int64(time.Microsecond)).UTC(); timestamp != base.Add(time.Duration(i)*time.Microsecond) {
+			t.Errorf("Timestamp of row %d should be read, is %s instead.", i, timestamp)
+		}
+		if i%4 == 0 {
+			if len(row[8]) != 1 || !row[8][0].IsNull() {
+				t.Errorf("List of row %d should be empty, is %v instead.", i, row[8])
+			}
+		} else if len(row[8]) != i%4 {
+			t.Errorf("List of row %d should have %d values, has %d instead.", i, i%4, len(row[8]))
+		} else {
+			for j, v := range row[8] {
+				if v.Int32() != int32(i*10+j) {
+					t.Errorf("Value %d of the list of row %d should be %d, is %d instead.", j, i, i*10+j, v.Int32())
+				}
+			}
+		}
+	}
+	if _, err := readers[0].ReadRow(nil); err == nil {
+		t.Errorf("Reading past the last row should fail.")
+	}
+}
+
+func TestReadHybrid(t *testing.T) {
+	// A run of four 5, followed by a bit-packed group of 1 to 8 on 4 bits.
+	data := []byte{0x08, 0x05, 0x03, 0x21, 0x43, 0x65, 0x87}
+	expected := []int{5, 5, 5, 5, 1, 2, 3, 4, 5, 6, 7, 8}
+	values, err := readHybrid(data, 4, len(expected))
+	if err != nil {
+		t.Fatalf("Failed to decode values: %s.", err.Error())
+	}
+	for i, v := range expected {
+		if values[i] != v {
+			t.Errorf("Value %d should be %d, is %d instead.", i, v, values[i])
+		}
+	}
+	if _, err := readHybrid(data[:4], 4, len(expected)); err != ErrInvalidFile {
+		t.Errorf("Truncated values should not be decoded.")
+	}
+}
+
+func TestOpenInvalidFile(t *testing.T) {
+	for _, data := range []string{"", "PAR1", "PAR1\xff\xff\xff\xffPAR1", "not a parquet file at all"} {
+		if _, err := Open(strings.NewReader(data), int64(len(data))); err == nil {
+			t.Errorf("Opening %q should fail.", data)
+		}
+	}
+}
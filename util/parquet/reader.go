@@ -0,0 +1,599 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package parquet
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+
+	"github.com/golang/snappy"
+)
+
+// Compression codecs of column chunks.
+const (
+	codecUncompressed = 0
+	codecSnappy       = 1
+	codecGzip         = 2
+)
+
+// Types of pages.
+const (
+	pageData       = 0
+	pageDictionary = 2
+	pageDataV2     = 3
+)
+
+// Encodings of values and levels.
+const (
+	encodingPlain           = 0
+	encodingPlainDictionary = 2
+	encodingRle             = 3
+	encodingDelta           = 5
+	encodingDeltaLength     = 6
+	encodingDeltaByteArray  = 7
+	encodingRleDictionary   = 8
+)
+
+const (
+	// maxPageSize bounds the size of the pages read from a file.
+	maxPageSize = 256 << 20
+
+	// maxPageValues bounds the number of values in the pages read from a
+	// file.
+	maxPageValues = 1 << 24
+)
+
+// ColumnReader reads the values of a column in a row group.
+type ColumnReader struct {
+	r          *bufio.Reader
+	column     *Column
+	codec      int64
+	remaining  int64
+	dictionary []Value
+	// values are the values of the current page, null ones included.
+	values []Value
+	// repetitions are the repetition levels of the values of the current
+	// page, if the column is repeated.
+	repetitions []int
+	cursor      int
+}
+
+func newColumnReader(r io.Reader, column *Column, chunk columnChunk) *ColumnReader {
+	return &ColumnReader{
+		r:         bufio.NewReader(r),
+		column:    column,
+		codec:     chunk.codec,
+		remaining: chunk.numValues,
+	}
+}
+
+// ReadRow appends to values the values of the column in the next row: a
+// single value for flat columns, and all the values of the row for repeated
+// columns such as the keys and the values of maps. It returns io.EOF once all
+// rows were read.
+func (c *ColumnReader) ReadRow(values []Value) ([]Value, error) {
+	for read := 0; ; read++ {
+		if c.cursor >= len(c.values) {
+			if err := c.readPage(); err == io.EOF && read > 0 {
+				return values, nil
+			} else if err != nil {
+				return values, err
+			}
+		}
+		if read > 0 && (c.repetitions == nil || c.repetitions[c.cursor] == 0) {
+			return values, nil
+		}
+		values = append(values, c.values[c.cursor])
+		c.cursor++
+	}
+}
+
+// readPage reads the next data page of the column chunk, reading the
+// dictionary page on the way if there is one.
+func (c *ColumnReader) readPage() error {
+	c.values, c.repetitions, c.cursor = nil, nil, 0
+	for len(c.values) == 0 {
+		if c.remaining <= 0 {
+			return io.EOF
+		}
+		header, err := readThriftStructure(c.r)
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		} else if err != nil {
+			return err
+		}
+		size, uncompressedSize := header.int(3, -1), header.int(2, -1)
+		if size < 0 || size > maxPageSize || uncompressedSize < 0 || uncompressedSize > maxPageSize {
+			return ErrInvalidFile
+		}
+		page := make([]byte, size)
+		if _, err := io.ReadFull(c.r, page); err != nil {
+			return err
+		}
+		switch header.int(1, -1) {
+		case pageDictionary:
+			err = c.readDictionaryPage(header.structure(7), page, int(uncompressedSize))
+		case pageData:
+			err = c.readDataPage(header.structure(5), page, int(uncompressedSize))
+		case pageDataV2:
+			err = c.readDataPageV2(header.structure(8), page, int(uncompressedSize))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readDictionaryPage reads the dictionary of the column chunk.
+func (c *ColumnReader) readDictionaryPage(header thriftStructure, page []byte, uncompressedSize int) error {
+	n := header.int(1, -1)
+	if n < 0 || n > maxPageValues {
+		return ErrInvalidFile
+	} else if encoding := header.int(2, encodingPlain); encoding != encodingPlain && encoding != encodingPlainDictionary {
+		return ErrUnsupportedEncoding
+	}
+	data, err := decompress(c.codec, page, uncompressedSize)
+	if err != nil {
+		return err
+	}
+	c.dictionary, err = readPlain(data, c.column, int(n))
+	return err
+}
+
+// readDataPage reads a data page, where the repetition levels, the definition
+// levels and the values are compressed together.
+func (c *ColumnReader) readDataPage(header thriftStructure, page []byte, uncompressedSize int) error {
+	n := header.int(1, -1)
+	if n < 0 || n > maxPageValues {
+		return ErrInvalidFile
+	}
+	data, err := decompress(c.codec, page, uncompressedSize)
+	if err != nil {
+		return err
+	}
+	var repetitions, definitions []int
+	if c.column.maxRepetitionLevel > 0 {
+		if header.int(4, encodingRle) != encodingRle {
+			return ErrUnsupportedEncoding
+		} else if repetitions, data, err = readPrefixedLevels(data, c.column.maxRepetitionLevel, int(n)); err != nil {
+			return err
+		}
+	}
+	if c.column.maxDefinitionLevel > 0 {
+		if header.int(3, encodingRle) != encodingRle {
+			return ErrUnsupportedEncoding
+		} else if definitions, data, err = readPrefixedLevels(data, c.column.maxDefinitionLevel, int(n)); err != nil {
+			return err
+		}
+	}
+	return c.readValues(header.int(2, encodingPlain), data, int(n), repetitions, definitions)
+}
+
+// readDataPageV2 reads a data page where the repetition and definition levels
+// are stored uncompressed before the values.
+func (c *ColumnReader) readDataPageV2(header thriftStructure, page []byte, uncompressedSize int) error {
+	n := header.int(1, -1)
+	repetitionsSize, definitionsSize := header.int(6, 0), header.int(5, 0)
+	if n < 0 || n > maxPageValues || repetitionsSize < 0 || definitionsSize < 0 || repetitionsSize+definitionsSize > int64(len(page)) {
+		return ErrInvalidFile
+	}
+	var repetitions, definitions []int
+	var err error
+	if c.column.maxRepetitionLevel > 0 {
+		if repetitions, err = readLevels(page[:repetitionsSize], c.column.maxRepetitionLevel, int(n)); err != nil {
+			return err
+		}
+	}
+	if c.column.maxDefinitionLevel > 0 {
+		if definitions, err = readLevels(page[repetitionsSize:repetitionsSize+definitionsSize], c.column.maxDefinitionLevel, int(n)); err != nil {
+			return err
+		}
+	}
+	data := page[repetitionsSize+definitionsSize:]
+	if header.bool(7, true) {
+		levelsSize := int(repetitionsSize + definitionsSize)
+		if data, err = decompress(c.codec, data, uncompressedSize-levelsSize); err != nil {
+			return err
+		}
+	}
+	return c.readValues(header.int(4, encodingPlain), data, int(n), repetitions, definitions)
+}
+
+// readValues decodes the values of a data page, and places them among the
+// null values according to their definition levels.
+func (c *ColumnReader) readValues(encoding int64, data []byte, n int, repetitions, definitions []int) error {
+	defined := n
+	if definitions != nil {
+		defined = 0
+		for _, d := range definitions {
+			if d == c.column.maxDefinitionLevel {
+				defined++
+			}
+		}
+	}
+	var values []Value
+	var err error
+	switch encoding {
+	case encodingPlain:
+		values, err = readPlain(data, c.column, defined)
+	case encodingPlainDictionary, encodingRleDictionary:
+		values, err = c.readDictionaryIndices(data, defined)
+	case encodingRle:
+		values, err = readRleBooleans(data, c.column, defined)
+	case encodingDelta:
+		values, err = readDeltaIntegers(data, c.column, defined)
+	case encodingDeltaLength:
+		values, err = readDeltaLengthByteArrays(data, c.column, defined)
+	case encodingDeltaByteArray:
+		values, err = readDeltaByteArrays(data, c.column, defined)
+	default:
+		err = ErrUnsupportedEncoding
+	}
+	if err != nil {
+		return err
+	}
+	c.remaining -= int64(n)
+	c.repetitions = repetitions
+	if definitions == nil {
+		c.values = values
+		return nil
+	}
+	c.values = make([]Value, n)
+	for i, d := range definitions {
+		if d == c.column.maxDefinitionLevel {
+			c.values[i], values = values[0], values[1:]
+		} else {
+			c.values[i].null = true
+		}
+	}
+	return nil
+}
+
+// readDictionaryIndices decodes dictionary encoded values.
+func (c *ColumnReader) readDictionaryIndices(data []byte, n int) ([]Value, error) {
+	if n == 0 {
+		return nil, nil
+	} else if len(data) == 0 {
+		return nil, ErrInvalidFile
+	}
+	indices, err := readHybrid(data[1:], uint(data[0]), n)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]Value, n)
+	for i, index := range indices {
+		if index >= len(c.dictionary) {
+			return nil, ErrInvalidFile
+		}
+		values[i] = c.dictionary[index]
+	}
+	return values, nil
+}
+
+// readRleBooleans decodes RLE encoded boolean values.
+func readRleBooleans(data []byte, column *Column, n int) ([]Value, error) {
+	if column.Type != Boolean {
+		return nil, ErrUnsupportedEncoding
+	}
+	booleans, _, err := readPrefixedHybrid(data, 1, n)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]Value, n)
+	for i, b := range booleans {
+		values[i].bits = uint64(b)
+	}
+	return values, nil
+}
+
+// readPlain decodes n plain encoded values.
+func readPlain(data []byte, column *Column, n int) ([]Value, error) {
+	values := make([]Value, n)
+	size := 0
+	switch column.Type {
+	case Boolean:
+		if len(data) < (n+7)/8 {
+			return nil, ErrInvalidFile
+		}
+		for i := range values {
+			values[i].bits = uint64(data[i/8]>>uint(i%8)) & 1
+		}
+		return values, nil
+	case Int32, Float:
+		size = 4
+	case Int64, Double:
+		size = 8
+	case Int96:
+		size = 12
+	case FixedLenByteArray:
+		size = column.typeLength
+	case ByteArray:
+		for i := range values {
+			if len(data) < 4 {
+				return nil, ErrInvalidFile
+			}
+			length := binary.LittleEndian.Uint32(data)
+			if uint64(length) > uint64(len(data)-4) {
+				return nil, ErrInvalidFile
+			}
+			values[i].bytes = data[4 : 4+length]
+			data = data[4+length:]
+		}
+		return values, nil
+	default:
+		return nil, ErrUnsupportedEncoding
+	}
+	if size <= 0 || len(data)/size < n {
+		return nil, ErrInvalidFile
+	}
+	for i := range values {
+		v := data[i*size : (i+1)*size]
+		switch column.Type {
+		case Int32, Float:
+			values[i].bits = uint64(binary.LittleEndian.Uint32(v))
+		case Int64, Double:
+			values[i].bits = binary.LittleEndian.Uint64(v)
+		default:
+			values[i].bytes = v
+		}
+	}
+	return values, nil
+}
+
+// readPrefixedLevels decodes the levels of a data page, prefixed with their
+// size, and returns the rest of the page.
+func readPrefixedLevels(data []byte, maxLevel, n int) ([]int, []byte, error) {
+	return readPrefixedHybrid(data, bitWidth(maxLevel), n)
+}
+
+// readLevels decodes the levels of a data page.
+func readLevels(data []byte, maxLevel, n int) ([]int, error) {
+	return readHybrid(data, bitWidth(maxLevel), n)
+}
+
+// bitWidth returns the number of bits needed to encode values up to max.
+func bitWidth(max int) uint {
+	var width uint
+	for ; max > 0; max >>= 1 {
+		width++
+	}
+	return width
+}
+
+// readPrefixedHybrid decodes values encoded with the RLE and bit-packing
+// hybrid encoding, prefixed with their size, and returns the data following
+// them.
+func readPrefixedHybrid(data []byte, width uint, n int) ([]int, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, ErrInvalidFile
+	}
+	size := binary.LittleEndian.Uint32(data)
+	if uint64(size) > uint64(len(data)-4) {
+		return nil, nil, ErrInvalidFile
+	}
+	values, err := readHybrid(data[4:4+size], width, n)
+	return values, data[4+size:], err
+}
+
+// readHybrid decodes n values encoded with the RLE and bit-packing hybrid
+// encoding, where each value is width bits wide.
+func readHybrid(data []byte, width uint, n int) ([]int, error) {
+	if width > 32 {
+		return nil, ErrInvalidFile
+	}
+	values := make([]int, 0, n)
+	byteWidth := int(width+7) / 8
+	for len(values) < n {
+		header, read := binary.Uvarint(data)
+		if read <= 0 {
+			return nil, ErrInvalidFile
+		}
+		data = data[read:]
+		if header&1 == 0 {
+			if len(data) < byteWidth {
+				return nil, ErrInvalidFile
+			}
+			value := 0
+			for i := 0; i < byteWidth; i++ {
+				value |= int(data[i]) << (8 * uint(i))
+			}
+			data = data[byteWidth:]
+			for count := header >> 1; count > 0 && len(values) < n; count-- {
+				values = append(values, value)
+			}
+		} else {
+			count := int(header>>1) * 8
+			packed := data
+			if size := count * int(width) / 8; size <= len(data) {
+				packed, data = data[:size], data[size:]
+			} else {
+				data = nil
+			}
+			for i := 0; i < count && len(values) < n; i++ {
+				value, ok := unpack(packed, width, i)
+				if !ok {
+					return nil, ErrInvalidFile
+				}
+				values = append(values, int(value))
+			}
+		}
+	}
+	return values, nil
+}
+
+// unpack returns the i-th value of width bits in bit-packed data, where
+// values are packed from the least significant bit of each byte. It returns
+// false if the data is too short.
+func unpack(packed []byte, width uint, i int) (uint64, bool) {
+	var value uint64
+	position := uint(i) * width
+	for bit := uint(0); bit < width; bit++ {
+		if position/8 >= uint(len(packed)) {
+			return 0, false
+		}
+		value |= uint64(packed[position/8]>>(position%8)&1) << bit
+		position++
+	}
+	return value, true
+}
+
+// readDeltaIntegers decodes delta encoded integer values.
+func readDeltaIntegers(data []byte, column *Column, n int) ([]Value, error) {
+	if column.Type != Int32 && column.Type != Int64 {
+		return nil, ErrUnsupportedEncoding
+	}
+	integers, _, err := readDeltaBinaryPacked(data, n)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]Value, n)
+	for i, integer := range integers {
+		if column.Type == Int32 {
+			values[i].bits = uint64(uint32(integer))
+		} else {
+			values[i].bits = uint64(integer)
+		}
+	}
+	return values, nil
+}
+
+// readDeltaLengthByteArrays decodes byte arrays whose delta encoded lengths
+// precede their concatenated contents.
+func readDeltaLengthByteArrays(data []byte, column *Column, n int) ([]Value, error) {
+	if column.Type != ByteArray {
+		return nil, ErrUnsupportedEncoding
+	}
+	lengths, data, err := readDeltaBinaryPacked(data, n)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]Value, n)
+	for i, length := range lengths {
+		if length < 0 || length > int64(len(data)) {
+			return nil, ErrInvalidFile
+		}
+		values[i].bytes, data = data[:length], data[length:]
+	}
+	return values, nil
+}
+
+// readDeltaByteArrays decodes byte arrays stored as the length of the prefix
+// they share with the previous one, followed by their suffix.
+func readDeltaByteArrays(data []byte, column *Column, n int) ([]Value, error) {
+	if column.Type != ByteArray && column.Type != FixedLenByteArray {
+		return nil, ErrUnsupportedEncoding
+	}
+	prefixes, data, err := readDeltaBinaryPacked(data, n)
+	if err != nil {
+		return nil, err
+	}
+	suffixes, err := readDeltaLengthByteArrays(data, &Column{Type: ByteArray}, n)
+	if err != nil {
+		return nil, err
+	}
+	var previous []byte
+	for i, prefix := range prefixes {
+		if prefix < 0 || prefix > int64(len(previous)) {
+			return nil, ErrInvalidFile
+		}
+		value := make([]byte, 0, int(prefix)+len(suffixes[i].bytes))
+		value = append(append(value, previous[:prefix]...), suffixes[i].bytes...)
+		suffixes[i].bytes, previous = value, value
+	}
+	return suffixes, nil
+}
+
+// readDeltaBinaryPacked decodes n integers encoded as the differences between
+// consecutive values, bit-packed by miniblocks. It returns the data following
+// them.
+func readDeltaBinaryPacked(data []byte, n int) ([]int64, []byte, error) {
+	var header [3]uint64
+	for i := range header {
+		v, read := binary.Uvarint(data)
+		if read <= 0 {
+			return nil, nil, ErrInvalidFile
+		}
+		header[i], data = v, data[read:]
+	}
+	blockSize, miniblocks, total := header[0], header[1], header[2]
+	first, read := binary.Varint(data)
+	if read <= 0 || miniblocks == 0 || blockSize%miniblocks != 0 || total != uint64(n) {
+		return nil, nil, ErrInvalidFile
+	}
+	data = data[read:]
+	if n == 0 {
+		return nil, data, nil
+	}
+	miniblockSize := int(blockSize / miniblocks)
+	values := make([]int64, 1, n)
+	values[0] = first
+	for len(values) < n {
+		minDelta, read := binary.Varint(data)
+		if read <= 0 || uint64(len(data)-read) < miniblocks {
+			return nil, nil, ErrInvalidFile
+		}
+		widths := data[read : read+int(miniblocks)]
+		data = data[read+int(miniblocks):]
+		for _, width := range widths {
+			if len(values) == n {
+				break
+			} else if width > 64 {
+				return nil, nil, ErrInvalidFile
+			}
+			size := miniblockSize * int(width) / 8
+			if size > len(data) {
+				return nil, nil, ErrInvalidFile
+			}
+			for i := 0; i < miniblockSize && len(values) < n; i++ {
+				delta, _ := unpack(data[:size], uint(width), i)
+				values = append(values, values[len(values)-1]+minDelta+int64(delta))
+			}
+			data = data[size:]
+		}
+	}
+	return values, data, nil
+}
+
+// decompress decompresses a page to its uncompressed size.
+func decompress(codec int64, data []byte, size int) ([]byte, error) {
+	if size < 0 {
+		return nil, ErrInvalidFile
+	}
+	switch codec {
+	case codecUncompressed:
+		return data, nil
+	case codecSnappy:
+		if decodedSize, err := snappy.DecodedLen(data); err != nil || decodedSize != size {
+			return nil, ErrInvalidFile
+		}
+		return snappy.Decode(make([]byte, size), data)
+	case codecGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		decompressed := make([]byte, size)
+		if _, err := io.ReadFull(r, decompressed); err != nil {
+			return nil, err
+		}
+		return decompressed, nil
+	default:
+		return nil, ErrUnsupportedCodec
+	}
+}
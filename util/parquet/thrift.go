@@ -0,0 +1,216 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package parquet
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// Types of the Thrift compact protocol, which Parquet uses to encode its
+// metadata and page headers.
+const (
+	thriftStop         = 0
+	thriftBooleanTrue  = 1
+	thriftBooleanFalse = 2
+	thriftByte         = 3
+	thriftI16          = 4
+	thriftI32          = 5
+	thriftI64          = 6
+	thriftDouble       = 7
+	thriftBinary       = 8
+	thriftList         = 9
+	thriftSet          = 10
+	thriftMap          = 11
+	thriftStruct       = 12
+)
+
+// maxThriftDepth bounds the nesting of the Thrift structures decoded from a
+// file, so that a corrupted file cannot exhaust the stack.
+const maxThriftDepth = 32
+
+// thriftStructure is a decoded Thrift structure, mapping field identifiers to
+// their values. Integers are decoded as int64, booleans as bool, binaries as
+// []byte, lists and sets as []interface{} and structures as thriftStructure.
+type thriftStructure map[int16]interface{}
+
+// int returns the integer value of a field, or def if it is absent.
+func (s thriftStructure) int(id int16, def int64) int64 {
+	if v, ok := s[id].(int64); ok {
+		return v
+	}
+	return def
+}
+
+// bool returns the boolean value of a field, or def if it is absent.
+func (s thriftStructure) bool(id int16, def bool) bool {
+	if v, ok := s[id].(bool); ok {
+		return v
+	}
+	return def
+}
+
+// string returns the binary value of a field as a string.
+func (s thriftStructure) string(id int16) string {
+	v, _ := s[id].([]byte)
+	return string(v)
+}
+
+// structure returns the structure value of a field, or nil if it is absent.
+func (s thriftStructure) structure(id int16) thriftStructure {
+	v, _ := s[id].(thriftStructure)
+	return v
+}
+
+// list returns the list value of a field, or nil if it is absent.
+func (s thriftStructure) list(id int16) []interface{} {
+	v, _ := s[id].([]interface{})
+	return v
+}
+
+// thriftReader is what Thrift structures are decoded from.
+type thriftReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// readThriftStructure decodes a Thrift structure encoded with the compact
+// protocol.
+func readThriftStructure(r thriftReader) (thriftStructure, error) {
+	return readThriftStructureAt(r, 0)
+}
+
+func readThriftStructureAt(r thriftReader, depth int) (thriftStructure, error) {
+	if depth > maxThriftDepth {
+		return nil, ErrInvalidFile
+	}
+	s := make(thriftStructure)
+	var id int16
+	for {
+		header, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		t := header & 0x0f
+		if t == thriftStop {
+			return s, nil
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else if v, err := binary.ReadVarint(r); err != nil {
+			return nil, err
+		} else {
+			id = int16(v)
+		}
+		if t == thriftBooleanTrue || t == thriftBooleanFalse {
+			s[id] = t == thriftBooleanTrue
+		} else if s[id], err = readThriftValue(r, t, depth); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readThriftValue decodes a value of type t.
+func readThriftValue(r thriftReader, t byte, depth int) (interface{}, error) {
+	switch t {
+	case thriftBooleanTrue, thriftBooleanFalse:
+		// Booleans in collections are encoded on a byte of their own.
+		b, err := r.ReadByte()
+		return b == thriftBooleanTrue, err
+	case thriftByte:
+		b, err := r.ReadByte()
+		return int64(int8(b)), err
+	case thriftI16, thriftI32, thriftI64:
+		return binary.ReadVarint(r)
+	case thriftDouble:
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[:])), nil
+	case thriftBinary:
+		return readThriftBinary(r)
+	case thriftList, thriftSet:
+		return readThriftList(r, depth)
+	case thriftMap:
+		return nil, skipThriftMap(r, depth)
+	case thriftStruct:
+		return readThriftStructureAt(r, depth+1)
+	default:
+		return nil, ErrInvalidFile
+	}
+}
+
+// readThriftBinary decodes a length-prefixed binary.
+func readThriftBinary(r thriftReader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	} else if size > math.MaxInt32 {
+		return nil, ErrInvalidFile
+	}
+	b := make([]byte, size)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
+
+// readThriftList decodes a list or a set.
+func readThriftList(r thriftReader, depth int) ([]interface{}, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	size := uint64(header >> 4)
+	if size == 0x0f {
+		if size, err = binary.ReadUvarint(r); err != nil {
+			return nil, err
+		}
+	}
+	if size > math.MaxInt32 {
+		return nil, ErrInvalidFile
+	}
+	var list []interface{}
+	for i := uint64(0); i < size; i++ {
+		v, err := readThriftValue(r, header&0x0f, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// skipThriftMap reads a map, which Parquet only uses in fields this package
+// does not need.
+func skipThriftMap(r thriftReader, depth int) error {
+	size, err := binary.ReadUvarint(r)
+	if err != nil || size == 0 {
+		return err
+	}
+	types, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < size; i++ {
+		if _, err := readThriftValue(r, types>>4, depth+1); err != nil {
+			return err
+		}
+		if _, err := readThriftValue(r, types&0x0f, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
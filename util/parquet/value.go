@@ -0,0 +1,75 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package parquet
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Value is a value read from a column. Its accessors must match the type of
+// the column it was read from.
+type Value struct {
+	null  bool
+	bits  uint64
+	bytes []byte
+}
+
+// IsNull tells whether the value is null.
+func (v Value) IsNull() bool {
+	return v.null
+}
+
+// Boolean returns the value of a Boolean column.
+func (v Value) Boolean() bool {
+	return v.bits != 0
+}
+
+// Int32 returns the value of an Int32 column.
+func (v Value) Int32() int32 {
+	return int32(uint32(v.bits))
+}
+
+// Int64 returns the value of an Int64 column.
+func (v Value) Int64() int64 {
+	return int64(v.bits)
+}
+
+// Int96 returns the value of an Int96 column, as three little-endian 32 bit
+// words.
+func (v Value) Int96() [3]uint32 {
+	var i [3]uint32
+	if len(v.bytes) == 12 {
+		for j := range i {
+			i[j] = binary.LittleEndian.Uint32(v.bytes[j*4:])
+		}
+	}
+	return i
+}
+
+// Float returns the value of a Float column.
+func (v Value) Float() float32 {
+	return math.Float32frombits(uint32(v.bits))
+}
+
+// Double returns the value of a Double column.
+func (v Value) Double() float64 {
+	return math.Float64frombits(v.bits)
+}
+
+// ByteArray returns the value of a ByteArray or FixedLenByteArray column.
+func (v Value) ByteArray() []byte {
+	return v.bytes
+}
@@ -0,0 +1,109 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package util
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrAddressNotPublic = errors.New("address is not public")
+
+// nonPublicNetworks are the networks the URLs given by users cannot reach:
+// loopback, link local (including the instance metadata service), private,
+// shared and multicast addresses.
+var nonPublicNetworks = mustParseNetworks(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+func mustParseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// IsPublicAddress returns true if ip is not in one of nonPublicNetworks.
+func IsPublicAddress(ip net.IP) bool {
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPublicHost returns false if host is obviously not public: localhost or a
+// non public IP literal. Hostnames resolving to non public addresses are
+// rejected when connecting, by PublicAddressControl.
+func IsPublicHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	} else if ip := net.ParseIP(host); ip != nil && !IsPublicAddress(ip) {
+		return false
+	}
+	return true
+}
+
+// PublicAddressControl is a net.Dialer Control function which prevents
+// connections to non public addresses.
+func PublicAddressControl(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	} else if ip := net.ParseIP(host); ip == nil || !IsPublicAddress(ip) {
+		return ErrAddressNotPublic
+	}
+	return nil
+}
+
+// PublicHTTPTransport returns an HTTP transport which only connects to public
+// addresses and does not use any proxy, so that the URLs given by users
+// cannot be used to reach internal services.
+func PublicHTTPTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   PublicAddressControl,
+	}
+	return &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
@@ -0,0 +1,53 @@
+//   Copyright 2017 MSolution.IO
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package util
+
+import (
+	"testing"
+)
+
+func TestIsPublicHost(t *testing.T) {
+	for host, public := range map[string]bool{
+		"localhost":              false,
+		"metadata.localhost.":    false,
+		"169.254.169.254":        false,
+		"10.0.0.12":              false,
+		"::1":                    false,
+		"::ffff:169.254.169.254": false,
+		"hooks.slack.com":        true,
+		"52.216.0.1":             true,
+	} {
+		if IsPublicHost(host) != public {
+			t.Errorf("Host %s should be public: %t.", host, public)
+		}
+	}
+}
+
+func TestPublicAddressControl(t *testing.T) {
+	for address, public := range map[string]bool{
+		"169.254.169.254:80": false,
+		"127.0.0.1:9000":     false,
+		"192.168.1.1:443":    false,
+		"172.20.0.5:9000":    false,
+		"[fd00::1]:443":      false,
+		"[fe80::1]:443":      false,
+		"52.216.0.1:443":     true,
+		"[2600:1f18::1]:443": true,
+	} {
+		if err := PublicAddressControl("tcp", address, nil); (err == nil) != public {
+			t.Errorf("Address %s should be public: %t, got %v.", address, public, err)
+		}
+	}
+}